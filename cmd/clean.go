@@ -0,0 +1,125 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/mona-actions/gh-migrate-releases/pkg/clean"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// cleanCmd represents the clean command
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Deletes releases from a target repository that were migrated from a source repository",
+	Long:  "Deletes releases from a target repository that were migrated from a source repository",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get parameters
+		sourceOrganization := cmd.Flag("source-organization").Value.String()
+		targetOrganization := cmd.Flag("target-organization").Value.String()
+		sourceToken := cmd.Flag("source-token").Value.String()
+		sourceTokenFile := cmd.Flag("source-token-file").Value.String()
+		sourceAppID := cmd.Flag("source-app-id").Value.String()
+		sourceAppInstallationID := cmd.Flag("source-app-installation-id").Value.String()
+		sourceAppPrivateKeyFile := cmd.Flag("source-app-private-key-file").Value.String()
+		targetToken := cmd.Flag("target-token").Value.String()
+		targetTokenFile := cmd.Flag("target-token-file").Value.String()
+		targetAppID := cmd.Flag("target-app-id").Value.String()
+		targetAppInstallationID := cmd.Flag("target-app-installation-id").Value.String()
+		targetAppPrivateKeyFile := cmd.Flag("target-app-private-key-file").Value.String()
+		ghSourceHostname := cmd.Flag("source-hostname").Value.String()
+		ghTargetHostname := cmd.Flag("target-hostname").Value.String()
+		repository := cmd.Flag("repository").Value.String()
+		tagPrefix := cmd.Flag("tag-prefix").Value.String()
+		deleteTags := cmd.Flag("delete-tags").Value.String()
+		dryRun := cmd.Flag("dry-run").Value.String()
+		confirm := cmd.Flag("confirm").Value.String()
+
+		// Set ENV variables
+		os.Setenv("GHMT_SOURCE_ORGANIZATION", sourceOrganization)
+		os.Setenv("GHMT_TARGET_ORGANIZATION", targetOrganization)
+		os.Setenv("GHMT_SOURCE_TOKEN", sourceToken)
+		os.Setenv("GHMT_SOURCE_TOKEN_FILE", sourceTokenFile)
+		os.Setenv("GHMT_SOURCE_APP_ID", sourceAppID)
+		os.Setenv("GHMT_SOURCE_APP_INSTALLATION_ID", sourceAppInstallationID)
+		os.Setenv("GHMT_SOURCE_APP_PRIVATE_KEY_FILE", sourceAppPrivateKeyFile)
+		os.Setenv("GHMT_TARGET_TOKEN", targetToken)
+		os.Setenv("GHMT_TARGET_TOKEN_FILE", targetTokenFile)
+		os.Setenv("GHMT_TARGET_APP_ID", targetAppID)
+		os.Setenv("GHMT_TARGET_APP_INSTALLATION_ID", targetAppInstallationID)
+		os.Setenv("GHMT_TARGET_APP_PRIVATE_KEY_FILE", targetAppPrivateKeyFile)
+		os.Setenv("GHMT_SOURCE_HOSTNAME", ghSourceHostname)
+		os.Setenv("GHMT_TARGET_HOSTNAME", ghTargetHostname)
+		os.Setenv("GHMT_REPOSITORY", repository)
+		os.Setenv("GHMT_TAG_PREFIX", tagPrefix)
+		os.Setenv("GHMT_DELETE_TAGS", deleteTags)
+		os.Setenv("GHMT_DRY_RUN", dryRun)
+		os.Setenv("GHMT_CONFIRM", confirm)
+
+		// Bind ENV variables in Viper
+		viper.BindEnv("SOURCE_ORGANIZATION")
+		viper.BindEnv("TARGET_ORGANIZATION")
+		viper.BindEnv("SOURCE_TOKEN")
+		viper.BindEnv("SOURCE_TOKEN_FILE")
+		viper.BindEnv("SOURCE_APP_ID")
+		viper.BindEnv("SOURCE_APP_INSTALLATION_ID")
+		viper.BindEnv("SOURCE_APP_PRIVATE_KEY_FILE")
+		viper.BindEnv("TARGET_TOKEN")
+		viper.BindEnv("TARGET_TOKEN_FILE")
+		viper.BindEnv("TARGET_APP_ID")
+		viper.BindEnv("TARGET_APP_INSTALLATION_ID")
+		viper.BindEnv("TARGET_APP_PRIVATE_KEY_FILE")
+		viper.BindEnv("SOURCE_HOSTNAME")
+		viper.BindEnv("TARGET_HOSTNAME")
+		viper.BindEnv("REPOSITORY")
+		viper.BindEnv("TAG_PREFIX")
+		viper.BindEnv("DELETE_TAGS")
+		viper.BindEnv("DRY_RUN")
+		viper.BindEnv("CONFIRM")
+
+		// Call cleanreleases
+		err := clean.CleanReleases()
+		if err != nil {
+			pterm.Error.Printf("Error cleaning releases: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	// Flags
+	cleanCmd.Flags().StringP("source-organization", "s", "", "Source Organization the releases were migrated from")
+	cleanCmd.MarkFlagRequired("source-organization")
+
+	cleanCmd.Flags().StringP("target-organization", "t", "", "Target Organization to delete releases from")
+	cleanCmd.MarkFlagRequired("target-organization")
+
+	cleanCmd.Flags().StringP("source-token", "a", "", "Source Organization GitHub token. Scopes: read:org, read:user, user:email")
+	cleanCmd.Flags().String("source-token-file", "", "File to read the source token from instead of --source-token")
+	cleanCmd.Flags().Int64("source-app-id", 0, "ID of the GitHub App authenticating to the source instead of --source-token")
+	cleanCmd.Flags().Int64("source-app-installation-id", 0, "ID of the installation of the source GitHub App in the source organization")
+	cleanCmd.Flags().String("source-app-private-key-file", "", "PEM private key file of the source GitHub App")
+
+	cleanCmd.Flags().StringP("target-token", "b", "", "Target Organization GitHub token. Scopes: admin:org")
+	cleanCmd.Flags().String("target-token-file", "", "File to read the target token from instead of --target-token")
+	cleanCmd.Flags().Int64("target-app-id", 0, "ID of the GitHub App authenticating to the target instead of --target-token")
+	cleanCmd.Flags().Int64("target-app-installation-id", 0, "ID of the installation of the target GitHub App in the target organization")
+	cleanCmd.Flags().String("target-app-private-key-file", "", "PEM private key file of the target GitHub App")
+
+	cleanCmd.Flags().StringP("repository", "r", "", "repository whose migrated releases should be deleted")
+	cleanCmd.MarkFlagRequired("repository")
+
+	cleanCmd.Flags().StringP("source-hostname", "u", "", "GitHub Enterprise source hostname url (optional) Ex. github.example.com")
+	cleanCmd.Flags().StringP("target-hostname", "v", "", "GitHub Enterprise target hostname url (optional) Ex. github.example.com")
+
+	cleanCmd.Flags().String("tag-prefix", "", "Tag prefix the releases were migrated with, e.g. {{.Repository}}/")
+	cleanCmd.Flags().Bool("delete-tags", false, "Also delete the tags of the deleted releases")
+	cleanCmd.Flags().Bool("dry-run", false, "Only list the releases that would be deleted")
+	cleanCmd.Flags().Bool("confirm", false, "Confirm deletion of the matching releases in the target repository")
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/mona-actions/gh-migrate-releases/pkg/doctor"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks that tokens, hostnames and the mapping file are ready for a migration",
+	Long:  "Checks that tokens, hostnames and the mapping file are ready for a migration",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get parameters
+		sourceOrganization := cmd.Flag("source-organization").Value.String()
+		targetOrganization := cmd.Flag("target-organization").Value.String()
+		sourceToken := cmd.Flag("source-token").Value.String()
+		targetToken := cmd.Flag("target-token").Value.String()
+		ghSourceHostname := cmd.Flag("source-hostname").Value.String()
+		ghTargetHostname := cmd.Flag("target-hostname").Value.String()
+		sourceAPIURL := cmd.Flag("source-api-url").Value.String()
+		sourceUploadURL := cmd.Flag("source-upload-url").Value.String()
+		targetAPIURL := cmd.Flag("target-api-url").Value.String()
+		targetUploadURL := cmd.Flag("target-upload-url").Value.String()
+		repository := cmd.Flag("repository").Value.String()
+		mappingFile := cmd.Flag("mapping-file").Value.String()
+		sourceTokenFile := cmd.Flag("source-token-file").Value.String()
+		targetTokenFile := cmd.Flag("target-token-file").Value.String()
+		sourceAppID := cmd.Flag("source-app-id").Value.String()
+		sourceAppInstallationID := cmd.Flag("source-app-installation-id").Value.String()
+		sourceAppPrivateKeyFile := cmd.Flag("source-app-private-key-file").Value.String()
+		targetAppID := cmd.Flag("target-app-id").Value.String()
+		targetAppInstallationID := cmd.Flag("target-app-installation-id").Value.String()
+		targetAppPrivateKeyFile := cmd.Flag("target-app-private-key-file").Value.String()
+
+		// Set ENV variables
+		os.Setenv("GHMT_SOURCE_ORGANIZATION", sourceOrganization)
+		os.Setenv("GHMT_TARGET_ORGANIZATION", targetOrganization)
+		os.Setenv("GHMT_SOURCE_TOKEN", sourceToken)
+		os.Setenv("GHMT_TARGET_TOKEN", targetToken)
+		os.Setenv("GHMT_SOURCE_HOSTNAME", ghSourceHostname)
+		os.Setenv("GHMT_TARGET_HOSTNAME", ghTargetHostname)
+		os.Setenv("GHMT_SOURCE_API_URL", sourceAPIURL)
+		os.Setenv("GHMT_SOURCE_UPLOAD_URL", sourceUploadURL)
+		os.Setenv("GHMT_TARGET_API_URL", targetAPIURL)
+		os.Setenv("GHMT_TARGET_UPLOAD_URL", targetUploadURL)
+		os.Setenv("GHMT_REPOSITORY", repository)
+		os.Setenv("GHMT_MAPPING_FILE", mappingFile)
+		os.Setenv("GHMT_SOURCE_TOKEN_FILE", sourceTokenFile)
+		os.Setenv("GHMT_TARGET_TOKEN_FILE", targetTokenFile)
+		os.Setenv("GHMT_SOURCE_APP_ID", sourceAppID)
+		os.Setenv("GHMT_SOURCE_APP_INSTALLATION_ID", sourceAppInstallationID)
+		os.Setenv("GHMT_SOURCE_APP_PRIVATE_KEY_FILE", sourceAppPrivateKeyFile)
+		os.Setenv("GHMT_TARGET_APP_ID", targetAppID)
+		os.Setenv("GHMT_TARGET_APP_INSTALLATION_ID", targetAppInstallationID)
+		os.Setenv("GHMT_TARGET_APP_PRIVATE_KEY_FILE", targetAppPrivateKeyFile)
+
+		// Bind ENV variables in Viper
+		viper.BindEnv("SOURCE_ORGANIZATION")
+		viper.BindEnv("TARGET_ORGANIZATION")
+		viper.BindEnv("SOURCE_TOKEN")
+		viper.BindEnv("TARGET_TOKEN")
+		viper.BindEnv("SOURCE_HOSTNAME")
+		viper.BindEnv("TARGET_HOSTNAME")
+		viper.BindEnv("SOURCE_API_URL")
+		viper.BindEnv("SOURCE_UPLOAD_URL")
+		viper.BindEnv("TARGET_API_URL")
+		viper.BindEnv("TARGET_UPLOAD_URL")
+		viper.BindEnv("REPOSITORY")
+		viper.BindEnv("MAPPING_FILE")
+		viper.BindEnv("SOURCE_TOKEN_FILE")
+		viper.BindEnv("TARGET_TOKEN_FILE")
+		viper.BindEnv("SOURCE_APP_ID")
+		viper.BindEnv("SOURCE_APP_INSTALLATION_ID")
+		viper.BindEnv("SOURCE_APP_PRIVATE_KEY_FILE")
+		viper.BindEnv("TARGET_APP_ID")
+		viper.BindEnv("TARGET_APP_INSTALLATION_ID")
+		viper.BindEnv("TARGET_APP_PRIVATE_KEY_FILE")
+
+		// Call rundoctor
+		err := doctor.RunDoctor()
+		if err != nil {
+			pterm.Error.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Flags
+	doctorCmd.Flags().StringP("source-organization", "s", "", "Source Organization to sync releases from")
+
+	doctorCmd.Flags().StringP("target-organization", "t", "", "Target Organization to sync releases to")
+	doctorCmd.MarkFlagRequired("target-organization")
+
+	doctorCmd.Flags().StringP("source-token", "a", "", "Source Organization GitHub token. Scopes: read:org, read:user, user:email")
+
+	doctorCmd.Flags().StringP("target-token", "b", "", "Target Organization GitHub token. Scopes: admin:org")
+
+	doctorCmd.Flags().String("source-token-file", "", "File to read the source token from instead of --source-token")
+	doctorCmd.Flags().String("target-token-file", "", "File to read the target token from instead of --target-token")
+	doctorCmd.Flags().Int64("source-app-id", 0, "ID of the GitHub App authenticating to the source instead of --source-token")
+	doctorCmd.Flags().Int64("source-app-installation-id", 0, "ID of the installation of the source GitHub App in the source organization")
+	doctorCmd.Flags().String("source-app-private-key-file", "", "PEM private key file of the source GitHub App")
+	doctorCmd.Flags().Int64("target-app-id", 0, "ID of the GitHub App authenticating to the target instead of --target-token")
+	doctorCmd.Flags().Int64("target-app-installation-id", 0, "ID of the installation of the target GitHub App in the target organization")
+	doctorCmd.Flags().String("target-app-private-key-file", "", "PEM private key file of the target GitHub App")
+
+	doctorCmd.Flags().StringP("repository", "r", "", "repository to check, as name or owner/name")
+	doctorCmd.MarkFlagRequired("repository")
+
+	doctorCmd.Flags().StringP("mapping-file", "m", "", "Mapping file path to validate")
+
+	doctorCmd.Flags().StringP("source-hostname", "u", "", "GitHub Enterprise source hostname url (optional) Ex. github.example.com")
+	doctorCmd.Flags().StringP("target-hostname", "v", "", "GitHub Enterprise target hostname url (optional) Ex. github.example.com")
+
+	doctorCmd.Flags().String("source-api-url", "", "Full source API base URL used instead of the one derived from --source-hostname, e.g. https://github.example.com/github/api/v3")
+	doctorCmd.Flags().String("source-upload-url", "", "Full source uploads base URL used instead of the one derived from --source-hostname")
+	doctorCmd.Flags().String("target-api-url", "", "Full target API base URL used instead of the one derived from --target-hostname, e.g. https://github.example.com/github/api/v3")
+	doctorCmd.Flags().String("target-upload-url", "", "Full target uploads base URL used instead of the one derived from --target-hostname")
+}
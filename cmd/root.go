@@ -6,6 +6,8 @@ package cmd
 import (
 	"os"
 
+	"github.com/mona-actions/gh-migrate-releases/internal/logging"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -39,6 +41,8 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 
+	rootCmd.PersistentFlags().String("log-format", "text", "Log output format: text, or json for one JSON object per line")
+
 	// Initialize Cobra
 	cobra.OnInitialize(initConfig)
 }
@@ -49,4 +53,14 @@ func initConfig() {
 
 	// Read in environment variables that match
 	viper.AutomaticEnv()
+
+	// Select the log format before any output
+	if logFormat := rootCmd.PersistentFlags().Lookup("log-format"); logFormat.Changed {
+		os.Setenv("GHMT_LOG_FORMAT", logFormat.Value.String())
+	}
+	viper.BindEnv("LOG_FORMAT")
+	if err := logging.Setup(viper.GetString("LOG_FORMAT")); err != nil {
+		pterm.Error.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 }
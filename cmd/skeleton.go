@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+
+	"github.com/mona-actions/gh-migrate-releases/pkg/skeleton"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// skeletonCmd represents the mapping-skeleton command
+var skeletonCmd = &cobra.Command{
+	Use:   "mapping-skeleton",
+	Short: "Creates a mapping file listing the source handles with blank target handles",
+	Long:  "Creates a mapping file listing the source release authors, and optionally commit authors, with blank target handles to fill in",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get parameters
+		sourceOrganization := cmd.Flag("source-organization").Value.String()
+		sourceToken := cmd.Flag("source-token").Value.String()
+		sourceTokenFile := cmd.Flag("source-token-file").Value.String()
+		sourceAppID := cmd.Flag("source-app-id").Value.String()
+		sourceAppInstallationID := cmd.Flag("source-app-installation-id").Value.String()
+		sourceAppPrivateKeyFile := cmd.Flag("source-app-private-key-file").Value.String()
+		ghSourceHostname := cmd.Flag("source-hostname").Value.String()
+		repository := cmd.Flag("repository").Value.String()
+		outputFile := cmd.Flag("output-file").Value.String()
+		includeCommitAuthors := cmd.Flag("include-commit-authors").Value.String()
+
+		// Set ENV variables
+		os.Setenv("GHMT_SOURCE_ORGANIZATION", sourceOrganization)
+		os.Setenv("GHMT_SOURCE_TOKEN", sourceToken)
+		os.Setenv("GHMT_SOURCE_TOKEN_FILE", sourceTokenFile)
+		os.Setenv("GHMT_SOURCE_APP_ID", sourceAppID)
+		os.Setenv("GHMT_SOURCE_APP_INSTALLATION_ID", sourceAppInstallationID)
+		os.Setenv("GHMT_SOURCE_APP_PRIVATE_KEY_FILE", sourceAppPrivateKeyFile)
+		os.Setenv("GHMT_SOURCE_HOSTNAME", ghSourceHostname)
+		os.Setenv("GHMT_REPOSITORY", repository)
+		os.Setenv("GHMT_OUTPUT_FILE", outputFile)
+		os.Setenv("GHMT_INCLUDE_COMMIT_AUTHORS", includeCommitAuthors)
+
+		// Bind ENV variables in Viper
+		viper.BindEnv("SOURCE_ORGANIZATION")
+		viper.BindEnv("SOURCE_TOKEN")
+		viper.BindEnv("SOURCE_TOKEN_FILE")
+		viper.BindEnv("SOURCE_APP_ID")
+		viper.BindEnv("SOURCE_APP_INSTALLATION_ID")
+		viper.BindEnv("SOURCE_APP_PRIVATE_KEY_FILE")
+		viper.BindEnv("SOURCE_HOSTNAME")
+		viper.BindEnv("REPOSITORY")
+		viper.BindEnv("OUTPUT_FILE")
+		viper.BindEnv("INCLUDE_COMMIT_AUTHORS")
+
+		// Call createmappingskeleton
+		err := skeleton.CreateMappingSkeleton()
+		if err != nil {
+			pterm.Error.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(skeletonCmd)
+
+	// Flags
+	skeletonCmd.Flags().StringP("source-organization", "s", "", "Source Organization of the repository")
+	skeletonCmd.MarkFlagRequired("source-organization")
+
+	skeletonCmd.Flags().StringP("source-token", "a", "", "Source Organization GitHub token. Scopes: read:org, read:user, user:email")
+	skeletonCmd.Flags().String("source-token-file", "", "File to read the source token from instead of --source-token")
+	skeletonCmd.Flags().Int64("source-app-id", 0, "ID of the GitHub App authenticating to the source instead of --source-token")
+	skeletonCmd.Flags().Int64("source-app-installation-id", 0, "ID of the installation of the source GitHub App in the source organization")
+	skeletonCmd.Flags().String("source-app-private-key-file", "", "PEM private key file of the source GitHub App")
+
+	skeletonCmd.Flags().StringP("repository", "r", "", "repository to scan for handles")
+	skeletonCmd.MarkFlagRequired("repository")
+
+	skeletonCmd.Flags().StringP("source-hostname", "u", "", "GitHub Enterprise source hostname url (optional) Ex. github.example.com")
+
+	skeletonCmd.Flags().StringP("output-file", "o", "mapping-skeleton.csv", "File to write the mapping skeleton to")
+
+	skeletonCmd.Flags().Bool("include-commit-authors", false, "Also list the commit authors of the repository")
+}
@@ -4,9 +4,13 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/mona-actions/gh-migrate-releases/pkg/sync"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -27,6 +31,96 @@ var syncCmd = &cobra.Command{
 		repository := cmd.Flag("repository").Value.String()
 		mappingFile := cmd.Flag("mapping-file").Value.String()
 		repositoryList := cmd.Flag("repository-list-file").Value.String()
+		skipEmptyReleases := cmd.Flag("skip-empty-releases").Value.String()
+		prefetchConcurrency := cmd.Flag("prefetch-concurrency").Value.String()
+		prereleasesOnly := cmd.Flag("include-prereleases-only").Value.String()
+		stableOnly := cmd.Flag("include-stable-only").Value.String()
+		assetCache := cmd.Flag("asset-cache").Value.String()
+		replaceBrokenAssets := cmd.Flag("replace-broken-assets").Value.String()
+		failOnAssetError := cmd.Flag("fail-on-asset-error").Value.String()
+		neverMarkLatest := cmd.Flag("never-mark-latest").Value.String()
+		legacyLatest := cmd.Flag("legacy-latest").Value.String()
+		migrateAnnotatedTags := cmd.Flag("migrate-annotated-tags").Value.String()
+		incrementalIssueComments := cmd.Flag("incremental-issue-comments").Value.String()
+		migrateFields := cmd.Flag("migrate-fields").Value.String()
+		backend := cmd.Flag("backend").Value.String()
+		fakeScenario := cmd.Flag("fake-scenario").Value.String()
+		createDelay := cmd.Flag("create-delay").Value.String()
+		mappingStats := cmd.Flag("mapping-stats").Value.String()
+		bodyTemplate := cmd.Flag("body-template").Value.String()
+		maxRetries := cmd.Flag("max-retries").Value.String()
+		maxTotalRetries := cmd.Flag("max-total-retries").Value.String()
+		failuresFile := cmd.Flag("failures-file").Value.String()
+		rateLimitBytesPerSec := cmd.Flag("rate-limit-bytes-per-sec").Value.String()
+		createMissingTags := cmd.Flag("create-missing-tags").Value.String()
+		recordSourceIDs := cmd.Flag("record-source-ids").Value.String()
+		summaryFile := cmd.Flag("summary-file").Value.String()
+		matchAssetLabels := cmd.Flag("match-asset-labels").Value.String()
+		twoPhase := cmd.Flag("two-phase").Value.String()
+		phaseCheckpoint := cmd.Flag("phase-checkpoint").Value.String()
+		verifyUploads := cmd.Flag("verify-uploads").Value.String()
+		migrateAutolinks := cmd.Flag("migrate-autolinks").Value.String()
+		maxRepos := cmd.Flag("max-repos").Value.String()
+		missingCommitStrategy := cmd.Flag("missing-commit-strategy").Value.String()
+		tagPrefix := cmd.Flag("tag-prefix").Value.String()
+		dedupeAssets := cmd.Flag("dedupe-assets").Value.String()
+		sourceTokenFile := cmd.Flag("source-token-file").Value.String()
+		targetTokenFile := cmd.Flag("target-token-file").Value.String()
+		newerThanTarget := cmd.Flag("newer-than-target").Value.String()
+		customHeaders := cmd.Flag("custom-headers").Value.String()
+		preserveTargetLatest := cmd.Flag("preserve-target-latest").Value.String()
+		maxAssetSize := cmd.Flag("max-asset-size").Value.String()
+		oversizeAssetStrategy := cmd.Flag("oversize-asset-strategy").Value.String()
+		keepTmp := cmd.Flag("keep-tmp").Value.String()
+		preserveAssetOrder := cmd.Flag("preserve-asset-order").Value.String()
+		assetPerPage := cmd.Flag("asset-per-page").Value.String()
+		caseInsensitiveTags := cmd.Flag("case-insensitive-tags").Value.String()
+		downloadConcurrency := cmd.Flag("download-concurrency").Value.String()
+		uploadConcurrency := cmd.Flag("upload-concurrency").Value.String()
+		excludeRepositories := cmd.Flag("exclude-repositories").Value.String()
+		skipArchived := cmd.Flag("skip-archived").Value.String()
+		skipForks := cmd.Flag("skip-forks").Value.String()
+		checksumsFile := cmd.Flag("checksums-file").Value.String()
+		checksumsAlgorithm := cmd.Flag("checksums-algorithm").Value.String()
+		issueCommentInterval := cmd.Flag("issue-comment-interval").Value.String()
+		rateLimitRequestsPerSec := cmd.Flag("rate-limit-requests-per-sec").Value.String()
+		sourceAPIURL := cmd.Flag("source-api-url").Value.String()
+		sourceUploadURL := cmd.Flag("source-upload-url").Value.String()
+		targetAPIURL := cmd.Flag("target-api-url").Value.String()
+		targetUploadURL := cmd.Flag("target-upload-url").Value.String()
+		repoDelay := cmd.Flag("repo-delay").Value.String()
+		repoDelayJitter := cmd.Flag("repo-delay-jitter").Value.String()
+		acceptExistingAssets := cmd.Flag("accept-existing-assets").Value.String()
+		assetNameTemplate := cmd.Flag("asset-name-template").Value.String()
+		updateExisting := cmd.Flag("update-existing").Value.String()
+		requireEnterprise := cmd.Flag("require-enterprise").Value.String()
+		includeTagsWithoutReleases := cmd.Flag("include-tags-without-releases").Value.String()
+		startFromRepo := cmd.Flag("start-from-repo").Value.String()
+		linkSourceRelease := cmd.Flag("link-source-release").Value.String()
+		allowedContentTypes := cmd.Flag("allowed-content-types").Value.String()
+		failedAssetsFile := cmd.Flag("failed-assets-file").Value.String()
+		retryManifest := cmd.Flag("retry-manifest").Value.String()
+		autoConcurrency := cmd.Flag("auto-concurrency").Value.String()
+		autoConcurrencyMax := cmd.Flag("auto-concurrency-max").Value.String()
+		assetConcurrency := cmd.Flag("asset-concurrency").Value.String()
+		retryBaseDelay := cmd.Flag("retry-base-delay").Value.String()
+		verifyAssets := cmd.Flag("verify-assets").Value.String()
+		includeDrafts := cmd.Flag("include-drafts").Value.String()
+		publishDrafts := cmd.Flag("publish-drafts").Value.String()
+		tagFilter := cmd.Flag("tag-filter").Value.String()
+		tagExclude := cmd.Flag("tag-exclude").Value.String()
+		since := cmd.Flag("since").Value.String()
+		until := cmd.Flag("until").Value.String()
+		stateFile := cmd.Flag("state-file").Value.String()
+		tempDir := cmd.Flag("temp-dir").Value.String()
+		sourceAppID := cmd.Flag("source-app-id").Value.String()
+		sourceAppInstallationID := cmd.Flag("source-app-installation-id").Value.String()
+		sourceAppPrivateKeyFile := cmd.Flag("source-app-private-key-file").Value.String()
+		targetAppID := cmd.Flag("target-app-id").Value.String()
+		targetAppInstallationID := cmd.Flag("target-app-installation-id").Value.String()
+		targetAppPrivateKeyFile := cmd.Flag("target-app-private-key-file").Value.String()
+		authorAttribution := cmd.Flag("author-attribution").Value.String()
+		authorAttributionPosition := cmd.Flag("author-attribution-position").Value.String()
 
 		// Set ENV variables
 		os.Setenv("GHMT_SOURCE_ORGANIZATION", sourceOrganization)
@@ -38,6 +132,101 @@ var syncCmd = &cobra.Command{
 		os.Setenv("GHMT_REPOSITORY", repository)
 		os.Setenv("GHMT_MAPPING_FILE", mappingFile)
 		os.Setenv("GHMT_REPOSITORY_LIST", repositoryList)
+		os.Setenv("GHMT_SKIP_EMPTY_RELEASES", skipEmptyReleases)
+		os.Setenv("GHMT_PREFETCH_CONCURRENCY", prefetchConcurrency)
+		os.Setenv("GHMT_PRERELEASES_ONLY", prereleasesOnly)
+		os.Setenv("GHMT_STABLE_ONLY", stableOnly)
+		os.Setenv("GHMT_ASSET_CACHE", assetCache)
+		os.Setenv("GHMT_REPLACE_BROKEN_ASSETS", replaceBrokenAssets)
+		os.Setenv("GHMT_FAIL_ON_ASSET_ERROR", failOnAssetError)
+		os.Setenv("GHMT_NEVER_MARK_LATEST", neverMarkLatest)
+		os.Setenv("GHMT_LEGACY_LATEST", legacyLatest)
+		os.Setenv("GHMT_MIGRATE_ANNOTATED_TAGS", migrateAnnotatedTags)
+		os.Setenv("GHMT_INCREMENTAL_ISSUE_COMMENTS", incrementalIssueComments)
+		os.Setenv("GHMT_MIGRATE_FIELDS", migrateFields)
+		os.Setenv("GHMT_BACKEND", backend)
+		os.Setenv("GHMT_FAKE_SCENARIO", fakeScenario)
+		os.Setenv("GHMT_CREATE_DELAY", createDelay)
+		os.Setenv("GHMT_MAPPING_STATS", mappingStats)
+		os.Setenv("GHMT_BODY_TEMPLATE", bodyTemplate)
+		os.Setenv("GHMT_MAX_RETRIES", maxRetries)
+		os.Setenv("GHMT_MAX_TOTAL_RETRIES", maxTotalRetries)
+		os.Setenv("GHMT_FAILURES_FILE", failuresFile)
+		os.Setenv("GHMT_RATE_LIMIT_BYTES_PER_SEC", rateLimitBytesPerSec)
+		os.Setenv("GHMT_CREATE_MISSING_TAGS", createMissingTags)
+		os.Setenv("GHMT_RECORD_SOURCE_IDS", recordSourceIDs)
+		os.Setenv("GHMT_SUMMARY_FILE", summaryFile)
+		os.Setenv("GHMT_MATCH_ASSET_LABELS", matchAssetLabels)
+		os.Setenv("GHMT_TWO_PHASE", twoPhase)
+		os.Setenv("GHMT_PHASE_CHECKPOINT", phaseCheckpoint)
+		os.Setenv("GHMT_VERIFY_UPLOADS", verifyUploads)
+		os.Setenv("GHMT_MIGRATE_AUTOLINKS", migrateAutolinks)
+		os.Setenv("GHMT_MAX_REPOS", maxRepos)
+		os.Setenv("GHMT_MISSING_COMMIT_STRATEGY", missingCommitStrategy)
+		os.Setenv("GHMT_TAG_PREFIX", tagPrefix)
+		os.Setenv("GHMT_DEDUPE_ASSETS", dedupeAssets)
+		os.Setenv("GHMT_SOURCE_TOKEN_FILE", sourceTokenFile)
+		os.Setenv("GHMT_TARGET_TOKEN_FILE", targetTokenFile)
+		os.Setenv("GHMT_NEWER_THAN_TARGET", newerThanTarget)
+		os.Setenv("GHMT_CUSTOM_HEADERS", customHeaders)
+		os.Setenv("GHMT_PRESERVE_TARGET_LATEST", preserveTargetLatest)
+		os.Setenv("GHMT_MAX_ASSET_SIZE", maxAssetSize)
+		os.Setenv("GHMT_OVERSIZE_ASSET_STRATEGY", oversizeAssetStrategy)
+		os.Setenv("GHMT_KEEP_TMP", keepTmp)
+		os.Setenv("GHMT_PRESERVE_ASSET_ORDER", preserveAssetOrder)
+		os.Setenv("GHMT_ASSET_PER_PAGE", assetPerPage)
+		os.Setenv("GHMT_CASE_INSENSITIVE_TAGS", caseInsensitiveTags)
+		os.Setenv("GHMT_DOWNLOAD_CONCURRENCY", downloadConcurrency)
+		os.Setenv("GHMT_UPLOAD_CONCURRENCY", uploadConcurrency)
+		os.Setenv("GHMT_EXCLUDE_REPOSITORIES", excludeRepositories)
+		os.Setenv("GHMT_SKIP_ARCHIVED", skipArchived)
+		os.Setenv("GHMT_SKIP_FORKS", skipForks)
+		os.Setenv("GHMT_CHECKSUMS_FILE", checksumsFile)
+		os.Setenv("GHMT_CHECKSUMS_ALGORITHM", checksumsAlgorithm)
+
+		// The target confirmation can be set with GHMT_CONFIRM_TARGET, e.g. in CI
+		if confirmTarget := cmd.Flag("confirm-target"); confirmTarget.Changed {
+			os.Setenv("GHMT_CONFIRM_TARGET", confirmTarget.Value.String())
+		}
+		os.Setenv("GHMT_ISSUE_COMMENT_INTERVAL", issueCommentInterval)
+		os.Setenv("GHMT_RATE_LIMIT_REQUESTS_PER_SEC", rateLimitRequestsPerSec)
+		os.Setenv("GHMT_SOURCE_API_URL", sourceAPIURL)
+		os.Setenv("GHMT_SOURCE_UPLOAD_URL", sourceUploadURL)
+		os.Setenv("GHMT_TARGET_API_URL", targetAPIURL)
+		os.Setenv("GHMT_TARGET_UPLOAD_URL", targetUploadURL)
+		os.Setenv("GHMT_REPO_DELAY", repoDelay)
+		os.Setenv("GHMT_REPO_DELAY_JITTER", repoDelayJitter)
+		os.Setenv("GHMT_ACCEPT_EXISTING_ASSETS", acceptExistingAssets)
+		os.Setenv("GHMT_ASSET_NAME_TEMPLATE", assetNameTemplate)
+		os.Setenv("GHMT_UPDATE_EXISTING", updateExisting)
+		os.Setenv("GHMT_REQUIRE_ENTERPRISE", requireEnterprise)
+		os.Setenv("GHMT_INCLUDE_TAGS_WITHOUT_RELEASES", includeTagsWithoutReleases)
+		os.Setenv("GHMT_START_FROM_REPO", startFromRepo)
+		os.Setenv("GHMT_LINK_SOURCE_RELEASE", linkSourceRelease)
+		os.Setenv("GHMT_ALLOWED_CONTENT_TYPES", allowedContentTypes)
+		os.Setenv("GHMT_FAILED_ASSETS_FILE", failedAssetsFile)
+		os.Setenv("GHMT_RETRY_MANIFEST", retryManifest)
+		os.Setenv("GHMT_AUTO_CONCURRENCY", autoConcurrency)
+		os.Setenv("GHMT_AUTO_CONCURRENCY_MAX", autoConcurrencyMax)
+		os.Setenv("GHMT_ASSET_CONCURRENCY", assetConcurrency)
+		os.Setenv("GHMT_RETRY_BASE_DELAY", retryBaseDelay)
+		os.Setenv("GHMT_VERIFY_ASSETS", verifyAssets)
+		os.Setenv("GHMT_INCLUDE_DRAFTS", includeDrafts)
+		os.Setenv("GHMT_PUBLISH_DRAFTS", publishDrafts)
+		os.Setenv("GHMT_TAG_FILTER", tagFilter)
+		os.Setenv("GHMT_TAG_EXCLUDE", tagExclude)
+		os.Setenv("GHMT_SINCE", since)
+		os.Setenv("GHMT_UNTIL", until)
+		os.Setenv("GHMT_STATE_FILE", stateFile)
+		os.Setenv("GHMT_TEMP_DIR", tempDir)
+		os.Setenv("GHMT_SOURCE_APP_ID", sourceAppID)
+		os.Setenv("GHMT_SOURCE_APP_INSTALLATION_ID", sourceAppInstallationID)
+		os.Setenv("GHMT_SOURCE_APP_PRIVATE_KEY_FILE", sourceAppPrivateKeyFile)
+		os.Setenv("GHMT_TARGET_APP_ID", targetAppID)
+		os.Setenv("GHMT_TARGET_APP_INSTALLATION_ID", targetAppInstallationID)
+		os.Setenv("GHMT_TARGET_APP_PRIVATE_KEY_FILE", targetAppPrivateKeyFile)
+		os.Setenv("GHMT_AUTHOR_ATTRIBUTION", authorAttribution)
+		os.Setenv("GHMT_AUTHOR_ATTRIBUTION_POSITION", authorAttributionPosition)
 
 		// Bind ENV variables in Viper
 		viper.BindEnv("SOURCE_ORGANIZATION")
@@ -49,9 +238,63 @@ var syncCmd = &cobra.Command{
 		viper.BindEnv("REPOSITORY")
 		viper.BindEnv("MAPPING_FILE")
 		viper.BindEnv("REPOSITORY_LIST")
+		viper.BindEnv("SKIP_EMPTY_RELEASES")
+		viper.BindEnv("PREFETCH_CONCURRENCY")
+		viper.BindEnv("PRERELEASES_ONLY")
+		viper.BindEnv("STABLE_ONLY")
+		viper.BindEnv("ASSET_CACHE")
+		viper.BindEnv("REPLACE_BROKEN_ASSETS")
+		viper.BindEnv("FAIL_ON_ASSET_ERROR")
+		viper.BindEnv("NEVER_MARK_LATEST")
+		viper.BindEnv("LEGACY_LATEST")
+		viper.BindEnv("MIGRATE_ANNOTATED_TAGS")
+		viper.BindEnv("INCREMENTAL_ISSUE_COMMENTS")
+		viper.BindEnv("MIGRATE_FIELDS")
+		viper.BindEnv("BACKEND")
+		viper.BindEnv("FAKE_SCENARIO")
+		viper.BindEnv("CREATE_DELAY")
+		viper.BindEnv("MAPPING_STATS")
+		viper.BindEnv("BODY_TEMPLATE")
+		viper.BindEnv("MAX_RETRIES")
+		viper.BindEnv("MAX_TOTAL_RETRIES")
+		viper.BindEnv("FAILURES_FILE")
+		viper.BindEnv("RATE_LIMIT_BYTES_PER_SEC")
+		viper.BindEnv("CREATE_MISSING_TAGS")
+		viper.BindEnv("RECORD_SOURCE_IDS")
+		viper.BindEnv("SUMMARY_FILE")
+		viper.BindEnv("MATCH_ASSET_LABELS")
+		viper.BindEnv("TWO_PHASE")
+		viper.BindEnv("PHASE_CHECKPOINT")
+		viper.BindEnv("VERIFY_UPLOADS")
+		viper.BindEnv("MIGRATE_AUTOLINKS")
+		viper.BindEnv("MAX_REPOS")
+		viper.BindEnv("MISSING_COMMIT_STRATEGY")
+		viper.BindEnv("TAG_PREFIX")
+		viper.BindEnv("DEDUPE_ASSETS")
+		viper.BindEnv("SOURCE_TOKEN_FILE")
+		viper.BindEnv("TARGET_TOKEN_FILE")
+		viper.BindEnv("NEWER_THAN_TARGET")
+		viper.BindEnv("CUSTOM_HEADERS")
+		viper.BindEnv("PRESERVE_TARGET_LATEST")
+
+		// Stop after the current repository on the first interrupt, so the temp directory is
+		// removed, and exit right away on the next one
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		go func() {
+			<-interrupts
+			signal.Stop(interrupts)
+			pterm.Warning.Println("Interrupted, stopping after the current repository. Interrupt again to exit right away.")
+			cancel()
+		}()
 
-		// Call syncreleases
-		sync.SyncReleases()
+		// Build the migrator from the configuration and run it
+		if err := sync.SyncReleases(ctx); err != nil {
+			pterm.Error.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -65,10 +308,8 @@ func init() {
 	syncCmd.MarkFlagRequired("target-organization")
 
 	syncCmd.Flags().StringP("source-token", "a", "", "Source Organization GitHub token. Scopes: read:org, read:user, user:email")
-	syncCmd.MarkFlagRequired("source-token")
 
 	syncCmd.Flags().StringP("target-token", "b", "", "Target Organization GitHub token. Scopes: admin:org")
-	syncCmd.MarkFlagRequired("target-token")
 
 	syncCmd.Flags().StringP("repository", "r", "", "repository to export/import releases from/to; can't be used with --repository-list")
 
@@ -79,4 +320,181 @@ func init() {
 	syncCmd.Flags().StringP("source-hostname", "u", "", "GitHub Enterprise source hostname url (optional) Ex. github.example.com")
 	syncCmd.Flags().StringP("target-hostname", "v", "", "GitHub Enterprise target hostname url (optional) Ex. github.example.com")
 
+	syncCmd.Flags().Bool("skip-empty-releases", false, "Skip releases with no name, no body and no assets (tag-only releases)")
+
+	syncCmd.Flags().Int("prefetch-concurrency", 4, "Number of repositories from the repository list to fetch releases from concurrently")
+
+	syncCmd.Flags().Bool("include-prereleases-only", false, "Only migrate prereleases; can't be used with --include-stable-only")
+	syncCmd.Flags().Bool("include-stable-only", false, "Only migrate stable releases; can't be used with --include-prereleases-only")
+
+	syncCmd.Flags().Bool("asset-cache", false, "Cache downloaded assets by digest so identical assets are only downloaded once")
+
+	syncCmd.Flags().Bool("replace-broken-assets", false, "Delete and re-upload target assets left empty by a failed upload")
+
+	syncCmd.Flags().Bool("fail-on-asset-error", false, "Count a release as failed when any of its assets fails to migrate")
+
+	syncCmd.Flags().Bool("never-mark-latest", false, "Never mark a migrated release as latest; can't be used with --legacy-latest")
+	syncCmd.Flags().Bool("legacy-latest", false, "Let GitHub pick the latest release by date and version; can't be used with --never-mark-latest")
+
+	syncCmd.Flags().Bool("migrate-annotated-tags", false, "Recreate annotated tags with their original message and tagger before creating releases")
+
+	syncCmd.Flags().Bool("incremental-issue-comments", false, "In GitHub Actions, comment each repository result on the issue as soon as it completes")
+
+	syncCmd.Flags().String("migrate-fields", "", "Comma-separated release fields to migrate: body, name, draft, prerelease, discussion_category, make_latest, assets (default all)")
+
+	syncCmd.Flags().String("backend", "github", "Backend to migrate releases with: github, or fake for an in-memory simulation")
+	syncCmd.Flags().String("fake-scenario", "", "JSON scenario file configuring the fake backend latency, error rate and asset sizes")
+
+	syncCmd.Flags().Duration("create-delay", 0, "Minimum delay between release creations, e.g. 2s")
+
+	syncCmd.Flags().Bool("mapping-stats", false, "Report the substitutions made with the mapping file and the rules that never matched")
+
+	syncCmd.Flags().String("body-template", "", "Go template file used to render release bodies, with access to the release and source context")
+
+	syncCmd.Flags().Int("max-retries", 0, "Number of times a failed asset download or upload is retried")
+	syncCmd.Flags().Int("max-total-retries", 0, "Number of retries allowed across the whole run before failures fail fast (default unlimited)")
+
+	syncCmd.Flags().String("failures-file", "", "File to write the repositories with failed releases to, in the repository list format")
+
+	syncCmd.Flags().Int64("rate-limit-bytes-per-sec", 0, "Maximum bytes per second transferred by asset downloads and uploads (default unthrottled)")
+
+	syncCmd.Flags().Bool("create-missing-tags", false, "Create missing tags at the source commit before creating releases")
+
+	syncCmd.Flags().Bool("record-source-ids", false, "Record the source release ID and URL in the release body")
+
+	syncCmd.Flags().String("summary-file", "", "File to write the run summary to as JSON, including API requests and time spent per stage")
+
+	syncCmd.Flags().Bool("match-asset-labels", false, "Consider an asset with the same name and size but a different label as missing")
+
+	syncCmd.Flags().Bool("two-phase", false, "Create the releases of all repositories first, then migrate all assets")
+	syncCmd.Flags().String("phase-checkpoint", "phase-checkpoint.json", "File recording the progress of a --two-phase migration")
+
+	syncCmd.Flags().Bool("verify-uploads", false, "Check that each uploaded asset is complete before deleting the local copy")
+
+	syncCmd.Flags().Bool("migrate-autolinks", false, "Recreate the source repository autolink references in the target repository")
+
+	syncCmd.Flags().Int("max-repos", 0, "Only process the first N repositories of the repository list")
+
+	syncCmd.Flags().String("missing-commit-strategy", "fail", "How to create a release whose commit SHA is missing in the target: fail, skip, default-branch or draft")
+
+	syncCmd.Flags().String("tag-prefix", "", "Template prefixed to the tag of migrated releases, e.g. {{.Repository}}/")
+
+	syncCmd.Flags().Bool("dedupe-assets", false, "Download identical assets only once during the run, without keeping them between runs like --asset-cache")
+
+	syncCmd.Flags().String("source-token-file", "", "File to read the source token from instead of --source-token")
+
+	syncCmd.Flags().String("target-token-file", "", "File to read the target token from instead of --target-token")
+
+	syncCmd.Flags().Bool("newer-than-target", false, "Only migrate the releases published after the newest release of the target repository")
+
+	syncCmd.Flags().String("custom-headers", "", "Comma-separated Name=value headers added to every request, e.g. for a proxy")
+
+	syncCmd.Flags().Bool("preserve-target-latest", false, "Don't mark the source latest release as latest when the target already has a newer latest release")
+
+	syncCmd.Flags().Int64("max-asset-size", 2147483648, "Size in bytes from which an asset is too large to upload, handled by --oversize-asset-strategy")
+
+	syncCmd.Flags().String("oversize-asset-strategy", "skip", "How to handle an asset of --max-asset-size or larger: skip, split or fail")
+
+	syncCmd.Flags().Bool("keep-tmp", false, "Keep the downloaded assets in the temp directory after uploading them, for inspection")
+
+	syncCmd.Flags().Bool("preserve-asset-order", false, "Re-upload target assets out of the source order so the release lists its assets in the source order")
+
+	syncCmd.Flags().Int("asset-per-page", 100, "Number of assets listed per page, from 1 to 100, lower for slow instances")
+
+	syncCmd.Flags().Bool("case-insensitive-tags", false, "Match existing target releases by tag regardless of case, e.g. V1.0 and v1.0")
+
+	syncCmd.Flags().Int("download-concurrency", 1, "Number of assets of a release downloaded concurrently")
+
+	syncCmd.Flags().Int("upload-concurrency", 1, "Number of assets of a release uploaded concurrently")
+
+	syncCmd.Flags().String("exclude-repositories", "", "Comma-separated repositories, or a file listing them, to leave out of the migration")
+
+	syncCmd.Flags().Bool("skip-archived", false, "Leave archived repositories out when migrating every repository of the source organization")
+
+	syncCmd.Flags().Bool("skip-forks", false, "Leave forks out when migrating every repository of the source organization")
+
+	syncCmd.Flags().String("checksums-file", "", "Name of a checksums file, e.g. SHA256SUMS, regenerated from the migrated assets of each release")
+
+	syncCmd.Flags().String("checksums-algorithm", "sha256", "Hash algorithm of the checksums file: md5, sha1, sha256 or sha512")
+
+	syncCmd.Flags().String("confirm-target", "", "Target organization, or organization/repository, named again to guard against migrating into the wrong target")
+
+	syncCmd.Flags().Duration("issue-comment-interval", 0, "With --incremental-issue-comments, edit a single comment with the results at most once per interval instead of commenting each repository")
+
+	syncCmd.Flags().Int64("rate-limit-requests-per-sec", 10, "Maximum asset download and upload requests sent per second across all workers, 0 for unlimited")
+
+	syncCmd.Flags().String("source-api-url", "", "Full source API base URL used instead of the one derived from --source-hostname, e.g. https://github.example.com/github/api/v3")
+
+	syncCmd.Flags().String("source-upload-url", "", "Full source uploads base URL used instead of the one derived from --source-hostname")
+
+	syncCmd.Flags().String("target-api-url", "", "Full target API base URL used instead of the one derived from --target-hostname, e.g. https://github.example.com/github/api/v3")
+
+	syncCmd.Flags().String("target-upload-url", "", "Full target uploads base URL used instead of the one derived from --target-hostname")
+
+	syncCmd.Flags().Duration("repo-delay", 0, "Delay between the migrations of two repositories, e.g. 30s")
+
+	syncCmd.Flags().Duration("repo-delay-jitter", 0, "Maximum random delay added to --repo-delay, e.g. 10s")
+
+	syncCmd.Flags().Bool("accept-existing-assets", false, "Count an upload answered with 422 already_exists as done, e.g. when a retried upload had succeeded")
+
+	syncCmd.Flags().String("asset-name-template", "", "Go template naming the uploaded assets, e.g. mytool-{{.Tag}}-{{.Name}}; the downloaded files keep the source names")
+
+	syncCmd.Flags().Bool("update-existing", false, "Update the name, body and flags of existing target releases that differ from the source")
+
+	syncCmd.Flags().Bool("require-enterprise", false, "Refuse to run when the source or target resolves to github.com instead of a GitHub Enterprise hostname")
+
+	syncCmd.Flags().Bool("include-tags-without-releases", false, "Create target releases from the annotated source tags that have no release, with the tag message as the body")
+
+	syncCmd.Flags().String("start-from-repo", "", "Skip the entries of the repository list up to and including this repository, e.g. the last one completed by an interrupted run")
+
+	syncCmd.Flags().Bool("link-source-release", false, "Add a link back to the source release at the end of the release body")
+
+	syncCmd.Flags().String("allowed-content-types", "", "Comma-separated content types, or type/* wildcards, of the assets to migrate; other assets are skipped and reported")
+
+	syncCmd.Flags().String("failed-assets-file", "", "File to write the assets that failed to download or upload to, to retry them with --retry-manifest")
+
+	syncCmd.Flags().String("retry-manifest", "", "Failed assets file of a prior run; only transfers again the listed assets still missing from the target")
+
+	syncCmd.Flags().Bool("auto-concurrency", false, "Tune the asset download and upload concurrency to the rate limit headroom instead of --download-concurrency and --upload-concurrency")
+
+	syncCmd.Flags().Int("auto-concurrency-max", 8, "Maximum asset concurrency reached with --auto-concurrency")
+
+	syncCmd.Flags().Int("asset-concurrency", 0, "Number of assets of a release downloaded and uploaded concurrently, overriding --download-concurrency and --upload-concurrency")
+
+	syncCmd.Flags().Duration("retry-base-delay", time.Second, "Delay before the first retry of an asset download or upload, doubled for each following retry, with a random jitter")
+
+	syncCmd.Flags().Bool("verify-assets", false, "Verify each uploaded asset like --verify-uploads and download it back to compare its SHA-256 when the target reports no digest")
+
+	syncCmd.Flags().Bool("include-drafts", false, "Migrate the draft releases of the source, skipped by default")
+
+	syncCmd.Flags().Bool("publish-drafts", false, "Publish the migrated draft releases in the target instead of creating them as drafts")
+
+	syncCmd.Flags().String("tag-filter", "", "Regular expression the tag of a release must match to be migrated, e.g. ^v2\\.")
+
+	syncCmd.Flags().String("tag-exclude", "", "Regular expression of the tags of the releases to leave out, e.g. nightly")
+
+	syncCmd.Flags().String("since", "", "Only migrate the releases published at or after this RFC3339 date, e.g. 2024-01-01T00:00:00Z")
+
+	syncCmd.Flags().String("until", "", "Only migrate the releases published at or before this RFC3339 date, e.g. 2024-12-31T23:59:59Z")
+
+	syncCmd.Flags().String("state-file", "", "JSON file recording each release migrated with its assets; a re-run skips the recorded releases")
+
+	syncCmd.Flags().String("temp-dir", "", "Directory the downloaded assets of each run are written to, in a unique directory removed at the end of the run (default: the system temp directory)")
+
+	syncCmd.Flags().Int64("source-app-id", 0, "ID of the GitHub App authenticating to the source instead of --source-token")
+
+	syncCmd.Flags().Int64("source-app-installation-id", 0, "ID of the installation of the source GitHub App in the source organization")
+
+	syncCmd.Flags().String("source-app-private-key-file", "", "PEM private key file of the source GitHub App")
+
+	syncCmd.Flags().Int64("target-app-id", 0, "ID of the GitHub App authenticating to the target instead of --target-token")
+
+	syncCmd.Flags().Int64("target-app-installation-id", 0, "ID of the installation of the target GitHub App in the target organization")
+
+	syncCmd.Flags().String("target-app-private-key-file", "", "PEM private key file of the target GitHub App")
+
+	syncCmd.Flags().String("author-attribution", "", "Template of a line attributing each release to its source author, mapped with --mapping-file, e.g. \"Originally released by {{.Author}} on {{.Date}}\"")
+
+	syncCmd.Flags().String("author-attribution-position", "bottom", "Where --author-attribution adds the attribution line in the release body: top or bottom")
+
 }
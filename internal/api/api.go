@@ -7,53 +7,122 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
 	"github.com/gofri/go-github-ratelimit/github_ratelimit"
 	"github.com/google/go-github/v62/github"
 	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/pterm/pterm"
 	"github.com/spf13/viper"
 	"golang.org/x/oauth2"
 )
 
+// ErrReleaseExists is returned when creating a release whose tag already has a release in the target
+var ErrReleaseExists = errors.New("release already exists")
+
+// ErrIssueCommentForbidden is returned when the target token can't comment on an issue, e.g.
+// without the issues:write permission
+var ErrIssueCommentForbidden = errors.New("no permission to comment on the issue")
+
+// ErrReleaseNotReady is returned when the upload URL of a release answers 404, which happens
+// moments after the release is created until it is addressable
+var ErrReleaseNotReady = errors.New("release not ready for uploads")
+
+// releaseNotReadyDelays are the delays before retrying an upload answered with 404
+var releaseNotReadyDelays = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+
 type Releases []Release
 
 type Release struct {
 	*github.RepositoryRelease
 }
 
-var tmpDir = "tmp"
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*github.Client{}
+)
+
+// newSourceClient returns the client of the source, served by SOURCE_API_URL and
+// SOURCE_UPLOAD_URL when set instead of the URLs of SOURCE_HOSTNAME
+func newSourceClient() (*github.Client, error) {
+	return newGHRestClient(currentToken("SOURCE_TOKEN"), viper.GetString("SOURCE_HOSTNAME"), viper.GetString("SOURCE_API_URL"), viper.GetString("SOURCE_UPLOAD_URL"))
+}
+
+// newTargetClient returns the client of the target, served by TARGET_API_URL and
+// TARGET_UPLOAD_URL when set instead of the URLs of TARGET_HOSTNAME
+func newTargetClient() (*github.Client, error) {
+	return newGHRestClient(currentToken("TARGET_TOKEN"), viper.GetString("TARGET_HOSTNAME"), viper.GetString("TARGET_API_URL"), viper.GetString("TARGET_UPLOAD_URL"))
+}
+
+// newGHRestClient returns a client for the given token and hostname, whose API and uploads base
+// URLs are replaced by apiURL and uploadURL when they are not empty. Clients are shared so that
+// concurrent callers go through the same rate limit waiter. An invalid base URL is returned as
+// an error and not cached.
+func newGHRestClient(token string, hostname string, apiURL string, uploadURL string) (*github.Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	key := hostname + "\x00" + token
+	if apiURL != "" || uploadURL != "" {
+		key += "\x00" + apiURL + "\x00" + uploadURL
+	}
+	if client, ok := clients[key]; ok {
+		return client, nil
+	}
+
+	client, err := buildGHRestClient(token, hostname, apiURL, uploadURL)
+	if err != nil {
+		return nil, err
+	}
+	clients[key] = client
+	return client, nil
+}
 
-func newGHRestClient(token string, hostname string) *github.Client {
-	ctx := context.Background()
+func buildGHRestClient(token string, hostname string, apiURL string, uploadURL string) (*github.Client, error) {
+	// Count the requests sent by the client, including the ones retried by the rate limiter
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, rawHTTPClient)
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
 	rateLimiter, err := github_ratelimit.NewRateLimitWaiterClient(tc.Transport)
-
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("unable to create rate limit waiter: %v", err)
 	}
 
 	client := github.NewClient(rateLimiter)
 
-	if hostname != "" {
-		hostname = strings.TrimSuffix(hostname, "/")
-		client, err = github.NewClient(rateLimiter).WithEnterpriseURLs("https://"+hostname+"/api/v3", "https://"+hostname+"/api/uploads")
-		if err != nil {
-			panic(err)
-		}
+	// WithEnterpriseURLs would add the GHES /api/uploads/ path to data residency upload URLs
+	baseURL, uploads := endpointURLs(hostname)
+	if apiURL != "" {
+		baseURL = withTrailingSlash(apiURL)
+	}
+	if uploadURL != "" {
+		uploads = withTrailingSlash(uploadURL)
+	}
+	client.BaseURL, err = url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %q: %v", baseURL, err)
+	}
+	client.UploadURL, err = url.Parse(uploads)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload URL %q: %v", uploads, err)
 	}
 
-	return client
+	return client, nil
 }
 
 func GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
-	client := newGHRestClient(viper.GetString("source_token"), viper.GetString("source_hostname"))
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
 
@@ -76,15 +145,51 @@ func GetSourceRepositoryReleases(owner string, repository string) ([]*github.Rep
 
 }
 
+// GetSourceRepositoryCommitAuthors returns the number of commits of a source repository by the
+// handle of their author. Commits whose author has no GitHub account are not counted.
+func GetSourceRepositoryCommitAuthors(owner string, repository string) (map[string]int, error) {
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	authors := map[string]int{}
+	opts := &github.CommitsListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		commits, resp, err := client.Repositories.ListCommits(ctx, owner, repository, opts)
+		if err != nil {
+			return authors, fmt.Errorf("unable to get commits: %v", err)
+		}
+		for _, commit := range commits {
+			if login := commit.GetAuthor().GetLogin(); login != "" {
+				authors[login]++
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return authors, nil
+}
+
 func GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
-	client := newGHRestClient(viper.GetString("source_token"), viper.GetString("source_hostname"))
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
 
 	release, resp, err := client.Repositories.GetLatestRelease(ctx, owner, repository)
 
 	if err != nil {
-		if resp.StatusCode == http.StatusNotFound {
+		// resp is nil when the request failed before reaching the server, e.g. on a bad hostname
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
 			return nil, fmt.Errorf("no releases found for repository %s/%s", owner, repository)
 		}
 		return nil, fmt.Errorf("unable to get latest release: %v", err)
@@ -93,13 +198,243 @@ func GetSourceRepositoryLatestRelease(owner string, repository string) (*github.
 	return release, nil
 }
 
-// AssetExists checks if an asset with the same name and size already exists in a release
+// endpointURLs returns the API and uploads base URLs for a hostname:
+//   - github.com when the hostname is empty or github.com
+//   - https://api.<tenant>.ghe.com/ for GitHub Enterprise Cloud with data residency, given as
+//     <tenant>.ghe.com or api.<tenant>.ghe.com
+//   - https://<hostname>/api/v3/ for GitHub Enterprise Server
+func endpointURLs(hostname string) (string, string) {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://"), "/")
+
+	switch {
+	case host == "" || host == "github.com" || host == "api.github.com":
+		return "https://api.github.com/", "https://uploads.github.com/"
+	case strings.HasSuffix(host, ".ghe.com"):
+		tenant := strings.TrimPrefix(host, "api.")
+		return "https://api." + tenant + "/", "https://uploads." + tenant + "/"
+	default:
+		return "https://" + host + "/api/v3/", "https://" + host + "/api/uploads/"
+	}
+}
+
+// ResolvesToGitHubDotCom reports whether the API of a side, at apiURL when set or derived from
+// hostname otherwise, is the one of github.com
+func ResolvesToGitHubDotCom(hostname string, apiURL string) bool {
+	if apiURL == "" {
+		apiURL, _ = endpointURLs(hostname)
+	}
+	parsed, err := url.Parse(apiURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	return host == "github.com" || host == "api.github.com"
+}
+
+// ValidateEndpointURL checks that an API or uploads base URL given in place of the URLs derived
+// from the hostname, e.g. https://github.example.com/github/api/v3 behind a routing proxy, is an
+// absolute http or https URL
+func ValidateEndpointURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", value, err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("invalid URL %q: expected an http or https URL", value)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", value)
+	}
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("invalid URL %q: a base URL can't have a query or fragment", value)
+	}
+	return nil
+}
+
+// withTrailingSlash adds the trailing slash go-github requires on base URLs
+func withTrailingSlash(value string) string {
+	if strings.HasSuffix(value, "/") {
+		return value
+	}
+	return value + "/"
+}
+
+// apiHost returns the host serving the API of the side, at its API URL when set
+func (e endpoints) apiHost() string {
+	baseURL, _ := endpointURLs(e.hostname)
+	if e.apiURL != "" {
+		baseURL = e.apiURL
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		return e.hostname
+	}
+	return parsed.Hostname()
+}
+
+// uploadsURL returns the base URL of the release asset uploads of the side, its upload URL when set
+func (e endpoints) uploadsURL() string {
+	if e.uploadURL != "" {
+		return withTrailingSlash(e.uploadURL)
+	}
+	_, uploads := endpointURLs(e.hostname)
+	return uploads
+}
+
+// ResolveSourceHost checks that the API host of the source resolves
+func ResolveSourceHost() error {
+	return resolveHost(sourceEndpoints().apiHost())
+}
+
+// ResolveTargetHost checks that the API host of the target resolves
+func ResolveTargetHost() error {
+	return resolveHost(targetEndpoints().apiHost())
+}
+
+func resolveHost(host string) error {
+	_, err := net.LookupHost(host)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s: %v", host, err)
+	}
+	return nil
+}
+
+// CheckUploadsEndpoint checks that the release asset uploads endpoint of the target answers.
+// Any HTTP response, even an error status, means the endpoint is reachable.
+func CheckUploadsEndpoint() error {
+	uploads := targetEndpoints().uploadsURL()
+	req, err := http.NewRequest("GET", uploads, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	resp, err := rawHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach %s: %v", uploads, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// GetSourceRepository retrieves a repository from the source
+func GetSourceRepository(owner string, repository string) (*github.Repository, error) {
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	repo, _, err := client.Repositories.Get(ctx, owner, repository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get source repository %s/%s: %v", owner, repository, err)
+	}
+
+	return repo, nil
+}
+
+// ListSourceRepositories lists all repositories of a source organization
+func ListSourceRepositories(organization string) ([]*github.Repository, error) {
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	var allRepositories []*github.Repository
+	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		repositories, resp, err := client.Repositories.ListByOrg(ctx, organization, opts)
+		if err != nil {
+			return allRepositories, fmt.Errorf("unable to list repositories of organization %s: %v", organization, err)
+		}
+		allRepositories = append(allRepositories, repositories...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allRepositories, nil
+}
+
+// GetTargetRepository retrieves a repository from the target
+func GetTargetRepository(owner string, repository string) (*github.Repository, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	repo, _, err := client.Repositories.Get(ctx, owner, repository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get target repository %s/%s: %v", owner, repository, err)
+	}
+
+	return repo, nil
+}
+
+// GetTargetRepositoryLatestRelease returns the latest release of a target repository, or nil when
+// the repository has no release
+func GetTargetRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	release, resp, err := client.Repositories.GetLatestRelease(ctx, owner, repository)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get target latest release: %v", err)
+	}
+
+	return release, nil
+}
+
+// GetTargetRepositoryReleases lists all releases of a target repository with their assets
+func GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	var allReleases []*github.RepositoryRelease
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		releases, resp, err := client.Repositories.ListReleases(ctx, owner, repository, opts)
+		if err != nil {
+			return allReleases, fmt.Errorf("unable to get target releases: %v", err)
+		}
+		allReleases = append(allReleases, releases...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return allReleases, nil
+}
+
+// AssetExists checks if an asset with the same name and size already exists in a release. An
+// asset whose upload was interrupted doesn't count, even with the full size.
 func AssetExists(release *github.RepositoryRelease, assetName string, assetSize int64) bool {
 	if release == nil || release.Assets == nil {
 		return false
 	}
 
 	for _, existingAsset := range release.Assets {
+		if isStuckAsset(existingAsset) {
+			continue
+		}
 		if existingAsset.GetName() == assetName && int64(existingAsset.GetSize()) == assetSize {
 			return true
 		}
@@ -108,15 +443,100 @@ func AssetExists(release *github.RepositoryRelease, assetName string, assetSize
 	return false
 }
 
-// GetReleaseByTag retrieves a release from the target repository by its tag name
+// MislabeledAsset returns the asset of a release with the same name and size as asset but a
+// different label, which AssetExists considers present
+func MislabeledAsset(release *github.RepositoryRelease, asset *github.ReleaseAsset) *github.ReleaseAsset {
+	if release == nil {
+		return nil
+	}
+
+	for _, existingAsset := range release.Assets {
+		if existingAsset.GetName() == asset.GetName() && existingAsset.GetSize() == asset.GetSize() && existingAsset.GetLabel() != asset.GetLabel() {
+			return existingAsset
+		}
+	}
+
+	return nil
+}
+
+// StuckAsset returns the asset with the given name in a release if its upload was interrupted,
+// e.g. by a crash, leaving it in a state other than uploaded that blocks a new upload
+func StuckAsset(release *github.RepositoryRelease, assetName string) *github.ReleaseAsset {
+	if release == nil {
+		return nil
+	}
+
+	for _, existingAsset := range release.Assets {
+		if existingAsset.GetName() == assetName && isStuckAsset(existingAsset) {
+			return existingAsset
+		}
+	}
+
+	return nil
+}
+
+// isStuckAsset reports whether an asset is in a state other than uploaded. Assets without a
+// state, e.g. built locally, are not stuck.
+func isStuckAsset(asset *github.ReleaseAsset) bool {
+	return asset.State != nil && asset.GetState() != "uploaded"
+}
+
+// BrokenAsset returns the asset with the given name in a release if a failed upload left it
+// empty. An asset not in the uploaded state is a StuckAsset, deleted without REPLACE_BROKEN_ASSETS.
+func BrokenAsset(release *github.RepositoryRelease, assetName string) *github.ReleaseAsset {
+	if release == nil {
+		return nil
+	}
+
+	for _, existingAsset := range release.Assets {
+		if existingAsset.GetName() == assetName && existingAsset.GetSize() == 0 {
+			return existingAsset
+		}
+	}
+
+	return nil
+}
+
+// DeleteReleaseAsset deletes an asset from a release in the target repository
+func DeleteReleaseAsset(owner string, repository string, assetID int64) error {
+	client, err := newTargetClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	_, err = client.Repositories.DeleteReleaseAsset(ctx, owner, repository, assetID)
+	if err != nil {
+		return fmt.Errorf("error deleting release asset: %v", err)
+	}
+
+	return nil
+}
+
+// GetReleaseByTag retrieves a release from the target repository by its tag name. With
+// CASE_INSENSITIVE_TAGS, the releases are listed to find a tag differing in case when no tag
+// matches exactly.
 func GetReleaseByTag(owner string, repository string, tagName string) (*github.RepositoryRelease, error) {
-	client := newGHRestClient(viper.GetString("TARGET_TOKEN"), viper.GetString("TARGET_HOSTNAME"))
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
 
-	release, resp, err := client.Repositories.GetReleaseByTag(ctx, owner, repository, tagName)
+	// Tags such as release/2.1 must be escaped to be a single path segment
+	release, resp, err := client.Repositories.GetReleaseByTag(ctx, owner, repository, url.PathEscape(tagName))
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			if caseInsensitiveTags() {
+				releases, err := GetTargetRepositoryReleases(owner, repository)
+				if err != nil {
+					return nil, err
+				}
+				if release := FindReleaseByTag(releases, tagName); release != nil {
+					return release, nil
+				}
+			}
 			return nil, fmt.Errorf("release not found for tag %s", tagName)
 		}
 		return nil, fmt.Errorf("unable to get release by tag: %v", err)
@@ -137,29 +557,25 @@ func ReleaseExists(owner string, repository string, release *github.RepositoryRe
 	}
 
 	// Check if name and target_commitish match
-	nameMatches := existingRelease.GetName() == release.GetName()
-	commitMatches := existingRelease.GetTargetCommitish() == release.GetTargetCommitish()
-
-	if nameMatches && commitMatches {
-		return existingRelease, true
-	}
-
-	return existingRelease, false
+	return existingRelease, ReleaseMatches(existingRelease, release)
 }
 
 func DownloadReleaseAssets(asset *github.ReleaseAsset) error {
 
-	token := viper.Get("SOURCE_TOKEN").(string)
+	token := currentToken("SOURCE_TOKEN")
+	source := sourceEndpoints()
 
 	// Download the asset using URL if not nil, else DownloadURL
 	url := asset.GetBrowserDownloadURL()
 	if asset.URL != nil {
-		url = *asset.URL
+		url = rebaseURL(*asset.URL, source.apiURL)
+	}
+	if err := checkTransferURL(url, source, targetEndpoints()); err != nil {
+		return err
 	}
-	dirName := tmpDir
-	fileName := dirName + "/" + asset.GetName()
+	fileName := localAssetPath(asset)
 
-	err := os.MkdirAll(dirName, 0755)
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
 	if err != nil {
 		return err
 	}
@@ -171,35 +587,31 @@ func DownloadReleaseAssets(asset *github.ReleaseAsset) error {
 	return nil
 }
 
-func DownloadReleaseZip(release *github.RepositoryRelease) error {
-	token := viper.Get("SOURCE_TOKEN").(string)
-	repo := viper.Get("REPOSITORY").(string)
-	if release.TagName == nil {
-		return errors.New("TagName is nil")
-	}
-	tag := *release.TagName
-	var tagName string
-
-	url := *release.ZipballURL
-
-	if len(tag) > 1 && tag[0] == 'v' && unicode.IsDigit(rune(tag[1])) {
-		tagName = strings.TrimPrefix(tag, "v")
-	} else {
-		tagName = tag
-	}
-
-	fileName := fmt.Sprintf("%s-%s.zip", repo, tagName)
+// DownloadReleaseZip downloads the source code zip archive of a release. When checksum is not
+// empty the sha256 of the downloaded archive must match it.
+func DownloadReleaseZip(release *github.RepositoryRelease, checksum string) error {
+	return downloadReleaseArchive(release, release.GetZipballURL(), "zip", checksum)
+}
 
-	err := DownloadFileFromURL(url, fileName, token)
-	if err != nil {
-		return err
-	}
+// DownloadReleaseTarball downloads the source code tarball of a release. When checksum is not
+// empty the sha256 of the downloaded archive must match it.
+func DownloadReleaseTarball(release *github.RepositoryRelease, checksum string) error {
+	return downloadReleaseArchive(release, release.GetTarballURL(), "tar.gz", checksum)
+}
 
-	return nil
+// sanitizeFileName replaces the characters of a tag that are not safe in a file name, such as
+// the slash of release/2.1, so the archive is written in the temp directory
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(".-_+", r) {
+			return r
+		}
+		return '-'
+	}, name)
 }
 
-func DownloadReleaseTarball(release *github.RepositoryRelease) error {
-	token := viper.Get("SOURCE_TOKEN").(string)
+func downloadReleaseArchive(release *github.RepositoryRelease, url string, extension string, checksum string) error {
+	token := currentToken("SOURCE_TOKEN")
 	repo := viper.Get("REPOSITORY").(string)
 	if release.TagName == nil {
 		return errors.New("TagName is nil")
@@ -207,21 +619,31 @@ func DownloadReleaseTarball(release *github.RepositoryRelease) error {
 	tag := *release.TagName
 	var tagName string
 
-	url := *release.TarballURL
-
 	if len(tag) > 1 && tag[0] == 'v' && unicode.IsDigit(rune(tag[1])) {
 		tagName = strings.TrimPrefix(tag, "v")
 	} else {
 		tagName = tag
 	}
 
-	fileName := fmt.Sprintf("%s-%s.tar.gz", repo, tagName)
+	fileName := filepath.Join(TempDir(), fmt.Sprintf("%s-%s.%s", repo, sanitizeFileName(tagName), extension))
 
 	err := DownloadFileFromURL(url, fileName, token)
 	if err != nil {
+		os.Remove(fileName)
 		return err
 	}
 
+	if checksum != "" {
+		sum, err := fileSHA256(fileName)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, checksum) {
+			os.Remove(fileName)
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", fileName, checksum, sum)
+		}
+	}
+
 	return nil
 }
 
@@ -235,36 +657,63 @@ func DownloadFileFromURL(url, fileName, token string) error {
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		panic(fmt.Errorf("error creating request: %s", err))
+		return fmt.Errorf("error creating request: %s", err)
 	}
 
 	req.Header.Add("Authorization", "Bearer "+token)
 	req.Header.Add("Accept", "application/octet-stream")
 
 	// Get the data
-	resp, err := http.DefaultClient.Do(req)
+	waitForTransferRequest()
+	resp, err := rawHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("error getting file: %v  err:%v", fileName, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP request failed with status code %d, Message: %s", resp.StatusCode, resp.Body)
+		return newStatusError("download of", fileName, resp)
 	}
 
 	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	return err
+	body, done := trackProgress(throttleReader(resp.Body), "Downloading", filepath.Base(fileName), resp.ContentLength)
+	written, err := io.Copy(out, body)
+	done()
+	if err != nil {
+		return fmt.Errorf("error writing file: %v err: %v", fileName, err)
+	}
+
+	// Verify the whole file was received
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("incomplete download of %v: got %d bytes, expected %d", fileName, written, resp.ContentLength)
+	}
+
+	return nil
+}
+
+// stripAuthOnRedirect drops the Authorization header when a download or upload is redirected
+// to another host, e.g. the storage backend serving assets and archives
+func stripAuthOnRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
 }
 
-func CreateRelease(repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
-	client := newGHRestClient(viper.GetString("TARGET_TOKEN"), viper.GetString("TARGET_HOSTNAME"))
+func CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
-	newRelease, _, err := client.Repositories.CreateRelease(ctx, viper.Get("TARGET_ORGANIZATION").(string), repository, release)
+	newRelease, _, err := client.Repositories.CreateRelease(ctx, owner, repository, release)
 	if err != nil {
-		if strings.Contains(err.Error(), "already_exists") {
-			return nil, fmt.Errorf("release already exists: %v", release.GetName())
+		if hasErrorCode(err, "already_exists") {
+			return nil, fmt.Errorf("%w: %v", ErrReleaseExists, release.GetName())
 		} else {
 			return nil, err
 		}
@@ -273,16 +722,66 @@ func CreateRelease(repository string, release *github.RepositoryRelease) (*githu
 	return newRelease, nil
 }
 
+// hasErrorCode reports whether an API validation error has an error with the given code, which
+// unlike the error messages is part of the documented API
+func hasErrorCode(err error, code string) bool {
+	var errorResponse *github.ErrorResponse
+	if !errors.As(err, &errorResponse) {
+		return false
+	}
+	for _, e := range errorResponse.Errors {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadAssetViaURL uploads a downloaded asset to the upload URL of a target release. An upload
+// answered with 404, e.g. right after the release was created on a busy instance, is retried
+// after a short delay.
 func UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	return UploadAssetAs(uploadURL, asset, asset.GetName())
+}
 
-	dirName := tmpDir
-	fileName := dirName + "/" + asset.GetName()
+// UploadAssetAs uploads a downloaded asset like UploadAssetViaURL, naming it name in the target
+// release while the local file keeps the name of the source asset
+func UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error {
+	err := uploadAsset(uploadURL, asset, name)
+	for _, delay := range releaseNotReadyDelays {
+		if !errors.Is(err, ErrReleaseNotReady) {
+			break
+		}
+		pterm.Info.Printf("Release not ready for uploads, retrying asset %s in %v\n", name, delay)
+		time.Sleep(delay)
+		err = uploadAsset(uploadURL, asset, name)
+	}
+	return err
+}
+
+// AssetContentType returns the content type an asset is uploaded with: its content type in the
+// source, or the one of its file extension when it has none
+func AssetContentType(asset *github.ReleaseAsset) string {
+	if asset.GetContentType() != "" {
+		return asset.GetContentType()
+	}
+	return mime.TypeByExtension(filepath.Ext(asset.GetName()))
+}
+
+// openAssetFile opens the local file of an asset to upload
+var openAssetFile = files.OpenFile
+
+func uploadAsset(uploadURL string, asset *github.ReleaseAsset, name string) error {
+
+	fileName := localAssetPath(asset)
 
-	// Open the file
-	file, err := files.OpenFile(fileName)
+	// Open the file, closed here as the request body wrappers don't close it, and before it is
+	// deleted as an open file can't be deleted on Windows
+	file, err := openAssetFile(fileName)
 	if err != nil {
 		return fmt.Errorf("error opening file: %v err: %v", file, err)
 	}
+	defer file.Close()
 
 	// Get the file size
 	stat, err := file.Stat()
@@ -290,23 +789,26 @@ func UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
 		return fmt.Errorf("error getting file size of %v err: %v ", fileName, err)
 	}
 
-	// Get the media type
-	mediaType := mime.TypeByExtension(filepath.Ext(file.Name()))
-	if *asset.ContentType != "" {
-		mediaType = asset.GetContentType()
-	}
+	mediaType := AssetContentType(asset)
 
 	uploadURL = strings.TrimSuffix(uploadURL, "{?name,label}")
+	target := targetEndpoints()
+	uploadURL = rebaseURL(uploadURL, target.uploadURL)
+	if err := checkTransferURL(uploadURL, target, sourceEndpoints()); err != nil {
+		return err
+	}
 
 	// Add the name and label to the URL
 	params := url.Values{}
-	params.Add("name", asset.GetName())
+	params.Add("name", name)
 	params.Add("label", asset.GetLabel())
 
 	uploadURLWithParams := fmt.Sprintf("%s?%s", uploadURL, params.Encode())
 
 	// Create the request
-	req, err := http.NewRequest("POST", uploadURLWithParams, file)
+	body, done := trackProgress(throttleReader(file), "Uploading", name, stat.Size())
+	defer done()
+	req, err := http.NewRequest("POST", uploadURLWithParams, body)
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
@@ -314,22 +816,46 @@ func UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
 	// Set the headers
 	req.ContentLength = stat.Size()
 	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Authorization", "Bearer "+viper.Get("TARGET_TOKEN").(string))
+	req.Header.Set("Authorization", "Bearer "+currentToken("TARGET_TOKEN"))
 	req.Header.Set("Content-Type", mediaType)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	waitForTransferRequest()
+	resp, err := rawHTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("error uploading asset to release: %v err: %v", uploadURL, err)
 	}
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %v", ErrReleaseNotReady, uploadURL)
+	}
+	// A previous attempt reported as failed may have uploaded the asset
+	if viper.GetBool("ACCEPT_EXISTING_ASSETS") && isAssetAlreadyExists(resp) {
+		pterm.Info.Printf("Asset %s already exists in the release, counting the upload as done\n", name)
+		file.Close()
+		if err := removeTmpFile(fileName); err != nil {
+			return fmt.Errorf("error deleting asset from local storage: %v err: %v", asset.Name, err)
+		}
+		return nil
+	}
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("error uploading asset to release: %v err: %v", uploadURL, resp.Body)
+		return newStatusError("upload to", uploadURL, resp)
 	}
 
-	err = files.RemoveFile(fileName)
+	// Keep the local file until the uploaded asset is confirmed, so the upload can be retried
+	if viper.GetBool("VERIFY_UPLOADS") || viper.GetBool("VERIFY_ASSETS") {
+		var uploaded github.ReleaseAsset
+		if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+			return fmt.Errorf("error reading uploaded asset: %v", err)
+		}
+		if err := verifyUploadedAsset(rebaseURL(uploaded.GetURL(), target.apiURL), fileName, stat.Size(), viper.GetBool("VERIFY_ASSETS")); err != nil {
+			return err
+		}
+	}
+
+	file.Close()
+	err = removeTmpFile(fileName)
 	if err != nil {
 		return fmt.Errorf("error deleting asset from local storage: %v err: %v", asset.Name, err)
 	}
@@ -337,16 +863,124 @@ func UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
 	return nil
 }
 
+// isAssetAlreadyExists reports whether an upload was refused because the release already has an
+// asset with the same name
+func isAssetAlreadyExists(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	var errorResponse github.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
+		return false
+	}
+	for _, e := range errorResponse.Errors {
+		if e.Code == "already_exists" {
+			return true
+		}
+	}
+	return false
+}
+
 func WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
 
-	client := newGHRestClient(viper.GetString("TARGET_TOKEN"), viper.GetString("TARGET_HOSTNAME"))
+	client, err := newTargetClient()
+	if err != nil {
+		return err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
-	_, _, err := client.Issues.CreateComment(ctx, owner, repository, issueNumber, &github.IssueComment{Body: &comment})
+	_, resp, err := client.Issues.CreateComment(ctx, owner, repository, issueNumber, &github.IssueComment{Body: &comment})
+	if err != nil {
+		return issueCommentError(resp, err)
+	}
+
+	return nil
+}
+
+// UpsertIssueComment edits the issue comment with the given ID, or else the comment containing
+// marker, and creates the comment when there is none. It returns the ID of the comment, to
+// edit it directly the next time.
+func UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	// Find the comment of a previous attempt of the run by its marker
+	if commentID == 0 {
+		opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for commentID == 0 {
+			comments, resp, err := client.Issues.ListComments(ctx, owner, repository, issueNumber, opts)
+			if err != nil {
+				return 0, issueCommentError(resp, err)
+			}
+			for _, existing := range comments {
+				if strings.Contains(existing.GetBody(), marker) {
+					commentID = existing.GetID()
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	if commentID != 0 {
+		_, resp, err := client.Issues.EditComment(ctx, owner, repository, commentID, &github.IssueComment{Body: &comment})
+		if err != nil {
+			return 0, issueCommentError(resp, err)
+		}
+		return commentID, nil
+	}
+
+	created, resp, err := client.Issues.CreateComment(ctx, owner, repository, issueNumber, &github.IssueComment{Body: &comment})
+	if err != nil {
+		return 0, issueCommentError(resp, err)
+	}
+	return created.GetID(), nil
+}
+
+// issueCommentError wraps the error of an issue comment request refused to the token
+func issueCommentError(resp *github.Response, err error) error {
+	// GitHub answers 404 instead of 403 for repositories the token can't write to
+	if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("%w: %v", ErrIssueCommentForbidden, err)
+	}
+	return err
+}
+
+// DeleteRelease deletes a release from the target repository by its ID
+func DeleteRelease(owner string, repository string, releaseID int64) error {
+	client, err := newTargetClient()
 	if err != nil {
 		return err
 	}
 
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	_, err = client.Repositories.DeleteRelease(ctx, owner, repository, releaseID)
+	if err != nil {
+		return fmt.Errorf("error deleting release: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteTag deletes a tag reference from the target repository
+func DeleteTag(owner string, repository string, tagName string) error {
+	client, err := newTargetClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	_, err = client.Git.DeleteRef(ctx, owner, repository, "tags/"+tagName)
+	if err != nil {
+		return fmt.Errorf("error deleting tag %s: %v", tagName, err)
+	}
+
 	return nil
 }
 
@@ -369,11 +1003,30 @@ func GetDatafromGitHubContext() (string, string, int, error) {
 	return organization, repository, issueNumber, nil
 }
 
+// UpdateRelease edits the fields set in release of an existing target release
+func UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	client, err := newTargetClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+	updated, _, err := client.Repositories.EditRelease(ctx, owner, repository, releaseID, release)
+	if err != nil {
+		return nil, fmt.Errorf("error updating release: %v", err)
+	}
+
+	return updated, nil
+}
+
 func SetLatestRelease(owner string, repository string, releaseID int64) error {
-	client := newGHRestClient(viper.GetString("TARGET_TOKEN"), viper.GetString("TARGET_HOSTNAME"))
+	client, err := newTargetClient()
+	if err != nil {
+		return err
+	}
 
 	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
-	_, _, err := client.Repositories.EditRelease(ctx, owner, repository, releaseID, &github.RepositoryRelease{
+	_, _, err = client.Repositories.EditRelease(ctx, owner, repository, releaseID, &github.RepositoryRelease{
 		MakeLatest: github.String("true"),
 	})
 	if err != nil {
@@ -0,0 +1,423 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/spf13/viper"
+)
+
+func TestBrokenAsset(t *testing.T) {
+	release := &github.RepositoryRelease{
+		Assets: []*github.ReleaseAsset{
+			{ID: github.Int64(1), Name: github.String("empty.zip"), Size: github.Int(0), State: github.String("uploaded")},
+			{ID: github.Int64(2), Name: github.String("starter.zip"), Size: github.Int(100), State: github.String("starter")},
+			{ID: github.Int64(3), Name: github.String("good.zip"), Size: github.Int(100), State: github.String("uploaded")},
+		},
+	}
+
+	if asset := BrokenAsset(release, "empty.zip"); asset.GetID() != 1 {
+		t.Errorf("Expected the 0-byte asset to be broken")
+	}
+	if asset := BrokenAsset(release, "starter.zip"); asset != nil {
+		t.Errorf("Expected the non-uploaded asset to be left to StuckAsset")
+	}
+	if asset := BrokenAsset(release, "good.zip"); asset != nil {
+		t.Errorf("Expected the uploaded asset not to be broken")
+	}
+	if asset := BrokenAsset(release, "missing.zip"); asset != nil {
+		t.Errorf("Expected a missing asset not to be broken")
+	}
+	if asset := BrokenAsset(nil, "empty.zip"); asset != nil {
+		t.Errorf("Expected no broken asset for a nil release")
+	}
+}
+
+func TestStuckAsset(t *testing.T) {
+	release := &github.RepositoryRelease{
+		Assets: []*github.ReleaseAsset{
+			{ID: github.Int64(1), Name: github.String("starter.zip"), Size: github.Int(100), State: github.String("starter")},
+			{ID: github.Int64(2), Name: github.String("good.zip"), Size: github.Int(100), State: github.String("uploaded")},
+			{ID: github.Int64(3), Name: github.String("local.zip"), Size: github.Int(100)},
+		},
+	}
+
+	if asset := StuckAsset(release, "starter.zip"); asset.GetID() != 1 {
+		t.Errorf("Expected the asset in the starter state to be stuck")
+	}
+	if asset := StuckAsset(release, "good.zip"); asset != nil {
+		t.Errorf("Expected the uploaded asset not to be stuck")
+	}
+	if asset := StuckAsset(release, "local.zip"); asset != nil {
+		t.Errorf("Expected an asset without a state not to be stuck")
+	}
+
+	// An interrupted upload can report the full size without being usable
+	if AssetExists(release, "starter.zip", 100) {
+		t.Errorf("Expected the stuck asset not to count as existing")
+	}
+	if !AssetExists(release, "good.zip", 100) || !AssetExists(release, "local.zip", 100) {
+		t.Errorf("Expected the uploaded assets to exist")
+	}
+}
+
+func TestGetReleaseByTagEscapesTag(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	tags := map[string]string{
+		"release/2.1":    "/api/v3/repos/target-org/repo/releases/tags/release%2F2.1",
+		"v1.0+build.5":   "/api/v3/repos/target-org/repo/releases/tags/v1.0+build.5",
+		"my release 1.0": "/api/v3/repos/target-org/repo/releases/tags/my%20release%201.0",
+	}
+
+	var gotPath string
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Write([]byte(`{"id": 1}`))
+	}))
+
+	for tag, path := range tags {
+		release, err := GetReleaseByTag("target-org", "repo", tag)
+		if err != nil {
+			t.Fatalf("GetReleaseByTag(%q) returned an error: %v", tag, err)
+		}
+		if release.GetID() != 1 {
+			t.Errorf("Unexpected release for tag %q: %v", tag, release)
+		}
+		if gotPath != path {
+			t.Errorf("GetReleaseByTag(%q) requested %s, want %s", tag, gotPath, path)
+		}
+	}
+}
+
+func TestMislabeledAsset(t *testing.T) {
+	release := &github.RepositoryRelease{
+		Assets: []*github.ReleaseAsset{
+			{ID: github.Int64(1), Name: github.String("app.zip"), Size: github.Int(100), Label: github.String("Old label")},
+			{ID: github.Int64(2), Name: github.String("docs.zip"), Size: github.Int(100), Label: github.String("Docs")},
+		},
+	}
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), Size: github.Int(100), Label: github.String("New label")}
+	if mislabeled := MislabeledAsset(release, asset); mislabeled.GetID() != 1 {
+		t.Errorf("Expected the asset with a different label to be detected")
+	}
+
+	asset = &github.ReleaseAsset{Name: github.String("docs.zip"), Size: github.Int(100), Label: github.String("Docs")}
+	if mislabeled := MislabeledAsset(release, asset); mislabeled != nil {
+		t.Errorf("Expected an asset with the same label not to be mislabeled")
+	}
+}
+
+func TestGetSourceRepositoryLatestReleaseTransportFailure(t *testing.T) {
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_HOSTNAME", "unreachable.example.com")
+	defer viper.Reset()
+
+	// The server is closed so requests fail without a response
+	server := setupTestClient(t, "source-token", "unreachable.example.com", http.NotFoundHandler())
+	server.Close()
+
+	_, err := GetSourceRepositoryLatestRelease("source-org", "repo")
+	if err == nil {
+		t.Errorf("Expected an error for a transport-level failure")
+	}
+}
+
+func TestBuildGHRestClientEndpoints(t *testing.T) {
+	tests := []struct {
+		hostname  string
+		baseURL   string
+		uploadURL string
+	}{
+		{"", "https://api.github.com/", "https://uploads.github.com/"},
+		{"github.com", "https://api.github.com/", "https://uploads.github.com/"},
+		{"github.example.com", "https://github.example.com/api/v3/", "https://github.example.com/api/uploads/"},
+		{"github.example.com/", "https://github.example.com/api/v3/", "https://github.example.com/api/uploads/"},
+		{"octocorp.ghe.com", "https://api.octocorp.ghe.com/", "https://uploads.octocorp.ghe.com/"},
+		{"api.octocorp.ghe.com", "https://api.octocorp.ghe.com/", "https://uploads.octocorp.ghe.com/"},
+	}
+
+	for _, tt := range tests {
+		client, err := buildGHRestClient("token", tt.hostname, "", "")
+		if err != nil {
+			t.Fatalf("buildGHRestClient for %q returned an error: %v", tt.hostname, err)
+		}
+		if got := client.BaseURL.String(); got != tt.baseURL {
+			t.Errorf("BaseURL for %q = %s, want %s", tt.hostname, got, tt.baseURL)
+		}
+		if got := client.UploadURL.String(); got != tt.uploadURL {
+			t.Errorf("UploadURL for %q = %s, want %s", tt.hostname, got, tt.uploadURL)
+		}
+	}
+}
+
+func TestBuildGHRestClientExplicitURLs(t *testing.T) {
+	client, err := buildGHRestClient("token", "github.example.com", "https://github.example.com/github/api/v3/", "https://github.example.com/github/api/uploads")
+	if err != nil {
+		t.Fatalf("buildGHRestClient returned an error: %v", err)
+	}
+	if got := client.BaseURL.String(); got != "https://github.example.com/github/api/v3/" {
+		t.Errorf("Expected the explicit API URL to be used verbatim, got %s", got)
+	}
+	if got := client.UploadURL.String(); got != "https://github.example.com/github/api/uploads/" {
+		t.Errorf("Expected the explicit upload URL to be used with a trailing slash, got %s", got)
+	}
+
+	// Without an explicit upload URL, the one of the hostname is kept
+	client, err = buildGHRestClient("token", "github.example.com", "https://github.example.com/github/api/v3/", "")
+	if err != nil {
+		t.Fatalf("buildGHRestClient returned an error: %v", err)
+	}
+	if got := client.UploadURL.String(); got != "https://github.example.com/api/uploads/" {
+		t.Errorf("Expected the upload URL of the hostname, got %s", got)
+	}
+}
+
+func TestBuildGHRestClientInvalidURL(t *testing.T) {
+	if _, err := buildGHRestClient("token", "github.example.com", "://github.example.com/api/v3", ""); err == nil || !strings.Contains(err.Error(), "invalid API URL") {
+		t.Errorf("Expected an error for an invalid API URL, got %v", err)
+	}
+	if _, err := buildGHRestClient("token", "github.example.com", "", "://github.example.com/api/uploads"); err == nil || !strings.Contains(err.Error(), "invalid upload URL") {
+		t.Errorf("Expected an error for an invalid upload URL, got %v", err)
+	}
+
+	// The error is returned by the API calls instead of a panic
+	viper.Set("TARGET_API_URL", "://github.example.com/api/v3")
+	defer viper.Reset()
+	if _, err := GetTargetRepository("owner", "repo"); err == nil || !strings.Contains(err.Error(), "invalid API URL") {
+		t.Errorf("Expected GetTargetRepository to return the client error, got %v", err)
+	}
+}
+
+func TestTargetAPIURLRoutesRequests(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1, "tag_name": "v1.0.0"}`))
+	}))
+	defer server.Close()
+
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_API_URL", server.URL+"/github/api/v3")
+	defer viper.Reset()
+
+	if _, err := CreateRelease("target-org", "repo", &github.RepositoryRelease{TagName: github.String("v1.0.0")}); err != nil {
+		t.Fatalf("CreateRelease returned an error: %v", err)
+	}
+	if path != "/github/api/v3/repos/target-org/repo/releases" {
+		t.Errorf("Expected the request under the explicit API URL, got %s", path)
+	}
+}
+
+func TestValidateEndpointURL(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"https://github.example.com/github/api/v3", true},
+		{"http://proxy.internal:8080/api/v3/", true},
+		{"github.example.com/api/v3", false},
+		{"ftp://github.example.com/api/v3", false},
+		{"https:///api/v3", false},
+		{"https://github.example.com/api/v3?route=github", false},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateEndpointURL(tt.value); (err == nil) != tt.valid {
+			t.Errorf("ValidateEndpointURL(%q) = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestResolvesToGitHubDotCom(t *testing.T) {
+	tests := []struct {
+		hostname string
+		apiURL   string
+		want     bool
+	}{
+		{"", "", true},
+		{"github.com", "", true},
+		{"https://github.com/", "", true},
+		{"github.example.com", "", false},
+		{"octocorp.ghe.com", "", false},
+		{"", "https://github.example.com/github/api/v3", false},
+		{"github.example.com", "https://api.github.com/", true},
+	}
+
+	for _, tt := range tests {
+		if got := ResolvesToGitHubDotCom(tt.hostname, tt.apiURL); got != tt.want {
+			t.Errorf("ResolvesToGitHubDotCom(%q, %q) = %v, want %v", tt.hostname, tt.apiURL, got, tt.want)
+		}
+	}
+}
+
+func TestCreateReleaseAlreadyExists(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	// The error is detected by its code whatever the wording of the message
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed", "errors": [{"resource": "Release", "code": "already_exists", "field": "tag_name", "message": "some new wording"}]}`))
+	}))
+
+	_, err := CreateRelease("target-org", "repo", &github.RepositoryRelease{TagName: github.String("v1.0.0"), Name: github.String("v1.0.0")})
+	if !errors.Is(err, ErrReleaseExists) {
+		t.Errorf("Expected ErrReleaseExists, got %v", err)
+	}
+}
+
+func TestCreateReleaseOtherValidationError(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed", "errors": [{"resource": "Release", "code": "invalid", "field": "target_commitish", "message": "release already exists elsewhere"}]}`))
+	}))
+
+	_, err := CreateRelease("target-org", "repo", &github.RepositoryRelease{TagName: github.String("v1.0.0")})
+	if err == nil || errors.Is(err, ErrReleaseExists) {
+		t.Errorf("Expected a validation error other than ErrReleaseExists, got %v", err)
+	}
+}
+
+func TestWriteToIssueForbidden(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	}))
+
+	err := WriteToIssue("org", "repo", 1, "Migrated")
+	if !errors.Is(err, ErrIssueCommentForbidden) {
+		t.Errorf("Expected ErrIssueCommentForbidden, got %v", err)
+	}
+}
+
+func TestUpsertIssueCommentEditsMarkedComment(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	var edited string
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/org/repo/issues/1/comments":
+			w.Write([]byte(`[{"id": 10, "body": "Started"}, {"id": 11, "body": "<!-- marker -->\nold"}]`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v3/repos/org/repo/issues/comments/11":
+			var comment github.IssueComment
+			json.NewDecoder(r.Body).Decode(&comment)
+			edited = comment.GetBody()
+			w.Write([]byte(`{"id": 11}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+
+	commentID, err := UpsertIssueComment("org", "repo", 1, 0, "<!-- marker -->", "<!-- marker -->\nnew")
+	if err != nil {
+		t.Fatalf("UpsertIssueComment returned an error: %v", err)
+	}
+	if commentID != 11 || edited != "<!-- marker -->\nnew" {
+		t.Errorf("Expected comment 11 to be edited, got comment %d with %q", commentID, edited)
+	}
+}
+
+func TestListSourceRepositoriesPaginated(t *testing.T) {
+	viper.Set("SOURCE_TOKEN", "source-token")
+	defer viper.Reset()
+
+	var server *httptest.Server
+	server = setupTestClient(t, "source-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/orgs/source-org/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "", "1":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/orgs/source-org/repos?page=2>; rel="next"`, server.URL))
+			w.Write([]byte(`[{"name": "tools"}, {"name": "cli", "archived": true}]`))
+		case "2":
+			w.Write([]byte(`[{"name": "docs", "fork": true}]`))
+		default:
+			t.Errorf("Unexpected page %s", r.URL.Query().Get("page"))
+		}
+	}))
+
+	repositories, err := ListSourceRepositories("source-org")
+	if err != nil {
+		t.Fatalf("ListSourceRepositories returned an error: %v", err)
+	}
+
+	var names []string
+	for _, repository := range repositories {
+		names = append(names, repository.GetName())
+	}
+	if strings.Join(names, ",") != "tools,cli,docs" {
+		t.Errorf("Expected the repositories of both pages, got %v", names)
+	}
+}
+
+func TestUploadAssetClosesFile(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	t.Cleanup(viper.Reset)
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+
+	var opened []*os.File
+	openAssetFile = func(fileName string) (*os.File, error) {
+		file, err := os.Open(fileName)
+		if err == nil {
+			opened = append(opened, file)
+		}
+		return file, err
+	}
+	t.Cleanup(func() { openAssetFile = files.OpenFile })
+
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":7}`))
+	}))
+	t.Cleanup(server.Close)
+
+	uploadURL := server.URL + "/repos/target-org/repo/releases/1/assets{?name,label}"
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	for _, code := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		status = code
+		if err := os.WriteFile(filepath.Join(tmpDir, "app.zip"), []byte("asset contents"), 0644); err != nil {
+			t.Fatalf("Failed to write asset: %v", err)
+		}
+		err := uploadAsset(uploadURL, asset, "app.zip")
+		if (err == nil) != (code == http.StatusCreated) {
+			t.Fatalf("Unexpected result of the upload answered with %d: %v", code, err)
+		}
+	}
+
+	if len(opened) != 2 {
+		t.Fatalf("Expected the asset to be opened twice, got %d", len(opened))
+	}
+	for i, file := range opened {
+		if err := file.Close(); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Expected upload %d to close the asset file, got %v", i+1, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "app.zip")); !os.IsNotExist(err) {
+		t.Errorf("Expected the uploaded asset to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// Installation tokens last an hour and are renewed this long before they expire, so a request
+// never goes out with an expired token
+const appTokenRenewal = 5 * time.Minute
+
+// appCredentials authenticate as a GitHub App installation instead of with a personal access
+// token
+type appCredentials struct {
+	appID          int64
+	installationID int64
+	privateKey     *rsa.PrivateKey
+	// apiURL is the API base URL of the side, ending with a slash
+	apiURL string
+}
+
+// appToken is the installation token of an app, renewed when it is about to expire
+type appToken struct {
+	mu          sync.Mutex
+	credentials appCredentials
+	token       string
+	expiresAt   time.Time
+	now         func() time.Time
+}
+
+// appTokens are the installation tokens of the source and target, keyed by SOURCE_TOKEN or
+// TARGET_TOKEN. A side without app credentials uses its personal access token.
+var appTokens = struct {
+	mu     sync.Mutex
+	tokens map[string]*appToken
+}{tokens: map[string]*appToken{}}
+
+// loadAppCredentials reads the app credentials of a side, e.g. SOURCE_APP_ID,
+// SOURCE_APP_INSTALLATION_ID and SOURCE_APP_PRIVATE_KEY_FILE for SOURCE. It returns false when
+// none of them are set.
+func loadAppCredentials(side string) (appCredentials, bool, error) {
+	appID := viper.GetInt64(side + "_APP_ID")
+	installationID := viper.GetInt64(side + "_APP_INSTALLATION_ID")
+	keyFile := viper.GetString(side + "_APP_PRIVATE_KEY_FILE")
+	if appID == 0 && installationID == 0 && keyFile == "" {
+		return appCredentials{}, false, nil
+	}
+	if appID == 0 || installationID == 0 || keyFile == "" {
+		return appCredentials{}, true, fmt.Errorf("app authentication needs an app ID, an installation ID and a private key file")
+	}
+
+	privateKey, err := loadPrivateKey(keyFile)
+	if err != nil {
+		return appCredentials{}, true, err
+	}
+	apiURL := viper.GetString(side + "_API_URL")
+	if apiURL == "" {
+		apiURL, _ = endpointURLs(viper.GetString(side + "_HOSTNAME"))
+	}
+	return appCredentials{appID: appID, installationID: installationID, privateKey: privateKey, apiURL: withTrailingSlash(apiURL)}, true, nil
+}
+
+// loadPrivateKey reads the PEM private key of an app, as downloaded from its settings
+func loadPrivateKey(fileName string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read private key file: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key file %s is not PEM encoded", fileName)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %v", fileName, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not an RSA key", fileName)
+	}
+	return key, nil
+}
+
+// appJWT returns the JSON Web Token authenticating as the app itself, valid for 10 minutes with
+// an issue date in the past to allow for clock drift
+func appJWT(credentials appCredentials, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": credentials.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, credentials.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing app token: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// createInstallationToken exchanges the app JWT for a token of the installation
+func createInstallationToken(credentials appCredentials, now time.Time) (string, time.Time, error) {
+	jwt, err := appJWT(credentials, now)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%sapp/installations/%d/access_tokens", credentials.apiURL, credentials.installationID)
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := rawHTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("unable to create installation token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("unable to create token of installation %d: status code %d", credentials.installationID, resp.StatusCode)
+	}
+
+	var created struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || created.Token == "" {
+		return "", time.Time{}, fmt.Errorf("unable to parse installation token: %v", err)
+	}
+	return created.Token, created.ExpiresAt, nil
+}
+
+// get returns the installation token, creating a new one when it is about to expire
+func (t *appToken) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.token != "" && now.Add(appTokenRenewal).Before(t.expiresAt) {
+		return t.token, nil
+	}
+	token, expiresAt, err := createInstallationToken(t.credentials, now)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// resolveAppToken returns the installation token of a side, SOURCE or TARGET, and registers it so
+// it is renewed during the run. It returns false when the side has no app credentials.
+func resolveAppToken(side string) (string, bool, error) {
+	credentials, ok, err := loadAppCredentials(side)
+	if !ok || err != nil {
+		return "", ok, err
+	}
+	source := &appToken{credentials: credentials, now: time.Now}
+	token, err := source.get()
+	if err != nil {
+		return "", true, err
+	}
+
+	appTokens.mu.Lock()
+	defer appTokens.mu.Unlock()
+	appTokens.tokens[side+"_TOKEN"] = source
+	return token, true, nil
+}
+
+// currentToken returns the token of SOURCE_TOKEN or TARGET_TOKEN, renewing the installation token
+// of an app before it expires
+func currentToken(key string) string {
+	appTokens.mu.Lock()
+	source, ok := appTokens.tokens[key]
+	appTokens.mu.Unlock()
+	if !ok {
+		return viper.GetString(key)
+	}
+
+	token, err := source.get()
+	if err != nil {
+		// The current token may still be accepted
+		pterm.Warning.Printf("Error renewing the %s: %v\n", strings.ToLower(strings.ReplaceAll(key, "_", " ")), err)
+		return viper.GetString(key)
+	}
+	viper.Set(key, token)
+	return token
+}
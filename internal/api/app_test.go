@@ -0,0 +1,156 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// useAppServer serves installation tokens for installation 42 of app 7, checking the app JWT
+// against key, and returns the number of tokens created
+func useAppServer(t *testing.T, key *rsa.PrivateKey) *int {
+	t.Helper()
+
+	var created int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			http.Error(w, "invalid JWT", http.StatusUnauthorized)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var claims struct {
+			Issuer int64 `json:"iss"`
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if json.Unmarshal(payload, &claims) != nil || claims.Issuer != 7 {
+			http.Error(w, "unexpected issuer", http.StatusUnauthorized)
+			return
+		}
+		created++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"installation-token-%d","expires_at":%q}`, created, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	t.Cleanup(server.Close)
+
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(keyFile, pemKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("SOURCE_API_URL", server.URL)
+	viper.Set("SOURCE_APP_ID", 7)
+	viper.Set("SOURCE_APP_INSTALLATION_ID", 42)
+	viper.Set("SOURCE_APP_PRIVATE_KEY_FILE", keyFile)
+	viper.Set("TARGET_TOKEN", "target-secret")
+	t.Cleanup(func() {
+		viper.Reset()
+		appTokens.tokens = map[string]*appToken{}
+	})
+	return &created
+}
+
+func TestResolveTokensWithApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := useAppServer(t, key)
+
+	if err := ResolveTokens(); err != nil {
+		t.Fatalf("ResolveTokens returned an error: %v", err)
+	}
+	if viper.GetString("SOURCE_TOKEN") != "installation-token-1" || viper.GetString("TARGET_TOKEN") != "target-secret" {
+		t.Errorf("Expected the source installation token and the target personal access token")
+	}
+
+	// The token is reused until it is about to expire
+	if got := currentToken("SOURCE_TOKEN"); got != "installation-token-1" || *created != 1 {
+		t.Errorf("Expected the installation token to be reused, got %s after %d tokens", got, *created)
+	}
+	appTokens.tokens["SOURCE_TOKEN"].now = func() time.Time { return time.Now().Add(58 * time.Minute) }
+	if got := currentToken("SOURCE_TOKEN"); got != "installation-token-2" || viper.GetString("SOURCE_TOKEN") != got {
+		t.Errorf("Expected a renewed installation token, got %s", got)
+	}
+	if got := currentToken("TARGET_TOKEN"); got != "target-secret" {
+		t.Errorf("Expected the target personal access token, got %s", got)
+	}
+}
+
+func TestResolveTokensWithAppErrors(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useAppServer(t, key)
+
+	// App credentials and a token for the same side
+	viper.Set("SOURCE_TOKEN", "source-secret")
+	if err := ResolveTokens(); err == nil || !strings.Contains(err.Error(), "both a token and app credentials") {
+		t.Errorf("Expected an error for a token with app credentials, got %v", err)
+	}
+	viper.Set("SOURCE_TOKEN", "")
+
+	// Incomplete app credentials
+	viper.Set("SOURCE_APP_INSTALLATION_ID", 0)
+	if err := ResolveTokens(); err == nil || !strings.Contains(err.Error(), "needs an app ID, an installation ID and a private key file") {
+		t.Errorf("Expected an error for incomplete app credentials, got %v", err)
+	}
+
+	// An installation the app doesn't have
+	viper.Set("SOURCE_APP_INSTALLATION_ID", 43)
+	if err := ResolveTokens(); err == nil || !strings.Contains(err.Error(), "status code 404") {
+		t.Errorf("Expected an error for an unknown installation, got %v", err)
+	}
+}
+
+func TestLoadPrivateKeyPKCS8(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadPrivateKey(keyFile)
+	if err != nil || !loaded.Equal(key) {
+		t.Errorf("Expected the PKCS#8 key to be loaded, got %v", err)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPrivateKey(keyFile); err == nil {
+		t.Error("Expected an error for a file without a PEM key")
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestDownloadReleaseZipRedirect(t *testing.T) {
+	content := []byte("zip contents")
+	sum := sha256.Sum256(content)
+
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "unexpected authorization header", http.StatusBadRequest)
+			return
+		}
+		w.Write(content)
+	}))
+	defer storage.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, storage.URL+"/archive.zip", http.StatusFound)
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	viper.Set("SOURCE_TOKEN", "token")
+	viper.Set("REPOSITORY", "repo")
+	defer viper.Reset()
+
+	release := &github.RepositoryRelease{TagName: github.String("v1.0.0"), ZipballURL: github.String(server.URL + "/zipball/v1.0.0")}
+	err := DownloadReleaseZip(release, hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("DownloadReleaseZip returned an error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "repo-1.0.0.zip"))
+	if err != nil || string(data) != string(content) {
+		t.Errorf("Downloaded archive does not match the expected content")
+	}
+}
+
+func TestDownloadReleaseTarballChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tarball contents"))
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	viper.Set("SOURCE_TOKEN", "token")
+	viper.Set("REPOSITORY", "repo")
+	defer viper.Reset()
+
+	release := &github.RepositoryRelease{TagName: github.String("v1.0.0"), TarballURL: github.String(server.URL)}
+	err := DownloadReleaseTarball(release, "0000")
+	if err == nil {
+		t.Errorf("Expected a checksum mismatch error")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "repo-1.0.0.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("Archive with a mismatched checksum should be removed")
+	}
+}
+
+func TestDownloadFileFromURLTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("only a few bytes"))
+	}))
+	defer server.Close()
+
+	fileName := filepath.Join(t.TempDir(), "truncated.zip")
+	err := DownloadFileFromURL(server.URL, fileName, "token")
+	if err == nil {
+		t.Errorf("Expected an error for a truncated download")
+	}
+}
+
+func TestDownloadFileFromURLInvalidURL(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "asset.zip")
+	if err := DownloadFileFromURL("http://[::1", fileName, "token"); err == nil {
+		t.Errorf("Expected an error for an invalid download URL")
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"release/2.1":    "release-2.1",
+		"v1.0+build.5":   "v1.0+build.5",
+		"my release 1.0": "my-release-1.0",
+	}
+
+	for tag, want := range tests {
+		if got := sanitizeFileName(tag); got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestDownloadReleaseZipSlashTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("zip contents"))
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	viper.Set("SOURCE_TOKEN", "token")
+	viper.Set("REPOSITORY", "repo")
+	defer viper.Reset()
+
+	release := &github.RepositoryRelease{TagName: github.String("release/2.1"), ZipballURL: github.String(server.URL)}
+	err := DownloadReleaseZip(release, "")
+	if err != nil {
+		t.Fatalf("DownloadReleaseZip returned an error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "repo-release-2.1.zip")); err != nil {
+		t.Errorf("Expected the archive to be written with a sanitized name: %v", err)
+	}
+}
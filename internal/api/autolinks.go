@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// listAutolinks returns all the autolink references of a repository
+func listAutolinks(ctx context.Context, client *github.Client, owner string, repository string) ([]*github.Autolink, error) {
+	var allAutolinks []*github.Autolink
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		autolinks, resp, err := client.Repositories.ListAutolinks(ctx, owner, repository, opts)
+		if err != nil {
+			return nil, err
+		}
+		allAutolinks = append(allAutolinks, autolinks...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allAutolinks, nil
+}
+
+// MigrateAutolinks recreates the autolink references of a source repository in the target
+// repository so references such as JIRA-123 in release bodies render as links there too.
+// Autolinks whose key prefix already exists in the target are skipped. It returns the number of
+// autolinks created.
+func MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error) {
+	sourceClient, err := newSourceClient()
+	if err != nil {
+		return 0, err
+	}
+	targetClient, err := newTargetClient()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	sourceAutolinks, err := listAutolinks(ctx, sourceClient, sourceOwner, repository)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get source autolinks: %v", err)
+	}
+	targetAutolinks, err := listAutolinks(ctx, targetClient, targetOwner, repository)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get target autolinks: %v", err)
+	}
+
+	existing := make(map[string]bool, len(targetAutolinks))
+	for _, autolink := range targetAutolinks {
+		existing[autolink.GetKeyPrefix()] = true
+	}
+
+	var created int
+	for _, autolink := range sourceAutolinks {
+		if existing[autolink.GetKeyPrefix()] {
+			continue
+		}
+		_, _, err := targetClient.Repositories.AddAutolink(ctx, targetOwner, repository, &github.AutolinkOptions{
+			KeyPrefix:      autolink.KeyPrefix,
+			URLTemplate:    autolink.URLTemplate,
+			IsAlphanumeric: autolink.IsAlphanumeric,
+		})
+		if err != nil {
+			return created, fmt.Errorf("unable to create autolink %s: %v", autolink.GetKeyPrefix(), err)
+		}
+		created++
+	}
+
+	return created, nil
+}
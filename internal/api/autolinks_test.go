@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMigrateAutolinks(t *testing.T) {
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_HOSTNAME", "source.example.com")
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "target.example.com")
+	defer viper.Reset()
+
+	source := http.NewServeMux()
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/autolinks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id":1,"key_prefix":"JIRA-","url_template":"https://jira.example.com/browse/JIRA-<num>","is_alphanumeric":false},
+			{"id":2,"key_prefix":"TICKET-","url_template":"https://tickets.example.com/<num>","is_alphanumeric":true}
+		]`))
+	})
+	setupTestClient(t, "source-token", "source.example.com", source)
+
+	var created []map[string]interface{}
+	target := http.NewServeMux()
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/autolinks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":9,"key_prefix":"TICKET-","url_template":"https://tickets.example.com/<num>","is_alphanumeric":true}]`))
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/autolinks", func(w http.ResponseWriter, r *http.Request) {
+		var autolink map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&autolink)
+		created = append(created, autolink)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":10}`))
+	})
+	setupTestClient(t, "target-token", "target.example.com", target)
+
+	count, err := MigrateAutolinks("source-org", "target-org", "repo")
+	if err != nil {
+		t.Fatalf("MigrateAutolinks returned an error: %v", err)
+	}
+
+	// The existing TICKET- autolink is skipped
+	if count != 1 || len(created) != 1 {
+		t.Fatalf("Expected 1 autolink to be created, got %d", len(created))
+	}
+	if created[0]["key_prefix"] != "JIRA-" || created[0]["url_template"] != "https://jira.example.com/browse/JIRA-<num>" || created[0]["is_alphanumeric"] != false {
+		t.Errorf("Created autolink does not match the source: %v", created[0])
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+var cacheHits, cacheMisses atomic.Int64
+
+// CacheStats returns the number of asset downloads served from and added to the asset cache
+func CacheStats() (int64, int64) {
+	return cacheHits.Load(), cacheMisses.Load()
+}
+
+// assetsPerPage returns the page size of the asset listings set by ASSET_PER_PAGE, clamped to the
+// 1 to 100 range GitHub accepts and defaulting to 100. Smaller pages avoid the latency spikes of
+// listing hundreds of assets at once on some GitHub Enterprise Server instances.
+func assetsPerPage() int {
+	perPage := viper.GetInt("ASSET_PER_PAGE")
+	switch {
+	case perPage <= 0 || perPage > 100:
+		return 100
+	default:
+		return perPage
+	}
+}
+
+// GetReleaseAssetDigests returns the sha256 digests of the assets of a source release keyed by
+// asset ID. go-github does not expose the digest field, so the assets are listed directly.
+func GetReleaseAssetDigests(owner string, repository string, releaseID int64) (map[int64]string, error) {
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	digests := make(map[int64]string)
+	perPage := assetsPerPage()
+	page := 1
+	for {
+		u := fmt.Sprintf("repos/%s/%s/releases/%d/assets?per_page=%d&page=%d", owner, repository, releaseID, perPage, page)
+		req, err := client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %v", err)
+		}
+
+		var assets []struct {
+			ID     int64  `json:"id"`
+			Digest string `json:"digest"`
+		}
+		resp, err := client.Do(ctx, req, &assets)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get release assets: %v", err)
+		}
+
+		for _, asset := range assets {
+			if sha, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok {
+				digests[asset.ID] = sha
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		page = resp.NextPage
+	}
+
+	return digests, nil
+}
+
+// cacheDirectory returns the directory of the asset cache, kept between runs next to their temp
+// directories with ASSET_CACHE, or only used during the run to deduplicate downloads with
+// DEDUPE_ASSETS
+func cacheDirectory() string {
+	if viper.GetBool("ASSET_CACHE") {
+		return filepath.Join(tempRootDir(), "cache")
+	}
+	return filepath.Join(TempDir(), "run-cache")
+}
+
+// ClearRunCache removes the assets kept during the run to deduplicate downloads, unless KEEP_TMP
+// keeps them
+func ClearRunCache() error {
+	if viper.GetBool("KEEP_TMP") {
+		pterm.Info.Printf("Keeping deduplicated assets in %s\n", filepath.Join(TempDir(), "run-cache"))
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(TempDir(), "run-cache"))
+}
+
+// DownloadReleaseAssetsCached downloads an asset through the content-addressed cache stored
+// under the temp directory. An asset whose digest is already cached is not downloaded again.
+func DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	if digest == "" {
+		return DownloadReleaseAssets(asset)
+	}
+
+	cacheDir := cacheDirectory()
+	cachedFile := filepath.Join(cacheDir, digest)
+	fileName := localAssetPath(asset)
+
+	if _, err := os.Stat(cachedFile); err == nil {
+		cacheHits.Add(1)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+		return copyFile(cachedFile, fileName)
+	}
+
+	cacheMisses.Add(1)
+	err := DownloadReleaseAssets(asset)
+	if err != nil {
+		return err
+	}
+
+	sum, err := fileSHA256(fileName)
+	if err != nil {
+		return err
+	}
+	if sum != digest {
+		return fmt.Errorf("digest mismatch for asset %s: expected %s, got %s", asset.GetName(), digest, sum)
+	}
+
+	err = os.MkdirAll(cacheDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return copyFile(fileName, cachedFile)
+}
+
+func fileSHA256(fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyFile copies src to dst through a temporary file so that dst is never partially written
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// Concurrent copies of the same asset use different temporary files
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.part")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestDownloadReleaseAssetsCached(t *testing.T) {
+	content := []byte("installer contents")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+	viper.Set("SOURCE_TOKEN", "token")
+
+	hits, misses := CacheStats()
+
+	// The same asset attached to releases of two different repositories
+	first := &github.ReleaseAsset{Name: github.String("installer.exe"), URL: github.String(server.URL + "/repo1/assets/1")}
+	second := &github.ReleaseAsset{Name: github.String("installer.exe"), URL: github.String(server.URL + "/repo2/assets/2")}
+
+	for _, asset := range []*github.ReleaseAsset{first, second} {
+		err := DownloadReleaseAssetsCached(asset, digest)
+		if err != nil {
+			t.Fatalf("DownloadReleaseAssetsCached returned an error: %v", err)
+		}
+
+		data, err := os.ReadFile(tmpDir + "/installer.exe")
+		if err != nil || string(data) != string(content) {
+			t.Errorf("Downloaded asset does not match the expected content")
+		}
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 download request, got %d", requests.Load())
+	}
+
+	newHits, newMisses := CacheStats()
+	if newHits-hits != 1 || newMisses-misses != 1 {
+		t.Errorf("Expected 1 cache hit and 1 miss, got %d and %d", newHits-hits, newMisses-misses)
+	}
+}
+
+func TestDownloadReleaseAssetsCachedDigestMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted"))
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+	viper.Set("SOURCE_TOKEN", "token")
+
+	asset := &github.ReleaseAsset{Name: github.String("bin.zip"), URL: github.String(server.URL)}
+	err := DownloadReleaseAssetsCached(asset, "0000")
+	if err == nil {
+		t.Errorf("Expected a digest mismatch error")
+	}
+
+	if _, err := os.Stat(tmpDir + "/cache/0000"); !os.IsNotExist(err) {
+		t.Errorf("Mismatched asset should not be added to the cache")
+	}
+}
+
+func TestDownloadReleaseAssetsCachedWithinRun(t *testing.T) {
+	content := []byte("LICENSE contents")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+	viper.Set("SOURCE_TOKEN", "token")
+	viper.Set("DEDUPE_ASSETS", true)
+	defer viper.Reset()
+
+	// The same asset attached to two releases of a repository
+	for _, id := range []string{"1", "2"} {
+		asset := &github.ReleaseAsset{Name: github.String("LICENSE"), URL: github.String(server.URL + "/repo/assets/" + id)}
+		if err := DownloadReleaseAssetsCached(asset, digest); err != nil {
+			t.Fatalf("DownloadReleaseAssetsCached returned an error: %v", err)
+		}
+		// Each release gets its own copy to upload
+		if data, err := os.ReadFile(tmpDir + "/LICENSE"); err != nil || string(data) != string(content) {
+			t.Errorf("Downloaded asset does not match the expected content")
+		}
+		os.Remove(tmpDir + "/LICENSE")
+	}
+
+	if requests.Load() != 1 {
+		t.Errorf("Expected 1 download request, got %d", requests.Load())
+	}
+	if _, err := os.Stat(tmpDir + "/cache"); !os.IsNotExist(err) {
+		t.Errorf("Deduplicated assets should not be added to the asset cache kept between runs")
+	}
+
+	if err := ClearRunCache(); err != nil {
+		t.Fatalf("ClearRunCache returned an error: %v", err)
+	}
+	if _, err := os.Stat(tmpDir + "/run-cache"); !os.IsNotExist(err) {
+		t.Errorf("Expected the deduplicated assets to be removed")
+	}
+}
+
+func TestGetReleaseAssetDigestsPerPage(t *testing.T) {
+	var perPage string
+	setupTestClient(t, "per-page-token", "per-page.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perPage = r.URL.Query().Get("per_page")
+		w.Write([]byte(`[{"id":1,"digest":"sha256:abc"}]`))
+	}))
+	viper.Set("SOURCE_TOKEN", "per-page-token")
+	viper.Set("SOURCE_HOSTNAME", "per-page.example.com")
+	defer viper.Reset()
+
+	tests := []struct {
+		configured int
+		expected   string
+	}{
+		{0, "100"},
+		{25, "25"},
+		{1, "1"},
+		{250, "100"},
+		{-5, "100"},
+	}
+	for _, test := range tests {
+		viper.Set("ASSET_PER_PAGE", test.configured)
+		digests, err := GetReleaseAssetDigests("owner", "repo", 1)
+		if err != nil {
+			t.Fatalf("GetReleaseAssetDigests returned an error: %v", err)
+		}
+		if digests[1] != "abc" {
+			t.Errorf("Expected the digest of asset 1, got %v", digests)
+		}
+		if perPage != test.expected {
+			t.Errorf("With ASSET_PER_PAGE %d, expected per_page %s, got %s", test.configured, test.expected, perPage)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// checksumAlgorithms are the hash algorithms of the checksums files, selected with CHECKSUMS_ALGORITHM
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// ParseChecksumAlgorithm validates a CHECKSUMS_ALGORITHM value, defaulting to sha256
+func ParseChecksumAlgorithm(value string) (string, error) {
+	algorithm := strings.ToLower(strings.TrimSpace(value))
+	if algorithm == "" {
+		return "sha256", nil
+	}
+	if _, ok := checksumAlgorithms[algorithm]; !ok {
+		return "", fmt.Errorf("unknown checksum algorithm %q, valid algorithms are: md5, sha1, sha256, sha512", value)
+	}
+	return algorithm, nil
+}
+
+// NewChecksumHash returns a hash of an algorithm validated by ParseChecksumAlgorithm
+func NewChecksumHash(algorithm string) hash.Hash {
+	return checksumAlgorithms[algorithm]()
+}
+
+// FormatChecksums renders checksums keyed by asset name in the format of sha256sum and similar
+// tools, sorted by name
+func FormatChecksums(checksums map[string]string) []byte {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&builder, "%s  %s\n", checksums[name], name)
+	}
+	return []byte(builder.String())
+}
+
+// HashTargetAsset downloads an asset of a target release without keeping it and returns its
+// hex-encoded checksum
+func HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error) {
+	target := targetEndpoints()
+	assetURL := rebaseURL(asset.GetURL(), target.apiURL)
+	if err := checkTransferURL(assetURL, target, sourceEndpoints()); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("GET", assetURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Add("Authorization", "Bearer "+currentToken("TARGET_TOKEN"))
+	req.Header.Add("Accept", "application/octet-stream")
+
+	waitForTransferRequest()
+	resp, err := rawHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error getting asset %s: %v", asset.GetName(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error getting asset %s: HTTP status code %d", asset.GetName(), resp.StatusCode)
+	}
+
+	checksum := NewChecksumHash(algorithm)
+	if _, err := io.Copy(checksum, throttleReader(resp.Body)); err != nil {
+		return "", fmt.Errorf("error reading asset %s: %v", asset.GetName(), err)
+	}
+	return hex.EncodeToString(checksum.Sum(nil)), nil
+}
+
+// WriteAssetFile writes a generated asset, such as a checksums file, to the temp directory and
+// returns it ready to be uploaded
+func WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error) {
+	if err := os.MkdirAll(TempDir(), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(TempDir(), name), content, 0644); err != nil {
+		return nil, fmt.Errorf("error writing asset %s: %v", name, err)
+	}
+	return &github.ReleaseAsset{
+		Name:        github.String(name),
+		ContentType: github.String("text/plain"),
+		Size:        github.Int(len(content)),
+	}, nil
+}
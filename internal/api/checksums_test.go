@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestHashTargetAsset(t *testing.T) {
+	content := []byte("installer contents")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer target-token" || r.Header.Get("Accept") != "application/octet-stream" {
+			t.Errorf("Unexpected headers %v", r.Header)
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	sha256Sum := sha256.Sum256(content)
+	sha512Sum := sha512.Sum512(content)
+	expected := map[string]string{
+		"sha256": hex.EncodeToString(sha256Sum[:]),
+		"sha512": hex.EncodeToString(sha512Sum[:]),
+	}
+
+	asset := &github.ReleaseAsset{Name: github.String("installer.exe"), URL: github.String(server.URL + "/assets/1")}
+	for algorithm, want := range expected {
+		got, err := HashTargetAsset(asset, algorithm)
+		if err != nil {
+			t.Fatalf("HashTargetAsset returned an error: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s checksum is %s, want %s", algorithm, got, want)
+		}
+	}
+}
+
+func TestFormatChecksums(t *testing.T) {
+	got := string(FormatChecksums(map[string]string{"b.zip": "22", "a.tar.gz": "11"}))
+	if want := "11  a.tar.gz\n22  b.zip\n"; got != want {
+		t.Errorf("FormatChecksums returned %q, want %q", got, want)
+	}
+}
+
+func TestParseChecksumAlgorithm(t *testing.T) {
+	if algorithm, err := ParseChecksumAlgorithm(""); err != nil || algorithm != "sha256" {
+		t.Errorf("Expected sha256 by default, got %q: %v", algorithm, err)
+	}
+	if algorithm, err := ParseChecksumAlgorithm("SHA512"); err != nil || algorithm != "sha512" {
+		t.Errorf("Expected sha512, got %q: %v", algorithm, err)
+	}
+	if _, err := ParseChecksumAlgorithm("crc32"); err == nil {
+		t.Errorf("Expected an error for an unknown algorithm")
+	}
+}
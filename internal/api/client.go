@@ -0,0 +1,145 @@
+package api
+
+import "github.com/google/go-github/v62/github"
+
+// Client is the set of GitHub operations used to migrate releases
+type Client interface {
+	GetSourceRepository(owner string, repository string) (*github.Repository, error)
+	GetTargetRepository(owner string, repository string) (*github.Repository, error)
+	ListSourceRepositories(organization string) ([]*github.Repository, error)
+	GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error)
+	GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error)
+	GetSourceTagsWithoutReleases(owner string, repository string, releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, error)
+	GetReleaseAssetDigests(owner string, repository string, releaseID int64) (map[int64]string, error)
+	GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error)
+	GetTargetRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error)
+	GetReleaseByTag(owner string, repository string, tagName string) (*github.RepositoryRelease, error)
+	ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool)
+	MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error)
+	CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error)
+	MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error)
+	CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error)
+	UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error)
+	SetLatestRelease(owner string, repository string, releaseID int64) error
+	DeleteReleaseAsset(owner string, repository string, assetID int64) error
+	DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error
+	SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error)
+	HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error)
+	WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error)
+	UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error
+	UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error
+	WriteToIssue(owner string, repository string, issueNumber int, comment string) error
+	UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error)
+}
+
+// restClient implements Client with the GitHub REST API
+type restClient struct{}
+
+// NewRESTClient returns a Client backed by the GitHub REST API
+func NewRESTClient() Client {
+	return restClient{}
+}
+
+func (restClient) GetSourceRepository(owner string, repository string) (*github.Repository, error) {
+	return GetSourceRepository(owner, repository)
+}
+
+func (restClient) ListSourceRepositories(organization string) ([]*github.Repository, error) {
+	return ListSourceRepositories(organization)
+}
+
+func (restClient) GetTargetRepository(owner string, repository string) (*github.Repository, error) {
+	return GetTargetRepository(owner, repository)
+}
+
+func (restClient) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	return GetSourceRepositoryReleases(owner, repository)
+}
+
+func (restClient) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	return GetSourceRepositoryLatestRelease(owner, repository)
+}
+
+func (restClient) GetSourceTagsWithoutReleases(owner string, repository string, releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, error) {
+	return GetSourceTagsWithoutReleases(owner, repository, releases)
+}
+
+func (restClient) GetReleaseAssetDigests(owner string, repository string, releaseID int64) (map[int64]string, error) {
+	return GetReleaseAssetDigests(owner, repository, releaseID)
+}
+
+func (restClient) GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	return GetTargetRepositoryReleases(owner, repository)
+}
+
+func (restClient) GetTargetRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	return GetTargetRepositoryLatestRelease(owner, repository)
+}
+
+func (restClient) GetReleaseByTag(owner string, repository string, tagName string) (*github.RepositoryRelease, error) {
+	return GetReleaseByTag(owner, repository, tagName)
+}
+
+func (restClient) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	return ReleaseExists(owner, repository, release)
+}
+
+func (restClient) MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return MigrateAnnotatedTag(sourceOwner, targetOwner, repository, tagName)
+}
+
+func (restClient) CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return CreateMissingTag(sourceOwner, targetOwner, repository, tagName)
+}
+
+func (restClient) MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error) {
+	return MigrateAutolinks(sourceOwner, targetOwner, repository)
+}
+
+func (restClient) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	return CreateRelease(owner, repository, release)
+}
+
+func (restClient) UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	return UpdateRelease(owner, repository, releaseID, release)
+}
+
+func (restClient) SetLatestRelease(owner string, repository string, releaseID int64) error {
+	return SetLatestRelease(owner, repository, releaseID)
+}
+
+func (restClient) DeleteReleaseAsset(owner string, repository string, assetID int64) error {
+	return DeleteReleaseAsset(owner, repository, assetID)
+}
+
+func (restClient) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	return DownloadReleaseAssetsCached(asset, digest)
+}
+
+func (restClient) SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error) {
+	return SplitAsset(asset, partSize)
+}
+
+func (restClient) HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error) {
+	return HashTargetAsset(asset, algorithm)
+}
+
+func (restClient) WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error) {
+	return WriteAssetFile(name, content)
+}
+
+func (restClient) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	return UploadAssetViaURL(uploadURL, asset)
+}
+
+func (restClient) UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error {
+	return UploadAssetAs(uploadURL, asset, name)
+}
+
+func (restClient) WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
+	return WriteToIssue(owner, repository, issueNumber, comment)
+}
+
+func (restClient) UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error) {
+	return UpsertIssueComment(owner, repository, issueNumber, commentID, marker, comment)
+}
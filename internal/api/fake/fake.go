@@ -0,0 +1,604 @@
+// Package fake provides an in-memory api.Client used to exercise the sync loop without GitHub.
+// It simulates latency, errors and asset sizes as described by a Scenario.
+package fake
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+)
+
+// ErrSimulated is returned by calls failing because of the scenario error rate
+var ErrSimulated = errors.New("fake: simulated failure")
+
+// Scenario configures the fake backend
+type Scenario struct {
+	// Seed makes the simulated failures deterministic
+	Seed int64 `json:"seed"`
+	// LatencyMs is added to every call
+	LatencyMs int `json:"latency_ms"`
+	// ErrorRate is the probability, between 0 and 1, for a call to fail
+	ErrorRate float64 `json:"error_rate"`
+	// ReleasesPerRepository is the number of releases of every source repository
+	ReleasesPerRepository int `json:"releases_per_repository"`
+	// AssetsPerRelease is the number of assets of every source release
+	AssetsPerRelease int `json:"assets_per_release"`
+	// AssetSize is the size in bytes of every source asset
+	AssetSize int `json:"asset_size"`
+	// ArchivedRepositories lists the owner/name of the archived source and target repositories
+	ArchivedRepositories []string `json:"archived_repositories"`
+	// Repositories lists the names of the repositories of every source organization
+	Repositories []string `json:"repositories"`
+	// ForkRepositories lists the owner/name of the source repositories that are forks
+	ForkRepositories []string `json:"fork_repositories"`
+	// TagsWithoutReleases is the number of annotated tags of every source repository, newer than
+	// its releases, that have no release
+	TagsWithoutReleases int `json:"tags_without_releases"`
+}
+
+// DefaultScenario is used when no scenario file is provided
+var DefaultScenario = Scenario{
+	Seed:                  1,
+	ReleasesPerRepository: 3,
+	AssetsPerRelease:      2,
+	AssetSize:             1024,
+}
+
+// LoadScenario reads a JSON scenario file, using DefaultScenario values for missing keys
+func LoadScenario(fileName string) (Scenario, error) {
+	scenario := DefaultScenario
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return scenario, err
+	}
+	err = json.Unmarshal(data, &scenario)
+	if err != nil {
+		return scenario, fmt.Errorf("error parsing scenario %s: %v", fileName, err)
+	}
+	return scenario, nil
+}
+
+// Backend is an in-memory implementation of api.Client
+type Backend struct {
+	scenario Scenario
+
+	mu       sync.Mutex
+	random   *rand.Rand
+	nextID   int64
+	source   map[string][]*github.RepositoryRelease
+	target   map[string][]*github.RepositoryRelease
+	latest   map[string]int64
+	comments []string
+	files    map[string][]byte
+}
+
+var _ api.Client = (*Backend)(nil)
+
+// New returns a fake backend for the given scenario
+func New(scenario Scenario) *Backend {
+	return &Backend{
+		scenario: scenario,
+		random:   rand.New(rand.NewSource(scenario.Seed)),
+		nextID:   1,
+		source:   map[string][]*github.RepositoryRelease{},
+		target:   map[string][]*github.RepositoryRelease{},
+		latest:   map[string]int64{},
+	}
+}
+
+// call simulates the latency and failures of a request
+func (b *Backend) call() error {
+	if b.scenario.LatencyMs > 0 {
+		time.Sleep(time.Duration(b.scenario.LatencyMs) * time.Millisecond)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.scenario.ErrorRate > 0 && b.random.Float64() < b.scenario.ErrorRate {
+		return ErrSimulated
+	}
+	return nil
+}
+
+func (b *Backend) id() int64 {
+	id := b.nextID
+	b.nextID++
+	return id
+}
+
+// sourceReleases generates the releases of a source repository on first access, newest first
+// like the GitHub API. Must be called with b.mu held.
+func (b *Backend) sourceReleases(owner string, repository string) []*github.RepositoryRelease {
+	key := owner + "/" + repository
+	if releases, ok := b.source[key]; ok {
+		return releases
+	}
+
+	var releases []*github.RepositoryRelease
+	for i := b.scenario.ReleasesPerRepository; i >= 1; i-- {
+		tag := fmt.Sprintf("v%d.0.0", i)
+		release := &github.RepositoryRelease{
+			ID:              github.Int64(b.id()),
+			TagName:         github.String(tag),
+			TargetCommitish: github.String("main"),
+			Name:            github.String("Release " + tag),
+			Body:            github.String("Notes for " + tag),
+			PublishedAt:     &github.Timestamp{Time: time.Date(2024, time.Month(i), 1, 0, 0, 0, 0, time.UTC)},
+		}
+		for j := 1; j <= b.scenario.AssetsPerRelease; j++ {
+			release.Assets = append(release.Assets, &github.ReleaseAsset{
+				ID:   github.Int64(b.id()),
+				Name: github.String(fmt.Sprintf("asset-%d.zip", j)),
+				Size: github.Int(b.scenario.AssetSize),
+			})
+		}
+		releases = append(releases, release)
+	}
+
+	b.source[key] = releases
+	return releases
+}
+
+func (b *Backend) repository(owner string, repository string) (*github.Repository, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+
+	return b.describeRepository(owner, repository), nil
+}
+
+func (b *Backend) describeRepository(owner string, repository string) *github.Repository {
+	return &github.Repository{
+		Name:     github.String(repository),
+		FullName: github.String(owner + "/" + repository),
+		Owner:    &github.User{Login: github.String(owner)},
+		Archived: github.Bool(slices.Contains(b.scenario.ArchivedRepositories, owner+"/"+repository)),
+		Fork:     github.Bool(slices.Contains(b.scenario.ForkRepositories, owner+"/"+repository)),
+	}
+}
+
+func (b *Backend) ListSourceRepositories(organization string) ([]*github.Repository, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+
+	var repositories []*github.Repository
+	for _, name := range b.scenario.Repositories {
+		repositories = append(repositories, b.describeRepository(organization, name))
+	}
+	return repositories, nil
+}
+
+func (b *Backend) GetSourceRepository(owner string, repository string) (*github.Repository, error) {
+	return b.repository(owner, repository)
+}
+
+func (b *Backend) GetTargetRepository(owner string, repository string) (*github.Repository, error) {
+	return b.repository(owner, repository)
+}
+
+func (b *Backend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Return copies so that the caller can modify them
+	var releases []*github.RepositoryRelease
+	for _, release := range b.sourceReleases(owner, repository) {
+		copied := *release
+		copied.Assets = append([]*github.ReleaseAsset(nil), release.Assets...)
+		releases = append(releases, &copied)
+	}
+	return releases, nil
+}
+
+func (b *Backend) GetSourceTagsWithoutReleases(owner string, repository string, releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+
+	released := map[string]bool{}
+	for _, release := range releases {
+		released[release.GetTagName()] = true
+	}
+
+	var tagReleases []*github.RepositoryRelease
+	for i := 1; i <= b.scenario.TagsWithoutReleases; i++ {
+		tagName := fmt.Sprintf("v%d.0.0", b.scenario.ReleasesPerRepository+i)
+		if released[tagName] {
+			continue
+		}
+		date := github.Timestamp{Time: time.Date(2024, time.Month(b.scenario.ReleasesPerRepository+i), 1, 0, 0, 0, 0, time.UTC)}
+		tagReleases = append(tagReleases, &github.RepositoryRelease{
+			TagName:         github.String(tagName),
+			Name:            github.String(tagName),
+			Body:            github.String("Tag message of " + tagName),
+			TargetCommitish: github.String("main"),
+			Draft:           github.Bool(false),
+			Prerelease:      github.Bool(false),
+			CreatedAt:       &date,
+			PublishedAt:     &date,
+		})
+	}
+	return tagReleases, nil
+}
+
+func (b *Backend) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	releases := b.sourceReleases(owner, repository)
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no releases found for repository %s/%s", owner, repository)
+	}
+	latest := *releases[0]
+	return &latest, nil
+}
+
+// GetReleaseAssetDigests returns the sha256 of the assets of a source release, hashed like
+// HashTargetAsset
+func (b *Backend) GetReleaseAssetDigests(owner string, repository string, releaseID int64) (map[int64]string, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	digests := map[int64]string{}
+	for _, release := range b.sourceReleases(owner, repository) {
+		if release.GetID() != releaseID {
+			continue
+		}
+		for _, asset := range release.Assets {
+			digests[asset.GetID()] = hashAsset(asset, "sha256")
+		}
+	}
+	return digests, nil
+}
+
+func (b *Backend) findTargetRelease(owner string, repository string, tagName string) *github.RepositoryRelease {
+	for _, release := range b.target[owner+"/"+repository] {
+		if release.GetTagName() == tagName {
+			return release
+		}
+	}
+	return nil
+}
+
+func (b *Backend) GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var releases []*github.RepositoryRelease
+	for _, release := range b.target[owner+"/"+repository] {
+		copied := *release
+		copied.Assets = append([]*github.ReleaseAsset(nil), release.Assets...)
+		releases = append(releases, &copied)
+	}
+	return releases, nil
+}
+
+func (b *Backend) GetTargetRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := owner + "/" + repository
+	for _, release := range b.target[key] {
+		if release.GetID() == b.latest[key] {
+			copied := *release
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *Backend) GetReleaseByTag(owner string, repository string, tagName string) (*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	release := api.FindReleaseByTag(b.target[owner+"/"+repository], tagName)
+	if release == nil {
+		return nil, fmt.Errorf("release not found for tag %s", tagName)
+	}
+	copied := *release
+	return &copied, nil
+}
+
+func (b *Backend) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	existingRelease, err := b.GetReleaseByTag(owner, repository, release.GetTagName())
+	if err != nil {
+		return nil, false
+	}
+	return existingRelease, api.ReleaseMatches(existingRelease, release)
+}
+
+func (b *Backend) MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return false, b.call()
+}
+
+func (b *Backend) CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return false, b.call()
+}
+
+func (b *Backend) MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error) {
+	return 0, b.call()
+}
+
+func (b *Backend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.findTargetRelease(owner, repository, release.GetTagName()) != nil {
+		return nil, fmt.Errorf("%w: %v", api.ErrReleaseExists, release.GetName())
+	}
+
+	id := b.id()
+	created := &github.RepositoryRelease{
+		ID:              github.Int64(id),
+		TagName:         release.TagName,
+		TargetCommitish: release.TargetCommitish,
+		Name:            release.Name,
+		Body:            release.Body,
+		Draft:           release.Draft,
+		Prerelease:      release.Prerelease,
+		PublishedAt:     &github.Timestamp{Time: time.Now()},
+		UploadURL:       github.String(fmt.Sprintf("fake://%s/%s/releases/%d/assets{?name,label}", owner, repository, id)),
+	}
+	if created.TargetCommitish == nil {
+		created.TargetCommitish = github.String("main")
+	}
+	// Like GitHub, a created prerelease or draft doesn't become latest
+	if release.GetMakeLatest() != "false" && release.GetMakeLatest() != "legacy" && !release.GetPrerelease() && !release.GetDraft() {
+		b.latest[owner+"/"+repository] = id
+	}
+
+	key := owner + "/" + repository
+	b.target[key] = append(b.target[key], created)
+	copied := *created
+	return &copied, nil
+}
+
+func (b *Backend) UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if err := b.call(); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, existing := range b.target[owner+"/"+repository] {
+		if existing.GetID() != releaseID {
+			continue
+		}
+		if release.Name != nil {
+			existing.Name = release.Name
+		}
+		if release.Body != nil {
+			existing.Body = release.Body
+		}
+		if release.Draft != nil {
+			existing.Draft = release.Draft
+		}
+		if release.Prerelease != nil {
+			existing.Prerelease = release.Prerelease
+		}
+		updated := *existing
+		updated.Assets = append([]*github.ReleaseAsset(nil), existing.Assets...)
+		return &updated, nil
+	}
+	return nil, fmt.Errorf("release %d not found", releaseID)
+}
+
+func (b *Backend) SetLatestRelease(owner string, repository string, releaseID int64) error {
+	if err := b.call(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latest[owner+"/"+repository] = releaseID
+	return nil
+}
+
+func (b *Backend) DeleteReleaseAsset(owner string, repository string, assetID int64) error {
+	if err := b.call(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, release := range b.target[owner+"/"+repository] {
+		for i, asset := range release.Assets {
+			if asset.GetID() == assetID {
+				release.Assets = append(release.Assets[:i], release.Assets[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("asset %d not found", assetID)
+}
+
+// DownloadReleaseAssetsCached does not write anything to disk, assets only exist as sizes
+func (b *Backend) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	return b.call()
+}
+
+// HashTargetAsset hashes the name and size of the asset, as the fake assets have no content, so
+// the checksums of different assets differ
+func (b *Backend) HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error) {
+	if err := b.call(); err != nil {
+		return "", err
+	}
+	return hashAsset(asset, algorithm), nil
+}
+
+// hashAsset hashes the name and size of a fake asset with algorithm
+func hashAsset(asset *github.ReleaseAsset, algorithm string) string {
+	checksum := api.NewChecksumHash(algorithm)
+	fmt.Fprintf(checksum, "%s:%d", asset.GetName(), asset.GetSize())
+	return hex.EncodeToString(checksum.Sum(nil))
+}
+
+// WriteAssetFile keeps the content of the generated asset in memory, returned by AssetFile
+func (b *Backend) WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.files == nil {
+		b.files = map[string][]byte{}
+	}
+	b.files[name] = content
+	return &github.ReleaseAsset{Name: github.String(name), ContentType: github.String("text/plain"), Size: github.Int(len(content))}, nil
+}
+
+// AssetFile returns the content of the last generated asset with the given name
+func (b *Backend) AssetFile(name string) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.files[name]
+}
+
+// SplitAsset returns the parts and manifest of an asset without writing them, as the fake
+// downloads don't write files
+func (b *Backend) SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", partSize)
+	}
+
+	var parts []*github.ReleaseAsset
+	for offset, index := int64(0), 1; offset < int64(asset.GetSize()); offset, index = offset+partSize, index+1 {
+		parts = append(parts, &github.ReleaseAsset{
+			Name: github.String(fmt.Sprintf("%s.part%03d", asset.GetName(), index)),
+			Size: github.Int(int(min(partSize, int64(asset.GetSize())-offset))),
+		})
+	}
+	return append(parts, &github.ReleaseAsset{Name: github.String(api.SplitManifestName(asset.GetName())), Size: github.Int(1)}), nil
+}
+
+func (b *Backend) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	return b.UploadAssetAs(uploadURL, asset, asset.GetName())
+}
+
+func (b *Backend) UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error {
+	if err := b.call(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var owner, repository string
+	var releaseID int64
+	path := strings.TrimSuffix(strings.TrimPrefix(uploadURL, "fake://"), "{?name,label}")
+	_, err := fmt.Sscanf(strings.ReplaceAll(path, "/", " "), "%s %s releases %d assets", &owner, &repository, &releaseID)
+	if err != nil {
+		return fmt.Errorf("invalid upload url %s: %v", uploadURL, err)
+	}
+
+	for _, release := range b.target[owner+"/"+repository] {
+		if release.GetID() == releaseID {
+			release.Assets = append(release.Assets, &github.ReleaseAsset{
+				ID:    github.Int64(b.id()),
+				Name:  github.String(name),
+				Label: asset.Label,
+				Size:  asset.Size,
+				State: github.String("uploaded"),
+			})
+			return nil
+		}
+	}
+	return fmt.Errorf("release %d not found", releaseID)
+}
+
+func (b *Backend) WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
+	if err := b.call(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.comments = append(b.comments, comment)
+	return nil
+}
+
+// UpsertIssueComment edits the comment with the given ID, an index in Comments starting at 1, or
+// else the comment containing marker, and adds the comment when there is none
+func (b *Backend) UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error) {
+	if err := b.call(); err != nil {
+		return 0, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if commentID == 0 {
+		for i, existing := range b.comments {
+			if strings.Contains(existing, marker) {
+				commentID = int64(i + 1)
+			}
+		}
+	}
+	if commentID > 0 && commentID <= int64(len(b.comments)) {
+		b.comments[commentID-1] = comment
+		return commentID, nil
+	}
+	b.comments = append(b.comments, comment)
+	return int64(len(b.comments)), nil
+}
+
+// Comments returns the issue comments written so far
+func (b *Backend) Comments() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.comments...)
+}
+
+// TargetReleases returns the releases created in a target repository sorted by tag
+func (b *Backend) TargetReleases(owner string, repository string) []*github.RepositoryRelease {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var releases []*github.RepositoryRelease
+	for _, release := range b.target[owner+"/"+repository] {
+		copied := *release
+		copied.Assets = append([]*github.ReleaseAsset(nil), release.Assets...)
+		releases = append(releases, &copied)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].GetTagName() < releases[j].GetTagName() })
+	return releases
+}
+
+// LatestReleaseID returns the ID of the release marked as latest in a target repository
+func (b *Backend) LatestReleaseID(owner string, repository string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.latest[owner+"/"+repository]
+}
@@ -0,0 +1,77 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario("testdata/scenario.json")
+	if err != nil {
+		t.Fatalf("LoadScenario returned an error: %v", err)
+	}
+	if scenario.Seed != 42 || scenario.ReleasesPerRepository != 10 || scenario.AssetsPerRelease != 3 || scenario.ErrorRate != 0.05 {
+		t.Errorf("Loaded scenario does not match the file: %+v", scenario)
+	}
+}
+
+func TestBackendCreateAndUpload(t *testing.T) {
+	backend := New(Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 42})
+
+	releases, err := backend.GetSourceRepositoryReleases("source-org", "repo")
+	if err != nil || len(releases) != 2 || releases[0].GetTagName() != "v2.0.0" {
+		t.Fatalf("Unexpected source releases: %v, %v", releases, err)
+	}
+
+	created, err := backend.CreateRelease("target-org", "repo", releases[0])
+	if err != nil {
+		t.Fatalf("CreateRelease returned an error: %v", err)
+	}
+	if _, err := backend.CreateRelease("target-org", "repo", releases[0]); err == nil {
+		t.Errorf("Expected an error when creating the same release twice")
+	}
+
+	err = backend.UploadAssetViaURL(created.GetUploadURL(), releases[0].Assets[0])
+	if err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+
+	target := backend.TargetReleases("target-org", "repo")
+	if len(target) != 1 || len(target[0].Assets) != 1 || target[0].Assets[0].GetSize() != 42 {
+		t.Errorf("Unexpected target releases: %v", target)
+	}
+
+	existing, matches := backend.ReleaseExists("target-org", "repo", releases[0])
+	if !matches || existing.GetID() != created.GetID() {
+		t.Errorf("Expected the created release to exist")
+	}
+}
+
+func TestBackendErrorRate(t *testing.T) {
+	failures := func() int {
+		backend := New(Scenario{Seed: 7, ErrorRate: 0.5, ReleasesPerRepository: 1})
+		var failed int
+		for i := 0; i < 100; i++ {
+			_, err := backend.GetSourceRepositoryLatestRelease("org", "repo")
+			if errors.Is(err, ErrSimulated) {
+				failed++
+			}
+		}
+		return failed
+	}
+
+	first, second := failures(), failures()
+	if first != second {
+		t.Errorf("Expected deterministic failures for the same seed, got %d and %d", first, second)
+	}
+	if first == 0 || first == 100 {
+		t.Errorf("Expected some but not all calls to fail, got %d failures", first)
+	}
+
+	backend := New(Scenario{})
+	if _, err := backend.CreateRelease("target-org", "repo", &github.RepositoryRelease{TagName: github.String("v1")}); err != nil {
+		t.Errorf("Expected no failures without an error rate, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// customHeaders are added to every request sent to GitHub, e.g. for a proxy requiring a routing
+// or authentication header
+var customHeaders struct {
+	mu     sync.RWMutex
+	header http.Header
+}
+
+// ParseCustomHeaders parses comma-separated Name=value pairs, validating the header names
+func ParseCustomHeaders(value string) (http.Header, error) {
+	header := http.Header{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, headerValue, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found {
+			return nil, fmt.Errorf("invalid custom header %q, expected Name=value", name)
+		}
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid custom header name %q", name)
+		}
+		if strings.ContainsAny(headerValue, "\r\n") {
+			return nil, fmt.Errorf("invalid value for custom header %q", name)
+		}
+		header.Add(name, strings.TrimSpace(headerValue))
+	}
+	return header, nil
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name, made of token characters
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SetCustomHeaders sets the headers added to the requests of the go-github clients and of the
+// asset downloads and uploads
+func SetCustomHeaders(header http.Header) {
+	customHeaders.mu.Lock()
+	defer customHeaders.mu.Unlock()
+
+	customHeaders.header = header
+}
+
+// headerTransport adds the custom headers to every request going through it
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	customHeaders.mu.RLock()
+	header := customHeaders.header
+	customHeaders.mu.RUnlock()
+
+	if len(header) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	return t.base.RoundTrip(req)
+}
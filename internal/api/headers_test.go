@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestParseCustomHeaders(t *testing.T) {
+	header, err := ParseCustomHeaders("X-Proxy-Route=github, X-Auth-Proxy=a=b")
+	if err != nil {
+		t.Fatalf("ParseCustomHeaders returned an error: %v", err)
+	}
+	if got := header.Get("X-Proxy-Route"); got != "github" {
+		t.Errorf("Expected X-Proxy-Route github, got %q", got)
+	}
+	if got := header.Get("X-Auth-Proxy"); got != "a=b" {
+		t.Errorf("Expected X-Auth-Proxy a=b, got %q", got)
+	}
+
+	for _, value := range []string{"X-Proxy-Route", "X Proxy=github", "=github", "X-Proxy:Route=github", "X-Proxy=a\r\nHost: evil"} {
+		if _, err := ParseCustomHeaders(value); err == nil {
+			t.Errorf("Expected an error for custom headers %q", value)
+		}
+	}
+}
+
+func TestCustomHeadersOnUpload(t *testing.T) {
+	header, err := ParseCustomHeaders("X-Proxy-Route=github")
+	if err != nil {
+		t.Fatalf("ParseCustomHeaders returned an error: %v", err)
+	}
+	SetCustomHeaders(header)
+	t.Cleanup(func() { SetCustomHeaders(nil) })
+
+	viper.Set("TARGET_TOKEN", "target-token")
+	t.Cleanup(viper.Reset)
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	var route string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route = r.Header.Get("X-Proxy-Route")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	}))
+	t.Cleanup(server.Close)
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	if err := UploadAssetViaURL(server.URL+"/repos/target-org/repo/releases/1/assets{?name,label}", asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+
+	if route != "github" {
+		t.Errorf("Expected the custom header on the upload request, got %q", route)
+	}
+}
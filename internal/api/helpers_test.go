@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// setupTestClient registers a client for the given token and hostname that sends its requests
+// to a test server backed by handler
+func setupTestClient(t *testing.T, token string, hostname string, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := github.NewClient(nil).WithEnterpriseURLs(server.URL+"/api/v3/", server.URL+"/api/uploads/")
+	if err != nil {
+		t.Fatalf("Failed to create test client: %v", err)
+	}
+
+	clientsMu.Lock()
+	clients[hostname+"\x00"+token] = client
+	clientsMu.Unlock()
+	t.Cleanup(func() {
+		clientsMu.Lock()
+		delete(clients, hostname+"\x00"+token)
+		clientsMu.Unlock()
+	})
+
+	return server
+}
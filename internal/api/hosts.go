@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// endpoints are the URLs serving the source or the target of the migration
+type endpoints struct {
+	side      string
+	hostname  string
+	apiURL    string
+	uploadURL string
+}
+
+func sourceEndpoints() endpoints {
+	return endpoints{side: "source", hostname: viper.GetString("SOURCE_HOSTNAME"), apiURL: viper.GetString("SOURCE_API_URL"), uploadURL: viper.GetString("SOURCE_UPLOAD_URL")}
+}
+
+func targetEndpoints() endpoints {
+	return endpoints{side: "target", hostname: viper.GetString("TARGET_HOSTNAME"), apiURL: viper.GetString("TARGET_API_URL"), uploadURL: viper.GetString("TARGET_UPLOAD_URL")}
+}
+
+// hosts returns the hosts serving the API, the uploads and the web pages, where browser download
+// URLs point, of the side, including those of the explicit URLs
+func (e endpoints) hosts() map[string]bool {
+	apiURL, uploadURL := endpointURLs(e.hostname)
+	hosts := map[string]bool{}
+	for _, value := range []string{apiURL, uploadURL, e.apiURL, e.uploadURL} {
+		if host := urlHost(value); host != "" {
+			hosts[host] = true
+		}
+	}
+	// api.github.com and api.<tenant>.ghe.com serve the API of github.com and <tenant>.ghe.com
+	hosts[strings.TrimPrefix(urlHost(apiURL), "api.")] = true
+	return hosts
+}
+
+// urlHost returns the lowercase host, with its port, of a URL
+func urlHost(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
+
+// checkTransferURL refuses a URL served by the other side of the migration, so a download only
+// goes to the source and an upload only to the target, and a token is never sent to the other
+// instance. A migration within a single instance allows both.
+func checkTransferURL(value string, own endpoints, other endpoints) error {
+	host := urlHost(value)
+	ownHosts := own.hosts()
+	if ownHosts[host] {
+		return nil
+	}
+	if other.hosts()[host] {
+		return fmt.Errorf("refusing to use %s with the %s token: %s is a host of the %s", value, own.side, host, other.side)
+	}
+	return nil
+}
+
+// rebaseURL moves a URL returned by GitHub, such as the upload URL of a release or the API URL of
+// an asset, under an explicit base URL, keeping its path from repos/ on. The instance returns its
+// own URLs, which a routing proxy in front of it doesn't serve.
+func rebaseURL(value string, base string) string {
+	if base == "" {
+		return value
+	}
+	index := strings.Index(value, "/repos/")
+	if index < 0 {
+		return value
+	}
+	return withTrailingSlash(base) + value[index+1:]
+}
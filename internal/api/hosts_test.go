@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+// TestGitHubDotComToEnterpriseServer migrates an asset from github.com, played by a server
+// standing in for its API, to a GitHub Enterprise Server reached through a routing proxy
+func TestGitHubDotComToEnterpriseServer(t *testing.T) {
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+
+	var downloadPath, downloadToken string
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloadPath = r.URL.Path
+		downloadToken = r.Header.Get("Authorization")
+		w.Write([]byte("asset contents"))
+	}))
+	t.Cleanup(source.Close)
+
+	var uploadPath, uploadToken, uploadName string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploadPath = r.URL.Path
+		uploadToken = r.Header.Get("Authorization")
+		uploadName = r.URL.Query().Get("name")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 9}`))
+	}))
+	t.Cleanup(target.Close)
+
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_API_URL", source.URL)
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "github.example.com")
+	viper.Set("TARGET_API_URL", target.URL+"/github/api/v3")
+	viper.Set("TARGET_UPLOAD_URL", target.URL+"/github/api/uploads")
+	t.Cleanup(viper.Reset)
+
+	// The URLs as returned by each instance, which don't go through the test servers
+	asset := &github.ReleaseAsset{
+		Name:               github.String("app.zip"),
+		ContentType:        github.String("application/zip"),
+		URL:                github.String("https://api.github.com/repos/source-org/repo/releases/assets/7"),
+		BrowserDownloadURL: github.String("https://github.com/source-org/repo/releases/download/v1.0.0/app.zip"),
+	}
+	if err := DownloadReleaseAssets(asset); err != nil {
+		t.Fatalf("DownloadReleaseAssets returned an error: %v", err)
+	}
+	if downloadPath != "/repos/source-org/repo/releases/assets/7" || downloadToken != "Bearer source-token" {
+		t.Errorf("Expected the asset to be downloaded from the source with its token, got %s with %q", downloadPath, downloadToken)
+	}
+
+	uploadURL := "https://github.example.com/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}"
+	if err := UploadAssetViaURL(uploadURL, asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+	if uploadPath != "/github/api/uploads/repos/target-org/repo/releases/1/assets" || uploadToken != "Bearer target-token" || uploadName != "app.zip" {
+		t.Errorf("Expected the asset to be uploaded to the target with its token, got %s with %q as %q", uploadPath, uploadToken, uploadName)
+	}
+}
+
+func TestTransfersRefuseTheOtherSide(t *testing.T) {
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "github.example.com")
+	t.Cleanup(viper.Reset)
+
+	// An asset URL of the target would send the source token to the target
+	asset := &github.ReleaseAsset{
+		Name:        github.String("app.zip"),
+		ContentType: github.String("application/zip"),
+		URL:         github.String("https://github.example.com/api/v3/repos/target-org/repo/releases/assets/7"),
+	}
+	if err := DownloadReleaseAssets(asset); err == nil || !strings.Contains(err.Error(), "source token") {
+		t.Errorf("Expected the download from the target to be refused, got %v", err)
+	}
+
+	// An upload URL of github.com would send the target token to the source
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	err := UploadAssetViaURL("https://uploads.github.com/repos/source-org/repo/releases/1/assets{?name,label}", asset)
+	if err == nil || !strings.Contains(err.Error(), "target token") {
+		t.Errorf("Expected the upload to the source to be refused, got %v", err)
+	}
+}
+
+func TestTransfersWithinOneInstance(t *testing.T) {
+	viper.Set("SOURCE_HOSTNAME", "github.example.com")
+	viper.Set("TARGET_HOSTNAME", "github.example.com")
+	t.Cleanup(viper.Reset)
+
+	if err := checkTransferURL("https://github.example.com/api/uploads/repos/target-org/repo/releases/1/assets", targetEndpoints(), sourceEndpoints()); err != nil {
+		t.Errorf("Expected an upload within the instance to be allowed, got %v", err)
+	}
+}
+
+func TestRebaseURL(t *testing.T) {
+	tests := []struct {
+		value string
+		base  string
+		want  string
+	}{
+		{"https://github.example.com/api/uploads/repos/org/repo/releases/1/assets", "", "https://github.example.com/api/uploads/repos/org/repo/releases/1/assets"},
+		{"https://github.example.com/api/uploads/repos/org/repo/releases/1/assets", "https://proxy.internal/github/api/uploads", "https://proxy.internal/github/api/uploads/repos/org/repo/releases/1/assets"},
+		{"https://github.com/org/repo/releases/download/v1/app.zip", "https://proxy.internal/api", "https://github.com/org/repo/releases/download/v1/app.zip"},
+	}
+
+	for _, tt := range tests {
+		if got := rebaseURL(tt.value, tt.base); got != tt.want {
+			t.Errorf("rebaseURL(%q, %q) = %s, want %s", tt.value, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestEndpointsDiagnosticURLs(t *testing.T) {
+	tests := []struct {
+		endpoints endpoints
+		apiHost   string
+		uploads   string
+	}{
+		{endpoints{hostname: "github.example.com"}, "github.example.com", "https://github.example.com/api/uploads/"},
+		{endpoints{hostname: "octocorp.ghe.com"}, "api.octocorp.ghe.com", "https://uploads.octocorp.ghe.com/"},
+		{endpoints{hostname: "github.example.com", apiURL: "https://proxy.internal:8443/github/api/v3", uploadURL: "https://proxy.internal:8443/github/api/uploads"}, "proxy.internal", "https://proxy.internal:8443/github/api/uploads/"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.endpoints.apiHost(); got != tt.apiHost {
+			t.Errorf("apiHost of %+v = %s, want %s", tt.endpoints, got, tt.apiHost)
+		}
+		if got := tt.endpoints.uploadsURL(); got != tt.uploads {
+			t.Errorf("uploadsURL of %+v = %s, want %s", tt.endpoints, got, tt.uploads)
+		}
+	}
+}
+
+func TestCheckUploadsEndpointUsesUploadURL(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+	}))
+	t.Cleanup(server.Close)
+	viper.Set("TARGET_HOSTNAME", "github.invalid")
+	viper.Set("TARGET_UPLOAD_URL", server.URL+"/github/api/uploads")
+	t.Cleanup(viper.Reset)
+
+	if err := CheckUploadsEndpoint(); err != nil {
+		t.Fatalf("CheckUploadsEndpoint returned an error: %v", err)
+	}
+	if requested != "/github/api/uploads/" {
+		t.Errorf("Expected the explicit upload URL to be checked, got %q", requested)
+	}
+}
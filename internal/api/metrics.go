@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// requestCounter counts the HTTP requests sent to GitHub by category
+type requestCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+var requests = &requestCounter{counts: map[string]int{}}
+
+func (c *requestCounter) add(category string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[category]++
+}
+
+// RequestCount is the number of requests sent for a category
+type RequestCount struct {
+	Category string
+	Count    int
+}
+
+// RequestCounts returns the number of requests sent per category, sorted by category, and the total
+func RequestCounts() ([]RequestCount, int) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	var counts []RequestCount
+	var total int
+	for category, count := range requests.counts {
+		counts = append(counts, RequestCount{Category: category, Count: count})
+		total += count
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Category < counts[j].Category })
+
+	return counts, total
+}
+
+// requestCategory classifies a request as list, create, edit, delete, comment, upload or download
+func requestCategory(req *http.Request) string {
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/comments"):
+		return "comment"
+	case req.Method == http.MethodPost && strings.Contains(req.URL.Path, "/uploads/"),
+		req.Method == http.MethodPost && strings.HasPrefix(req.URL.Host, "uploads."):
+		return "upload"
+	case req.Method == http.MethodGet && req.Header.Get("Accept") == "application/octet-stream":
+		return "download"
+	case req.Method == http.MethodPost:
+		return "create"
+	case req.Method == http.MethodPatch:
+		return "edit"
+	case req.Method == http.MethodDelete:
+		return "delete"
+	default:
+		return "list"
+	}
+}
+
+// countingTransport counts every request going through it and records the rate limit of the
+// responses
+type countingTransport struct {
+	base    http.RoundTripper
+	counter *requestCounter
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.counter.add(requestCategory(req))
+	resp, err := t.base.RoundTrip(req)
+	observeRate(resp)
+	return resp, err
+}
+
+// rawHTTPClient is used for the asset downloads and uploads sent outside of go-github
+var rawHTTPClient = &http.Client{
+	Transport:     &countingTransport{base: &headerTransport{base: http.DefaultTransport}, counter: requests},
+	CheckRedirect: stripAuthOnRedirect,
+}
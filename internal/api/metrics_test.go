@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCountingTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	counter := &requestCounter{counts: map[string]int{}}
+	client := &http.Client{Transport: &countingTransport{base: http.DefaultTransport, counter: counter}}
+
+	calls := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/repos/org/repo/releases"},
+		{http.MethodGet, "/repos/org/repo/releases/latest"},
+		{http.MethodPost, "/repos/org/repo/releases"},
+		{http.MethodPatch, "/repos/org/repo/releases/1"},
+		{http.MethodPost, "/repos/org/repo/issues/1/comments"},
+	}
+
+	for _, call := range calls {
+		req, err := http.NewRequest(call.method, server.URL+call.path, strings.NewReader(""))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	expected := map[string]int{"list": 2, "create": 1, "edit": 1, "comment": 1}
+	for category, count := range expected {
+		if counter.counts[category] != count {
+			t.Errorf("Expected %d %s requests, got %d", count, category, counter.counts[category])
+		}
+	}
+}
+
+func TestCountingTransportObservesRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "4321")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &countingTransport{base: http.DefaultTransport, counter: &requestCounter{counts: map[string]int{}}}}
+	for _, path := range []string{"/limited", "/unlimited"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// The response without rate limit headers keeps the last observed rate limit
+	remaining, limit, ok := RateLimitHeadroom()
+	if !ok || remaining != 4321 || limit != 5000 {
+		t.Errorf("RateLimitHeadroom() = %d, %d, %v, want 4321, 5000, true", remaining, limit, ok)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+)
+
+// progressThreshold is the size above which an asset download or upload reports its progress
+var progressThreshold int64 = 100 * 1024 * 1024
+
+// isTerminal reports whether progress can be drawn as a progress bar rather than logged, which
+// isn't the case with raw output such as JSON logs
+var isTerminal = func() bool {
+	return !pterm.RawOutput && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// progressReader is a reader calling report with the total bytes read so far after every read
+type progressReader struct {
+	reader io.Reader
+	read   int64
+	report func(read int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.report(r.read)
+	}
+	return n, err
+}
+
+// percentReporter returns a report function calling emit with the percentage transferred of total
+// bytes, each time it crosses another step
+func percentReporter(total int64, step int, emit func(percent int)) func(read int64) {
+	last := 0
+	return func(read int64) {
+		percent := int(read * 100 / total)
+		if percent > 100 {
+			percent = 100
+		}
+		if percent-last >= step || (percent == 100 && last < 100) {
+			last = percent
+			emit(percent)
+		}
+	}
+}
+
+// trackProgress wraps reader so a transfer of total bytes above the progress threshold drives a
+// progress bar, or logs its percentage when the output isn't a terminal. The returned function
+// stops the progress bar once the transfer is over.
+func trackProgress(reader io.Reader, action, name string, total int64) (io.Reader, func()) {
+	if total <= 0 || total < progressThreshold {
+		return reader, func() {}
+	}
+
+	title := fmt.Sprintf("%s %s", action, name)
+	if !isTerminal() {
+		report := percentReporter(total, 10, func(percent int) {
+			pterm.Info.Printf("%s: %d%%\n", title, percent)
+		})
+		return &progressReader{reader: reader, report: report}, func() {}
+	}
+
+	bar, err := pterm.DefaultProgressbar.WithTotal(100).WithTitle(title).WithRemoveWhenDone(true).Start()
+	if err != nil {
+		return reader, func() {}
+	}
+	report := percentReporter(total, 1, func(percent int) {
+		bar.Add(percent - bar.Current)
+	})
+	return &progressReader{reader: reader, report: report}, func() { bar.Stop() }
+}
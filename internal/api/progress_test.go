@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestProgressReaderReportsBytesRead(t *testing.T) {
+	var reported []int64
+	reader := &progressReader{
+		reader: bytes.NewReader(make([]byte, 2500)),
+		report: func(read int64) { reported = append(reported, read) },
+	}
+
+	buf := make([]byte, 1000)
+	for {
+		if _, err := reader.Read(buf); err == io.EOF {
+			break
+		}
+	}
+
+	if len(reported) != 3 || reported[0] != 1000 || reported[1] != 2000 || reported[2] != 2500 {
+		t.Errorf("Expected progress at 1000, 2000 and 2500 bytes, got %v", reported)
+	}
+}
+
+func TestPercentReporterEmitsSteps(t *testing.T) {
+	var percents []int
+	report := percentReporter(1000, 25, func(percent int) { percents = append(percents, percent) })
+
+	for read := int64(100); read <= 1000; read += 100 {
+		report(read)
+	}
+
+	expected := []int{30, 60, 90, 100}
+	if len(percents) != len(expected) {
+		t.Fatalf("Expected percentages %v, got %v", expected, percents)
+	}
+	for i := range expected {
+		if percents[i] != expected[i] {
+			t.Errorf("Expected percentages %v, got %v", expected, percents)
+		}
+	}
+}
+
+func TestTrackProgressBelowThreshold(t *testing.T) {
+	source := bytes.NewReader(make([]byte, 10))
+	reader, done := trackProgress(source, "Downloading", "small.zip", 10)
+	defer done()
+
+	if reader != io.Reader(source) {
+		t.Error("Expected a small transfer not to be tracked")
+	}
+}
+
+func TestTrackProgressLogsWithoutTerminal(t *testing.T) {
+	previousThreshold, previousTerminal := progressThreshold, isTerminal
+	progressThreshold = 100
+	isTerminal = func() bool { return false }
+	t.Cleanup(func() {
+		progressThreshold, isTerminal = previousThreshold, previousTerminal
+	})
+
+	reader, done := trackProgress(bytes.NewReader(make([]byte, 1000)), "Downloading", "large.zip", 1000)
+	defer done()
+
+	tracked, ok := reader.(*progressReader)
+	if !ok {
+		t.Fatal("Expected a large transfer to be tracked")
+	}
+	if n, err := io.Copy(io.Discard, tracked); err != nil || n != 1000 {
+		t.Fatalf("Expected to read 1000 bytes, got %d: %v", n, err)
+	}
+	if tracked.read != 1000 {
+		t.Errorf("Expected 1000 bytes to be reported, got %d", tracked.read)
+	}
+}
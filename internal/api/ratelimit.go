@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// observedRate is the rate limit reported by the latest GitHub response, the same values
+// go-github parses into resp.Rate
+var observedRate struct {
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	observed  bool
+}
+
+// observeRate records the rate limit headers of a response, if any
+func observeRate(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil || limit <= 0 {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	observedRate.mu.Lock()
+	defer observedRate.mu.Unlock()
+
+	observedRate.limit = limit
+	observedRate.remaining = remaining
+	observedRate.observed = true
+}
+
+// RateLimitHeadroom returns the remaining requests and the limit of the latest response carrying
+// rate limit headers, and false when no response had them yet
+func RateLimitHeadroom() (remaining int, limit int, ok bool) {
+	observedRate.mu.Lock()
+	defer observedRate.mu.Unlock()
+
+	return observedRate.remaining, observedRate.limit, observedRate.observed
+}
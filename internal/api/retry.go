@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// retryBudget limits the number of retries across the whole run so a degraded instance
+// makes failures fail fast instead of retrying every operation
+type retryBudget struct {
+	mu        sync.Mutex
+	max       int
+	used      int
+	exhausted bool
+}
+
+var retries = &retryBudget{}
+
+// take reserves a retry, returning false once the budget is exhausted. A max of 0 is unlimited.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.max > 0 && b.used >= b.max {
+		if !b.exhausted {
+			b.exhausted = true
+			pterm.Warning.Printf("Retry budget of %d retries exhausted, failing fast for the rest of the run\n", b.max)
+		}
+		return false
+	}
+	b.used++
+	return true
+}
+
+// SetRetryBudget sets the number of retries allowed across the whole run and resets the
+// retries used so far. A max of 0 is unlimited.
+func SetRetryBudget(max int) {
+	retries.mu.Lock()
+	defer retries.mu.Unlock()
+
+	retries.max = max
+	retries.used = 0
+	retries.exhausted = false
+}
+
+// RetriesUsed returns the number of retries made so far and whether the retry budget was exhausted
+func RetriesUsed() (int, bool) {
+	retries.mu.Lock()
+	defer retries.mu.Unlock()
+
+	return retries.used, retries.exhausted
+}
+
+// maxRetryDelay caps the exponential backoff between two attempts
+const maxRetryDelay = 5 * time.Minute
+
+// backoff spaces the retries exponentially from a base delay, with a random jitter so concurrent
+// workers failing together don't retry together
+var backoff = struct {
+	mu     sync.Mutex
+	base   time.Duration
+	sleep  func(time.Duration)
+	jitter func(time.Duration) time.Duration
+}{sleep: time.Sleep, jitter: rand.N[time.Duration]}
+
+// SetRetryBaseDelay sets the delay before the first retry, doubled for each following retry. A
+// delay of 0 retries immediately.
+func SetRetryBaseDelay(delay time.Duration) {
+	backoff.mu.Lock()
+	defer backoff.mu.Unlock()
+
+	backoff.base = delay
+}
+
+// retryDelay returns the delay before a retry, starting at 0: half of the exponential delay plus
+// a random jitter of up to the other half
+func retryDelay(attempt int) time.Duration {
+	backoff.mu.Lock()
+	defer backoff.mu.Unlock()
+
+	if backoff.base <= 0 {
+		return 0
+	}
+	delay := maxRetryDelay
+	if attempt < 32 && backoff.base < maxRetryDelay>>attempt {
+		delay = backoff.base << attempt
+	}
+	return delay/2 + backoff.jitter(delay/2+1)
+}
+
+// StatusError is an asset download or upload answered with an unexpected HTTP status
+type StatusError struct {
+	Operation  string
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s failed with status code %d, Message: %s", e.Operation, e.URL, e.StatusCode, e.Message)
+}
+
+// newStatusError returns the error of an unexpected response, with the start of its body
+func newStatusError(operation string, url string, resp *http.Response) *StatusError {
+	message := make([]byte, 512)
+	n, _ := resp.Body.Read(message)
+	return &StatusError{Operation: operation, URL: url, StatusCode: resp.StatusCode, Message: string(message[:n])}
+}
+
+// isTransient reports whether an error may succeed when retried: a network error or a 5xx or 429
+// response. Other responses, such as a 403 or a 422, fail the same way on every attempt.
+func isTransient(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// Retry calls fn until it succeeds, retrying transient errors at most maxRetries times with an
+// exponential backoff, as long as the retry budget allows it. The last error is returned.
+func Retry(maxRetries int, fn func() error) error {
+	err := fn()
+	for attempt := 0; err != nil && attempt < maxRetries && isTransient(err); attempt++ {
+		if !retries.take() {
+			return err
+		}
+		delay := retryDelay(attempt)
+		pterm.Info.Printf("Retrying in %v after error (%d/%d): %v\n", delay.Round(time.Millisecond), attempt+1, maxRetries, err)
+		if delay > 0 {
+			backoff.sleep(delay)
+		}
+		err = fn()
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestRetry(t *testing.T) {
+	SetRetryBudget(0)
+	t.Cleanup(func() { SetRetryBudget(0) })
+
+	var calls int
+	err := Retry(3, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryBudgetExhausted(t *testing.T) {
+	SetRetryBudget(3)
+	t.Cleanup(func() { SetRetryBudget(0) })
+
+	failing := func(calls *int) func() error {
+		return func() error {
+			*calls++
+			return errors.New("transient")
+		}
+	}
+
+	// The first operation uses 2 retries of the budget
+	var first int
+	if err := Retry(2, failing(&first)); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if first != 3 {
+		t.Errorf("Expected 3 calls for the first operation, got %d", first)
+	}
+
+	// The second operation only gets the last retry of the budget
+	var second int
+	Retry(2, failing(&second))
+	if second != 2 {
+		t.Errorf("Expected 2 calls for the second operation, got %d", second)
+	}
+
+	// Once the budget is exhausted, operations fail fast
+	var third int
+	Retry(2, failing(&third))
+	if third != 1 {
+		t.Errorf("Expected 1 call once the budget is exhausted, got %d", third)
+	}
+
+	used, exhausted := RetriesUsed()
+	if used != 3 || !exhausted {
+		t.Errorf("Expected 3 retries used and the budget exhausted, got %d %v", used, exhausted)
+	}
+}
+
+// useBackoff sets a base delay and records the delays slept, without jitter
+func useBackoff(t *testing.T, base time.Duration) *[]time.Duration {
+	var slept []time.Duration
+	SetRetryBaseDelay(base)
+	backoff.sleep = func(delay time.Duration) { slept = append(slept, delay) }
+	backoff.jitter = func(n time.Duration) time.Duration { return n - 1 }
+	t.Cleanup(func() {
+		SetRetryBaseDelay(0)
+		backoff.sleep = time.Sleep
+	})
+	return &slept
+}
+
+func TestRetryBackoff(t *testing.T) {
+	SetRetryBudget(0)
+	slept := useBackoff(t, time.Second)
+
+	Retry(4, func() error { return errors.New("transient") })
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	if len(*slept) != len(want) {
+		t.Fatalf("Expected %d delays, got %v", len(want), *slept)
+	}
+	for i := range want {
+		if (*slept)[i] != want[i] {
+			t.Errorf("Delay %d = %v, want %v", i, (*slept)[i], want[i])
+		}
+	}
+
+	// The delay is capped
+	if delay := retryDelay(40); delay != maxRetryDelay {
+		t.Errorf("Expected the delay to be capped at %v, got %v", maxRetryDelay, delay)
+	}
+}
+
+func TestRetryOnlyTransientErrors(t *testing.T) {
+	SetRetryBudget(0)
+	useBackoff(t, 0)
+
+	tests := []struct {
+		err   error
+		calls int
+	}{
+		{&StatusError{StatusCode: http.StatusBadGateway}, 3},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, 3},
+		{&StatusError{StatusCode: http.StatusUnprocessableEntity}, 1},
+		{&StatusError{StatusCode: http.StatusForbidden}, 1},
+		{errors.New("connection reset by peer"), 3},
+	}
+	for _, tt := range tests {
+		var calls int
+		Retry(2, func() error {
+			calls++
+			return tt.err
+		})
+		if calls != tt.calls {
+			t.Errorf("Expected %d calls for %v, got %d", tt.calls, tt.err, calls)
+		}
+	}
+}
+
+func TestRetryUploadAfterServerError(t *testing.T) {
+	SetRetryBudget(0)
+	useBackoff(t, time.Millisecond)
+	viper.Set("TARGET_TOKEN", "target-token")
+	t.Cleanup(viper.Reset)
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	// The first upload is answered with a 502 after reading the whole body
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	err := Retry(2, func() error {
+		return UploadAssetViaURL(server.URL+"/repos/target-org/repo/releases/1/assets{?name,label}", asset)
+	})
+	if err != nil {
+		t.Fatalf("Expected the retried upload to succeed, got %v", err)
+	}
+	if len(bodies) != 2 || bodies[1] != "asset contents" {
+		t.Errorf("Expected the whole file to be uploaded again, got %q", bodies)
+	}
+	if _, err := os.Stat(tmpDir + "/app.zip"); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be removed once uploaded, got %v", err)
+	}
+}
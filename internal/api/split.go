@@ -0,0 +1,113 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// SplitManifest describes how the parts of a split asset are joined back into the asset
+type SplitManifest struct {
+	Name   string      `json:"name"`
+	Size   int64       `json:"size"`
+	SHA256 string      `json:"sha256"`
+	Parts  []SplitPart `json:"parts"`
+}
+
+// SplitPart is a part of a split asset, to be concatenated in order
+type SplitPart struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// SplitManifestName returns the name of the manifest asset of a split asset
+func SplitManifestName(assetName string) string {
+	return assetName + ".manifest.json"
+}
+
+// splitPartName returns the name of the part with the given index, starting at 1
+func splitPartName(assetName string, index int) string {
+	return fmt.Sprintf("%s.part%03d", assetName, index)
+}
+
+// SplitAsset splits a downloaded asset into parts of at most partSize bytes and writes their
+// manifest, removing the downloaded file. It returns the parts followed by the manifest, ready
+// to be uploaded.
+func SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error) {
+	if partSize <= 0 {
+		return nil, fmt.Errorf("invalid part size %d", partSize)
+	}
+
+	fileName := localAssetPath(asset)
+	in, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %v", err)
+	}
+	defer in.Close()
+
+	manifest := SplitManifest{Name: asset.GetName()}
+	assetHash := sha256.New()
+	var parts []*github.ReleaseAsset
+	for index := 1; ; index++ {
+		name := splitPartName(asset.GetName(), index)
+		out, err := os.Create(filepath.Join(TempDir(), name))
+		if err != nil {
+			return nil, err
+		}
+		partHash := sha256.New()
+		written, err := io.CopyN(io.MultiWriter(out, partHash, assetHash), in, partSize)
+		if err == io.EOF {
+			// The last part is shorter
+			err = nil
+		}
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing part %s: %v", name, err)
+		}
+		if written == 0 {
+			os.Remove(filepath.Join(TempDir(), name))
+			break
+		}
+
+		manifest.Size += written
+		manifest.Parts = append(manifest.Parts, SplitPart{Name: name, Size: written, SHA256: hex.EncodeToString(partHash.Sum(nil))})
+		parts = append(parts, &github.ReleaseAsset{
+			Name:        github.String(name),
+			ContentType: github.String("application/octet-stream"),
+			Size:        github.Int(int(written)),
+		})
+		if written < partSize {
+			break
+		}
+	}
+	manifest.SHA256 = hex.EncodeToString(assetHash.Sum(nil))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestName := SplitManifestName(asset.GetName())
+	if err := os.WriteFile(filepath.Join(TempDir(), manifestName), data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing manifest %s: %v", manifestName, err)
+	}
+	parts = append(parts, &github.ReleaseAsset{
+		Name:        github.String(manifestName),
+		ContentType: github.String("application/json"),
+		Size:        github.Int(len(data)),
+	})
+
+	in.Close()
+	if err := removeTmpFile(fileName); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestSplitAsset(t *testing.T) {
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	contents := []byte("0123456789")
+	if err := os.WriteFile(tmpDir+"/installer.iso", contents, 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	parts, err := SplitAsset(&github.ReleaseAsset{Name: github.String("installer.iso")}, 4)
+	if err != nil {
+		t.Fatalf("SplitAsset returned an error: %v", err)
+	}
+
+	wantNames := []string{"installer.iso.part001", "installer.iso.part002", "installer.iso.part003", "installer.iso.manifest.json"}
+	if len(parts) != len(wantNames) {
+		t.Fatalf("Expected %d assets, got %d", len(wantNames), len(parts))
+	}
+	var joined []byte
+	for i, part := range parts {
+		if part.GetName() != wantNames[i] {
+			t.Errorf("Expected asset %d to be %s, got %s", i, wantNames[i], part.GetName())
+		}
+		data, err := os.ReadFile(tmpDir + "/" + part.GetName())
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", part.GetName(), err)
+		}
+		if len(data) != part.GetSize() {
+			t.Errorf("Expected %s to be %d bytes, got %d", part.GetName(), part.GetSize(), len(data))
+		}
+		if i < len(parts)-1 {
+			joined = append(joined, data...)
+		}
+	}
+	if string(joined) != string(contents) {
+		t.Errorf("Expected the parts to join into the asset, got %q", joined)
+	}
+
+	var manifest SplitManifest
+	data, _ := os.ReadFile(tmpDir + "/installer.iso.manifest.json")
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	sum := sha256.Sum256(contents)
+	if manifest.Size != 10 || manifest.SHA256 != hex.EncodeToString(sum[:]) || len(manifest.Parts) != 3 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	if _, err := os.Stat(tmpDir + "/installer.iso"); !os.IsNotExist(err) {
+		t.Errorf("Expected the split asset to be removed")
+	}
+}
+
+func TestSplitAssetExactParts(t *testing.T) {
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	if err := os.WriteFile(tmpDir+"/app.bin", []byte("01234567"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	parts, err := SplitAsset(&github.ReleaseAsset{Name: github.String("app.bin")}, 4)
+	if err != nil {
+		t.Fatalf("SplitAsset returned an error: %v", err)
+	}
+	if len(parts) != 3 {
+		t.Errorf("Expected 2 parts and a manifest, got %d assets", len(parts))
+	}
+	if _, err := os.Stat(tmpDir + "/app.bin.part003"); !os.IsNotExist(err) {
+		t.Errorf("Expected no empty part to be left")
+	}
+}
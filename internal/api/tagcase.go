@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// caseInsensitiveTags reports whether releases are matched by tag regardless of case, set with
+// CASE_INSENSITIVE_TAGS for sources tagging both V1.0 and v1.0
+func caseInsensitiveTags() bool {
+	return viper.GetBool("CASE_INSENSITIVE_TAGS")
+}
+
+// FindReleaseByTag returns the release with the given tag, or nil. With CASE_INSENSITIVE_TAGS, a
+// release whose tag only differs in case is returned when no tag matches exactly, and reported.
+func FindReleaseByTag(releases []*github.RepositoryRelease, tagName string) *github.RepositoryRelease {
+	for _, release := range releases {
+		if release.GetTagName() == tagName {
+			return release
+		}
+	}
+	if !caseInsensitiveTags() {
+		return nil
+	}
+	for _, release := range releases {
+		if strings.EqualFold(release.GetTagName(), tagName) {
+			pterm.Warning.Printf("Tag %s matches the existing release tag %s with a different case\n", tagName, release.GetTagName())
+			return release
+		}
+	}
+	return nil
+}
+
+// ReleaseMatches reports whether an existing release has the name and target_commitish of
+// release. With CASE_INSENSITIVE_TAGS, names differing in case, e.g. V1.0 and v1.0, match.
+func ReleaseMatches(existingRelease *github.RepositoryRelease, release *github.RepositoryRelease) bool {
+	nameMatches := existingRelease.GetName() == release.GetName()
+	if caseInsensitiveTags() {
+		nameMatches = strings.EqualFold(existingRelease.GetName(), release.GetName())
+	}
+	return nameMatches && existingRelease.GetTargetCommitish() == release.GetTargetCommitish()
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestGetReleaseByTagCaseInsensitive(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	existing := `{"id": 1, "tag_name": "V1.0", "name": "V1.0", "target_commitish": "main"}`
+	setupTestClient(t, "target-token", "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/target-org/repo/releases/tags/V1.0":
+			w.Write([]byte(existing))
+		case "/api/v3/repos/target-org/repo/releases":
+			w.Write([]byte("[" + existing + "]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	lowercase := &github.RepositoryRelease{TagName: github.String("v1.0"), Name: github.String("v1.0"), TargetCommitish: github.String("main")}
+
+	for _, caseInsensitive := range []bool{false, true} {
+		viper.Set("CASE_INSENSITIVE_TAGS", caseInsensitive)
+
+		release, err := GetReleaseByTag("target-org", "repo", "V1.0")
+		if err != nil || release.GetID() != 1 {
+			t.Errorf("Case insensitive %v: expected the exact tag V1.0 to match, got %v, %v", caseInsensitive, release, err)
+		}
+
+		release, err = GetReleaseByTag("target-org", "repo", "v1.0")
+		if caseInsensitive && (err != nil || release.GetID() != 1) {
+			t.Errorf("Expected v1.0 to match V1.0 case insensitively, got %v, %v", release, err)
+		}
+		if !caseInsensitive && err == nil {
+			t.Errorf("Expected v1.0 not to match V1.0 by default, got %v", release)
+		}
+
+		_, exists := ReleaseExists("target-org", "repo", lowercase)
+		if exists != caseInsensitive {
+			t.Errorf("Case insensitive %v: expected release v1.0 to exist %v, got %v", caseInsensitive, caseInsensitive, exists)
+		}
+	}
+}
+
+func TestFindReleaseByTagPrefersExactMatch(t *testing.T) {
+	viper.Set("CASE_INSENSITIVE_TAGS", true)
+	defer viper.Reset()
+
+	releases := []*github.RepositoryRelease{
+		{ID: github.Int64(1), TagName: github.String("V1.0")},
+		{ID: github.Int64(2), TagName: github.String("v1.0")},
+	}
+	if release := FindReleaseByTag(releases, "v1.0"); release.GetID() != 2 {
+		t.Errorf("Expected the exact tag to be preferred, got release %d", release.GetID())
+	}
+	if release := FindReleaseByTag(releases, "v2.0"); release != nil {
+		t.Errorf("Expected no release for v2.0, got %v", release)
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// MigrateAnnotatedTag recreates an annotated source tag in the target repository with its
+// original message and tagger, so the tag metadata is not lost when the release creates a
+// lightweight tag. It returns false when the tag already exists in the target or is not
+// annotated in the source. Tag signatures cannot be recreated.
+func MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	sourceClient, err := newSourceClient()
+	if err != nil {
+		return false, err
+	}
+	targetClient, err := newTargetClient()
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	// Skip when the tag already exists in the target
+	_, resp, err := targetClient.Git.GetRef(ctx, targetOwner, repository, "tags/"+tagName)
+	if err == nil {
+		return false, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return false, fmt.Errorf("unable to get target tag %s: %v", tagName, err)
+	}
+
+	sourceRef, _, err := sourceClient.Git.GetRef(ctx, sourceOwner, repository, "tags/"+tagName)
+	if err != nil {
+		return false, fmt.Errorf("unable to get source tag %s: %v", tagName, err)
+	}
+	if sourceRef.GetObject().GetType() != "tag" {
+		// Lightweight tags are created along with the release
+		return false, nil
+	}
+
+	sourceTag, _, err := sourceClient.Git.GetTag(ctx, sourceOwner, repository, sourceRef.GetObject().GetSHA())
+	if err != nil {
+		return false, fmt.Errorf("unable to get source tag object %s: %v", tagName, err)
+	}
+
+	newTag, _, err := targetClient.Git.CreateTag(ctx, targetOwner, repository, &github.Tag{
+		Tag:     sourceTag.Tag,
+		Message: sourceTag.Message,
+		Tagger:  sourceTag.Tagger,
+		Object:  sourceTag.Object,
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to create tag object %s: %v", tagName, err)
+	}
+
+	_, _, err = targetClient.Git.CreateRef(ctx, targetOwner, repository, &github.Reference{
+		Ref:    github.String("refs/tags/" + tagName),
+		Object: &github.GitObject{SHA: newTag.SHA},
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to create tag reference %s: %v", tagName, err)
+	}
+
+	return true, nil
+}
+
+// GetSourceTagsWithoutReleases returns a release for each annotated tag of a source repository
+// that has none among releases, with the tag message as its body and the tag date as its creation
+// and publication dates. Lightweight tags have no message and are left out.
+func GetSourceTagsWithoutReleases(owner string, repository string, releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, error) {
+	client, err := newSourceClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	released := map[string]bool{}
+	for _, release := range releases {
+		released[release.GetTagName()] = true
+	}
+
+	var tagReleases []*github.RepositoryRelease
+	opts := &github.ReferenceListOptions{Ref: "tags", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		refs, resp, err := client.Git.ListMatchingRefs(ctx, owner, repository, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list source tags: %v", err)
+		}
+		for _, ref := range refs {
+			tagName := strings.TrimPrefix(ref.GetRef(), "refs/tags/")
+			if released[tagName] || ref.GetObject().GetType() != "tag" {
+				continue
+			}
+			tag, _, err := client.Git.GetTag(ctx, owner, repository, ref.GetObject().GetSHA())
+			if err != nil {
+				return nil, fmt.Errorf("unable to get source tag object %s: %v", tagName, err)
+			}
+			tagReleases = append(tagReleases, tagRelease(tagName, tag))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return tagReleases, nil
+}
+
+// tagRelease returns the release describing an annotated tag
+func tagRelease(tagName string, tag *github.Tag) *github.RepositoryRelease {
+	release := &github.RepositoryRelease{
+		TagName:         github.String(tagName),
+		Name:            github.String(tagName),
+		Body:            github.String(strings.TrimSpace(tag.GetMessage())),
+		TargetCommitish: tag.GetObject().SHA,
+		Draft:           github.Bool(false),
+		Prerelease:      github.Bool(false),
+	}
+	if date := tag.GetTagger().Date; date != nil {
+		release.CreatedAt = date
+		release.PublishedAt = date
+	}
+	return release
+}
+
+// peelTag returns the commit a tag reference points to, following annotated tag objects
+func peelTag(ctx context.Context, client *github.Client, owner string, repository string, ref *github.Reference) (string, error) {
+	object := ref.GetObject()
+	if object.GetType() != "tag" {
+		return object.GetSHA(), nil
+	}
+
+	tag, _, err := client.Git.GetTag(ctx, owner, repository, object.GetSHA())
+	if err != nil {
+		return "", fmt.Errorf("unable to get tag object %s: %v", object.GetSHA(), err)
+	}
+	return tag.GetObject().GetSHA(), nil
+}
+
+// CreateMissingTag creates a lightweight tag in the target repository at the commit of the source
+// tag, so the release is created on the existing tag instead of one derived from target_commitish.
+// It returns false when the tag already exists in the target at the same commit, including when it
+// was created concurrently, and an error when it exists at a different commit.
+func CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	sourceClient, err := newSourceClient()
+	if err != nil {
+		return false, err
+	}
+	targetClient, err := newTargetClient()
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	sourceRef, _, err := sourceClient.Git.GetRef(ctx, sourceOwner, repository, "tags/"+tagName)
+	if err != nil {
+		return false, fmt.Errorf("unable to get source tag %s: %v", tagName, err)
+	}
+	sourceSHA, err := peelTag(ctx, sourceClient, sourceOwner, repository, sourceRef)
+	if err != nil {
+		return false, err
+	}
+
+	// reconcile compares an existing target tag with the source tag
+	reconcile := func(targetRef *github.Reference) (bool, error) {
+		targetSHA, err := peelTag(ctx, targetClient, targetOwner, repository, targetRef)
+		if err != nil {
+			return false, err
+		}
+		if targetSHA != sourceSHA {
+			return false, fmt.Errorf("tag %s exists in the target at %s but points to %s in the source", tagName, targetSHA, sourceSHA)
+		}
+		return false, nil
+	}
+
+	targetRef, resp, err := targetClient.Git.GetRef(ctx, targetOwner, repository, "tags/"+tagName)
+	if err == nil {
+		return reconcile(targetRef)
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return false, fmt.Errorf("unable to get target tag %s: %v", tagName, err)
+	}
+
+	_, resp, err = targetClient.Git.CreateRef(ctx, targetOwner, repository, &github.Reference{
+		Ref:    github.String("refs/tags/" + tagName),
+		Object: &github.GitObject{SHA: github.String(sourceSHA)},
+	})
+	if err != nil {
+		// The tag was created since it was checked, e.g. by a release created concurrently
+		if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			targetRef, _, getErr := targetClient.Git.GetRef(ctx, targetOwner, repository, "tags/"+tagName)
+			if getErr == nil {
+				return reconcile(targetRef)
+			}
+		}
+		return false, fmt.Errorf("unable to create tag reference %s: %v", tagName, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestMigrateAnnotatedTag(t *testing.T) {
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_HOSTNAME", "source.example.com")
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "target.example.com")
+	defer viper.Reset()
+
+	source := http.NewServeMux()
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"tag","sha":"tagsha"}}`))
+	})
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/tags/tagsha", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag":"v1.0.0","sha":"tagsha","message":"Release 1.0.0","tagger":{"name":"Naruto","email":"naruto@example.com","date":"2024-01-01T00:00:00Z"},"object":{"type":"commit","sha":"commitsha"}}`))
+	})
+	setupTestClient(t, "source-token", "source.example.com", source)
+
+	var createdTag map[string]interface{}
+	var createdRef map[string]interface{}
+	target := http.NewServeMux()
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/git/tags", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&createdTag)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sha":"newtagsha"}`))
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&createdRef)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0"}`))
+	})
+	setupTestClient(t, "target-token", "target.example.com", target)
+
+	created, err := MigrateAnnotatedTag("source-org", "target-org", "repo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("MigrateAnnotatedTag returned an error: %v", err)
+	}
+	if !created {
+		t.Errorf("Expected the annotated tag to be created")
+	}
+
+	if createdTag["message"] != "Release 1.0.0" || createdTag["object"] != "commitsha" || createdTag["type"] != "commit" {
+		t.Errorf("Created tag does not match the source tag: %v", createdTag)
+	}
+	if tagger, ok := createdTag["tagger"].(map[string]interface{}); !ok || tagger["name"] != "Naruto" || tagger["date"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("Created tag does not keep the source tagger: %v", createdTag["tagger"])
+	}
+	if createdRef["ref"] != "refs/tags/v1.0.0" || createdRef["sha"] != "newtagsha" {
+		t.Errorf("Created reference does not point to the new tag: %v", createdRef)
+	}
+}
+
+func TestMigrateAnnotatedTagExistingTag(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "target.example.com")
+	defer viper.Reset()
+
+	target := http.NewServeMux()
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"tag","sha":"tagsha"}}`))
+	})
+	setupTestClient(t, "target-token", "target.example.com", target)
+
+	created, err := MigrateAnnotatedTag("source-org", "target-org", "repo", "v1.0.0")
+	if err != nil || created {
+		t.Errorf("Expected the existing tag to be skipped, got created=%v err=%v", created, err)
+	}
+}
+
+// setupTagServers registers source and target servers where v1.0.0 is an annotated source tag
+// of commitsha, and returns the target mux for the target tag handlers
+func setupTagServers(t *testing.T) *http.ServeMux {
+	t.Helper()
+
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_HOSTNAME", "source.example.com")
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("TARGET_HOSTNAME", "target.example.com")
+	t.Cleanup(viper.Reset)
+
+	source := http.NewServeMux()
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"tag","sha":"tagsha"}}`))
+	})
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/tags/tagsha", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag":"v1.0.0","sha":"tagsha","object":{"type":"commit","sha":"commitsha"}}`))
+	})
+	setupTestClient(t, "source-token", "source.example.com", source)
+
+	target := http.NewServeMux()
+	setupTestClient(t, "target-token", "target.example.com", target)
+	return target
+}
+
+func TestCreateMissingTag(t *testing.T) {
+	target := setupTagServers(t)
+
+	var createdRef map[string]interface{}
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&createdRef)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0"}`))
+	})
+
+	created, err := CreateMissingTag("source-org", "target-org", "repo", "v1.0.0")
+	if err != nil || !created {
+		t.Fatalf("Expected the tag to be created, got created=%v err=%v", created, err)
+	}
+	if createdRef["ref"] != "refs/tags/v1.0.0" || createdRef["sha"] != "commitsha" {
+		t.Errorf("Created reference does not point to the source commit: %v", createdRef)
+	}
+}
+
+func TestCreateMissingTagPreCreated(t *testing.T) {
+	target := setupTagServers(t)
+
+	// The tag was pre-created as a lightweight tag of the source commit
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"commit","sha":"commitsha"}}`))
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected tag creation")
+	})
+
+	created, err := CreateMissingTag("source-org", "target-org", "repo", "v1.0.0")
+	if err != nil || created {
+		t.Errorf("Expected the pre-created tag to be reused, got created=%v err=%v", created, err)
+	}
+}
+
+func TestCreateMissingTagConcurrentlyCreated(t *testing.T) {
+	target := setupTagServers(t)
+
+	// The tag is created by a release between the check and the creation
+	var checks int
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		checks++
+		if checks == 1 {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"commit","sha":"commitsha"}}`))
+	})
+	target.HandleFunc("POST /api/v3/repos/target-org/repo/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Reference already exists"}`, http.StatusUnprocessableEntity)
+	})
+
+	created, err := CreateMissingTag("source-org", "target-org", "repo", "v1.0.0")
+	if err != nil || created {
+		t.Errorf("Expected the concurrently created tag to be reconciled, got created=%v err=%v", created, err)
+	}
+}
+
+func TestCreateMissingTagConflict(t *testing.T) {
+	target := setupTagServers(t)
+
+	target.HandleFunc("GET /api/v3/repos/target-org/repo/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ref":"refs/tags/v1.0.0","object":{"type":"commit","sha":"othersha"}}`))
+	})
+
+	_, err := CreateMissingTag("source-org", "target-org", "repo", "v1.0.0")
+	if err == nil {
+		t.Errorf("Expected an error for a tag at a different commit")
+	}
+}
+
+func TestGetSourceTagsWithoutReleases(t *testing.T) {
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("SOURCE_HOSTNAME", "source.example.com")
+	defer viper.Reset()
+
+	source := http.NewServeMux()
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/matching-refs/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"ref":"refs/tags/v1.0.0","object":{"type":"tag","sha":"releasedsha"}},
+			{"ref":"refs/tags/v0.9.0","object":{"type":"tag","sha":"tagsha"}},
+			{"ref":"refs/tags/nightly","object":{"type":"commit","sha":"commitsha"}}
+		]`))
+	})
+	source.HandleFunc("GET /api/v3/repos/source-org/repo/git/tags/tagsha", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag":"v0.9.0","sha":"tagsha","message":"Beta release\n\n- First public build\n","tagger":{"name":"Naruto","date":"2023-06-01T00:00:00Z"},"object":{"type":"commit","sha":"betasha"}}`))
+	})
+	setupTestClient(t, "source-token", "source.example.com", source)
+
+	releases := []*github.RepositoryRelease{{TagName: github.String("v1.0.0")}}
+	tagReleases, err := GetSourceTagsWithoutReleases("source-org", "repo", releases)
+	if err != nil {
+		t.Fatalf("GetSourceTagsWithoutReleases returned an error: %v", err)
+	}
+	if len(tagReleases) != 1 {
+		t.Fatalf("Expected a release for the annotated tag without release only, got %d", len(tagReleases))
+	}
+
+	release := tagReleases[0]
+	if release.GetTagName() != "v0.9.0" || release.GetName() != "v0.9.0" || release.GetTargetCommitish() != "betasha" {
+		t.Errorf("Unexpected release for the tag: %+v", release)
+	}
+	if release.GetBody() != "Beta release\n\n- First public build" {
+		t.Errorf("Expected the tag message as the body, got %q", release.GetBody())
+	}
+	if release.GetPublishedAt().Format("2006-01-02") != "2023-06-01" {
+		t.Errorf("Expected the tag date as the publication date, got %v", release.GetPublishedAt())
+	}
+}
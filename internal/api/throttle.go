@@ -0,0 +1,92 @@
+package api
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// throttle limits the bytes transferred per second across all downloads and uploads of the run,
+// so a migration doesn't saturate a link shared with production traffic
+type throttle struct {
+	mu    sync.Mutex
+	rate  int64
+	next  time.Time
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+var bandwidth = &throttle{now: time.Now, sleep: time.Sleep}
+
+// SetBandwidthLimit limits the transfer rate of asset downloads and uploads. A rate of 0 is unthrottled.
+func SetBandwidthLimit(bytesPerSec int64) {
+	bandwidth.mu.Lock()
+	defer bandwidth.mu.Unlock()
+
+	bandwidth.rate = bytesPerSec
+	bandwidth.next = time.Time{}
+}
+
+// transferRequests paces the raw download and upload requests of all goroutines, which go-github
+// doesn't rate limit, so concurrent transfers don't trigger the secondary rate limits
+var transferRequests = &throttle{now: time.Now, sleep: time.Sleep}
+
+// SetRequestRateLimit limits the asset download and upload requests sent per second. A rate of 0
+// is unlimited.
+func SetRequestRateLimit(requestsPerSec int64) {
+	transferRequests.mu.Lock()
+	defer transferRequests.mu.Unlock()
+
+	transferRequests.rate = requestsPerSec
+	transferRequests.next = time.Time{}
+}
+
+// waitForTransferRequest blocks until a download or upload request can be sent without exceeding
+// the request rate
+func waitForTransferRequest() {
+	transferRequests.wait(1)
+}
+
+// wait blocks until n more bytes, or requests, can be transferred without exceeding the rate
+func (t *throttle) wait(n int) {
+	t.mu.Lock()
+	if t.rate <= 0 || n <= 0 {
+		t.mu.Unlock()
+		return
+	}
+
+	now := t.now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.rate))
+	delay := t.next.Sub(now)
+	t.mu.Unlock()
+
+	t.sleep(delay)
+}
+
+// throttledReader is a reader whose reads are limited by a throttle
+type throttledReader struct {
+	reader   io.Reader
+	throttle *throttle
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	// Read at most a second worth of data at once to keep the rate smooth
+	r.throttle.mu.Lock()
+	rate := r.throttle.rate
+	r.throttle.mu.Unlock()
+	if rate > 0 && int64(len(p)) > rate {
+		p = p[:rate]
+	}
+
+	n, err := r.reader.Read(p)
+	r.throttle.wait(n)
+	return n, err
+}
+
+// throttleReader wraps reader so it is limited by the bandwidth limit of the run
+func throttleReader(reader io.Reader) io.Reader {
+	return &throttledReader{reader: reader, throttle: bandwidth}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottledReaderHoldsRate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	limiter := &throttle{
+		rate:  1000,
+		now:   func() time.Time { return now.Add(slept) },
+		sleep: func(d time.Duration) { slept += d },
+	}
+
+	reader := &throttledReader{reader: bytes.NewReader(make([]byte, 5000)), throttle: limiter}
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil || n != 5000 {
+		t.Fatalf("Expected to read 5000 bytes, got %d: %v", n, err)
+	}
+
+	// 5000 bytes at 1000 bytes per second take 5 seconds
+	if slept < 4900*time.Millisecond || slept > 5100*time.Millisecond {
+		t.Errorf("Expected about 5s of throttling, got %v", slept)
+	}
+}
+
+func TestThrottledReaderUnthrottled(t *testing.T) {
+	var slept time.Duration
+	limiter := &throttle{now: time.Now, sleep: func(d time.Duration) { slept += d }}
+
+	reader := &throttledReader{reader: bytes.NewReader(make([]byte, 5000)), throttle: limiter}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if slept != 0 {
+		t.Errorf("Expected no throttling without a rate, got %v", slept)
+	}
+}
+
+func TestTransferRequestsPacedAcrossGoroutines(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	var delays []time.Duration
+	limiter := &throttle{
+		rate:  10,
+		now:   func() time.Time { return start },
+		sleep: func(d time.Duration) { mu.Lock(); delays = append(delays, d); mu.Unlock() },
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.wait(1)
+		}()
+	}
+	wg.Wait()
+
+	// 20 requests at 10 per second are spread over 2 seconds, 100ms apart
+	slices.Sort(delays)
+	for i, delay := range delays {
+		if want := time.Duration(i+1) * 100 * time.Millisecond; delay != want {
+			t.Errorf("Expected request %d to wait %v, got %v", i, want, delay)
+		}
+	}
+}
+
+func TestDownloadFileFromURLPacesRequests(t *testing.T) {
+	SetRequestRateLimit(20)
+	defer SetRequestRateLimit(0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	started := time.Now()
+	for i := range 5 {
+		if err := DownloadFileFromURL(server.URL, fmt.Sprintf("%s/asset%d", dir, i), "token"); err != nil {
+			t.Fatalf("DownloadFileFromURL returned an error: %v", err)
+		}
+	}
+
+	// The first request is sent right away and the next ones 50ms apart
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected 5 requests at 20 per second to take at least 200ms, took %v", elapsed)
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// tempRoot is the directory the temp directory of each run is created in, TEMP_DIR or a directory
+// of the system temp directory when empty. tmpDir is the temp directory of the current run, empty
+// until it is first used.
+var (
+	tempDirMu sync.Mutex
+	tempRoot  string
+	tmpDir    string
+)
+
+// SetTempDir sets the directory the temp directory of each run is created in, creating it if
+// needed. With an empty dir, runs use a directory of the system temp directory.
+func SetTempDir(dir string) error {
+	tempDirMu.Lock()
+	defer tempDirMu.Unlock()
+
+	tempRoot = dir
+	tmpDir = ""
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating temp directory: %v", err)
+	}
+	return nil
+}
+
+// tempRootDir returns the directory the temp directory of each run is created in
+func tempRootDir() string {
+	if tempRoot != "" {
+		return tempRoot
+	}
+	return filepath.Join(os.TempDir(), "gh-migrate-releases")
+}
+
+// TempDir returns the temp directory of the run the downloaded assets are written to, a new
+// directory of the temp root created on first use so concurrent runs don't share their files
+func TempDir() string {
+	tempDirMu.Lock()
+	defer tempDirMu.Unlock()
+
+	if tmpDir != "" {
+		return tmpDir
+	}
+	root := tempRootDir()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		pterm.Warning.Printf("Error creating temp directory: %v\n", err)
+		return root
+	}
+	dir, err := os.MkdirTemp(root, "run-")
+	if err != nil {
+		// Not recorded, so the root is never removed with the run directory
+		pterm.Warning.Printf("Error creating temp directory: %v\n", err)
+		return root
+	}
+	tmpDir = dir
+	return tmpDir
+}
+
+// RemoveTempDir removes the temp directory of the run with the files left in it, e.g. by a failed
+// upload, unless KEEP_TMP keeps them. The next run gets a new temp directory.
+func RemoveTempDir() error {
+	tempDirMu.Lock()
+	defer tempDirMu.Unlock()
+
+	dir := tmpDir
+	tmpDir = ""
+	if dir == "" {
+		return nil
+	}
+	if viper.GetBool("KEEP_TMP") {
+		pterm.Info.Printf("Keeping downloaded files in %s\n", dir)
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error removing temp directory: %v", err)
+	}
+	return nil
+}
+
+// removeTmpFile removes a downloaded file once it is no longer needed, unless KEEP_TMP keeps the
+// downloaded files for inspection, e.g. after a failed upload
+func removeTmpFile(fileName string) error {
+	if viper.GetBool("KEEP_TMP") {
+		pterm.Info.Printf("Keeping downloaded file %s\n", fileName)
+		return nil
+	}
+	if err := files.RemoveFile(fileName); err != nil {
+		return err
+	}
+	// The directory of a source asset is removed with its last file
+	if dir := filepath.Dir(fileName); dir != filepath.Clean(TempDir()) {
+		os.Remove(dir)
+	}
+	return nil
+}
+
+// localAssetPath returns the path of the downloaded file of an asset. A source asset is stored in
+// a directory named after its ID, so assets with the same name in different releases transferred
+// at the same time don't overwrite each other. Generated files, such as split parts, have no ID
+// and are stored at the root of the temp directory.
+func localAssetPath(asset *github.ReleaseAsset) string {
+	if asset.GetID() == 0 {
+		return filepath.Join(TempDir(), asset.GetName())
+	}
+	return filepath.Join(TempDir(), fmt.Sprint(asset.GetID()), asset.GetName())
+}
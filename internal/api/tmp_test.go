@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+func TestUploadAssetViaURLKeepTmp(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("KEEP_TMP", true)
+	t.Cleanup(viper.Reset)
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	if err := os.MkdirAll(tmpDir+"/run-cache", 0755); err != nil {
+		t.Fatalf("Failed to create run cache: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	}))
+	t.Cleanup(server.Close)
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	if err := UploadAssetViaURL(server.URL+"/repos/target-org/repo/releases/1/assets{?name,label}", asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+	if _, err := os.Stat(tmpDir + "/app.zip"); err != nil {
+		t.Errorf("Expected the uploaded file to be kept: %v", err)
+	}
+
+	if err := ClearRunCache(); err != nil {
+		t.Fatalf("ClearRunCache returned an error: %v", err)
+	}
+	if _, err := os.Stat(tmpDir + "/run-cache"); err != nil {
+		t.Errorf("Expected the run cache to be kept: %v", err)
+	}
+}
+
+func TestLocalAssetPathSeparatesAssetsWithTheSameName(t *testing.T) {
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+
+	first := localAssetPath(&github.ReleaseAsset{ID: github.Int64(1), Name: github.String("app.zip")})
+	second := localAssetPath(&github.ReleaseAsset{ID: github.Int64(2), Name: github.String("app.zip")})
+	if first == second || filepath.Base(first) != "app.zip" {
+		t.Fatalf("Expected distinct paths keeping the asset name, got %s and %s", first, second)
+	}
+	if got := localAssetPath(&github.ReleaseAsset{Name: github.String("app.zip.part001")}); got != filepath.Join(tmpDir, "app.zip.part001") {
+		t.Errorf("Expected a generated file at the root of the temp directory, got %s", got)
+	}
+
+	// The directory of the asset is removed with its file
+	if err := os.MkdirAll(filepath.Dir(first), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(first, []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeTmpFile(first); err != nil {
+		t.Fatalf("removeTmpFile returned an error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(first)); !os.IsNotExist(err) {
+		t.Errorf("Expected the asset directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(tmpDir); err != nil {
+		t.Errorf("Expected the temp directory to be kept: %v", err)
+	}
+}
+
+func TestTempDirIsRemovedAtTheEndOfTheRun(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "temp")
+	if err := SetTempDir(root); err != nil {
+		t.Fatalf("SetTempDir returned an error: %v", err)
+	}
+	t.Cleanup(func() { SetTempDir("") })
+
+	dir := TempDir()
+	if filepath.Dir(dir) != root || TempDir() != dir {
+		t.Fatalf("Expected a single run directory in %s, got %s", root, dir)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "leftover.zip"), []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveTempDir(); err != nil {
+		t.Fatalf("RemoveTempDir returned an error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the run directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("Expected the temp root to be kept: %v", err)
+	}
+
+	// The next run gets a new directory
+	if next := TempDir(); next == dir || filepath.Dir(next) != root {
+		t.Errorf("Expected a new run directory in %s, got %s", root, next)
+	}
+	RemoveTempDir()
+}
+
+func TestRemoveTempDirKeepTmp(t *testing.T) {
+	viper.Set("KEEP_TMP", true)
+	t.Cleanup(viper.Reset)
+	if err := SetTempDir(t.TempDir()); err != nil {
+		t.Fatalf("SetTempDir returned an error: %v", err)
+	}
+	t.Cleanup(func() { SetTempDir("") })
+
+	dir := TempDir()
+	if err := RemoveTempDir(); err != nil {
+		t.Fatalf("RemoveTempDir returned an error: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("Expected the run directory to be kept: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const vaultScheme = "vault://"
+
+// ResolveTokens replaces the source and target tokens with the installation tokens of the GitHub
+// Apps of SOURCE_APP_ID and TARGET_APP_ID, or with the tokens read from the files of
+// SOURCE_TOKEN_FILE and TARGET_TOKEN_FILE, or from Vault when a token is a vault://path#key
+// reference. Both tokens are required. Resolved tokens are never included in errors or logs.
+func ResolveTokens() error {
+	for _, key := range []string{"SOURCE_TOKEN", "TARGET_TOKEN"} {
+		if err := ResolveToken(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveToken resolves a single token like ResolveTokens, SOURCE_TOKEN or TARGET_TOKEN, for the
+// commands only calling the API of one side
+func ResolveToken(key string) error {
+	name := strings.ToLower(strings.ReplaceAll(key, "_", " "))
+	appTokens.mu.Lock()
+	delete(appTokens.tokens, key)
+	appTokens.mu.Unlock()
+
+	// An app installation token is renewed during the run
+	token, isApp, err := resolveAppToken(strings.TrimSuffix(key, "_TOKEN"))
+	if isApp {
+		if err == nil && (viper.GetString(key) != "" || viper.GetString(key+"_FILE") != "") {
+			err = fmt.Errorf("cannot specify both a token and app credentials")
+		}
+		if err != nil {
+			return fmt.Errorf("error resolving %s: %v", name, err)
+		}
+		viper.Set(key, token)
+		return nil
+	}
+
+	token, err = resolveToken(viper.GetString(key), viper.GetString(key+"_FILE"))
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %v", name, err)
+	}
+	if token == "" {
+		return fmt.Errorf("no %s provided, set it, a %s file or app credentials", name, name)
+	}
+	viper.Set(key, token)
+	return nil
+}
+
+// resolveToken returns the token read from fileName when set, resolving a Vault reference
+func resolveToken(token string, fileName string) (string, error) {
+	if fileName != "" {
+		if token != "" {
+			return "", fmt.Errorf("cannot specify both a token and a token file")
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", fmt.Errorf("unable to read token file: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("token file %s is empty", fileName)
+		}
+	}
+
+	if strings.HasPrefix(token, vaultScheme) {
+		return readVaultSecret(token)
+	}
+	return token, nil
+}
+
+// readVaultSecret reads the key of a vault://path#key reference from the Vault server of
+// VAULT_ADDR, authenticating with VAULT_TOKEN. Both KV version 1 and 2 secrets are supported.
+func readVaultSecret(reference string) (string, error) {
+	path, key, found := strings.Cut(strings.TrimPrefix(reference, vaultScheme), "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %s, expected vault://path#key", reference)
+	}
+
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return "", fmt.Errorf("VAULT_ADDR is required to read %s", reference)
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %v", reference, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to read %s: status code %d", reference, resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("unable to parse %s: %v", reference, err)
+	}
+
+	// KV version 2 nests the secret data under data.data
+	data := secret.Data
+	if nested, ok := secret.Data["data"]; ok {
+		var kv2 map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &kv2); err == nil {
+			data = kv2
+		}
+	}
+
+	var value string
+	if raw, ok := data[key]; !ok || json.Unmarshal(raw, &value) != nil || value == "" {
+		return "", fmt.Errorf("key %s not found in %s", key, reference)
+	}
+	return value, nil
+}
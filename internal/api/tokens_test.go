@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestResolveTokensFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "source-token")
+	targetFile := filepath.Join(dir, "target-token")
+	os.WriteFile(sourceFile, []byte("source-secret\n"), 0600)
+	os.WriteFile(targetFile, []byte("  target-secret  "), 0600)
+
+	viper.Set("SOURCE_TOKEN_FILE", sourceFile)
+	viper.Set("TARGET_TOKEN_FILE", targetFile)
+	defer viper.Reset()
+
+	if err := ResolveTokens(); err != nil {
+		t.Fatalf("ResolveTokens returned an error: %v", err)
+	}
+	if viper.GetString("SOURCE_TOKEN") != "source-secret" || viper.GetString("TARGET_TOKEN") != "target-secret" {
+		t.Errorf("Unexpected resolved tokens")
+	}
+}
+
+func TestResolveTokenErrors(t *testing.T) {
+	dir := t.TempDir()
+	emptyFile := filepath.Join(dir, "empty")
+	os.WriteFile(emptyFile, []byte("\n"), 0600)
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("file-secret"), 0600)
+
+	if _, err := resolveToken("", filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing token file")
+	}
+	if _, err := resolveToken("", emptyFile); err == nil {
+		t.Error("Expected an error for an empty token file")
+	}
+	_, err := resolveToken("flag-secret", tokenFile)
+	if err == nil {
+		t.Error("Expected an error for both a token and a token file")
+	} else if strings.Contains(err.Error(), "secret") {
+		t.Errorf("Error includes a token value: %v", err)
+	}
+	if token, err := resolveToken("plain-token", ""); err != nil || token != "plain-token" {
+		t.Errorf("Expected a plain token to be kept, got %v", err)
+	}
+
+	viper.Set("SOURCE_TOKEN", "source")
+	defer viper.Reset()
+	if err := ResolveTokens(); err == nil || !strings.Contains(err.Error(), "target token") {
+		t.Errorf("Expected an error for a missing target token, got %v", err)
+	}
+
+	// A command only calling the source doesn't need a target token
+	viper.Set("SOURCE_TOKEN", "")
+	viper.Set("SOURCE_TOKEN_FILE", tokenFile)
+	if err := ResolveToken("SOURCE_TOKEN"); err != nil || viper.GetString("SOURCE_TOKEN") != "file-secret" {
+		t.Errorf("Expected the source token to be read from its file, got %v", err)
+	}
+}
+
+func TestResolveTokenFromVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/github":
+			w.Write([]byte(`{"data": {"data": {"source": "kv2-secret"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/github":
+			w.Write([]byte(`{"data": {"target": "kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+
+	if token, err := resolveToken("vault://secret/data/github#source", ""); err != nil || token != "kv2-secret" {
+		t.Errorf("Expected the KV v2 secret, got %v", err)
+	}
+	if token, err := resolveToken("vault://kv/github#target", ""); err != nil || token != "kv1-secret" {
+		t.Errorf("Expected the KV v1 secret, got %v", err)
+	}
+	if _, err := resolveToken("vault://kv/github#missing", ""); err == nil {
+		t.Error("Expected an error for a missing key")
+	}
+	if _, err := resolveToken("vault://kv/github", ""); err == nil {
+		t.Error("Expected an error for a reference without a key")
+	}
+}
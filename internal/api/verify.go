@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// verifyUploadedAsset fetches an uploaded asset from the target and checks that it is in the
+// uploaded state with the size of the local file, and the digest when the target reports one.
+// With compareContent, an asset without a digest is downloaded back to compare its SHA-256 with
+// the local file. A mismatched asset is deleted so the upload can be retried.
+func verifyUploadedAsset(assetURL string, fileName string, size int64, compareContent bool) error {
+	client, err := newTargetClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(context.Background(), github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
+
+	req, err := client.NewRequest("GET", assetURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	var uploaded struct {
+		Size   int64  `json:"size"`
+		State  string `json:"state"`
+		Digest string `json:"digest"`
+	}
+	_, err = client.Do(ctx, req, &uploaded)
+	if err != nil {
+		return fmt.Errorf("unable to get uploaded asset %s: %v", assetURL, err)
+	}
+
+	mismatch := ""
+	switch {
+	case uploaded.State != "uploaded":
+		mismatch = fmt.Sprintf("state is %s", uploaded.State)
+	case uploaded.Size != size:
+		mismatch = fmt.Sprintf("size is %d, expected %d", uploaded.Size, size)
+	case strings.HasPrefix(uploaded.Digest, "sha256:"):
+		sum, err := fileSHA256(fileName)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(strings.TrimPrefix(uploaded.Digest, "sha256:"), sum) {
+			mismatch = fmt.Sprintf("digest is %s, expected sha256:%s", uploaded.Digest, sum)
+		}
+	case compareContent:
+		sum, err := fileSHA256(fileName)
+		if err != nil {
+			return err
+		}
+		uploadedSum, err := HashTargetAsset(&github.ReleaseAsset{Name: github.String(filepath.Base(fileName)), URL: github.String(assetURL)}, "sha256")
+		if err != nil {
+			return err
+		}
+		if uploadedSum != sum {
+			mismatch = fmt.Sprintf("content has sha256 %s, expected %s", uploadedSum, sum)
+		}
+	}
+	if mismatch == "" {
+		return nil
+	}
+
+	// Delete the broken asset so the next attempt can upload it again
+	req, err = client.NewRequest("DELETE", assetURL, nil)
+	if err == nil {
+		_, err = client.Do(ctx, req, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("uploaded asset %s is broken (%s) and could not be deleted: %v", assetURL, mismatch, err)
+	}
+	return fmt.Errorf("uploaded asset %s is broken: %s", assetURL, mismatch)
+}
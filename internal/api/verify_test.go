@@ -0,0 +1,271 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/spf13/viper"
+)
+
+// setupUploadServer serves an upload endpoint and the uploaded asset, reported with the given size
+func setupUploadServer(t *testing.T, reportedSize int) (string, *bool) {
+	t.Helper()
+
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("VERIFY_UPLOADS", true)
+	t.Cleanup(viper.Reset)
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+
+	deleted := false
+	mux := http.NewServeMux()
+	server := setupTestClient(t, "target-token", "", mux)
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":7,"url":"%s/api/v3/repos/target-org/repo/releases/assets/7"}`, server.URL)
+	})
+	mux.HandleFunc("GET /api/v3/repos/target-org/repo/releases/assets/7", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":7,"state":"uploaded","size":%d}`, reportedSize)
+	})
+	mux.HandleFunc("DELETE /api/v3/repos/target-org/repo/releases/assets/7", func(w http.ResponseWriter, r *http.Request) {
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return server.URL + "/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}", &deleted
+}
+
+func TestUploadAssetViaURLVerified(t *testing.T) {
+	uploadURL, deleted := setupUploadServer(t, len("asset contents"))
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	if err := UploadAssetViaURL(uploadURL, asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+
+	if _, err := os.Stat(tmpDir + "/app.zip"); !os.IsNotExist(err) {
+		t.Errorf("Expected the local file to be deleted once the upload is verified")
+	}
+	if *deleted {
+		t.Errorf("Expected the verified asset to be kept")
+	}
+}
+
+func TestUploadAssetViaURLVerificationMismatch(t *testing.T) {
+	uploadURL, deleted := setupUploadServer(t, 3)
+
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	if err := UploadAssetViaURL(uploadURL, asset); err == nil {
+		t.Fatalf("Expected an error for an uploaded asset with the wrong size")
+	}
+
+	if _, err := os.Stat(tmpDir + "/app.zip"); err != nil {
+		t.Errorf("Expected the local file to be retained for a retry: %v", err)
+	}
+	if !*deleted {
+		t.Errorf("Expected the broken asset to be deleted")
+	}
+}
+
+func TestUploadAssetViaURLRetriesReleaseNotReady(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+	releaseNotReadyDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { releaseNotReadyDelays = []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} }()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+
+	// The release was just created and answers 404 once before accepting uploads
+	var attempts int
+	var received string
+	mux := http.NewServeMux()
+	server := setupTestClient(t, "target-token", "", mux)
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	})
+	uploadURL := server.URL + "/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}"
+
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	if err := UploadAssetViaURL(uploadURL, asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+	if attempts != 2 || received != "asset contents" {
+		t.Errorf("Expected the upload to succeed on the second attempt with the full asset, got %d attempts and %q", attempts, received)
+	}
+
+	// A release that never becomes addressable fails once the retries are used
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/2/assets", http.NotFound)
+	err := UploadAssetViaURL(server.URL+"/api/uploads/repos/target-org/repo/releases/2/assets{?name,label}", asset)
+	if !errors.Is(err, ErrReleaseNotReady) {
+		t.Errorf("Expected ErrReleaseNotReady, got %v", err)
+	}
+}
+
+func TestUploadAssetViaURLAcceptsExistingAssetOnRetry(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("ACCEPT_EXISTING_ASSETS", true)
+	defer viper.Reset()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+
+	// The first attempt uploads the asset but its response is lost, so the retry finds it
+	var attempts int
+	mux := http.NewServeMux()
+	server := setupTestClient(t, "target-token", "", mux)
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message": "Validation Failed", "errors": [{"resource": "ReleaseAsset", "code": "already_exists", "field": "name"}]}`))
+	})
+	uploadURL := server.URL + "/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}"
+
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+	err := Retry(1, func() error {
+		return UploadAssetViaURL(uploadURL, asset)
+	})
+	if err != nil {
+		t.Fatalf("Expected the existing asset to count as uploaded, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if _, err := os.Stat(tmpDir + "/app.zip"); !os.IsNotExist(err) {
+		t.Errorf("Expected the local file to be deleted once the asset is found in the release")
+	}
+
+	// Without the option, the existing asset is an error
+	viper.Set("ACCEPT_EXISTING_ASSETS", false)
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	if err := UploadAssetViaURL(uploadURL, asset); err == nil {
+		t.Error("Expected an error for an existing asset without --accept-existing-assets")
+	}
+}
+
+func TestUploadAssetAsUploadsWithName(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	defer viper.Reset()
+
+	tmpDir = t.TempDir()
+	defer func() { tmpDir = "" }()
+
+	var name, received string
+	mux := http.NewServeMux()
+	server := setupTestClient(t, "target-token", "", mux)
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		name = r.URL.Query().Get("name")
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":7}`))
+	})
+
+	if err := os.WriteFile(tmpDir+"/binary", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	asset := &github.ReleaseAsset{Name: github.String("binary"), ContentType: github.String("application/octet-stream")}
+	if err := UploadAssetAs(server.URL+"/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}", asset, "mytool-v1.0.0-linux-amd64"); err != nil {
+		t.Fatalf("UploadAssetAs returned an error: %v", err)
+	}
+	if name != "mytool-v1.0.0-linux-amd64" || received != "asset contents" {
+		t.Errorf("Expected the source file uploaded as mytool-v1.0.0-linux-amd64, got %q with %q", name, received)
+	}
+}
+
+func TestUploadAssetViaURLVerifiesContent(t *testing.T) {
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("VERIFY_ASSETS", true)
+	t.Cleanup(viper.Reset)
+
+	tmpDir = t.TempDir()
+	t.Cleanup(func() { tmpDir = "" })
+
+	// The target reports no digest, so the uploaded content is downloaded back
+	var stored string
+	deleted := false
+	mux := http.NewServeMux()
+	server := setupTestClient(t, "target-token", "", mux)
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stored = string(body)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":7,"url":"%s/api/v3/repos/target-org/repo/releases/assets/7"}`, server.URL)
+	})
+	mux.HandleFunc("GET /api/v3/repos/target-org/repo/releases/assets/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/octet-stream" {
+			w.Write([]byte(stored))
+			return
+		}
+		fmt.Fprintf(w, `{"id":7,"state":"uploaded","size":%d}`, len(stored))
+	})
+	mux.HandleFunc("DELETE /api/v3/repos/target-org/repo/releases/assets/7", func(w http.ResponseWriter, r *http.Request) {
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	uploadURL := server.URL + "/api/uploads/repos/target-org/repo/releases/1/assets{?name,label}"
+	asset := &github.ReleaseAsset{Name: github.String("app.zip"), ContentType: github.String("application/zip")}
+
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	if err := UploadAssetViaURL(uploadURL, asset); err != nil {
+		t.Fatalf("UploadAssetViaURL returned an error: %v", err)
+	}
+	if deleted {
+		t.Errorf("Expected the asset with the same content to be kept")
+	}
+
+	// An asset of the right size with a different content is deleted and the local file kept
+	if err := os.WriteFile(tmpDir+"/app.zip", []byte("asset contents"), 0644); err != nil {
+		t.Fatalf("Failed to write asset: %v", err)
+	}
+	mux.HandleFunc("POST /api/uploads/repos/target-org/repo/releases/2/assets", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		stored = "asset_contents"
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":7,"url":"%s/api/v3/repos/target-org/repo/releases/assets/7"}`, server.URL)
+	})
+	if err := UploadAssetViaURL(server.URL+"/api/uploads/repos/target-org/repo/releases/2/assets{?name,label}", asset); err == nil {
+		t.Fatal("Expected an error for an uploaded asset with a different content")
+	}
+	if !deleted {
+		t.Errorf("Expected the corrupted asset to be deleted")
+	}
+	if _, err := os.Stat(tmpDir + "/app.zip"); err != nil {
+		t.Errorf("Expected the local file to be retained for a retry: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package files
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"net/url"
 	"os"
 	"strings"
@@ -45,24 +46,62 @@ func CreateJSON(data interface{}, filename string) error {
 	return nil
 }
 
-// read repository list from file assuming each line is a repository
-func ReadRepositoryListFromFile(fileName string) ([]string, error) {
+// RepositoryEntry is a repository of a repository list with its optional per-repository overrides
+type RepositoryEntry struct {
+	Repository string
+	// Target is the owner/name the repository is migrated to, empty for the target organization
+	Target    string
+	Overrides map[string]string
+}
+
+// read repository list from file assuming each line is a repository, optionally followed by
+// -> and the target repository, then key=value overrides separated by spaces, e.g.
+// "org/repo -> other-org/repo skip_assets=true tag_filter=v2.*"
+func ReadRepositoryListFromFile(fileName string) ([]RepositoryEntry, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var repositories []string
+	var repositories []RepositoryEntry
 	scanner := bufio.NewScanner(file)
+	lineNumber := 0
 	for scanner.Scan() {
-		repo := scanner.Text()
-		parsedURL, err := url.Parse(repo)
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		source, target, overrides, err := splitRepositoryLine(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		repository, err := repositoryPath(source)
 		if err != nil {
 			return nil, err
 		}
-		path := strings.TrimPrefix(parsedURL.Path, "/")
-		repositories = append(repositories, path)
+		entry := RepositoryEntry{Repository: repository}
+		if target != "" {
+			entry.Target, err = repositoryPath(target)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, field := range overrides {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("line %d: invalid override %q, expected key=value", lineNumber, field)
+			}
+			if entry.Overrides == nil {
+				entry.Overrides = map[string]string{}
+			}
+			entry.Overrides[key] = value
+		}
+
+		repositories = append(repositories, entry)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -71,3 +110,36 @@ func ReadRepositoryListFromFile(fileName string) ([]string, error) {
 
 	return repositories, nil
 }
+
+// splitRepositoryLine splits the fields of a repository list line into the source repository, the
+// target repository following the arrow, if any, and the overrides. The arrow may be written
+// with or without spaces around it, e.g. "org/repo -> other-org/repo" or "org/repo->other-org/repo".
+func splitRepositoryLine(fields []string) (string, string, []string, error) {
+	source, rest := fields[0], fields[1:]
+	target, found := "", false
+	if before, after, cut := strings.Cut(source, "->"); cut {
+		source, target, found = before, after, true
+	} else if len(rest) > 0 && strings.HasPrefix(rest[0], "->") {
+		target, rest, found = strings.TrimPrefix(rest[0], "->"), rest[1:], true
+	}
+	if !found {
+		return source, "", rest, nil
+	}
+
+	if target == "" && len(rest) > 0 && !strings.Contains(rest[0], "=") {
+		target, rest = rest[0], rest[1:]
+	}
+	if source == "" || target == "" {
+		return "", "", nil, fmt.Errorf("invalid repository mapping, expected source -> target")
+	}
+	return source, target, rest, nil
+}
+
+// repositoryPath returns the owner/name of a repository given as owner/name, name or URL
+func repositoryPath(value string) (string, error) {
+	parsedURL, err := url.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(parsedURL.Path, "/"), nil
+}
@@ -2,6 +2,7 @@ package files_test
 
 import (
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/mona-actions/gh-migrate-releases/internal/files"
@@ -82,3 +83,74 @@ func TestRemoveFile(t *testing.T) {
 		t.Errorf("RemoveFile did not remove the file")
 	}
 }
+
+func TestReadRepositoryListFromFileOverrides(t *testing.T) {
+	fileName := "test.txt"
+	content := "https://github.com/org/repo1\norg/repo2 skip_assets=true tag_filter=v2.*\n\norg/repo3\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer os.Remove(fileName)
+
+	repositories, err := files.ReadRepositoryListFromFile(fileName)
+	if err != nil {
+		t.Fatalf("ReadRepositoryListFromFile returned an error: %v", err)
+	}
+
+	expected := []files.RepositoryEntry{
+		{Repository: "org/repo1"},
+		{Repository: "org/repo2", Overrides: map[string]string{"skip_assets": "true", "tag_filter": "v2.*"}},
+		{Repository: "org/repo3"},
+	}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
+
+func TestReadRepositoryListFromFileInvalidOverride(t *testing.T) {
+	fileName := "test.txt"
+	if err := os.WriteFile(fileName, []byte("org/repo skip_assets\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer os.Remove(fileName)
+
+	if _, err := files.ReadRepositoryListFromFile(fileName); err == nil {
+		t.Errorf("Expected an error for an override without a value")
+	}
+}
+
+func TestReadRepositoryListFromFileTargets(t *testing.T) {
+	fileName := "test.txt"
+	content := "org/repo1 -> other-org/repo1\norg/repo2->other-org/repo2 skip_assets=true\norg/repo3 tag_filter=v1->v2\nhttps://github.com/org/repo4 -> https://github.example.com/third-org/repo4\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer os.Remove(fileName)
+
+	repositories, err := files.ReadRepositoryListFromFile(fileName)
+	if err != nil {
+		t.Fatalf("ReadRepositoryListFromFile returned an error: %v", err)
+	}
+
+	expected := []files.RepositoryEntry{
+		{Repository: "org/repo1", Target: "other-org/repo1"},
+		{Repository: "org/repo2", Target: "other-org/repo2", Overrides: map[string]string{"skip_assets": "true"}},
+		{Repository: "org/repo3", Overrides: map[string]string{"tag_filter": "v1->v2"}},
+		{Repository: "org/repo4", Target: "third-org/repo4"},
+	}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
+
+func TestReadRepositoryListFromFileMissingTarget(t *testing.T) {
+	fileName := "test.txt"
+	if err := os.WriteFile(fileName, []byte("org/repo ->\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer os.Remove(fileName)
+
+	if _, err := files.ReadRepositoryListFromFile(fileName); err == nil {
+		t.Errorf("Expected an error for an arrow without a target")
+	}
+}
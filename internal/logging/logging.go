@@ -0,0 +1,157 @@
+// Package logging selects the format of the log output. In the json format every line printed
+// with pterm is written as a JSON object, so the log stream can be ingested by log collectors.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// Log formats selected with LOG_FORMAT
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// prefixLevels maps the prefixes of the pterm printers to log levels
+var prefixLevels = map[string]string{
+	"DEBUG":   "debug",
+	"INFO":    "info",
+	"SUCCESS": "info",
+	"WARNING": "warning",
+	"ERROR":   "error",
+	"FATAL":   "fatal",
+}
+
+// entry is a JSON log line
+type entry struct {
+	Level      string            `json:"level"`
+	Time       string            `json:"time"`
+	Repository string            `json:"repo,omitempty"`
+	Tag        string            `json:"tag,omitempty"`
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// jsonWriter writes each pterm output as a JSON line, with the repository and tag being migrated
+type jsonWriter struct {
+	mu         sync.Mutex
+	out        io.Writer
+	now        func() time.Time
+	repository string
+	tag        string
+}
+
+var output *jsonWriter
+
+// out receives all the output of the tool, stdout by default
+var out io.Writer = os.Stdout
+
+// Setup selects the log format, text or json, of the output written to stdout or the writer
+// set with SetOutput
+func Setup(format string) error {
+	return setup(format, out)
+}
+
+// Output returns the writer receiving the output
+func Output() io.Writer {
+	return out
+}
+
+// SetOutput routes the output of the pterm printers, spinners and progress bars to w, in the
+// selected log format
+func SetOutput(w io.Writer) {
+	out = w
+	pterm.SetDefaultOutput(w)
+	if output == nil {
+		return
+	}
+	output.mu.Lock()
+	defer output.mu.Unlock()
+	output.out = w
+}
+
+func setup(format string, out io.Writer) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		return nil
+	case FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q, valid formats are: %s, %s", format, FormatText, FormatJSON)
+	}
+
+	output = &jsonWriter{out: out, now: time.Now}
+
+	// Raw output keeps the prefix text of each printer, which gives the level of the line
+	pterm.DisableStyling()
+	for _, printer := range []*pterm.PrefixPrinter{&pterm.Debug, &pterm.Info, &pterm.Success, &pterm.Warning, &pterm.Error, &pterm.Fatal} {
+		printer.Writer = output
+	}
+	pterm.DefaultSpinner.Writer = output
+	pterm.DefaultProgressbar.Writer = output
+
+	return nil
+}
+
+// SetContext sets the repository and tag added to the following JSON log lines
+func SetContext(repository string, tag string) {
+	if output == nil {
+		return
+	}
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	output.repository = repository
+	output.tag = tag
+}
+
+// SetTag sets the tag added to the following JSON log lines, keeping the repository
+func SetTag(tag string) {
+	if output == nil {
+		return
+	}
+	output.mu.Lock()
+	defer output.mu.Unlock()
+
+	output.tag = tag
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	// Drop the line clearing of spinners and progress bars
+	message := strings.TrimSpace(strings.ReplaceAll(pterm.RemoveColorFromString(string(p)), "\r", ""))
+	if message == "" {
+		return len(p), nil
+	}
+
+	line := entry{Level: "info", Message: message}
+	prefix, rest, _ := strings.Cut(message, ":")
+	if level, ok := prefixLevels[strings.TrimSpace(prefix)]; ok {
+		line.Level = level
+		line.Message = strings.TrimSpace(rest)
+	} else {
+		// Lines without a level prefix are the texts of spinners and progress bars
+		line.Fields = map[string]string{"source": "progress"}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line.Time = w.now().UTC().Format(time.RFC3339Nano)
+	line.Repository = w.repository
+	line.Tag = w.tag
+	data, err := json.Marshal(line)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.out.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pterm/pterm"
+)
+
+// syncBuffer is a buffer safe for concurrent writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+// useJSONOutput switches the pterm printers to JSON lines written to a buffer for the duration of a test
+func useJSONOutput(t *testing.T) *syncBuffer {
+	t.Helper()
+
+	debug, info, success, warning, errorPrinter, fatal := pterm.Debug, pterm.Info, pterm.Success, pterm.Warning, pterm.Error, pterm.Fatal
+	spinner, progressbar := pterm.DefaultSpinner, pterm.DefaultProgressbar
+	t.Cleanup(func() {
+		pterm.Debug, pterm.Info, pterm.Success, pterm.Warning, pterm.Error, pterm.Fatal = debug, info, success, warning, errorPrinter, fatal
+		pterm.DefaultSpinner, pterm.DefaultProgressbar = spinner, progressbar
+		pterm.EnableStyling()
+		pterm.DisableDebugMessages()
+		output = nil
+	})
+
+	buf := &syncBuffer{}
+	if err := setup(FormatJSON, buf); err != nil {
+		t.Fatalf("setup returned an error: %v", err)
+	}
+	return buf
+}
+
+func TestJSONOutputLevels(t *testing.T) {
+	buf := useJSONOutput(t)
+	pterm.EnableDebugMessages()
+	SetContext("org/repo", "v1.0.0")
+
+	pterm.Debug.Println("debug message")
+	pterm.Info.Printf("Created release: %s\n", "v1.0.0")
+	pterm.Success.Println("done")
+	pterm.Warning.Printf("Error modifying release body: %v", "oops")
+	pterm.Error.Println("failed")
+
+	expected := []struct{ level, message string }{
+		{"debug", "debug message"},
+		{"info", "Created release: v1.0.0"},
+		{"info", "done"},
+		{"warning", "Error modifying release body: oops"},
+		{"error", "failed"},
+	}
+
+	lines := buf.lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %v", len(expected), len(lines), lines)
+	}
+	for i, line := range lines {
+		var got entry
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %q: %v", i, line, err)
+		}
+		if got.Level != expected[i].level || got.Message != expected[i].message {
+			t.Errorf("Line %d: got level %q and message %q, want %q and %q", i, got.Level, got.Message, expected[i].level, expected[i].message)
+		}
+		if got.Repository != "org/repo" || got.Tag != "v1.0.0" || got.Time == "" {
+			t.Errorf("Line %d: missing context: %+v", i, got)
+		}
+	}
+}
+
+func TestJSONOutputSpinnerText(t *testing.T) {
+	buf := useJSONOutput(t)
+
+	pterm.DefaultSpinner.Writer.Write([]byte("\rFetching releases from repository: repo\n"))
+
+	var got entry
+	if err := json.Unmarshal([]byte(buf.lines()[0]), &got); err != nil {
+		t.Fatalf("Spinner text is not valid JSON: %v", err)
+	}
+	if got.Level != "info" || got.Message != "Fetching releases from repository: repo" || got.Fields["source"] != "progress" {
+		t.Errorf("Unexpected spinner entry: %+v", got)
+	}
+}
+
+func TestJSONOutputConcurrentWrites(t *testing.T) {
+	buf := useJSONOutput(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			SetContext("org/repo", "")
+			pterm.Info.Printf("message %d\n", i)
+		}(i)
+	}
+	wg.Wait()
+
+	lines := buf.lines()
+	if len(lines) != 20 {
+		t.Fatalf("Expected 20 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("Invalid JSON line: %q", line)
+		}
+	}
+}
+
+func TestSetupUnknownFormat(t *testing.T) {
+	if err := setup("xml", &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unknown log format")
+	}
+	if err := setup("", &bytes.Buffer{}); err != nil || output != nil {
+		t.Errorf("Expected the text format by default, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// authorMarker marks the attribution line of the source author so it is only added once
+const authorMarker = "<!-- gh-migrate-releases:author -->"
+
+// AttributionData is the data available to an author attribution template
+type AttributionData struct {
+	// Author is the mention of the source author, mapped with the mapping file, e.g. @newuser
+	Author string
+	// Login is the login of the source author, e.g. olduser
+	Login string
+	// Date is the source publication date, or creation date of an unpublished release, e.g.
+	// January 2, 2006
+	Date        string
+	PublishedAt time.Time
+	Release     *github.RepositoryRelease
+}
+
+// ParseAttributionTemplate parses an author attribution template such as
+// "Originally released by {{.Author}} on {{.Date}}"
+func ParseAttributionTemplate(attribution string) (*template.Template, error) {
+	tmpl, err := template.New("author-attribution").Option("missingkey=error").Parse(attribution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse author attribution: %v", err)
+	}
+	return tmpl, nil
+}
+
+// AddAuthorAttribution adds a line attributing the release to its source author, rendered with
+// the attribution template, at the top or the bottom of the body. The author is mapped with the
+// handles of the mapping file, when there is one, as a mention. A release without an author is
+// returned unchanged.
+func AddAuthorAttribution(release *github.RepositoryRelease, attribution string, position string, filePath string) (*github.RepositoryRelease, error) {
+	if release == nil {
+		return nil, fmt.Errorf("release is nil")
+	}
+	login := release.GetAuthor().GetLogin()
+	if attribution == "" || login == "" || strings.Contains(release.GetBody(), authorMarker) {
+		return release, nil
+	}
+
+	author := "@" + login
+	if filePath != "" {
+		handleMap, err := loadHandleMap(filePath)
+		if err != nil {
+			return release, err
+		}
+		author = mapAuthor(login, handleMap)
+	}
+
+	tmpl, err := ParseAttributionTemplate(attribution)
+	if err != nil {
+		return release, err
+	}
+	date := release.GetPublishedAt().Time
+	if date.IsZero() {
+		date = release.GetCreatedAt().Time
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, AttributionData{
+		Author:      author,
+		Login:       login,
+		Date:        date.Format("January 2, 2006"),
+		PublishedAt: release.GetPublishedAt().Time,
+		Release:     release,
+	}); err != nil {
+		return release, fmt.Errorf("failed to render author attribution: %v", err)
+	}
+
+	// Keep the line endings of the body
+	releaseBody := release.GetBody()
+	newline := bodyNewline(releaseBody)
+	line := authorMarker + newline + "> " + strings.TrimSpace(rendered.String())
+	switch {
+	case releaseBody == "":
+		releaseBody = line
+	case position == "top":
+		releaseBody = line + newline + newline + releaseBody
+	default:
+		releaseBody = releaseBody + newline + newline + line
+	}
+	release.Body = &releaseBody
+
+	return release, nil
+}
+
+// mapAuthor returns the mention of a source login mapped with the handle map, which lists
+// handles as mentions like the mapping skeleton or as bare logins
+func mapAuthor(login string, handleMap map[string]string) string {
+	for _, source := range []string{"@" + login, login} {
+		if target, ok := handleMap[source]; ok {
+			recordHit(source, target, 1)
+			return "@" + strings.TrimPrefix(target, "@")
+		}
+	}
+	return "@" + login
+}
@@ -0,0 +1,62 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestAddAuthorAttribution(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "mapping.csv")
+	if err := os.WriteFile(mappingFile, []byte("source,target\n@olduser,@newuser\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ResetMappingStats)
+	newRelease := func() *github.RepositoryRelease {
+		return &github.RepositoryRelease{
+			Body:        github.String("Notes"),
+			Author:      &github.User{Login: github.String("olduser")},
+			PublishedAt: &github.Timestamp{Time: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		}
+	}
+	attribution := "Originally released by {{.Author}} on {{.Date}}"
+
+	release, err := AddAuthorAttribution(newRelease(), attribution, "bottom", mappingFile)
+	if err != nil {
+		t.Fatalf("AddAuthorAttribution returned an error: %v", err)
+	}
+	want := "Notes\n\n" + authorMarker + "\n> Originally released by @newuser on March 5, 2024"
+	if release.GetBody() != want {
+		t.Errorf("Expected body %q, got %q", want, release.GetBody())
+	}
+
+	// The attribution is only added once
+	release, err = AddAuthorAttribution(release, attribution, "bottom", mappingFile)
+	if err != nil || release.GetBody() != want {
+		t.Errorf("Expected the attribution to be added once, got %q (%v)", release.GetBody(), err)
+	}
+
+	release, err = AddAuthorAttribution(newRelease(), "By {{.Author}} ({{.Login}})", "top", "")
+	if err != nil {
+		t.Fatalf("AddAuthorAttribution returned an error: %v", err)
+	}
+	if want := authorMarker + "\n> By @olduser (olduser)\n\nNotes"; release.GetBody() != want {
+		t.Errorf("Expected body %q, got %q", want, release.GetBody())
+	}
+
+	// A release without an author is left as is
+	release = newRelease()
+	release.Author = nil
+	release, err = AddAuthorAttribution(release, attribution, "bottom", mappingFile)
+	if err != nil || release.GetBody() != "Notes" {
+		t.Errorf("Expected the body of a release without an author unchanged, got %q (%v)", release.GetBody(), err)
+	}
+
+	if _, err := AddAuthorAttribution(newRelease(), "{{.Unknown}}", "bottom", ""); err == nil || !strings.Contains(err.Error(), "render author attribution") {
+		t.Errorf("Expected an error for an unknown field, got %v", err)
+	}
+}
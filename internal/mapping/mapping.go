@@ -4,13 +4,61 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v62/github"
-	"github.com/spf13/viper"
 )
 
+// RuleStats is the number of substitutions made by a mapping file rule
+type RuleStats struct {
+	Source string
+	Target string
+	Hits   int
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*RuleStats{}
+)
+
+// recordHit adds the substitutions made by a rule to the mapping statistics
+func recordHit(source string, target string, hits int) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	rule, ok := stats[source]
+	if !ok {
+		rule = &RuleStats{Source: source, Target: target}
+		stats[source] = rule
+	}
+	rule.Hits += hits
+}
+
+// MappingStats returns the substitutions made by every loaded mapping rule, sorted by source.
+// Rules with no hits never matched any release body.
+func MappingStats() []RuleStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	var rules []RuleStats
+	for _, rule := range stats {
+		rules = append(rules, *rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Source < rules[j].Source })
+	return rules
+}
+
+// ResetMappingStats clears the mapping statistics
+func ResetMappingStats() {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	stats = map[string]*RuleStats{}
+}
+
 func loadHandleMap(filePath string) (map[string]string, error) {
 
 	file, err := os.Open(filePath)
@@ -27,13 +75,51 @@ func loadHandleMap(filePath string) (map[string]string, error) {
 
 	handleMap := make(map[string]string)
 	for _, record := range records {
+		// A handle without a target, e.g. in a skeleton not filled in yet, is not mapped
+		if record[1] == "" {
+			continue
+		}
 		handleMap[record[0]] = record[1]
 	}
 
 	return handleMap, nil
 }
 
-func ModifyReleaseBody(releaseBody *string, filePath string) (*string, error) {
+// ValidateMappingFile checks that a mapping file can be read and that every row has a source
+// and a target handle
+func ValidateMappingFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		if len(record) < 2 {
+			return fmt.Errorf("line %d: expected a source and a target handle", i+1)
+		}
+	}
+
+	return nil
+}
+
+// BodyMapping is the source and target of a migration, whose links and organization names are
+// replaced in the release bodies
+type BodyMapping struct {
+	SourceHostname     string
+	TargetHostname     string
+	SourceOrganization string
+	TargetOrganization string
+}
+
+func ModifyReleaseBody(releaseBody *string, filePath string, migration BodyMapping) (*string, error) {
 	// Modify release body to map new handles and map old urls to new urls
 
 	updatedReleaseBody := ""
@@ -41,13 +127,22 @@ func ModifyReleaseBody(releaseBody *string, filePath string) (*string, error) {
 		updatedReleaseBody = *releaseBody
 	}
 
-	if viper.GetString("SOURCE_HOSTNAME") != "" {
-		// Replace source hostname with GHEC hostname github.com
-		updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, viper.GetString("SOURCE_HOSTNAME"), "github.com")
+	// Replace the source hostname with the target one, github.com unless migrating to GitHub
+	// Enterprise. Only the links to the source organization of github.com are replaced, the body
+	// may link to other projects of github.com.
+	sourceHost, targetHost := webHost(migration.SourceHostname), webHost(migration.TargetHostname)
+	if sourceHost != targetHost && migration.SourceHostname != "" {
+		updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, sourceHost, targetHost)
+	} else if sourceHost != targetHost && migration.SourceOrganization != "" {
+		sourceOrg := migration.SourceOrganization
+		updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, sourceHost+"/"+sourceOrg, targetHost+"/"+sourceOrg)
 	}
 
-	// Replace source organization with target organization
-	updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, viper.GetString("SOURCE_ORGANIZATION"), viper.GetString("TARGET_ORGANIZATION"))
+	// Replace source organization with target organization. An empty organization would match
+	// between every byte of the body.
+	if migration.SourceOrganization != "" {
+		updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, migration.SourceOrganization, migration.TargetOrganization)
+	}
 
 	// Load handle map from file
 	handleMap, err := loadHandleMap(filePath)
@@ -56,13 +151,71 @@ func ModifyReleaseBody(releaseBody *string, filePath string) (*string, error) {
 	}
 
 	// Replace old handles with new handles
-	for source, target := range handleMap {
-		updatedReleaseBody = strings.ReplaceAll(updatedReleaseBody, source, target)
-	}
+	updatedReleaseBody = replaceHandles(updatedReleaseBody, handleMap)
 
 	return &updatedReleaseBody, nil
 }
 
+// webHost returns the host of the web URLs of a hostname flag, github.com when it is empty
+func webHost(hostname string) string {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://"), "/")
+	if host == "" {
+		return "github.com"
+	}
+	return host
+}
+
+// replaceHandles replaces the handles of a body in a single pass, the longest handle first, so a
+// handle mapped to another mapped handle is not replaced twice and the result doesn't depend on
+// the map order. The bytes between the handles, line endings and multibyte characters
+// included, are copied as is.
+func replaceHandles(body string, handleMap map[string]string) string {
+	sources := make([]string, 0, len(handleMap))
+	for source := range handleMap {
+		if source != "" {
+			sources = append(sources, source)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if len(sources[i]) != len(sources[j]) {
+			return len(sources[i]) > len(sources[j])
+		}
+		return sources[i] < sources[j]
+	})
+
+	hits := make(map[string]int, len(sources))
+	var builder strings.Builder
+	builder.Grow(len(body))
+	for i := 0; i < len(body); {
+		matched := ""
+		for _, source := range sources {
+			if strings.HasPrefix(body[i:], source) {
+				matched = source
+				break
+			}
+		}
+		if matched == "" {
+			builder.WriteByte(body[i])
+			i++
+			continue
+		}
+		hits[matched]++
+		builder.WriteString(handleMap[matched])
+		i += len(matched)
+	}
+
+	for _, source := range sources {
+		recordHit(source, handleMap[source], hits[source])
+	}
+	return builder.String()
+}
+
+// sourceReleaseMarker marks the block recording the source release ID so it is only added once
+const sourceReleaseMarker = "<!-- gh-migrate-releases:source-release -->"
+
+// sourceLinkMarker marks the link back to the source release so it is only added once
+const sourceLinkMarker = "<!-- gh-migrate-releases:source-link -->"
+
 func AddSourceTimeStamps(release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
 	if release == nil {
 		return nil, fmt.Errorf("release is nil")
@@ -89,9 +242,46 @@ func AddSourceTimeStamps(release *github.RepositoryRelease) (*github.RepositoryR
 	}
 
 	// Add source timestamps to release body
-	releaseBody = releaseBody + "\n\n" + ">Release Originally Created on: " + createdAt + "\n" + "> Release Originally Published on: " + publishedAt
+	newline := bodyNewline(releaseBody)
+	releaseBody = releaseBody + newline + newline + ">Release Originally Created on: " + createdAt + newline + "> Release Originally Published on: " + publishedAt
 
 	release.Body = &releaseBody
 
 	return release, nil
 }
+
+// AddSourceReferences records the source release ID and URL with recordSourceIDs and links back
+// to the source release with linkSourceRelease. It is called after ModifyReleaseBody, which
+// would rewrite the source URL to the target.
+func AddSourceReferences(release *github.RepositoryRelease, recordSourceIDs bool, linkSourceRelease bool) (*github.RepositoryRelease, error) {
+	if release == nil {
+		return nil, fmt.Errorf("release is nil")
+	}
+
+	releaseBody := release.GetBody()
+	newline := bodyNewline(releaseBody)
+
+	// Record the source release ID and URL, which are not kept by the target
+	if recordSourceIDs && !strings.Contains(releaseBody, sourceReleaseMarker) {
+		releaseBody = releaseBody + newline + sourceReleaseMarker + newline + fmt.Sprintf("> Original Release ID: %d ([source](%s))", release.GetID(), release.GetHTMLURL())
+	}
+
+	// Link back to the source release for readers of the target during a transition period. A
+	// release discussion can't be commented through the REST API, so the link is kept in the body.
+	if linkSourceRelease && release.GetHTMLURL() != "" && !strings.Contains(releaseBody, sourceLinkMarker) {
+		releaseBody = releaseBody + newline + sourceLinkMarker + newline + fmt.Sprintf("> Migrated from %s", release.GetHTMLURL())
+	}
+
+	release.Body = &releaseBody
+
+	return release, nil
+}
+
+// bodyNewline returns the line ending of a body, CRLF for bodies written on Windows, so the lines
+// added to it keep its line endings
+func bodyNewline(body string) string {
+	if strings.Contains(body, "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
@@ -3,13 +3,13 @@ package mapping
 import (
 	"encoding/csv"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/v62/github"
-	"github.com/spf13/viper"
 )
 
 func TestLoadHandleMap(t *testing.T) {
@@ -26,6 +26,7 @@ func TestLoadHandleMap(t *testing.T) {
 	data := [][]string{
 		{"key1", "value1"},
 		{"key2", "value2"},
+		{"key3", ""},
 	}
 	writer := csv.NewWriter(file)
 	for _, record := range data {
@@ -82,13 +83,10 @@ func TestModifyReleaseBody(t *testing.T) {
 	}
 	writer.Flush()
 
-	// Set up viper configuration
-	viper.Set("SOURCE_HOSTNAME", "example.com")
-	viper.Set("SOURCE_ORGANIZATION", "source-org")
-	viper.Set("TARGET_ORGANIZATION", "target-org")
+	migration := BodyMapping{SourceHostname: "example.com", SourceOrganization: "source-org", TargetOrganization: "target-org"}
 
 	// Modify the release body
-	updatedReleaseBody, err := ModifyReleaseBody(&releaseBody, filePath)
+	updatedReleaseBody, err := ModifyReleaseBody(&releaseBody, filePath, migration)
 
 	if err != nil {
 		t.Errorf("ModifyReleaseBody returned an error: %v", err)
@@ -135,13 +133,10 @@ func TestModifyReleaseBodyWithNilBody(t *testing.T) {
 	}
 	writer.Flush()
 
-	// Set up viper configuration
-	viper.Set("SOURCE_HOSTNAME", "example.com")
-	viper.Set("SOURCE_ORGANIZATION", "source-org")
-	viper.Set("TARGET_ORGANIZATION", "target-org")
+	migration := BodyMapping{SourceHostname: "example.com", SourceOrganization: "source-org", TargetOrganization: "target-org"}
 
 	// Modify the release body
-	updatedReleaseBody, err := ModifyReleaseBody(releaseBody, filePath)
+	updatedReleaseBody, err := ModifyReleaseBody(releaseBody, filePath, migration)
 
 	if err != nil {
 		t.Errorf("ModifyReleaseBody returned an error: %v", err)
@@ -195,3 +190,281 @@ func TestAddSourceTimeStampsWithNilPublishedAt(t *testing.T) {
 		t.Errorf("Updated release body does not contain the expected published at timestamp")
 	}
 }
+
+func TestAddSourceReferencesLinksSourceReleaseOnce(t *testing.T) {
+	sourceURL := "https://github.com/source-org/repo/releases/tag/v1.0.0"
+	release := &github.RepositoryRelease{Body: github.String("Notes"), HTMLURL: github.String(sourceURL)}
+	updatedRelease, err := AddSourceReferences(release, false, true)
+	if err != nil {
+		t.Fatalf("AddSourceReferences returned an error: %v", err)
+	}
+	if !strings.Contains(updatedRelease.GetBody(), "> Migrated from "+sourceURL) {
+		t.Errorf("Expected the body to link to the source release, got %q", updatedRelease.GetBody())
+	}
+
+	// A body already linking to its source, e.g. a release migrated again, keeps a single link
+	updatedRelease, err = AddSourceReferences(updatedRelease, false, true)
+	if err != nil {
+		t.Fatalf("AddSourceReferences returned an error: %v", err)
+	}
+	if count := strings.Count(updatedRelease.GetBody(), sourceURL); count != 1 {
+		t.Errorf("Expected the source URL to be recorded once, got %d times in %q", count, updatedRelease.GetBody())
+	}
+}
+
+func TestModifyReleaseBodyMappingStats(t *testing.T) {
+	filePath := "test.csv"
+
+	// Create a test CSV file
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer file.Close()
+
+	// Write test data to the CSV file
+	data := [][]string{
+		{"@naruto", "@naruto.uzumaki"},
+		{"@sasuke", "@sasuke.uchiha"},
+		{"@kakashi", "@kakashi.hatake"},
+	}
+	writer := csv.NewWriter(file)
+	for _, record := range data {
+		err := writer.Write(record)
+		if err != nil {
+			t.Fatalf("Failed to write to test file: %v", err)
+		}
+	}
+	writer.Flush()
+
+	migration := BodyMapping{SourceOrganization: "source-org", TargetOrganization: "target-org"}
+	ResetMappingStats()
+
+	bodies := []string{"Thanks @naruto and @sasuke", "Fixed by @naruto"}
+	for _, body := range bodies {
+		_, err := ModifyReleaseBody(&body, filePath, migration)
+		if err != nil {
+			t.Errorf("ModifyReleaseBody returned an error: %v", err)
+		}
+	}
+
+	expected := map[string]int{"@naruto": 2, "@sasuke": 1, "@kakashi": 0}
+	rules := MappingStats()
+	if len(rules) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d", len(expected), len(rules))
+	}
+	for _, rule := range rules {
+		if rule.Hits != expected[rule.Source] {
+			t.Errorf("Expected %d hits for %s, got %d", expected[rule.Source], rule.Source, rule.Hits)
+		}
+	}
+
+	// Clean up the test file
+	err = os.Remove(filePath)
+	if err != nil {
+		t.Errorf("Failed to remove the test file: %v", err)
+	}
+}
+
+func TestValidateMappingFile(t *testing.T) {
+	filePath := "test.csv"
+
+	err := os.WriteFile(filePath, []byte("source,target\nnaruto,naruto.uzumaki\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := ValidateMappingFile(filePath); err != nil {
+		t.Errorf("ValidateMappingFile returned an error for a valid file: %v", err)
+	}
+
+	err = os.WriteFile(filePath, []byte("source,target\nnaruto\n"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := ValidateMappingFile(filePath); err == nil {
+		t.Errorf("ValidateMappingFile did not return an error for a row without target")
+	}
+
+	// Clean up the test file
+	err = os.Remove(filePath)
+	if err != nil {
+		t.Errorf("Failed to remove the test file: %v", err)
+	}
+
+	if err := ValidateMappingFile(filePath); err == nil {
+		t.Errorf("ValidateMappingFile did not return an error for a missing file")
+	}
+}
+
+func TestAddSourceReferencesRecordSourceIDs(t *testing.T) {
+	release := &github.RepositoryRelease{
+		ID:      github.Int64(123),
+		HTMLURL: github.String("https://github.com/source-org/repo/releases/tag/v1.0.0"),
+		Body:    github.String("Test release body"),
+	}
+	updatedRelease, err := AddSourceReferences(release, true, false)
+	if err != nil {
+		t.Fatalf("AddSourceReferences returned an error: %v", err)
+	}
+
+	// Adding the references again does not record the source release twice
+	updatedRelease, err = AddSourceReferences(updatedRelease, true, false)
+	if err != nil {
+		t.Fatalf("AddSourceReferences returned an error: %v", err)
+	}
+
+	if strings.Count(*updatedRelease.Body, "Original Release ID: 123") != 1 {
+		t.Errorf("Expected the source release ID once, got:\n%s", *updatedRelease.Body)
+	}
+	if strings.Count(*updatedRelease.Body, "https://github.com/source-org/repo/releases/tag/v1.0.0") != 1 {
+		t.Errorf("Expected the source release URL once, got:\n%s", *updatedRelease.Body)
+	}
+}
+
+func TestModifyReleaseBodyPreservesBytes(t *testing.T) {
+	filePath := t.TempDir() + "/mapping.csv"
+	content := "@naruto,@naruto.uzumaki\n@sasuke,@naruto\n@sakura,@sakura.haruno\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	migration := BodyMapping{SourceOrganization: "source-org", TargetOrganization: "target-org"}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "CRLF line endings",
+			body:     "## Changes\r\n\r\n- Fix by @naruto\r\n- Review by @sasuke\r\n",
+			expected: "## Changes\r\n\r\n- Fix by @naruto.uzumaki\r\n- Review by @naruto\r\n",
+		},
+		{
+			name:     "multibyte UTF-8",
+			body:     "リリース 🎉 by @sakura — café naïve\nsee https://github.com/source-org/repo ✓",
+			expected: "リリース 🎉 by @sakura.haruno — café naïve\nsee https://github.com/target-org/repo ✓",
+		},
+		{
+			name:     "no substitution",
+			body:     "\ttabs\r\nmixed\nendings\r\n nbsp and emoji 👩‍💻 ",
+			expected: "\ttabs\r\nmixed\nendings\r\n nbsp and emoji 👩‍💻 ",
+		},
+	}
+	for _, tt := range tests {
+		body := tt.body
+		updated, err := ModifyReleaseBody(&body, filePath, migration)
+		if err != nil {
+			t.Fatalf("%s: ModifyReleaseBody returned an error: %v", tt.name, err)
+		}
+		if *updated != tt.expected {
+			t.Errorf("%s: got %q, want %q", tt.name, *updated, tt.expected)
+		}
+	}
+
+	// An empty source organization leaves the body untouched
+	body := "Release by @someone"
+	updated, err := ModifyReleaseBody(&body, filePath, BodyMapping{TargetOrganization: "target-org"})
+	if err != nil || *updated != body {
+		t.Errorf("Expected the body to be kept without a source organization, got %q: %v", *updated, err)
+	}
+}
+
+func TestAddSourceTimeStampsKeepsCRLF(t *testing.T) {
+	release := &github.RepositoryRelease{Body: github.String("Line one\r\nLine two"), ID: github.Int64(1)}
+	release, err := AddSourceTimeStamps(release)
+	if err != nil {
+		t.Fatalf("AddSourceTimeStamps returned an error: %v", err)
+	}
+	if strings.Contains(strings.ReplaceAll(release.GetBody(), "\r\n", ""), "\n") {
+		t.Errorf("Expected only CRLF line endings, got %q", release.GetBody())
+	}
+}
+
+func TestModifyReleaseBodyBlankBodies(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mapping.csv")
+	if err := os.WriteFile(filePath, []byte("source,target\nnaruto,naruto.uzumaki\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	migration := BodyMapping{SourceOrganization: "source-org", TargetOrganization: "target-org"}
+
+	tests := []struct {
+		name string
+		body *string
+		want string
+	}{
+		{"nil", nil, ""},
+		{"empty", github.String(""), ""},
+		{"whitespace", github.String(" \r\n\t"), " \r\n\t"},
+	}
+	for _, tt := range tests {
+		updatedReleaseBody, err := ModifyReleaseBody(tt.body, filePath, migration)
+		if err != nil {
+			t.Errorf("%s: ModifyReleaseBody returned an error: %v", tt.name, err)
+			continue
+		}
+		if updatedReleaseBody == nil || *updatedReleaseBody != tt.want {
+			t.Errorf("%s: expected body %q, got %v", tt.name, tt.want, updatedReleaseBody)
+		}
+	}
+}
+
+func TestModifyReleaseBodyTargetHostname(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mapping.csv")
+	if err := os.WriteFile(filePath, []byte("source,target\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		sourceHostname string
+		targetHostname string
+		want           string
+	}{
+		{"GHES to GHEC", "ghes.example.com", "", "See https://github.com/target-org/repo and https://github.com/other/project"},
+		{"GHES to GHES", "ghes.example.com", "https://ghes2.example.com/", "See https://ghes2.example.com/target-org/repo and https://github.com/other/project"},
+		{"same GHES", "ghes.example.com", "ghes.example.com", "See https://ghes.example.com/target-org/repo and https://github.com/other/project"},
+		{"GHEC to GHES", "", "ghes2.example.com", "See https://ghes2.example.com/target-org/repo and https://github.com/other/project"},
+	}
+	for _, tt := range tests {
+		body := "See https://" + webHost(tt.sourceHostname) + "/source-org/repo and https://github.com/other/project"
+		migration := BodyMapping{SourceHostname: tt.sourceHostname, TargetHostname: tt.targetHostname, SourceOrganization: "source-org", TargetOrganization: "target-org"}
+		updatedReleaseBody, err := ModifyReleaseBody(&body, filePath, migration)
+		if err != nil {
+			t.Fatalf("%s: ModifyReleaseBody returned an error: %v", tt.name, err)
+		}
+		if *updatedReleaseBody != tt.want {
+			t.Errorf("%s: expected body %q, got %q", tt.name, tt.want, *updatedReleaseBody)
+		}
+	}
+}
+
+func TestAddSourceReferencesKeepsSourceURL(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mapping.csv")
+	if err := os.WriteFile(filePath, []byte("source,target\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	migration := BodyMapping{SourceOrganization: "source-org", TargetOrganization: "target-org"}
+
+	// The body is mapped after the timestamps and before the references, like by the sync
+	sourceURL := "https://github.com/source-org/repo/releases/tag/v1.0.0"
+	release := &github.RepositoryRelease{Body: github.String("See source-org/repo"), HTMLURL: github.String(sourceURL)}
+	release, err := AddSourceTimeStamps(release)
+	if err != nil {
+		t.Fatalf("AddSourceTimeStamps returned an error: %v", err)
+	}
+	release.Body, err = ModifyReleaseBody(release.Body, filePath, migration)
+	if err != nil {
+		t.Fatalf("ModifyReleaseBody returned an error: %v", err)
+	}
+	release, err = AddSourceReferences(release, false, true)
+	if err != nil {
+		t.Fatalf("AddSourceReferences returned an error: %v", err)
+	}
+
+	if !strings.HasPrefix(release.GetBody(), "See target-org/repo\n\n>Release Originally Created on: ") {
+		t.Errorf("Expected the mapped body followed by the timestamps, got %q", release.GetBody())
+	}
+	if !strings.HasSuffix(release.GetBody(), "> Migrated from "+sourceURL) {
+		t.Errorf("Expected the link to the unmapped source release, got %q", release.GetBody())
+	}
+}
@@ -0,0 +1,47 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// TagPrefixData is the data available to a TAG_PREFIX template
+type TagPrefixData struct {
+	Owner      string
+	Repository string
+}
+
+// ParseTagPrefix parses a TAG_PREFIX template such as "{{.Repository}}/"
+func ParseTagPrefix(prefix string) (*template.Template, error) {
+	tmpl, err := template.New("tag-prefix").Option("missingkey=error").Parse(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag prefix: %v", err)
+	}
+	return tmpl, nil
+}
+
+// ApplyTagPrefix prefixes the tag of each release with the rendered tag prefix, so releases of
+// several source repositories can be consolidated in one target without tag collisions. The
+// releases are then created, looked up and deleted in the target with the prefixed tag.
+func ApplyTagPrefix(releases []*github.RepositoryRelease, prefix string, owner string, repository string) error {
+	if prefix == "" {
+		return nil
+	}
+
+	tmpl, err := ParseTagPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, TagPrefixData{Owner: owner, Repository: repository}); err != nil {
+		return fmt.Errorf("failed to render tag prefix: %v", err)
+	}
+
+	for _, release := range releases {
+		release.TagName = github.String(rendered.String() + release.GetTagName())
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestApplyTagPrefix(t *testing.T) {
+	releases := []*github.RepositoryRelease{{TagName: github.String("v1.0")}, {TagName: github.String("v2.0")}}
+
+	if err := ApplyTagPrefix(releases, "{{.Owner}}-{{.Repository}}/", "org", "repo"); err != nil {
+		t.Fatalf("ApplyTagPrefix returned an error: %v", err)
+	}
+	if releases[0].GetTagName() != "org-repo/v1.0" || releases[1].GetTagName() != "org-repo/v2.0" {
+		t.Errorf("Unexpected prefixed tags: %s, %s", releases[0].GetTagName(), releases[1].GetTagName())
+	}
+
+	if err := ApplyTagPrefix(releases, "", "org", "repo"); err != nil || releases[0].GetTagName() != "org-repo/v1.0" {
+		t.Errorf("Expected tags to be unchanged without a prefix, got %s: %v", releases[0].GetTagName(), err)
+	}
+	if err := ApplyTagPrefix(releases, "{{.Branch}}/", "org", "repo"); err == nil {
+		t.Error("Expected an error for an unknown template field")
+	}
+}
@@ -0,0 +1,73 @@
+package mapping
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+const (
+	templateStartMarker = "<!-- gh-migrate-releases:body-template:start -->"
+	templateEndMarker   = "<!-- gh-migrate-releases:body-template:end -->"
+)
+
+// TemplateData is the data available to a body template
+type TemplateData struct {
+	// Release is the source release, with Body holding the body after mapping
+	Release *github.RepositoryRelease
+	// Body is the release body after mapping, to wrap or replace
+	Body               string
+	SourceOrganization string
+	TargetOrganization string
+	Repository         string
+	SourceURL          string
+	MigratedAt         time.Time
+}
+
+// ParseBodyTemplate reads and parses a body template file, once for all the releases of a run
+func ParseBodyTemplate(templatePath string) (*template.Template, error) {
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body template: %v", err)
+	}
+
+	tmpl, err := template.New("body").Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse body template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// ApplyBodyTemplate renders the body template with the release data and returns it as the new
+// release body. The rendered body is wrapped in markers so a body that was already templated is
+// returned unchanged.
+func ApplyBodyTemplate(releaseBody *string, tmpl *template.Template, data TemplateData) (*string, error) {
+	body := ""
+	if releaseBody != nil {
+		body = *releaseBody
+	}
+
+	if strings.Contains(body, templateStartMarker) {
+		return releaseBody, nil
+	}
+
+	data.Body = body
+	// A nil body would be rendered as "<nil>" by {{.Release.Body}}
+	if data.Release != nil && data.Release.Body == nil {
+		release := *data.Release
+		release.Body = &body
+		data.Release = &release
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return releaseBody, fmt.Errorf("failed to render body template: %v", err)
+	}
+
+	updatedReleaseBody := templateStartMarker + "\n" + strings.TrimSpace(rendered.String()) + "\n" + templateEndMarker
+	return &updatedReleaseBody, nil
+}
@@ -0,0 +1,90 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestApplyBodyTemplate(t *testing.T) {
+	body := "Fixed a bug"
+	data := TemplateData{
+		Release:            &github.RepositoryRelease{TagName: github.String("v1.0.0"), Body: &body},
+		SourceOrganization: "source-org",
+		TargetOrganization: "target-org",
+		Repository:         "repo",
+		SourceURL:          "https://github.com/source-org/repo/releases/tag/v1.0.0",
+		MigratedAt:         time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tmpl, err := ParseBodyTemplate("testdata/body.tmpl")
+	if err != nil {
+		t.Fatalf("ParseBodyTemplate returned an error: %v", err)
+	}
+	updatedReleaseBody, err := ApplyBodyTemplate(&body, tmpl, data)
+	if err != nil {
+		t.Fatalf("ApplyBodyTemplate returned an error: %v", err)
+	}
+
+	expected := templateStartMarker + "\n" +
+		"> Migrated from https://github.com/source-org/repo/releases/tag/v1.0.0 on 2024-05-01\n\n" +
+		"Fixed a bug\n\n" +
+		"Release v1.0.0 of target-org/repo\n" +
+		templateEndMarker
+	if *updatedReleaseBody != expected {
+		t.Errorf("Expected body:\n%s\ngot:\n%s", expected, *updatedReleaseBody)
+	}
+
+	// Applying the template again keeps the body unchanged
+	again, err := ApplyBodyTemplate(updatedReleaseBody, tmpl, data)
+	if err != nil {
+		t.Fatalf("ApplyBodyTemplate returned an error: %v", err)
+	}
+	if *again != *updatedReleaseBody {
+		t.Errorf("Expected template to be applied once, got:\n%s", *again)
+	}
+	if strings.Count(*again, templateStartMarker) != 1 {
+		t.Errorf("Expected a single template marker, got:\n%s", *again)
+	}
+}
+
+func TestParseBodyTemplateInvalid(t *testing.T) {
+	if _, err := ParseBodyTemplate("testdata/missing.tmpl"); err == nil {
+		t.Errorf("Expected an error for a missing template file")
+	}
+
+	templatePath := filepath.Join(t.TempDir(), "body.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{ .Body "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseBodyTemplate(templatePath); err == nil || !strings.Contains(err.Error(), "parse body template") {
+		t.Errorf("Expected an error for an invalid template, got %v", err)
+	}
+}
+
+func TestApplyBodyTemplateNilBody(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "body.tmpl")
+	if err := os.WriteFile(templatePath, []byte("[{{ .Body }}][{{ .Release.Body }}]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	release := &github.RepositoryRelease{TagName: github.String("v1.0.0")}
+
+	tmpl, err := ParseBodyTemplate(templatePath)
+	if err != nil {
+		t.Fatalf("ParseBodyTemplate returned an error: %v", err)
+	}
+	updatedReleaseBody, err := ApplyBodyTemplate(nil, tmpl, TemplateData{Release: release})
+	if err != nil {
+		t.Fatalf("ApplyBodyTemplate returned an error: %v", err)
+	}
+	if strings.Contains(*updatedReleaseBody, "<nil>") || !strings.Contains(*updatedReleaseBody, "[][]") {
+		t.Errorf("Expected a nil body to be rendered as empty, got:\n%s", *updatedReleaseBody)
+	}
+	if release.Body != nil {
+		t.Errorf("Expected the release of the template data not to be modified")
+	}
+}
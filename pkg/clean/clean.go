@@ -0,0 +1,116 @@
+package clean
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// lookupFunc returns the target release matching a source release by tag, and whether
+// its name and target_commitish also match
+type lookupFunc func(release *github.RepositoryRelease) (*github.RepositoryRelease, bool)
+
+// planDeletions splits the source releases into target releases that are safe to delete and
+// target releases that share a tag but were not created by a migration
+func planDeletions(releases []*github.RepositoryRelease, lookup lookupFunc) ([]*github.RepositoryRelease, []*github.RepositoryRelease) {
+	var toDelete, mismatched []*github.RepositoryRelease
+	for _, release := range releases {
+		existingRelease, matches := lookup(release)
+		if existingRelease == nil {
+			continue
+		}
+		if matches {
+			toDelete = append(toDelete, existingRelease)
+		} else {
+			mismatched = append(mismatched, existingRelease)
+		}
+	}
+	return toDelete, mismatched
+}
+
+func CleanReleases() error {
+	dryRun := viper.GetBool("DRY_RUN")
+	if !dryRun && !viper.GetBool("CONFIRM") {
+		return fmt.Errorf("refusing to delete releases without --confirm; use --dry-run to preview")
+	}
+
+	// Read the tokens from files or a secrets manager
+	if err := api.ResolveTokens(); err != nil {
+		return err
+	}
+
+	repository := viper.GetString("REPOSITORY")
+	owner := viper.GetString("SOURCE_ORGANIZATION")
+	// if repository includes owner, split it
+	if strings.Contains(repository, "/") {
+		repositoryParts := strings.Split(repository, "/")
+		owner = repositoryParts[0]
+		repository = repositoryParts[1]
+	}
+	targetOrg := viper.GetString("TARGET_ORGANIZATION")
+	tagPrefix := viper.GetString("TAG_PREFIX")
+	if _, err := mapping.ParseTagPrefix(tagPrefix); err != nil {
+		return err
+	}
+
+	fetchReleasesSpinner, _ := pterm.DefaultSpinner.Start("Fetching releases from repository: ", repository)
+	releases, err := api.GetSourceRepositoryReleases(owner, repository)
+	if err != nil {
+		fetchReleasesSpinner.Fail()
+		return err
+	}
+	fetchReleasesSpinner.UpdateText(fmt.Sprintf(" %d Releases fetched successfully!", len(releases)))
+	fetchReleasesSpinner.Success()
+
+	// The releases were migrated with the prefixed tag, which they are looked up and deleted with
+	if err := mapping.ApplyTagPrefix(releases, tagPrefix, owner, repository); err != nil {
+		return err
+	}
+
+	toDelete, mismatched := planDeletions(releases, func(release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+		return api.ReleaseExists(targetOrg, repository, release)
+	})
+
+	for _, release := range mismatched {
+		pterm.Warning.Printf("Release %s in target does not match the source name/commitish... skipping\n", release.GetTagName())
+	}
+
+	var failed int
+	for _, release := range toDelete {
+		if dryRun {
+			pterm.Info.Printf("[dry-run] Would delete release: %s\n", release.GetTagName())
+			continue
+		}
+
+		err := api.DeleteRelease(targetOrg, repository, release.GetID())
+		if err != nil {
+			pterm.Error.Printf("Error deleting release %s: %v\n", release.GetTagName(), err)
+			failed++
+			continue
+		}
+		pterm.Info.Printf("Deleted release: %s\n", release.GetTagName())
+
+		if viper.GetBool("DELETE_TAGS") {
+			err = api.DeleteTag(targetOrg, repository, release.GetTagName())
+			if err != nil {
+				pterm.Error.Printf("Error deleting tag %s: %v\n", release.GetTagName(), err)
+				failed++
+			}
+		}
+	}
+
+	pterm.Info.Printf("Releases matched: %d\n", len(toDelete))
+	pterm.Info.Printf("Skipped (mismatched): %d\n", len(mismatched))
+	pterm.Info.Printf("Failed: %d\n", failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d deletions failed", failed)
+	}
+
+	return nil
+}
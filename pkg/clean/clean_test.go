@@ -0,0 +1,56 @@
+package clean
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+)
+
+func TestPlanDeletions(t *testing.T) {
+	releases := []*github.RepositoryRelease{
+		{TagName: github.String("v1.0.0")},
+		{TagName: github.String("v1.1.0")},
+		{TagName: github.String("v1.2.0")},
+	}
+
+	target := map[string]struct {
+		release *github.RepositoryRelease
+		matches bool
+	}{
+		"v1.0.0": {&github.RepositoryRelease{ID: github.Int64(1), TagName: github.String("v1.0.0")}, true},
+		"v1.1.0": {&github.RepositoryRelease{ID: github.Int64(2), TagName: github.String("v1.1.0")}, false},
+	}
+
+	toDelete, mismatched := planDeletions(releases, func(release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+		existing := target[release.GetTagName()]
+		return existing.release, existing.matches
+	})
+
+	if len(toDelete) != 1 || toDelete[0].GetID() != 1 {
+		t.Errorf("Expected release 1 to be deleted, got %v", toDelete)
+	}
+	if len(mismatched) != 1 || mismatched[0].GetID() != 2 {
+		t.Errorf("Expected release 2 to be skipped as mismatched, got %v", mismatched)
+	}
+}
+
+func TestPlanDeletionsWithTagPrefix(t *testing.T) {
+	releases := []*github.RepositoryRelease{{TagName: github.String("v1.0.0")}}
+	if err := mapping.ApplyTagPrefix(releases, "{{.Owner}}/", "org-a", "tools"); err != nil {
+		t.Fatalf("ApplyTagPrefix returned an error: %v", err)
+	}
+
+	// The target holds the release with the prefixed tag only
+	target := map[string]*github.RepositoryRelease{
+		"org-a/v1.0.0": {ID: github.Int64(1), TagName: github.String("org-a/v1.0.0")},
+	}
+	toDelete, _ := planDeletions(releases, func(release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+		existing, ok := target[release.GetTagName()]
+		return existing, ok
+	})
+
+	if len(toDelete) != 1 || toDelete[0].GetTagName() != "org-a/v1.0.0" {
+		t.Errorf("Expected the prefixed release to be deleted, got %v", toDelete)
+	}
+}
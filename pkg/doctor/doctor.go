@@ -0,0 +1,112 @@
+package doctor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// check is a single diagnostic with the hint printed when it fails
+type check struct {
+	name string
+	hint string
+	run  func() error
+}
+
+// runChecks runs every check, prints a pass/fail checklist and returns the number of failures
+func runChecks(checks []check) int {
+	var failed int
+	for _, c := range checks {
+		err := c.run()
+		if err != nil {
+			failed++
+			pterm.Error.Printf("%s: %v\n", c.name, err)
+			pterm.Info.Printf("  Hint: %s\n", c.hint)
+			continue
+		}
+		pterm.Success.Println(c.name)
+	}
+	return failed
+}
+
+func buildChecks() []check {
+	repository := viper.GetString("REPOSITORY")
+	owner := viper.GetString("SOURCE_ORGANIZATION")
+	// if repository includes owner, split it
+	if strings.Contains(repository, "/") {
+		repositoryParts := strings.Split(repository, "/")
+		owner = repositoryParts[0]
+		repository = repositoryParts[1]
+	}
+	targetOrg := viper.GetString("TARGET_ORGANIZATION")
+
+	checks := []check{
+		{
+			name: "Source hostname resolves",
+			hint: "check --source-hostname or --source-api-url and your DNS or proxy settings",
+			run:  api.ResolveSourceHost,
+		},
+		{
+			name: "Target hostname resolves",
+			hint: "check --target-hostname or --target-api-url and your DNS or proxy settings",
+			run:  api.ResolveTargetHost,
+		},
+		{
+			name: fmt.Sprintf("Source token can read %s/%s", owner, repository),
+			hint: "check that the source token is valid, not expired, authorized for SSO and has the repo scope",
+			run: func() error {
+				_, err := api.GetSourceRepository(owner, repository)
+				return err
+			},
+		},
+		{
+			name: fmt.Sprintf("Target token can write %s/%s", targetOrg, repository),
+			hint: "create the target repository, or check that the target token is valid and has write access to it",
+			run: func() error {
+				repo, err := api.GetTargetRepository(targetOrg, repository)
+				if err != nil {
+					return err
+				}
+				permissions := repo.GetPermissions()
+				if !permissions["push"] && !permissions["admin"] {
+					return fmt.Errorf("token has no write permission")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Target uploads endpoint is reachable",
+			hint: "check --target-upload-url and that the uploads endpoint of the target is not blocked by a firewall or proxy",
+			run:  api.CheckUploadsEndpoint,
+		},
+	}
+
+	if viper.GetString("MAPPING_FILE") != "" {
+		checks = append(checks, check{
+			name: "Mapping file parses",
+			hint: "the mapping file must be a CSV with a source and a target handle on every line",
+			run:  func() error { return mapping.ValidateMappingFile(viper.GetString("MAPPING_FILE")) },
+		})
+	}
+
+	return checks
+}
+
+// RunDoctor checks that a migration can run with the current configuration
+func RunDoctor() error {
+	if err := api.ResolveTokens(); err != nil {
+		return err
+	}
+
+	failed := runChecks(buildChecks())
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+
+	pterm.Success.Println("All checks passed")
+	return nil
+}
@@ -0,0 +1,23 @@
+package doctor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunChecks(t *testing.T) {
+	var ran []string
+	checks := []check{
+		{name: "passes", run: func() error { ran = append(ran, "passes"); return nil }},
+		{name: "fails", hint: "fix it", run: func() error { ran = append(ran, "fails"); return errors.New("broken") }},
+		{name: "also passes", run: func() error { ran = append(ran, "also passes"); return nil }},
+	}
+
+	failed := runChecks(checks)
+	if failed != 1 {
+		t.Errorf("Expected 1 failed check, got %d", failed)
+	}
+	if len(ran) != 3 {
+		t.Errorf("Expected every check to run after a failure, got %v", ran)
+	}
+}
@@ -0,0 +1,114 @@
+package skeleton
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// handleCount is a source handle with the number of releases and commits it authored
+type handleCount struct {
+	Handle   string
+	Releases int
+	Commits  int
+}
+
+// countHandles deduplicates the authors of the releases and of the commits, most active first
+func countHandles(releases []*github.RepositoryRelease, commitAuthors map[string]int) []handleCount {
+	counts := map[string]*handleCount{}
+	count := func(handle string) *handleCount {
+		if counts[handle] == nil {
+			counts[handle] = &handleCount{Handle: handle}
+		}
+		return counts[handle]
+	}
+
+	for _, release := range releases {
+		if login := release.GetAuthor().GetLogin(); login != "" {
+			count(login).Releases++
+		}
+	}
+	for handle, commits := range commitAuthors {
+		count(handle).Commits += commits
+	}
+
+	handles := make([]handleCount, 0, len(counts))
+	for _, handle := range counts {
+		handles = append(handles, *handle)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		if total := handles[i].Releases + handles[i].Commits; total != handles[j].Releases+handles[j].Commits {
+			return total > handles[j].Releases+handles[j].Commits
+		}
+		return handles[i].Handle < handles[j].Handle
+	})
+	return handles
+}
+
+// writeSkeleton writes the handles as a mapping file whose target handles are left blank
+func writeSkeleton(w io.Writer, handles []handleCount) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"source", "target", "releases", "commits"}); err != nil {
+		return err
+	}
+	for _, handle := range handles {
+		// Handles are mapped as mentions, so other occurrences of the handle are left as is
+		if err := writer.Write([]string{"@" + handle.Handle, "", strconv.Itoa(handle.Releases), strconv.Itoa(handle.Commits)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CreateMappingSkeleton writes a mapping file listing the authors of the source releases, and the
+// commit authors with INCLUDE_COMMIT_AUTHORS, with a blank target handle to fill in
+func CreateMappingSkeleton() error {
+	// Only the source is read, the target token isn't needed
+	if err := api.ResolveToken("SOURCE_TOKEN"); err != nil {
+		return err
+	}
+
+	owner, repository := viper.GetString("SOURCE_ORGANIZATION"), viper.GetString("REPOSITORY")
+
+	fetchSpinner, _ := pterm.DefaultSpinner.Start("Fetching release authors from repository...")
+	releases, err := api.GetSourceRepositoryReleases(owner, repository)
+	if err != nil {
+		fetchSpinner.Fail()
+		return err
+	}
+
+	var commitAuthors map[string]int
+	if viper.GetBool("INCLUDE_COMMIT_AUTHORS") {
+		fetchSpinner.UpdateText("Fetching commit authors from repository...")
+		commitAuthors, err = api.GetSourceRepositoryCommitAuthors(owner, repository)
+		if err != nil {
+			fetchSpinner.Fail()
+			return err
+		}
+	}
+	fetchSpinner.Success()
+
+	handles := countHandles(releases, commitAuthors)
+
+	fileName := viper.GetString("OUTPUT_FILE")
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("error creating mapping skeleton: %v", err)
+	}
+	defer file.Close()
+
+	if err := writeSkeleton(file, handles); err != nil {
+		return fmt.Errorf("error writing mapping skeleton: %v", err)
+	}
+	pterm.Success.Printf("Wrote %d handles to %s, fill in their target handles\n", len(handles), fileName)
+	return nil
+}
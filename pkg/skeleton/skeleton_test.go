@@ -0,0 +1,38 @@
+package skeleton
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestMappingSkeleton(t *testing.T) {
+	release := func(login string) *github.RepositoryRelease {
+		return &github.RepositoryRelease{Author: &github.User{Login: github.String(login)}}
+	}
+	releases := []*github.RepositoryRelease{release("octocat"), release("hubot"), release("octocat"), {}}
+	commitAuthors := map[string]int{"hubot": 5, "monalisa": 1}
+
+	var out strings.Builder
+	if err := writeSkeleton(&out, countHandles(releases, commitAuthors)); err != nil {
+		t.Fatalf("writeSkeleton returned an error: %v", err)
+	}
+
+	want := "source,target,releases,commits\n" +
+		"@hubot,,1,5\n" +
+		"@octocat,,2,0\n" +
+		"@monalisa,,0,1\n"
+	if out.String() != want {
+		t.Errorf("Unexpected skeleton:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestMappingSkeletonReleaseAuthorsOnly(t *testing.T) {
+	releases := []*github.RepositoryRelease{{Author: &github.User{Login: github.String("octocat")}}}
+
+	handles := countHandles(releases, nil)
+	if len(handles) != 1 || handles[0].Handle != "octocat" || handles[0].Releases != 1 || handles[0].Commits != 0 {
+		t.Errorf("Unexpected handles: %+v", handles)
+	}
+}
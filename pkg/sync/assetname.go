@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// assetNameData is the data available to the ASSET_NAME_TEMPLATE template
+type assetNameData struct {
+	// Name is the name of the source asset, Base the name without its extension and Ext the
+	// extension, e.g. ".gz" for app.tar.gz
+	Name        string
+	Base        string
+	Ext         string
+	Label       string
+	ContentType string
+	// Tag is the tag of the target release, prefixed with TAG_PREFIX
+	Tag         string
+	ReleaseName string
+	Owner       string
+	Repository  string
+}
+
+// parseAssetNameTemplate parses an ASSET_NAME_TEMPLATE template such as "mytool-{{.Tag}}-{{.Name}}"
+func parseAssetNameTemplate(value string) (*template.Template, error) {
+	tmpl, err := template.New("asset-name").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asset name template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// renderAssetName renders the name of an asset in the target release
+func renderAssetName(tmpl *template.Template, owner string, repository string, release *github.RepositoryRelease, asset *github.ReleaseAsset) (string, error) {
+	ext := path.Ext(asset.GetName())
+	data := assetNameData{
+		Name:        asset.GetName(),
+		Base:        strings.TrimSuffix(asset.GetName(), ext),
+		Ext:         ext,
+		Label:       asset.GetLabel(),
+		ContentType: asset.GetContentType(),
+		Tag:         release.GetTagName(),
+		ReleaseName: release.GetName(),
+		Owner:       owner,
+		Repository:  repository,
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("failed to render asset name: %v", err)
+	}
+	name := strings.TrimSpace(rendered.String())
+	if name == "" {
+		return "", fmt.Errorf("asset name template renders an empty name for %s", asset.GetName())
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("asset name template renders %q for %s, which is not a file name", name, asset.GetName())
+	}
+	return name, nil
+}
+
+// targetAssetNames returns the name of each asset of a release in the target, rendered with the
+// template when there is one, and the number of assets whose name could not be rendered. Those
+// are left out of the names and not migrated.
+func targetAssetNames(tmpl *template.Template, owner string, repository string, release *github.RepositoryRelease) (map[*github.ReleaseAsset]string, int) {
+	names := make(map[*github.ReleaseAsset]string, len(release.Assets))
+	if tmpl == nil {
+		for _, asset := range release.Assets {
+			names[asset] = asset.GetName()
+		}
+		return names, 0
+	}
+
+	var failed int
+	renderedFrom := map[string]string{}
+	for _, asset := range release.Assets {
+		name, err := renderAssetName(tmpl, owner, repository, release, asset)
+		if err != nil {
+			pterm.Error.Printf("Error naming asset %s of release %s: %v", asset.GetName(), release.GetName(), err)
+			failed++
+			continue
+		}
+		// Two assets uploaded with the same name would collide in the target release
+		if other, ok := renderedFrom[name]; ok {
+			pterm.Error.Printf("Error naming asset %s of release %s: asset %s is already named %s", asset.GetName(), release.GetName(), other, name)
+			failed++
+			continue
+		}
+		renderedFrom[name] = asset.GetName()
+		names[asset] = name
+	}
+	return names, failed
+}
+
+// renamedAsset returns the asset as named in the target, a copy when the name differs
+func renamedAsset(asset *github.ReleaseAsset, name string) *github.ReleaseAsset {
+	if asset.GetName() == name {
+		return asset
+	}
+	renamed := *asset
+	renamed.Name = github.String(name)
+	return &renamed
+}
@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestRenderAssetName(t *testing.T) {
+	release := &github.RepositoryRelease{TagName: github.String("v1.2.0"), Name: github.String("Release 1.2")}
+	asset := &github.ReleaseAsset{Name: github.String("binary.tar.gz"), Label: github.String("Linux build")}
+
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"mytool-{{.Tag}}-linux-amd64", "mytool-v1.2.0-linux-amd64", false},
+		{"{{.Repository}}-{{.Base}}-{{.Tag}}{{.Ext}}", "repo-binary.tar-v1.2.0.gz", false},
+		{"{{.Owner}}_{{.Name}}", "source-org_binary.tar.gz", false},
+		{"{{.Label}}", "Linux build", false},
+		// A missing field is an error rather than an empty part of the name
+		{"{{.Version}}-{{.Name}}", "", true},
+		// The content type of the asset is empty here
+		{"{{.ContentType}}", "", true},
+		{"{{.Tag}}/{{.Name}}", "", true},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := parseAssetNameTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("parseAssetNameTemplate(%q) returned an error: %v", tt.template, err)
+		}
+		got, err := renderAssetName(tmpl, "source-org", "repo", release, asset)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("renderAssetName(%q) = %q, %v, want %q (error %v)", tt.template, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if _, err := parseAssetNameTemplate("{{.Tag"); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestTargetAssetNamesRejectsCollisions(t *testing.T) {
+	release := &github.RepositoryRelease{TagName: github.String("v1.0.0"), Assets: []*github.ReleaseAsset{
+		{Name: github.String("app-linux.zip")},
+		{Name: github.String("app-darwin.zip")},
+	}}
+	tmpl, _ := parseAssetNameTemplate("app-{{.Tag}}.zip")
+
+	names, failed := targetAssetNames(tmpl, "source-org", "repo", release)
+	if failed != 1 || len(names) != 1 || names[release.Assets[0]] != "app-v1.0.0.zip" {
+		t.Errorf("Expected the second asset with the same name to fail, got %v and %d failed", names, failed)
+	}
+}
+
+// downloadRecordingBackend records the names of the downloaded assets
+type downloadRecordingBackend struct {
+	*fake.Backend
+	downloaded []string
+}
+
+func (b *downloadRecordingBackend) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	b.downloaded = append(b.downloaded, asset.GetName())
+	return b.Backend.DownloadReleaseAssetsCached(asset, digest)
+}
+
+func TestMigrateReleaseAssetsWithNameTemplate(t *testing.T) {
+	backend := &downloadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 2, AssetSize: 10})}
+	testClient = backend
+	viper.Set("ASSET_NAME_TEMPLATE", "mytool-{{.Tag}}-{{.Name}}")
+
+	if result := newTestRun(t).migrateRepositoryReleases("repo", nil); result.Failed != 0 {
+		t.Fatalf("Expected the migration to succeed, got %+v", result)
+	}
+
+	var uploaded []string
+	for _, asset := range backend.TargetReleases("target-org", "repo")[0].Assets {
+		uploaded = append(uploaded, asset.GetName())
+	}
+	if want := []string{"mytool-v1.0.0-asset-1.zip", "mytool-v1.0.0-asset-2.zip"}; !slices.Equal(uploaded, want) {
+		t.Errorf("Expected the assets uploaded as %q, got %q", want, uploaded)
+	}
+	if want := []string{"asset-1.zip", "asset-2.zip"}; !slices.Equal(backend.downloaded, want) {
+		t.Errorf("Expected the downloads to keep the source names %q, got %q", want, backend.downloaded)
+	}
+
+	// A re-run finds the renamed assets in the target
+	backend.downloaded = nil
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if len(backend.downloaded) != 0 {
+		t.Errorf("Expected the renamed assets to be found in the target, got downloads %q", backend.downloaded)
+	}
+}
@@ -0,0 +1,59 @@
+package sync
+
+import (
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// outOfOrderAssets returns the target assets to delete so that uploading the missing source
+// assets one at a time gives the source order, GitHub listing assets in upload order. The target
+// assets matching the source in order are kept, the following ones matching a source asset are
+// returned. Target assets without a source asset are ignored.
+func outOfOrderAssets(sourceAssets []*github.ReleaseAsset, targetAssets []*github.ReleaseAsset) []*github.ReleaseAsset {
+	names := map[string]bool{}
+	for _, asset := range sourceAssets {
+		names[asset.GetName()] = true
+	}
+
+	var matching []*github.ReleaseAsset
+	for _, asset := range targetAssets {
+		if names[asset.GetName()] {
+			matching = append(matching, asset)
+		}
+	}
+
+	inOrder := 0
+	for inOrder < len(matching) && inOrder < len(sourceAssets) {
+		source, target := sourceAssets[inOrder], matching[inOrder]
+		if target.GetName() != source.GetName() || target.GetSize() != source.GetSize() {
+			break
+		}
+		inOrder++
+	}
+	return matching[inOrder:]
+}
+
+// reorderTargetAssets deletes the target assets out of the source order, so they are uploaded
+// again after the missing ones. It returns the number of assets that could not be deleted.
+func (r *run) reorderTargetAssets(targetOrg string, repository string, release *github.RepositoryRelease, newRelease *github.RepositoryRelease) int {
+	// Oversize assets are never uploaded as is and don't take a place in the target
+	maxSize := r.settings.maxAssetSize
+	var sourceAssets []*github.ReleaseAsset
+	for _, asset := range release.Assets {
+		if !isOversizeAsset(asset, maxSize) {
+			sourceAssets = append(sourceAssets, asset)
+		}
+	}
+
+	failed := 0
+	for _, asset := range outOfOrderAssets(sourceAssets, newRelease.Assets) {
+		pterm.Info.Printf("Re-uploading asset %s of release %s to preserve the source asset order", asset.GetName(), release.GetName())
+		if err := r.client.DeleteReleaseAsset(targetOrg, repository, asset.GetID()); err != nil {
+			pterm.Error.Printf("Error deleting out of order asset: %v", err)
+			failed++
+			continue
+		}
+		newRelease.Assets = removeAsset(newRelease.Assets, asset.GetID())
+	}
+	return failed
+}
@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestOutOfOrderAssets(t *testing.T) {
+	asset := func(name string) *github.ReleaseAsset {
+		return &github.ReleaseAsset{Name: github.String(name), Size: github.Int(10)}
+	}
+	source := []*github.ReleaseAsset{asset("installer.exe"), asset("app.zip"), asset("checksums.txt")}
+
+	tests := []struct {
+		target []*github.ReleaseAsset
+		want   string
+	}{
+		{nil, ""},
+		{[]*github.ReleaseAsset{asset("installer.exe"), asset("app.zip")}, ""},
+		{[]*github.ReleaseAsset{asset("app.zip"), asset("checksums.txt")}, "app.zip,checksums.txt"},
+		{[]*github.ReleaseAsset{asset("installer.exe"), asset("checksums.txt")}, "checksums.txt"},
+		{[]*github.ReleaseAsset{asset("notes.md"), asset("installer.exe")}, ""},
+	}
+
+	for _, tt := range tests {
+		var names []string
+		for _, asset := range outOfOrderAssets(source, tt.target) {
+			names = append(names, asset.GetName())
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("outOfOrderAssets() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// uploadRecordingBackend records the asset uploads in order and fails the uploads of an asset
+type uploadRecordingBackend struct {
+	*fake.Backend
+	failing string
+	uploads []string
+}
+
+func (b *uploadRecordingBackend) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	if asset.GetName() == b.failing {
+		return fmt.Errorf("upload failed")
+	}
+	b.uploads = append(b.uploads, asset.GetName())
+	return b.Backend.UploadAssetViaURL(uploadURL, asset)
+}
+
+func TestMigrateRepositoryReleasesPreserveAssetOrder(t *testing.T) {
+	backend := &uploadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 3, AssetSize: 10}), failing: "asset-1.zip"}
+	testClient = backend
+
+	// The first asset fails to upload, the others are uploaded after it
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	backend.failing = ""
+	backend.uploads = nil
+	viper.Set("PRESERVE_ASSET_ORDER", true)
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	if got := strings.Join(backend.uploads, ","); got != "asset-1.zip,asset-2.zip,asset-3.zip" {
+		t.Errorf("Expected the assets to be uploaded one at a time in the source order, got %s", got)
+	}
+	var names []string
+	for _, asset := range backend.TargetReleases("target-org", "repo")[0].Assets {
+		names = append(names, asset.GetName())
+	}
+	if got := strings.Join(names, ","); got != "asset-1.zip,asset-2.zip,asset-3.zip" {
+		t.Errorf("Expected the target assets in the source order, got %s", got)
+	}
+}
@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+	"github.com/pterm/pterm"
+)
+
+// failedAsset is an asset whose download or upload failed, as written to FAILED_ASSETS_FILE and
+// read back from RETRY_MANIFEST. The tag is the tag of the target release, with the tag prefix,
+// and the source tag the tag of the source release with the tag prefix, when the release
+// transform renamed it.
+type failedAsset struct {
+	Repository         string `json:"repository"`
+	TargetOrganization string `json:"target_organization"`
+	Tag                string `json:"tag"`
+	SourceTag          string `json:"source_tag,omitempty"`
+	Asset              string `json:"asset"`
+}
+
+func (r *run) recordFailedAsset(repository string, targetOrg string, tag string, sourceTag string, assetName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	asset := failedAsset{Repository: repository, TargetOrganization: targetOrg, Tag: tag, Asset: assetName}
+	if sourceTag != tag {
+		asset.SourceTag = sourceTag
+	}
+	r.failedAssets = append(r.failedAssets, asset)
+}
+
+// recordedFailedAssets returns the failed assets recorded during the run
+func (r *run) recordedFailedAssets() []failedAsset {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]failedAsset(nil), r.failedAssets...)
+}
+
+// writeFailedAssetsFile writes the assets that failed to transfer, to be passed as RETRY_MANIFEST
+// in the next run. No file is written when every asset was transferred.
+func writeFailedAssetsFile(fileName string, assets []failedAsset) error {
+	if len(assets) == 0 {
+		return nil
+	}
+	if err := files.CreateJSON(assets, fileName); err != nil {
+		return fmt.Errorf("error writing failed assets file: %v", err)
+	}
+	return nil
+}
+
+// loadRetryManifest reads the failed assets of a prior run
+func loadRetryManifest(fileName string) ([]failedAsset, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading retry manifest: %v", err)
+	}
+	var assets []failedAsset
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, fmt.Errorf("invalid retry manifest %s: %v", fileName, err)
+	}
+	for i, asset := range assets {
+		if asset.Repository == "" || asset.Tag == "" || asset.Asset == "" {
+			return nil, fmt.Errorf("invalid retry manifest %s: entry %d needs a repository, a tag and an asset", fileName, i+1)
+		}
+	}
+	return assets, nil
+}
+
+// retryRepositories returns the repositories of the failed assets, in the order of the manifest
+func retryRepositories(assets []failedAsset) []string {
+	var repositories []string
+	seen := map[string]bool{}
+	for _, asset := range assets {
+		if !seen[asset.Repository] {
+			seen[asset.Repository] = true
+			repositories = append(repositories, asset.Repository)
+		}
+	}
+	return repositories
+}
+
+// retryRepositoryAssets transfers again the failed assets of a repository listed in the retry
+// manifest. Only the listed assets are downloaded, and only when they are still missing from the
+// target release with the same tag. Each release with a listed asset counts as a release of the
+// result, failed when one of its assets still fails.
+func (r *run) retryRepositoryAssets(repositoryEntry string, assets []failedAsset) RepositoryResult {
+	owner, repository := r.settings.splitRepository(repositoryEntry)
+	result := RepositoryResult{Repository: repositoryEntry}
+
+	fetched := r.fetchRepositoryReleases(owner, repository)
+	if fetched.err != nil {
+		result.Err = fmt.Errorf("%w: %v", errFetchReleases, fetched.err)
+		return result
+	}
+	// The manifest holds the target tags, prefixed like in the prior run
+	if err := mapping.ApplyTagPrefix(fetched.releases, r.settings.tagPrefix, owner, repository); err != nil {
+		result.Err = err
+		return result
+	}
+	releases := map[string]*github.RepositoryRelease{}
+	for _, release := range fetched.releases {
+		releases[release.GetTagName()] = release
+	}
+
+	// Group the assets by target release, keeping the order of the manifest
+	type retryRelease struct {
+		targetOrg string
+		tag       string
+		sourceTag string
+		assets    map[string]bool
+	}
+	var retries []*retryRelease
+	byRelease := map[[2]string]*retryRelease{}
+	for _, asset := range assets {
+		targetOrg := asset.TargetOrganization
+		if targetOrg == "" {
+			targetOrg = r.settings.targetOrg(repositoryEntry)
+		}
+		key := [2]string{targetOrg, asset.Tag}
+		retry, ok := byRelease[key]
+		if !ok {
+			retry = &retryRelease{targetOrg: targetOrg, tag: asset.Tag, sourceTag: asset.Tag, assets: map[string]bool{}}
+			if asset.SourceTag != "" {
+				retry.sourceTag = asset.SourceTag
+			}
+			byRelease[key] = retry
+			retries = append(retries, retry)
+		}
+		retry.assets[asset.Asset] = true
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Retrying failed assets of repository...", repository)
+	for _, retry := range retries {
+		result.Releases++
+
+		release, ok := releases[retry.sourceTag]
+		if !ok {
+			pterm.Error.Printf("Source release %s of repository %s no longer exists, its assets can't be retried", retry.sourceTag, repositoryEntry)
+			result.Failed++
+			continue
+		}
+
+		newRelease, err := r.client.GetReleaseByTag(retry.targetOrg, repository, retry.tag)
+		if err != nil {
+			pterm.Error.Printf("Could not retrieve target release %s: %v", retry.tag, err)
+			for name := range retry.assets {
+				r.recordFailedAsset(owner+"/"+repository, retry.targetOrg, retry.tag, retry.sourceTag, name)
+			}
+			result.Failed++
+			continue
+		}
+
+		// Narrow the source release to the listed assets, under the tag of the target release
+		narrowed := *release
+		narrowed.TagName = github.String(retry.tag)
+		narrowed.Assets = nil
+		for _, asset := range release.Assets {
+			if retry.assets[asset.GetName()] {
+				narrowed.Assets = append(narrowed.Assets, asset)
+			}
+		}
+		missing := len(retry.assets) - len(narrowed.Assets)
+		if missing > 0 {
+			pterm.Error.Printf("%d assets of release %s no longer exist in the source and can't be retried", missing, release.GetName())
+		}
+
+		if missing+r.migrateReleaseAssets(owner, repository, retry.targetOrg, retry.sourceTag, &narrowed, newRelease, spinner) > 0 {
+			result.Failed++
+		}
+	}
+
+	if result.Failed > 0 {
+		spinner.UpdateText("Some assets failed to migrate")
+		spinner.Fail()
+		result.Err = fmt.Errorf("some assets failed to migrate")
+	} else {
+		spinner.UpdateText("All failed assets migrated successfully!")
+		spinner.Success()
+	}
+	return result
+}
+
+// retryFailedAssets returns the function retrying the failed assets of each repository of the
+// retry manifest
+func (r *run) retryFailedAssets(assets []failedAsset) migrateFunc {
+	byRepository := map[string][]failedAsset{}
+	for _, asset := range assets {
+		byRepository[asset.Repository] = append(byRepository[asset.Repository], asset)
+	}
+	return func(repository string, _ *repositoryReleases) RepositoryResult {
+		return r.retryRepositoryAssets(repository, byRepository[repository])
+	}
+}
@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+)
+
+func TestMigratorRetryManifest(t *testing.T) {
+	backend := &uploadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 3, AssetSize: 10}), failing: "asset-2.zip"}
+	testClient = backend
+
+	// The prior run fails to upload one asset of each release
+	r := newTestRun(t)
+	result := r.migrateRepositoryReleases("repo", nil)
+	if result.Failed != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	manifest := filepath.Join(t.TempDir(), "failed-assets.json")
+	if err := writeFailedAssetsFile(manifest, r.recordedFailedAssets()); err != nil {
+		t.Fatalf("writeFailedAssetsFile returned an error: %v", err)
+	}
+
+	// The releases are migrated newest first, a small manifest keeps the asset of the oldest
+	assets, err := loadRetryManifest(manifest)
+	if err != nil {
+		t.Fatalf("loadRetryManifest returned an error: %v", err)
+	}
+	if len(assets) != 2 || assets[1] != (failedAsset{Repository: "source-org/repo", TargetOrganization: "target-org", Tag: "v1.0.0", Asset: "asset-2.zip"}) {
+		t.Fatalf("Unexpected failed assets: %+v", assets)
+	}
+	if err := os.WriteFile(manifest, []byte(`[{"repository":"source-org/repo","target_organization":"target-org","tag":"v1.0.0","asset":"asset-2.zip"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend.failing = ""
+	backend.uploads = nil
+	migrator := NewMigrator(WithClient(backend), WithRetryManifest(manifest), WithEventHandler(&recordingEventHandler{}))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if summary.Releases != 1 || summary.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if got := strings.Join(backend.uploads, ","); got != "asset-2.zip" {
+		t.Errorf("Expected only the failed asset to be uploaded again, got %q", got)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		want := 2
+		if release.GetTagName() == "v1.0.0" {
+			want = 3
+		}
+		if got := len(release.Assets); got != want {
+			t.Errorf("Expected %d assets in release %s, got %d", want, release.GetTagName(), got)
+		}
+	}
+	if got := summary.run.recordedFailedAssets(); len(got) != 0 {
+		t.Errorf("Expected no asset to fail again, got %+v", got)
+	}
+
+	// The retried asset is now present and isn't uploaded again
+	backend.uploads = nil
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if len(backend.uploads) != 0 {
+		t.Errorf("Expected no upload for an asset already present, got %v", backend.uploads)
+	}
+}
+
+func TestLoadRetryManifestInvalidEntry(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "failed-assets.json")
+	if err := os.WriteFile(manifest, []byte(`[{"repository":"source-org/repo","tag":"v1.0.0"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRetryManifest(manifest); err == nil {
+		t.Error("Expected an error for an entry without an asset")
+	}
+}
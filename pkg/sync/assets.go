@@ -0,0 +1,322 @@
+package sync
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	gosync "sync"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// migrateReleaseAssets downloads the assets of a source release and uploads them to the target
+// release, skipping the ones already present. The release has the tag of the target release and
+// sourceTag is its tag before the release transform. It returns the number of assets that failed.
+func (r *run) migrateReleaseAssets(owner string, repository string, targetOrg string, sourceTag string, release *github.RepositoryRelease, newRelease *github.RepositoryRelease, spinner *pterm.SpinnerPrinter) int {
+	s := r.settings
+	var failed int
+
+	// The checksums file of the source is replaced by one generated from the target assets
+	checksumsFile := s.checksumsFile
+
+	// Get the asset digests used as keys of the asset cache, and as the checksums of the uploaded
+	// assets with sha256 checksums
+	cached := s.assetCache || s.dedupeAssets
+	var sourceDigests map[int64]string
+	if (cached || (checksumsFile != "" && s.checksumsAlgorithm == "sha256")) && len(release.Assets) > 0 {
+		var err error
+		sourceDigests, err = r.client.GetReleaseAssetDigests(owner, repository, release.GetID())
+		if err != nil && cached {
+			pterm.Warning.Printf("Could not get asset digests, the asset cache will not be used: %v", err)
+		}
+	}
+	var digests map[int64]string
+	if cached {
+		digests = sourceDigests
+	}
+
+	// The checksums file is only generated again when the run changed the assets of the release
+	changed := false
+
+	// The assets are compared with the target and uploaded with their name in the target
+	names, failedNames := targetAssetNames(s.assetNameTemplate, owner, repository, release)
+	failed += failedNames
+
+	// Assets uploaded after a missing one are uploaded again behind it
+	if s.preserveAssetOrder {
+		renamed := *release
+		renamed.Assets = nil
+		for _, asset := range release.Assets {
+			if name, ok := names[asset]; ok {
+				renamed.Assets = append(renamed.Assets, renamedAsset(asset, name))
+			}
+		}
+		failed += r.reorderTargetAssets(targetOrg, repository, &renamed, newRelease)
+	}
+
+	// The assets to download and upload, once checked against the target release
+	var transfers []assetTransfer
+	for _, asset := range release.Assets {
+		// An asset without a name can't be written to the tmp directory or uploaded
+		if strings.TrimSpace(asset.GetName()) == "" {
+			pterm.Warning.Printf("Skipping asset %d of release %s: it has no name", asset.GetID(), release.GetName())
+			failed++
+			continue
+		}
+
+		if checksumsFile != "" && asset.GetName() == checksumsFile {
+			continue
+		}
+
+		// Not migrated when its name could not be rendered, already counted as failed
+		name, ok := names[asset]
+		if !ok {
+			continue
+		}
+		target := renamedAsset(asset, name)
+
+		// The target may only permit some artifact types, checked before downloading the asset
+		if contentType := api.AssetContentType(asset); !isAllowedContentType(contentType, s.allowedContentTypes) {
+			r.recordRejectedAsset(owner+"/"+repository, release, asset)
+			pterm.Warning.Printf("Skipping asset %s of release %s: its content type %s is not allowed", asset.GetName(), release.GetName(), contentTypeOrUnknown(contentType))
+			continue
+		}
+
+		// An asset with a different label is only detected when labels are compared
+		if s.matchAssetLabels {
+			if mislabeled := api.MislabeledAsset(newRelease, target); mislabeled != nil {
+				if !s.replaceBrokenAssets {
+					pterm.Warning.Printf("Asset %s exists in release %s with label %q instead of %q; use --replace-broken-assets to replace it", name, release.GetName(), mislabeled.GetLabel(), asset.GetLabel())
+					continue
+				}
+				pterm.Info.Printf("Replacing asset %s with a different label in release %s", name, release.GetName())
+				err := r.client.DeleteReleaseAsset(targetOrg, repository, mislabeled.GetID())
+				if err != nil {
+					pterm.Error.Printf("Error deleting mislabeled asset: %v", err)
+					failed++
+					continue
+				}
+				newRelease.Assets = removeAsset(newRelease.Assets, mislabeled.GetID())
+				changed = true
+			}
+		}
+
+		// An upload interrupted by a crash leaves an asset that blocks the new upload
+		if stuck := api.StuckAsset(newRelease, name); stuck != nil {
+			pterm.Warning.Printf("Asset %s of release %s was left in state %s by an interrupted upload, deleting it to upload it again", name, release.GetName(), stuck.GetState())
+			if err := r.client.DeleteReleaseAsset(targetOrg, repository, stuck.GetID()); err != nil {
+				pterm.Error.Printf("Error deleting interrupted asset: %v", err)
+				failed++
+				continue
+			}
+			newRelease.Assets = removeAsset(newRelease.Assets, stuck.GetID())
+			changed = true
+		}
+
+		// Check if the asset already exists in the target release
+		if api.AssetExists(newRelease, name, int64(asset.GetSize())) {
+			spinner.UpdateText(fmt.Sprintf("Asset %s already exists, skipping...", name))
+			pterm.Info.Printf("Asset %s already exists in release %s, skipping", name, release.GetName())
+			continue
+		}
+
+		// A previous failed upload may have left an empty asset with the same name
+		if brokenAsset := api.BrokenAsset(newRelease, name); brokenAsset != nil {
+			if !s.replaceBrokenAssets {
+				pterm.Warning.Printf("Asset %s exists in release %s but is empty; use --replace-broken-assets to replace it", name, release.GetName())
+				failed++
+				continue
+			}
+			pterm.Info.Printf("Replacing broken asset %s in release %s", name, release.GetName())
+			err := r.client.DeleteReleaseAsset(targetOrg, repository, brokenAsset.GetID())
+			if err != nil {
+				pterm.Error.Printf("Error deleting broken asset: %v", err)
+				failed++
+				continue
+			}
+			newRelease.Assets = removeAsset(newRelease.Assets, brokenAsset.GetID())
+			changed = true
+		}
+
+		// GitHub rejects assets of the maximum asset size or larger
+		if isOversizeAsset(asset, s.maxAssetSize) {
+			if !r.migrateOversizeAsset(owner+"/"+repository, release, newRelease, asset, digests[asset.GetID()], s.oversizeAssetStrategy, s.maxAssetSize) {
+				failed++
+			} else if s.oversizeAssetStrategy == oversizeAssetSplit {
+				changed = true
+			}
+			continue
+		}
+
+		transfers = append(transfers, assetTransfer{asset: asset, name: name})
+	}
+
+	// Download and upload errors are recorded so the assets can be retried with RETRY_MANIFEST
+	failedTransfers := r.transferAssets(newRelease, transfers, digests, spinner)
+	if len(failedTransfers) > 0 {
+		spinner.UpdateText(fmt.Sprintf("%d assets of release %s failed to transfer", len(failedTransfers), release.GetName()))
+	}
+	for _, transfer := range failedTransfers {
+		r.recordFailedAsset(owner+"/"+repository, targetOrg, release.GetTagName(), sourceTag, transfer.asset.GetName())
+	}
+	failed += len(failedTransfers)
+
+	// The digests of the source assets are the sha256 checksums of their uploaded copies
+	uploaded := map[string]string{}
+	for _, transfer := range transfers {
+		if !slices.Contains(failedTransfers, transfer) {
+			changed = true
+			if digest := sourceDigests[transfer.asset.GetID()]; digest != "" && s.checksumsAlgorithm == "sha256" {
+				uploaded[transfer.name] = digest
+			}
+		}
+	}
+
+	if checksumsFile != "" && len(release.Assets) > 0 && (changed || !hasAsset(newRelease, checksumsFile)) {
+		if err := r.regenerateChecksums(targetOrg, repository, newRelease, checksumsFile, s.checksumsAlgorithm, uploaded); err != nil {
+			pterm.Error.Printf("Error generating checksums file %s of release %s: %v", checksumsFile, release.GetName(), err)
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// assetTransfer is a source asset to download and the name to upload it with
+type assetTransfer struct {
+	asset *github.ReleaseAsset
+	name  string
+}
+
+// transferAssets downloads the assets with DOWNLOAD_CONCURRENCY workers feeding UPLOAD_CONCURRENCY
+// upload workers, both set by ASSET_CONCURRENCY or tuned to the rate limit headroom with
+// AUTO_CONCURRENCY, and returns the assets that failed. Downloaded assets are handed to the
+// uploads in the source order, so a single upload worker, the only one with
+// PRESERVE_ASSET_ORDER, keeps the order of the assets.
+func (r *run) transferAssets(newRelease *github.RepositoryRelease, assets []assetTransfer, digests map[int64]string, spinner *pterm.SpinnerPrinter) []assetTransfer {
+	s := r.settings
+	downloadConcurrency := max(s.downloadConcurrency, 1)
+	uploadConcurrency := max(s.uploadConcurrency, 1)
+	if fixed := s.assetConcurrency; fixed > 0 {
+		downloadConcurrency, uploadConcurrency = fixed, fixed
+	}
+	if s.autoConcurrency {
+		downloadConcurrency = r.assetConcurrency.next()
+		uploadConcurrency = downloadConcurrency
+	}
+	if s.preserveAssetOrder {
+		uploadConcurrency = 1
+	}
+	maxRetries := s.maxRetries
+	var failed struct {
+		mu        gosync.Mutex
+		transfers []assetTransfer
+	}
+	fail := func(transfer assetTransfer) {
+		failed.mu.Lock()
+		defer failed.mu.Unlock()
+		failed.transfers = append(failed.transfers, transfer)
+	}
+	// The workers share the spinner of the release, updated by one worker at a time
+	var progressMu gosync.Mutex
+	progress := func(text string) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		spinner.UpdateText(text)
+	}
+
+	// Download stage, each download result being sent on the channel of its asset
+	downloads := make(chan int)
+	downloaded := make([]chan error, len(assets))
+	for i := range downloaded {
+		downloaded[i] = make(chan error, 1)
+	}
+	go func() {
+		for i := range assets {
+			downloads <- i
+		}
+		close(downloads)
+	}()
+	for range downloadConcurrency {
+		go func() {
+			for i := range downloads {
+				asset := assets[i].asset
+				progress("Downloading asset..." + asset.GetName())
+				stopDownload := r.timings.track(stageDownloading)
+				downloaded[i] <- api.Retry(maxRetries, func() error {
+					return r.client.DownloadReleaseAssetsCached(asset, digests[asset.GetID()])
+				})
+				stopDownload()
+			}
+		}()
+	}
+
+	// Feed the upload stage in the source order
+	uploads := make(chan assetTransfer)
+	go func() {
+		for i, transfer := range assets {
+			if err := <-downloaded[i]; err != nil {
+				pterm.Error.Printf("Error downloading assets: %v", err)
+				fail(transfer)
+				continue
+			}
+			uploads <- transfer
+		}
+		close(uploads)
+	}()
+
+	var uploaders gosync.WaitGroup
+	for range uploadConcurrency {
+		uploaders.Add(1)
+		go func() {
+			defer uploaders.Done()
+			for transfer := range uploads {
+				progress("Uploading assets..." + transfer.name)
+				stopUpload := r.timings.track(stageUploading)
+				err := api.Retry(maxRetries, func() error {
+					if transfer.name != transfer.asset.GetName() {
+						return r.client.UploadAssetAs(newRelease.GetUploadURL(), transfer.asset, transfer.name)
+					}
+					return r.client.UploadAssetViaURL(newRelease.GetUploadURL(), transfer.asset)
+				})
+				stopUpload()
+				if err != nil {
+					pterm.Error.Printf("Error uploading assets: %v", err)
+					fail(transfer)
+				}
+			}
+		}()
+	}
+	uploaders.Wait()
+
+	return failed.transfers
+}
+
+// releaseFailedByAssets reports whether a release counts as failed because some of its assets
+// failed. By default asset errors are only logged and the release still succeeds.
+func releaseFailedByAssets(failedAssets int, failOnAssetError bool) bool {
+	return failOnAssetError && failedAssets > 0
+}
+
+// removeAsset returns the assets without the asset with the given ID
+func removeAsset(assets []*github.ReleaseAsset, assetID int64) []*github.ReleaseAsset {
+	var kept []*github.ReleaseAsset
+	for _, asset := range assets {
+		if asset.GetID() != assetID {
+			kept = append(kept, asset)
+		}
+	}
+	return kept
+}
+
+// hasAsset reports whether the release has an asset with the given name
+func hasAsset(release *github.RepositoryRelease, name string) bool {
+	for _, asset := range release.Assets {
+		if asset.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
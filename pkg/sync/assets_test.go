@@ -0,0 +1,244 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+func TestReleaseFailedByAssets(t *testing.T) {
+	tests := []struct {
+		failedAssets     int
+		failOnAssetError bool
+		want             bool
+	}{
+		{0, false, false},
+		{2, false, false},
+		{0, true, false},
+		{2, true, true},
+	}
+
+	for _, tt := range tests {
+		if got := releaseFailedByAssets(tt.failedAssets, tt.failOnAssetError); got != tt.want {
+			t.Errorf("releaseFailedByAssets(%d, %v) = %v, want %v", tt.failedAssets, tt.failOnAssetError, got, tt.want)
+		}
+	}
+}
+
+// inFlight tracks the number of concurrent calls and the maximum reached
+type inFlight struct {
+	mu      gosync.Mutex
+	current int
+	max     int
+}
+
+func (f *inFlight) track() func() {
+	f.mu.Lock()
+	f.current++
+	f.max = max(f.max, f.current)
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.current--
+	}
+}
+
+// concurrencyTrackingBackend records the concurrent asset downloads and uploads
+type concurrencyTrackingBackend struct {
+	*fake.Backend
+	downloads inFlight
+	uploads   inFlight
+}
+
+func (b *concurrencyTrackingBackend) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	defer b.downloads.track()()
+	return b.Backend.DownloadReleaseAssetsCached(asset, digest)
+}
+
+func (b *concurrencyTrackingBackend) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	defer b.uploads.track()()
+	return b.Backend.UploadAssetViaURL(uploadURL, asset)
+}
+
+func TestTransferAssetsConcurrency(t *testing.T) {
+	backend := &concurrencyTrackingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 8, AssetSize: 10})}
+	testClient = backend
+	viper.Set("DOWNLOAD_CONCURRENCY", 4)
+	viper.Set("UPLOAD_CONCURRENCY", 2)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected the migration to succeed, got %d failed: %v", result.Failed, result.Err)
+	}
+
+	if backend.downloads.max > 4 || backend.downloads.max < 2 {
+		t.Errorf("Expected up to 4 concurrent downloads, got %d", backend.downloads.max)
+	}
+	if backend.uploads.max != 2 {
+		t.Errorf("Expected 2 concurrent uploads, got %d", backend.uploads.max)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")[0].Assets); got != 8 {
+		t.Errorf("Expected 8 assets uploaded, got %d", got)
+	}
+}
+
+func TestTransferAssetsAssetConcurrency(t *testing.T) {
+	backend := &concurrencyTrackingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 6, AssetSize: 10})}
+	testClient = backend
+	viper.Set("DOWNLOAD_CONCURRENCY", 1)
+	viper.Set("UPLOAD_CONCURRENCY", 1)
+	viper.Set("ASSET_CONCURRENCY", 3)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 || result.Releases != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	if backend.uploads.max != 3 || backend.downloads.max < 2 || backend.downloads.max > 3 {
+		t.Errorf("Expected up to 3 concurrent downloads and uploads, got %d and %d", backend.downloads.max, backend.uploads.max)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if got := len(release.Assets); got != 6 {
+			t.Errorf("Expected 6 assets uploaded to release %s, got %d", release.GetTagName(), got)
+		}
+	}
+}
+
+func TestTransferAssetsPreserveOrderUploadsOneAtATime(t *testing.T) {
+	backend := &concurrencyTrackingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 6, AssetSize: 10})}
+	testClient = backend
+	viper.Set("DOWNLOAD_CONCURRENCY", 3)
+	viper.Set("UPLOAD_CONCURRENCY", 3)
+	viper.Set("PRESERVE_ASSET_ORDER", true)
+
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	if backend.uploads.max != 1 {
+		t.Errorf("Expected uploads one at a time to keep the asset order, got %d concurrent", backend.uploads.max)
+	}
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	target := backend.TargetReleases("target-org", "repo")[0]
+	for i, asset := range source[0].Assets {
+		if i >= len(target.Assets) || target.Assets[i].GetName() != asset.GetName() {
+			t.Fatalf("Expected the target assets in the source order, got %v", target.Assets)
+		}
+	}
+}
+
+// failingUploadBackend fails the uploads of one asset
+type failingUploadBackend struct {
+	*fake.Backend
+	failing string
+}
+
+func (b *failingUploadBackend) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	if asset.GetName() == b.failing {
+		return fmt.Errorf("upload failed")
+	}
+	return b.Backend.UploadAssetViaURL(uploadURL, asset)
+}
+
+func TestTransferAssetsFailedUploadKeepsSpinner(t *testing.T) {
+	backend := &failingUploadBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 6, AssetSize: 10}), failing: "asset-1.zip"}
+	testClient = backend
+	viper.Set("DOWNLOAD_CONCURRENCY", 3)
+	viper.Set("UPLOAD_CONCURRENCY", 3)
+
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	newRelease, err := backend.CreateRelease("target-org", "repo", source[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transfers []assetTransfer
+	for _, asset := range source[0].Assets {
+		transfers = append(transfers, assetTransfer{asset: asset, name: asset.GetName()})
+	}
+
+	// The other transfers go on after the failed upload, the caller ends the spinner
+	spinner, _ := pterm.DefaultSpinner.Start("Migrating assets...")
+	defer spinner.Stop()
+	failed := newTestRun(t).transferAssets(newRelease, transfers, nil, spinner)
+	if len(failed) != 1 || failed[0].name != "asset-1.zip" {
+		t.Errorf("Expected asset-1.zip to fail, got %+v", failed)
+	}
+	if !spinner.IsActive {
+		t.Error("Expected the spinner of the release to be left running")
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")[0].Assets); got != 5 {
+		t.Errorf("Expected the 5 other assets to be uploaded, got %d", got)
+	}
+}
+
+// unnamedAssetBackend blanks the name of the first asset of each source release and records the
+// names of the transferred assets
+type unnamedAssetBackend struct {
+	*fake.Backend
+	transferred []string
+}
+
+func (b *unnamedAssetBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	for _, release := range releases {
+		unnamed := *release.Assets[0]
+		unnamed.Name = github.String("  ")
+		release.Assets[0] = &unnamed
+	}
+	return releases, err
+}
+
+func (b *unnamedAssetBackend) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	b.transferred = append(b.transferred, asset.GetName())
+	return b.Backend.DownloadReleaseAssetsCached(asset, digest)
+}
+
+func TestMigrateReleaseAssetsSkipsUnnamedAsset(t *testing.T) {
+	backend := &unnamedAssetBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 3, AssetSize: 10})}
+	testClient = backend
+	viper.Set("FAIL_ON_ASSET_ERROR", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Failed != 1 {
+		t.Errorf("Expected the unnamed asset to fail its release, got %d failed", result.Failed)
+	}
+	for _, name := range backend.transferred {
+		if strings.TrimSpace(name) == "" {
+			t.Errorf("Expected the unnamed asset not to be downloaded, got %q", backend.transferred)
+		}
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")[0].Assets); got != 2 {
+		t.Errorf("Expected the 2 named assets uploaded, got %d", got)
+	}
+}
+
+func TestMigrateReleaseAssetsForgetsReplacedBrokenAssets(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 2, AssetSize: 10})
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	// A failed upload left the first asset empty
+	newRelease := backend.TargetReleases("target-org", "repo")[0]
+	broken := newRelease.Assets[0]
+	broken.Size = github.Int(0)
+
+	viper.Set("REPLACE_BROKEN_ASSETS", true)
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	spinner, _ := pterm.DefaultSpinner.Start("Migrating assets...")
+	defer spinner.Stop()
+	if failed := newTestRun(t).migrateReleaseAssets("source-org", "repo", "target-org", source[0].GetTagName(), source[0], newRelease, spinner); failed != 0 {
+		t.Fatalf("Expected the broken asset to be replaced, got %d failed assets", failed)
+	}
+	for _, asset := range newRelease.Assets {
+		if asset.GetID() == broken.GetID() {
+			t.Errorf("Expected the deleted broken asset to be removed from the target release, got %v", newRelease.Assets)
+		}
+	}
+}
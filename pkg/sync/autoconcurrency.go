@@ -0,0 +1,54 @@
+package sync
+
+import (
+	gosync "sync"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// Shares of the rate limit remaining above which the asset concurrency is raised, and below
+// which it is halved
+const (
+	healthyRateHeadroom = 0.5
+	lowRateHeadroom     = 0.2
+)
+
+// concurrencyTuner adapts the number of asset transfer workers to the rate limit headroom. It
+// starts with a single worker, adds one for each release transferred while the remaining rate
+// limit is healthy, up to max, and halves the workers when it runs low.
+type concurrencyTuner struct {
+	mu       gosync.Mutex
+	current  int
+	max      int
+	headroom func() (remaining int, limit int, ok bool)
+}
+
+func newConcurrencyTuner(maxConcurrency int) *concurrencyTuner {
+	return &concurrencyTuner{current: 1, max: max(maxConcurrency, 1), headroom: api.RateLimitHeadroom}
+}
+
+// next returns the concurrency for the next release, adjusted to the latest observed rate limit.
+// The concurrency is kept until a response reports the rate limit.
+func (t *concurrencyTuner) next() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining, limit, ok := t.headroom()
+	if !ok || limit <= 0 {
+		return t.current
+	}
+
+	previous := t.current
+	share := float64(remaining) / float64(limit)
+	switch {
+	case share < lowRateHeadroom:
+		t.current = max(t.current/2, 1)
+	case share >= healthyRateHeadroom:
+		t.current = min(t.current+1, t.max)
+	}
+	if t.current != previous {
+		pterm.Info.Printf("Asset concurrency set to %d with %d of %d requests remaining in the rate limit", t.current, remaining, limit)
+	}
+	return t.current
+}
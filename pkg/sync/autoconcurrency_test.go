@@ -0,0 +1,39 @@
+package sync
+
+import "testing"
+
+func TestConcurrencyTunerFollowsRateLimit(t *testing.T) {
+	tuner := newConcurrencyTuner(3)
+
+	// Remaining requests of a 5000 requests limit before each release, 0 when not yet observed
+	trajectory := []struct {
+		remaining int
+		want      int
+	}{
+		{0, 1},
+		{4900, 2},
+		{4800, 3},
+		{4700, 3},
+		{2000, 3},
+		{900, 1},
+		{2600, 2},
+	}
+	for i, step := range trajectory {
+		tuner.headroom = func() (int, int, bool) { return step.remaining, 5000, i > 0 }
+		if got := tuner.next(); got != step.want {
+			t.Errorf("Step %d with %d remaining: concurrency = %d, want %d", i, step.remaining, got, step.want)
+		}
+	}
+}
+
+func TestConcurrencyTunerMinimum(t *testing.T) {
+	tuner := newConcurrencyTuner(0)
+	tuner.headroom = func() (int, int, bool) { return 5000, 5000, true }
+	if got := tuner.next(); got != 1 {
+		t.Errorf("Expected the concurrency to be capped at 1, got %d", got)
+	}
+	tuner.headroom = func() (int, int, bool) { return 0, 5000, true }
+	if got := tuner.next(); got != 1 {
+		t.Errorf("Expected at least one worker, got %d", got)
+	}
+}
@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// newClient returns the backend selected by BACKEND: the GitHub REST API by default, or an
+// in-memory fake configured by the FAKE_SCENARIO file
+func newClient() (api.Client, error) {
+	switch viper.GetString("BACKEND") {
+	case "", "github":
+		return api.NewRESTClient(), nil
+	case "fake":
+		scenario := fake.DefaultScenario
+		if viper.GetString("FAKE_SCENARIO") != "" {
+			var err error
+			scenario, err = fake.LoadScenario(viper.GetString("FAKE_SCENARIO"))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return fake.New(scenario), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q, valid backends are: github, fake", viper.GetString("BACKEND"))
+	}
+}
+
+// splitClient reads the source releases with source and writes the target releases with target.
+// The calls copying from the source to the target, such as tag migrations, go to target.
+type splitClient struct {
+	source api.Client
+	target api.Client
+}
+
+func (c splitClient) GetSourceRepository(owner string, repository string) (*github.Repository, error) {
+	return c.source.GetSourceRepository(owner, repository)
+}
+
+func (c splitClient) GetTargetRepository(owner string, repository string) (*github.Repository, error) {
+	return c.target.GetTargetRepository(owner, repository)
+}
+
+func (c splitClient) ListSourceRepositories(organization string) ([]*github.Repository, error) {
+	return c.source.ListSourceRepositories(organization)
+}
+
+func (c splitClient) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	return c.source.GetSourceRepositoryReleases(owner, repository)
+}
+
+func (c splitClient) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	return c.source.GetSourceRepositoryLatestRelease(owner, repository)
+}
+
+func (c splitClient) GetSourceTagsWithoutReleases(owner string, repository string, releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, error) {
+	return c.source.GetSourceTagsWithoutReleases(owner, repository, releases)
+}
+
+func (c splitClient) GetReleaseAssetDigests(owner string, repository string, releaseID int64) (map[int64]string, error) {
+	return c.source.GetReleaseAssetDigests(owner, repository, releaseID)
+}
+
+func (c splitClient) GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	return c.target.GetTargetRepositoryReleases(owner, repository)
+}
+
+func (c splitClient) GetTargetRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	return c.target.GetTargetRepositoryLatestRelease(owner, repository)
+}
+
+func (c splitClient) GetReleaseByTag(owner string, repository string, tagName string) (*github.RepositoryRelease, error) {
+	return c.target.GetReleaseByTag(owner, repository, tagName)
+}
+
+func (c splitClient) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	return c.target.ReleaseExists(owner, repository, release)
+}
+
+func (c splitClient) MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return c.target.MigrateAnnotatedTag(sourceOwner, targetOwner, repository, tagName)
+}
+
+func (c splitClient) CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	return c.target.CreateMissingTag(sourceOwner, targetOwner, repository, tagName)
+}
+
+func (c splitClient) MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error) {
+	return c.target.MigrateAutolinks(sourceOwner, targetOwner, repository)
+}
+
+func (c splitClient) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	return c.target.CreateRelease(owner, repository, release)
+}
+
+func (c splitClient) UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	return c.target.UpdateRelease(owner, repository, releaseID, release)
+}
+
+func (c splitClient) SetLatestRelease(owner string, repository string, releaseID int64) error {
+	return c.target.SetLatestRelease(owner, repository, releaseID)
+}
+
+func (c splitClient) DeleteReleaseAsset(owner string, repository string, assetID int64) error {
+	return c.target.DeleteReleaseAsset(owner, repository, assetID)
+}
+
+func (c splitClient) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	return c.source.DownloadReleaseAssetsCached(asset, digest)
+}
+
+func (c splitClient) SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error) {
+	return c.source.SplitAsset(asset, partSize)
+}
+
+func (c splitClient) HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error) {
+	return c.target.HashTargetAsset(asset, algorithm)
+}
+
+func (c splitClient) WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error) {
+	return c.target.WriteAssetFile(name, content)
+}
+
+func (c splitClient) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	return c.target.UploadAssetViaURL(uploadURL, asset)
+}
+
+func (c splitClient) UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error {
+	return c.target.UploadAssetAs(uploadURL, asset, name)
+}
+
+func (c splitClient) WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
+	return c.target.WriteToIssue(owner, repository, issueNumber, comment)
+}
+
+func (c splitClient) UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error) {
+	return c.target.UpsertIssueComment(owner, repository, issueNumber, commentID, marker, comment)
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"encoding/hex"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// regenerateChecksums uploads a checksums file named fileName listing the assets of the target
+// release, hashed from the target, so it matches the migrated assets rather than the source
+// ones. The assets with a checksum in known, keyed by name, such as the ones uploaded by the run,
+// are not downloaded again. A checksums file already matching the assets is kept.
+func (r *run) regenerateChecksums(targetOrg string, repository string, newRelease *github.RepositoryRelease, fileName string, algorithm string, known map[string]string) error {
+	// The target assets of a dry run are not hashed
+	if r.dryRun {
+		pterm.Info.Printf("Dry run: would generate checksums file %s of release %s\n", fileName, newRelease.GetName())
+		return nil
+	}
+
+	// The assets uploaded during the run are not listed in newRelease
+	release, err := r.client.GetReleaseByTag(targetOrg, repository, newRelease.GetTagName())
+	if err != nil {
+		return err
+	}
+
+	checksums := map[string]string{}
+	var existing *github.ReleaseAsset
+	for _, asset := range release.Assets {
+		if asset.GetName() == fileName {
+			existing = asset
+			continue
+		}
+		if checksum, ok := known[asset.GetName()]; ok {
+			checksums[asset.GetName()] = checksum
+			continue
+		}
+		checksum, err := r.client.HashTargetAsset(asset, algorithm)
+		if err != nil {
+			return err
+		}
+		checksums[asset.GetName()] = checksum
+	}
+	if len(checksums) == 0 {
+		return nil
+	}
+	content := api.FormatChecksums(checksums)
+
+	if existing != nil {
+		if int64(existing.GetSize()) == int64(len(content)) {
+			existingChecksum, err := r.client.HashTargetAsset(existing, algorithm)
+			if err != nil {
+				return err
+			}
+			contentChecksum := api.NewChecksumHash(algorithm)
+			contentChecksum.Write(content)
+			if existingChecksum == hex.EncodeToString(contentChecksum.Sum(nil)) {
+				pterm.Info.Printf("Checksums file %s of release %s is up to date", fileName, newRelease.GetName())
+				return nil
+			}
+		}
+		if err := r.client.DeleteReleaseAsset(targetOrg, repository, existing.GetID()); err != nil {
+			return err
+		}
+	}
+
+	asset, err := r.client.WriteAssetFile(fileName, content)
+	if err != nil {
+		return err
+	}
+	err = api.Retry(r.settings.maxRetries, func() error {
+		return r.client.UploadAssetViaURL(newRelease.GetUploadURL(), asset)
+	})
+	if err != nil {
+		return err
+	}
+	pterm.Info.Printf("Uploaded checksums file %s of %d assets to release %s", fileName, len(checksums), newRelease.GetName())
+	return nil
+}
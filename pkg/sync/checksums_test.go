@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestRegenerateChecksums(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 3, AssetSize: 10})
+	viper.Set("CHECKSUMS_FILE", "SHA256SUMS")
+
+	for run := 1; run <= 2; run++ {
+		result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+		if result.Err != nil || result.Failed != 0 {
+			t.Fatalf("Run %d: expected the migration to succeed, got %d failed: %v", run, result.Failed, result.Err)
+		}
+
+		release := backend.TargetReleases("target-org", "repo")[0]
+		checksums := map[string]string{}
+		var checksumsFiles int
+		for _, asset := range release.Assets {
+			if asset.GetName() == "SHA256SUMS" {
+				checksumsFiles++
+				continue
+			}
+			checksum, _ := backend.HashTargetAsset(asset, "sha256")
+			checksums[asset.GetName()] = checksum
+		}
+
+		if len(checksums) != 3 || checksumsFiles != 1 {
+			t.Fatalf("Run %d: expected 3 assets and a checksums file, got %v", run, release.Assets)
+		}
+		if got, want := string(backend.AssetFile("SHA256SUMS")), string(api.FormatChecksums(checksums)); got != want {
+			t.Errorf("Run %d: checksums file does not match the uploaded assets, got:\n%s\nwant:\n%s", run, got, want)
+		}
+	}
+}
+
+// hashCountingBackend counts the target assets hashed for a checksums file
+type hashCountingBackend struct {
+	*fake.Backend
+	hashed []string
+}
+
+func (b *hashCountingBackend) HashTargetAsset(asset *github.ReleaseAsset, algorithm string) (string, error) {
+	b.hashed = append(b.hashed, asset.GetName())
+	return b.Backend.HashTargetAsset(asset, algorithm)
+}
+
+func TestRegenerateChecksumsOnlyAfterUploads(t *testing.T) {
+	backend := &hashCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 3, AssetSize: 10})}
+	testClient = backend
+	viper.Set("CHECKSUMS_FILE", "SHA256SUMS")
+
+	// The uploaded assets are listed with the digests of their source assets
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if len(backend.hashed) != 0 {
+		t.Errorf("Expected the uploaded assets not to be downloaded again, got %v", backend.hashed)
+	}
+	written := string(backend.AssetFile("SHA256SUMS"))
+
+	// A run without any upload leaves the checksums file as is
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if len(backend.hashed) != 0 {
+		t.Errorf("Expected no asset to be hashed without an upload, got %v", backend.hashed)
+	}
+	if got := string(backend.AssetFile("SHA256SUMS")); got != written {
+		t.Errorf("Expected the checksums file to be kept, got:\n%s", got)
+	}
+
+	// The source digests are sha256, the assets are hashed from the target with another algorithm
+	viper.Set("CHECKSUMS_ALGORITHM", "md5")
+	newTestRun(t).migrateRepositoryReleases("other", nil)
+	if len(backend.hashed) != 3 {
+		t.Errorf("Expected the 3 assets to be hashed with md5, got %v", backend.hashed)
+	}
+}
+
+func TestRegenerateChecksumsDryRun(t *testing.T) {
+	backend := &hashCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 2, AssetSize: 10})}
+	viper.Set("CHECKSUMS_FILE", "SHA256SUMS")
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithDryRun(true), WithEventHandler(&recordingEventHandler{}))
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if len(backend.hashed) != 0 || backend.AssetFile("SHA256SUMS") != nil {
+		t.Errorf("Expected no checksums file to be generated in a dry run, got hashed assets %v", backend.hashed)
+	}
+}
@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Strategies, selected with MISSING_COMMIT_STRATEGY, for a release whose target commitish is a
+// commit SHA missing in the target repository
+const (
+	missingCommitFail          = "fail"
+	missingCommitSkip          = "skip"
+	missingCommitDefaultBranch = "default-branch"
+	missingCommitDraft         = "draft"
+)
+
+var missingCommitStrategies = []string{missingCommitFail, missingCommitSkip, missingCommitDefaultBranch, missingCommitDraft}
+
+var commitSHA = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// parseMissingCommitStrategy validates a MISSING_COMMIT_STRATEGY value, defaulting to failing the release
+func parseMissingCommitStrategy(value string) (string, error) {
+	strategy := strings.ToLower(strings.TrimSpace(value))
+	if strategy == "" {
+		return missingCommitFail, nil
+	}
+	for _, valid := range missingCommitStrategies {
+		if strategy == valid {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown missing commit strategy %q, valid strategies are: %s", value, strings.Join(missingCommitStrategies, ", "))
+}
+
+// isMissingCommitError reports whether creating a release failed because its target commitish
+// is a commit SHA missing in the target. Branch names are not concerned.
+func isMissingCommitError(release *github.RepositoryRelease, err error) bool {
+	if err == nil || !commitSHA.MatchString(release.GetTargetCommitish()) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "no commit found for sha")
+}
+
+// missingCommitPayload returns the payload to create the release with again for the strategy,
+// or nil when the release isn't created again. Without a target commitish, GitHub creates the
+// release on the default branch.
+func missingCommitPayload(payload *github.RepositoryRelease, strategy string) *github.RepositoryRelease {
+	switch strategy {
+	case missingCommitDefaultBranch:
+		retry := *payload
+		retry.TargetCommitish = nil
+		return &retry
+	case missingCommitDraft:
+		retry := *payload
+		retry.TargetCommitish = nil
+		retry.Draft = github.Bool(true)
+		return &retry
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestParseMissingCommitStrategy(t *testing.T) {
+	if strategy, err := parseMissingCommitStrategy(""); err != nil || strategy != missingCommitFail {
+		t.Errorf("Expected the fail strategy by default, got %q: %v", strategy, err)
+	}
+	if strategy, err := parseMissingCommitStrategy("Default-Branch"); err != nil || strategy != missingCommitDefaultBranch {
+		t.Errorf("Expected the default-branch strategy, got %q: %v", strategy, err)
+	}
+	if _, err := parseMissingCommitStrategy("retry"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestIsMissingCommitError(t *testing.T) {
+	err := errors.New("422 Validation Failed: No commit found for SHA: 0123456789abcdef0123456789abcdef01234567")
+	sha := &github.RepositoryRelease{TargetCommitish: github.String("0123456789abcdef0123456789abcdef01234567")}
+	branch := &github.RepositoryRelease{TargetCommitish: github.String("main")}
+
+	if !isMissingCommitError(sha, err) {
+		t.Error("Expected a missing commit error for a commit SHA")
+	}
+	if isMissingCommitError(branch, err) {
+		t.Error("Expected a branch name not to be handled as a missing commit")
+	}
+	if isMissingCommitError(sha, errors.New("release already exists")) {
+		t.Error("Expected other errors not to be handled as a missing commit")
+	}
+}
@@ -0,0 +1,247 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config describes a migration run by Migrate, in place of the flags of the sync command. The
+// fields take the values of the flags of the same name; the zero values are the defaults of the
+// flags.
+type Config struct {
+	SourceToken        string
+	TargetToken        string
+	SourceHostname     string
+	TargetHostname     string
+	SourceOrganization string
+	TargetOrganization string
+	// Repositories to migrate, as name or owner/name. Without any, every repository of the source
+	// organization is migrated.
+	Repositories []string
+	// SkipArchived and SkipForks leave out the archived and forked repositories of the source
+	// organization, without Repositories
+	SkipArchived bool
+	SkipForks    bool
+	DryRun       bool
+
+	// Release selection
+	// Fields are the release fields to migrate, such as "body" or "assets", all by default
+	Fields                     []string
+	TagFilter                  string
+	TagExclude                 string
+	Since                      time.Time
+	Until                      time.Time
+	PrereleasesOnly            bool
+	StableOnly                 bool
+	IncludeDrafts              bool
+	IncludeTagsWithoutReleases bool
+	SkipEmptyReleases          bool
+	NewerThanTarget            bool
+	TagPrefix                  string
+
+	// Release creation
+	MappingFile               string
+	AuthorAttribution         string
+	AuthorAttributionPosition string
+	RecordSourceIDs           bool
+	LinkSourceRelease         bool
+	// BodyTemplate is the file of the template the release bodies are rendered with
+	BodyTemplate          string
+	PublishDrafts         bool
+	NeverMarkLatest       bool
+	LegacyLatest          bool
+	PreserveTargetLatest  bool
+	MigrateAutolinks      bool
+	MigrateAnnotatedTags  bool
+	CreateMissingTags     bool
+	MissingCommitStrategy string
+	UpdateExisting        bool
+	CreateDelay           time.Duration
+	RepoDelay             time.Duration
+	RepoDelayJitter       time.Duration
+
+	// Assets
+	AssetNameTemplate     string
+	AllowedContentTypes   []string
+	MatchAssetLabels      bool
+	ReplaceBrokenAssets   bool
+	PreserveAssetOrder    bool
+	MaxAssetSize          int64
+	OversizeAssetStrategy string
+	ChecksumsFile         string
+	ChecksumsAlgorithm    string
+	DedupeAssets          bool
+	DownloadConcurrency   int
+	UploadConcurrency     int
+	AssetConcurrency      int
+	AutoConcurrency       bool
+	AutoConcurrencyMax    int
+	MaxRetries            int
+	FailOnAssetError      bool
+
+	// Reporting, by ReportSummary
+	SummaryFile      string
+	FailuresFile     string
+	FailedAssetsFile string
+	MappingStats     bool
+
+	// Options are applied to the Migrator last, e.g. WithEventHandler or WithOutput
+	Options []Option
+}
+
+// configValues holds the settings of a Config by the name of their flag, read like the viper
+// configuration of the sync command
+type configValues map[string]any
+
+func (v configValues) GetString(key string) string {
+	value, _ := v[key].(string)
+	return value
+}
+
+func (v configValues) GetBool(key string) bool {
+	value, _ := v[key].(bool)
+	return value
+}
+
+func (v configValues) GetInt(key string) int {
+	value, _ := v[key].(int)
+	return value
+}
+
+func (v configValues) GetInt64(key string) int64 {
+	value, _ := v[key].(int64)
+	return value
+}
+
+func (v configValues) GetDuration(key string) time.Duration {
+	value, _ := v[key].(time.Duration)
+	return value
+}
+
+// formatDate formats a date of the configuration like the SINCE and UNTIL flags, empty when unset
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(time.RFC3339)
+}
+
+// settings parses and validates the per-release settings of the configuration
+func (cfg Config) settings() (*settings, error) {
+	return parseSettings(configValues{
+		"SOURCE_HOSTNAME":               cfg.SourceHostname,
+		"TARGET_HOSTNAME":               cfg.TargetHostname,
+		"SOURCE_ORGANIZATION":           cfg.SourceOrganization,
+		"TARGET_ORGANIZATION":           cfg.TargetOrganization,
+		"MIGRATE_FIELDS":                strings.Join(cfg.Fields, ","),
+		"TAG_FILTER":                    cfg.TagFilter,
+		"TAG_EXCLUDE":                   cfg.TagExclude,
+		"SINCE":                         formatDate(cfg.Since),
+		"UNTIL":                         formatDate(cfg.Until),
+		"PRERELEASES_ONLY":              cfg.PrereleasesOnly,
+		"STABLE_ONLY":                   cfg.StableOnly,
+		"INCLUDE_DRAFTS":                cfg.IncludeDrafts,
+		"INCLUDE_TAGS_WITHOUT_RELEASES": cfg.IncludeTagsWithoutReleases,
+		"SKIP_EMPTY_RELEASES":           cfg.SkipEmptyReleases,
+		"NEWER_THAN_TARGET":             cfg.NewerThanTarget,
+		"TAG_PREFIX":                    cfg.TagPrefix,
+		"MAPPING_FILE":                  cfg.MappingFile,
+		"AUTHOR_ATTRIBUTION":            cfg.AuthorAttribution,
+		"AUTHOR_ATTRIBUTION_POSITION":   cfg.AuthorAttributionPosition,
+		"RECORD_SOURCE_IDS":             cfg.RecordSourceIDs,
+		"LINK_SOURCE_RELEASE":           cfg.LinkSourceRelease,
+		"BODY_TEMPLATE":                 cfg.BodyTemplate,
+		"PUBLISH_DRAFTS":                cfg.PublishDrafts,
+		"NEVER_MARK_LATEST":             cfg.NeverMarkLatest,
+		"LEGACY_LATEST":                 cfg.LegacyLatest,
+		"PRESERVE_TARGET_LATEST":        cfg.PreserveTargetLatest,
+		"MIGRATE_AUTOLINKS":             cfg.MigrateAutolinks,
+		"MIGRATE_ANNOTATED_TAGS":        cfg.MigrateAnnotatedTags,
+		"CREATE_MISSING_TAGS":           cfg.CreateMissingTags,
+		"MISSING_COMMIT_STRATEGY":       cfg.MissingCommitStrategy,
+		"UPDATE_EXISTING":               cfg.UpdateExisting,
+		"CREATE_DELAY":                  cfg.CreateDelay,
+		"REPO_DELAY":                    cfg.RepoDelay,
+		"REPO_DELAY_JITTER":             cfg.RepoDelayJitter,
+		"ASSET_NAME_TEMPLATE":           cfg.AssetNameTemplate,
+		"ALLOWED_CONTENT_TYPES":         strings.Join(cfg.AllowedContentTypes, ","),
+		"MATCH_ASSET_LABELS":            cfg.MatchAssetLabels,
+		"REPLACE_BROKEN_ASSETS":         cfg.ReplaceBrokenAssets,
+		"PRESERVE_ASSET_ORDER":          cfg.PreserveAssetOrder,
+		"MAX_ASSET_SIZE":                cfg.MaxAssetSize,
+		"OVERSIZE_ASSET_STRATEGY":       cfg.OversizeAssetStrategy,
+		"CHECKSUMS_FILE":                cfg.ChecksumsFile,
+		"CHECKSUMS_ALGORITHM":           cfg.ChecksumsAlgorithm,
+		"DEDUPE_ASSETS":                 cfg.DedupeAssets,
+		"DOWNLOAD_CONCURRENCY":          cfg.DownloadConcurrency,
+		"UPLOAD_CONCURRENCY":            cfg.UploadConcurrency,
+		"ASSET_CONCURRENCY":             cfg.AssetConcurrency,
+		"AUTO_CONCURRENCY":              cfg.AutoConcurrency,
+		"AUTO_CONCURRENCY_MAX":          cfg.AutoConcurrencyMax,
+		"MAX_RETRIES":                   cfg.MaxRetries,
+		"FAIL_ON_ASSET_ERROR":           cfg.FailOnAssetError,
+		"SUMMARY_FILE":                  cfg.SummaryFile,
+		"FAILURES_FILE":                 cfg.FailuresFile,
+		"FAILED_ASSETS_FILE":            cfg.FailedAssetsFile,
+		"MAPPING_STATS":                 cfg.MappingStats,
+	})
+}
+
+// connectionMu serializes the Migrate calls, whose connection is set in the viper configuration
+var connectionMu gosync.Mutex
+
+// useConnection sets the tokens and hostnames of the configuration in viper, where the GitHub
+// REST API client reads them from, and returns the function restoring the previous values
+func (cfg Config) useConnection() func() {
+	values := map[string]string{
+		"SOURCE_TOKEN":    cfg.SourceToken,
+		"TARGET_TOKEN":    cfg.TargetToken,
+		"SOURCE_HOSTNAME": cfg.SourceHostname,
+		"TARGET_HOSTNAME": cfg.TargetHostname,
+	}
+	previous := map[string]any{}
+	for key, value := range values {
+		previous[key] = viper.Get(key)
+		viper.Set(key, value)
+	}
+	return func() {
+		for key, value := range previous {
+			viper.Set(key, value)
+		}
+	}
+}
+
+// Migrate validates cfg and migrates the releases it describes, returning the summary of the run
+// instead of exiting on errors like the sync command. The settings of the run are read from cfg
+// only; its connection is set in the process configuration while the migration runs, so the
+// Migrate calls of a process run one at a time.
+func Migrate(ctx context.Context, cfg Config) (Summary, error) {
+	s, err := cfg.settings()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	connectionMu.Lock()
+	defer connectionMu.Unlock()
+	defer cfg.useConnection()()
+
+	options := []Option{WithEventHandler(eventHandler), withSettings(s), WithRepositories(cfg.Repositories...), WithDryRun(cfg.DryRun)}
+	migrator := NewMigrator(append(options, cfg.Options...)...)
+
+	// Migrate every repository of the source organization
+	if len(migrator.repositories) == 0 && migrator.retryManifest == "" {
+		if cfg.SourceOrganization == "" {
+			return Summary{}, fmt.Errorf("no repository or source organization specified")
+		}
+		migrator.repositories, err = discoverRepositories(migrator.source, cfg.SourceOrganization, cfg.SkipArchived, cfg.SkipForks)
+		if err != nil {
+			return Summary{}, err
+		}
+	}
+	return migrator.Migrate(ctx)
+}
@@ -0,0 +1,137 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestMigrate(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 10})
+	handler := &recordingEventHandler{}
+
+	summary, err := Migrate(context.Background(), Config{
+		SourceToken:        "source-token",
+		TargetToken:        "target-token",
+		SourceOrganization: "source-org",
+		TargetOrganization: "other-org",
+		Repositories:       []string{"repo1", "repo2"},
+		TagFilter:          `^v2\.`,
+		Options:            []Option{WithClient(backend), WithEventHandler(handler)},
+	})
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if summary.Releases != 2 || summary.Failed != 0 || len(handler.results) != 2 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	for _, repository := range []string{"repo1", "repo2"} {
+		releases := backend.TargetReleases("other-org", repository)
+		if len(releases) != 1 || releases[0].GetTagName() != "v2.0.0" {
+			t.Errorf("Expected only release v2.0.0 in %s with TAG_FILTER, got %d releases", repository, len(releases))
+		}
+	}
+}
+
+func TestMigrateInvalidConfig(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1})
+
+	_, err := Migrate(context.Background(), Config{
+		SourceToken:        "source-token",
+		TargetToken:        "target-token",
+		SourceOrganization: "source-org",
+		TargetOrganization: "target-org",
+		Repositories:       []string{"repo"},
+		PrereleasesOnly:    true,
+		StableOnly:         true,
+		Options:            []Option{WithClient(backend)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "prereleases only and stable only") {
+		t.Fatalf("Expected an error for conflicting settings, got %v", err)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")); got != 0 {
+		t.Errorf("Expected no release to be created, got %d", got)
+	}
+}
+
+func TestMigrateBodyTemplate(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	templatePath := filepath.Join(t.TempDir(), "body.tmpl")
+	cfg := Config{
+		SourceToken:        "source-token",
+		TargetToken:        "target-token",
+		SourceOrganization: "source-org",
+		TargetOrganization: "target-org",
+		Repositories:       []string{"repo"},
+		BodyTemplate:       templatePath,
+		Options:            []Option{WithClient(backend), WithEventHandler(&recordingEventHandler{})},
+	}
+
+	// An invalid template stops the run before any release is created
+	if err := os.WriteFile(templatePath, []byte("{{ .Repository "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "parse body template") {
+		t.Fatalf("Expected an error for an invalid body template, got %v", err)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")); got != 0 {
+		t.Errorf("Expected no release to be created, got %d", got)
+	}
+
+	// The template read at startup renders every release, even if the file changes during the run
+	if err := os.WriteFile(templatePath, []byte("Migrated from {{ .Repository }}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := cfg.settings()
+	if err != nil {
+		t.Fatalf("settings returned an error: %v", err)
+	}
+	if err := os.Remove(templatePath); err != nil {
+		t.Fatal(err)
+	}
+	migrator := NewMigrator(append([]Option{withSettings(s), WithRepositories(cfg.Repositories...)}, cfg.Options...)...)
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if !strings.Contains(release.GetBody(), "Migrated from repo") {
+			t.Errorf("Expected the body of %s to be rendered, got %q", release.GetTagName(), release.GetBody())
+		}
+	}
+}
+
+func TestMigrateKeepsProcessConfiguration(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	viper.Set("SOURCE_TOKEN", "process-token")
+	viper.Set("TAG_FILTER", `^v1\.`)
+
+	// The settings of the process configuration don't apply to the migration
+	_, err := Migrate(context.Background(), Config{
+		SourceToken:        "source-token",
+		TargetToken:        "target-token",
+		SourceOrganization: "source-org",
+		TargetOrganization: "target-org",
+		Repositories:       []string{"repo"},
+		Options:            []Option{WithClient(backend), WithEventHandler(&recordingEventHandler{})},
+	})
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")); got != 2 {
+		t.Errorf("Expected both releases to be migrated, got %d", got)
+	}
+
+	// The connection of the migration is only set while it runs
+	if got := viper.GetString("SOURCE_TOKEN"); got != "process-token" {
+		t.Errorf("Expected the source token of the process to be restored, got %q", got)
+	}
+	if got := viper.GetString("TARGET_TOKEN"); got != "" {
+		t.Errorf("Expected no target token left in the process configuration, got %q", got)
+	}
+}
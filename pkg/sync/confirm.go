@@ -0,0 +1,41 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkTargetConfirmation compares the target named again with CONFIRM_TARGET to the configured
+// target, so a migration into the wrong organization aborts before any write. The target
+// organization confirms any run, and targetOrg/repository also confirms a single repository
+// run. An empty confirmation is not checked.
+func checkTargetConfirmation(confirmation string, targetOrg string, repository string) error {
+	if confirmation == "" {
+		return nil
+	}
+	if confirmation == targetOrg {
+		return nil
+	}
+	if repository != "" {
+		if confirmation == targetOrg+"/"+repositoryName(repository) {
+			return nil
+		}
+	}
+	return fmt.Errorf("confirmed target %q does not match the target organization %q, aborting before any change", confirmation, targetOrg)
+}
+
+// checkEntryTargetsConfirmation refuses, when the target is confirmed with CONFIRM_TARGET,
+// repository list entries migrated to another organization than the confirmed one, which a
+// single confirmation can't cover
+func checkEntryTargetsConfirmation(confirmation string, targets map[string]string) error {
+	if confirmation == "" {
+		return nil
+	}
+	confirmedOrg, _, _ := strings.Cut(confirmation, "/")
+	for repository, owner := range targets {
+		if owner != confirmedOrg {
+			return fmt.Errorf("repository %s is migrated to %s, not the confirmed target %q, aborting before any change", repository, owner, confirmation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package sync
+
+import "testing"
+
+func TestCheckTargetConfirmation(t *testing.T) {
+	tests := []struct {
+		confirmation string
+		repository   string
+		valid        bool
+	}{
+		{"", "tools", true},
+		{"target-org", "", true},
+		{"target-org", "tools", true},
+		{"target-org/tools", "tools", true},
+		{"target-org/tools", "source-org/tools", true},
+		{"target-org/tools", "", false},
+		{"target-org/cli", "tools", false},
+		{"other-org", "tools", false},
+		{"Target-Org", "", false},
+		{"target-org/", "tools", false},
+	}
+
+	for _, tt := range tests {
+		err := checkTargetConfirmation(tt.confirmation, "target-org", tt.repository)
+		if (err == nil) != tt.valid {
+			t.Errorf("checkTargetConfirmation(%q, %q) returned %v, want valid %v", tt.confirmation, tt.repository, err, tt.valid)
+		}
+	}
+}
+
+func TestCheckEntryTargetsConfirmation(t *testing.T) {
+	targets := map[string]string{"tools": "other-org"}
+
+	if err := checkEntryTargetsConfirmation("", targets); err != nil {
+		t.Errorf("Expected no check without a confirmation, got %v", err)
+	}
+	if err := checkEntryTargetsConfirmation("other-org", targets); err != nil {
+		t.Errorf("Expected the entry targets to be confirmed, got %v", err)
+	}
+	if err := checkEntryTargetsConfirmation("target-org", targets); err == nil {
+		t.Errorf("Expected an entry migrated to another organization to be refused")
+	}
+}
@@ -0,0 +1,68 @@
+package sync
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+)
+
+// parseAllowedContentTypes parses an ALLOWED_CONTENT_TYPES value: comma-separated media types,
+// such as application/zip, or type wildcards, such as text/*. An empty value allows every type.
+func parseAllowedContentTypes(value string) ([]string, error) {
+	var allowed []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		mediaType, subtype, found := strings.Cut(entry, "/")
+		if !found || mediaType == "" || subtype == "" || mediaType == "*" {
+			return nil, fmt.Errorf("invalid allowed content type %q, expected a type/subtype media type or a type/* wildcard", entry)
+		}
+		allowed = append(allowed, entry)
+	}
+	return allowed, nil
+}
+
+// isAllowedContentType reports whether contentType, with its parameters ignored, is one of the
+// allowed media types. Any content type is allowed without an allowlist.
+func isAllowedContentType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if entry == mediaType || (strings.HasSuffix(entry, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(entry, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *run) recordRejectedAsset(repository string, release *github.RepositoryRelease, asset *github.ReleaseAsset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rejectedAssets = append(r.rejectedAssets, fmt.Sprintf("%s@%s: %s (%s)", repository, release.GetTagName(), asset.GetName(), contentTypeOrUnknown(api.AssetContentType(asset))))
+}
+
+// recordedRejectedAssets returns the assets rejected for their content type during the run
+func (r *run) recordedRejectedAssets() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.rejectedAssets...)
+}
+
+func contentTypeOrUnknown(contentType string) string {
+	if contentType == "" {
+		return "unknown content type"
+	}
+	return contentType
+}
@@ -0,0 +1,90 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// contentTypeBackend sets the content types of the assets of each source release, in order, and
+// records the names of the downloaded assets
+type contentTypeBackend struct {
+	*fake.Backend
+	contentTypes []string
+	downloaded   []string
+}
+
+func (b *contentTypeBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	for _, release := range releases {
+		for i, asset := range release.Assets {
+			typed := *asset
+			typed.ContentType = github.String(b.contentTypes[i])
+			release.Assets[i] = &typed
+		}
+	}
+	return releases, err
+}
+
+func (b *contentTypeBackend) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	b.downloaded = append(b.downloaded, asset.GetName())
+	return b.Backend.DownloadReleaseAssetsCached(asset, digest)
+}
+
+func TestMigrateReleaseAssetsSkipsDisallowedContentTypes(t *testing.T) {
+	backend := &contentTypeBackend{
+		Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 4, AssetSize: 10}),
+		// The first asset has no content type and is detected from its zip extension
+		contentTypes: []string{"", "application/x-msdownload", "text/plain; charset=utf-8", "application/x-sh"},
+	}
+	testClient = backend
+	viper.Set("ALLOWED_CONTENT_TYPES", "application/zip, text/*")
+
+	r := newTestRun(t)
+	result := r.migrateRepositoryReleases("repo", nil)
+	if result.Failed != 0 {
+		t.Errorf("Expected the rejected assets to be skipped without failing, got %d failed", result.Failed)
+	}
+
+	var uploaded []string
+	for _, asset := range backend.TargetReleases("target-org", "repo")[0].Assets {
+		uploaded = append(uploaded, asset.GetName())
+	}
+	if strings.Join(uploaded, ",") != "asset-1.zip,asset-3.zip" {
+		t.Errorf("Expected the assets with allowed content types uploaded, got %v", uploaded)
+	}
+	if strings.Join(backend.downloaded, ",") != "asset-1.zip,asset-3.zip" {
+		t.Errorf("Expected the rejected assets not to be downloaded, got %v", backend.downloaded)
+	}
+
+	rejected := r.recordedRejectedAssets()
+	if len(rejected) != 2 || !strings.Contains(rejected[0], "asset-2.zip (application/x-msdownload)") || !strings.Contains(rejected[1], "asset-4.zip (application/x-sh)") {
+		t.Errorf("Expected the rejected assets reported with their content types, got %v", rejected)
+	}
+}
+
+func TestParseAllowedContentTypes(t *testing.T) {
+	allowed, err := parseAllowedContentTypes(" Application/Zip ,text/*,")
+	if err != nil {
+		t.Fatalf("parseAllowedContentTypes returned an error: %v", err)
+	}
+	if strings.Join(allowed, ",") != "application/zip,text/*" {
+		t.Errorf("Unexpected allowed content types: %v", allowed)
+	}
+
+	for _, value := range []string{"zip", "*/*", "application/"} {
+		if _, err := parseAllowedContentTypes(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+
+	if !isAllowedContentType("application/octet-stream", nil) {
+		t.Errorf("Expected every content type to be allowed without an allowlist")
+	}
+	if isAllowedContentType("", []string{"application/zip"}) {
+		t.Errorf("Expected an unknown content type to be rejected by an allowlist")
+	}
+}
@@ -0,0 +1,38 @@
+package sync
+
+import (
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// discoverRepositories lists the repositories of a source organization to migrate them all,
+// leaving out the archived repositories with skipArchived and the forks with skipForks
+func discoverRepositories(backend api.Client, organization string, skipArchived bool, skipForks bool) ([]string, error) {
+	sourceRepositories, err := backend.ListSourceRepositories(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	var repositories []string
+	var archived, forks int
+	for _, repository := range sourceRepositories {
+		if skipArchived && repository.GetArchived() {
+			archived++
+			continue
+		}
+		if skipForks && repository.GetFork() {
+			forks++
+			continue
+		}
+		repositories = append(repositories, repository.GetName())
+	}
+
+	pterm.Info.Printf("Found %d repositories in organization %s\n", len(sourceRepositories), organization)
+	if archived > 0 {
+		pterm.Info.Printf("Skipping %d archived repositories\n", archived)
+	}
+	if forks > 0 {
+		pterm.Info.Printf("Skipping %d forks\n", forks)
+	}
+	return repositories, nil
+}
@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+)
+
+func TestDiscoverRepositories(t *testing.T) {
+	backend := fake.New(fake.Scenario{
+		Repositories:         []string{"tools", "cli", "docs", "template"},
+		ArchivedRepositories: []string{"source-org/cli"},
+		ForkRepositories:     []string{"source-org/docs"},
+	})
+
+	tests := []struct {
+		skipArchived bool
+		skipForks    bool
+		want         []string
+	}{
+		{false, false, []string{"tools", "cli", "docs", "template"}},
+		{true, false, []string{"tools", "docs", "template"}},
+		{false, true, []string{"tools", "cli", "template"}},
+		{true, true, []string{"tools", "template"}},
+	}
+	for _, tt := range tests {
+		repositories, err := discoverRepositories(backend, "source-org", tt.skipArchived, tt.skipForks)
+		if err != nil {
+			t.Fatalf("discoverRepositories returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(repositories, tt.want) {
+			t.Errorf("Skip archived %v, skip forks %v: got %v, want %v", tt.skipArchived, tt.skipForks, repositories, tt.want)
+		}
+	}
+}
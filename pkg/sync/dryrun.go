@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// dryRunClient reads the source and target releases but only logs the changes it would make to
+// the target. Assets are neither downloaded nor uploaded.
+type dryRunClient struct {
+	api.Client
+}
+
+func (c dryRunClient) MigrateAnnotatedTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	pterm.Info.Printf("Dry run: would migrate annotated tag %s to %s/%s\n", tagName, targetOwner, repository)
+	return false, nil
+}
+
+func (c dryRunClient) CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	pterm.Info.Printf("Dry run: would create missing tag %s in %s/%s\n", tagName, targetOwner, repository)
+	return false, nil
+}
+
+func (c dryRunClient) MigrateAutolinks(sourceOwner string, targetOwner string, repository string) (int, error) {
+	pterm.Info.Printf("Dry run: would migrate the autolinks of %s/%s\n", targetOwner, repository)
+	return 0, nil
+}
+
+func (c dryRunClient) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	pterm.Info.Printf("Dry run: would create release %s in %s/%s\n", release.GetTagName(), owner, repository)
+	created := *release
+	created.Assets = nil
+	return &created, nil
+}
+
+func (c dryRunClient) UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	pterm.Info.Printf("Dry run: would update release %s in %s/%s\n", release.GetTagName(), owner, repository)
+	updated := *release
+	updated.ID = github.Int64(releaseID)
+	updated.Assets = nil
+	return &updated, nil
+}
+
+func (c dryRunClient) SetLatestRelease(owner string, repository string, releaseID int64) error {
+	return nil
+}
+
+func (c dryRunClient) DeleteReleaseAsset(owner string, repository string, assetID int64) error {
+	pterm.Info.Printf("Dry run: would delete asset %d in %s/%s\n", assetID, owner, repository)
+	return nil
+}
+
+func (c dryRunClient) DownloadReleaseAssetsCached(asset *github.ReleaseAsset, digest string) error {
+	return nil
+}
+
+func (c dryRunClient) SplitAsset(asset *github.ReleaseAsset, partSize int64) ([]*github.ReleaseAsset, error) {
+	pterm.Info.Printf("Dry run: would split asset %s into parts of %d bytes\n", asset.GetName(), partSize)
+	return nil, nil
+}
+
+func (c dryRunClient) WriteAssetFile(name string, content []byte) (*github.ReleaseAsset, error) {
+	return &github.ReleaseAsset{Name: github.String(name), Size: github.Int(len(content))}, nil
+}
+
+func (c dryRunClient) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	pterm.Info.Printf("Dry run: would upload asset %s\n", asset.GetName())
+	return nil
+}
+
+func (c dryRunClient) UploadAssetAs(uploadURL string, asset *github.ReleaseAsset, name string) error {
+	pterm.Info.Printf("Dry run: would upload asset %s as %s\n", asset.GetName(), name)
+	return nil
+}
+
+func (c dryRunClient) WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
+	return nil
+}
+
+func (c dryRunClient) UpsertIssueComment(owner string, repository string, issueNumber int, commentID int64, marker string, comment string) (int64, error) {
+	return commentID, nil
+}
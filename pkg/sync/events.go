@@ -0,0 +1,199 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// RepositoryResult is the outcome of migrating the releases of a single repository
+type RepositoryResult struct {
+	Repository string
+	Releases   int
+	Failed     int
+	Skipped    int
+	// Updated and Unchanged are the existing target releases edited or already up to date with
+	// UPDATE_EXISTING
+	Updated   int
+	Unchanged int
+	Err       error
+	// ReleaseFlags are the draft and prerelease flags of the releases created or found in the target
+	ReleaseFlags []ReleaseFlags
+	// Duration is the time spent migrating the repository, across both phases of a two-phase
+	// migration
+	Duration time.Duration
+}
+
+// RepositoryStatus is the outcome of a repository reported at the end of a run
+type RepositoryStatus string
+
+const (
+	// StatusSucceeded is a repository whose releases all migrated
+	StatusSucceeded RepositoryStatus = "succeeded"
+	// StatusPartiallyFailed is a repository with some releases that failed to migrate
+	StatusPartiallyFailed RepositoryStatus = "partially failed"
+	// StatusErrored is a repository with no migrated release because of errors, e.g. releases that
+	// can't be fetched or an archived target
+	StatusErrored RepositoryStatus = "errored"
+)
+
+// Status returns the outcome of the repository
+func (r RepositoryResult) Status() RepositoryStatus {
+	switch {
+	case r.Err == nil && r.Failed == 0:
+		return StatusSucceeded
+	case r.Releases-r.Failed <= 0:
+		return StatusErrored
+	default:
+		return StatusPartiallyFailed
+	}
+}
+
+// EventHandler is notified as the migration progresses
+type EventHandler interface {
+	// RepositoryCompleted is called as soon as a repository has been migrated
+	RepositoryCompleted(result RepositoryResult)
+}
+
+// errFetchReleases marks the result of a repository whose source releases could not be fetched
+var errFetchReleases = errors.New("unable to fetch releases")
+
+type migrateFunc func(repository string, fetched *repositoryReleases) RepositoryResult
+
+// Summary aggregates the results of all migrated repositories
+type Summary struct {
+	Releases int
+	Failed   int
+	Skipped  int
+	// Updated and Unchanged are the existing target releases edited or already up to date with
+	// UPDATE_EXISTING
+	Updated   int
+	Unchanged int
+	// FailedRepositories is the number of repositories whose releases could not be fetched
+	FailedRepositories int
+	Results            []RepositoryResult
+	// Elapsed is the time the whole run took
+	Elapsed time.Duration
+
+	// run holds what the run recorded for its report
+	run *run
+}
+
+func (s *Summary) add(result RepositoryResult) {
+	s.Releases += result.Releases
+	s.Failed += result.Failed
+	s.Skipped += result.Skipped
+	s.Updated += result.Updated
+	s.Unchanged += result.Unchanged
+	if errors.Is(result.Err, errFetchReleases) {
+		s.FailedRepositories++
+	}
+	s.Results = append(s.Results, result)
+}
+
+// addAssets adds the result of the asset phase of a repository to the result of its release phase
+func (s *Summary) addAssets(result RepositoryResult) {
+	s.Failed += result.Failed
+	for i := range s.Results {
+		if s.Results[i].Repository != result.Repository {
+			continue
+		}
+		s.Results[i].Failed += result.Failed
+		s.Results[i].Duration += result.Duration
+		if result.Err != nil {
+			s.Results[i].Err = errors.Join(s.Results[i].Err, result.Err)
+		}
+		return
+	}
+	// The release phase ran in a previous run
+	s.Results = append(s.Results, result)
+}
+
+// eventHandler is the handler of the Migrators built from the viper configuration, nil for the
+// default handler
+var eventHandler EventHandler
+
+// SetEventHandler replaces the handler notified of the migration progress
+func SetEventHandler(handler EventHandler) {
+	eventHandler = handler
+}
+
+// defaultEventHandler prints each repository result and, when INCREMENTAL_ISSUE_COMMENTS is set
+// in GitHub Actions, comments it on the triggering issue. With ISSUE_COMMENT_INTERVAL, a single
+// comment is edited with the results instead.
+type defaultEventHandler struct {
+	run *run
+}
+
+func (h *defaultEventHandler) RepositoryCompleted(result RepositoryResult) {
+	pterm.Info.Println(formatRepositoryResult(result))
+
+	r := h.run
+	if !r.settings.incrementalIssueComments {
+		return
+	}
+	organization, repository, issueNumber, ok := triggeringIssue()
+	if !ok {
+		return
+	}
+
+	if interval := r.settings.issueCommentInterval; interval > 0 {
+		if r.issueProgress == nil {
+			r.issueProgress = newProgressComment(interval)
+		}
+		if comment, due := r.issueProgress.add(result); due {
+			r.writeProgressComment(organization, repository, issueNumber, comment)
+		}
+		return
+	}
+	r.writeIssueComment(organization, repository, issueNumber, formatRepositoryResult(result), "repository result")
+}
+
+// triggeringIssue returns the issue that triggered the GitHub Actions run, if any
+func triggeringIssue() (string, string, int, bool) {
+	if os.Getenv("CI") != "true" || os.Getenv("GITHUB_ACTIONS") != "true" {
+		return "", "", 0, false
+	}
+
+	organization, repository, issueNumber, err := api.GetDatafromGitHubContext()
+	if err != nil || issueNumber == 0 {
+		return "", "", 0, false // skip if is not an issue event
+	}
+	return organization, repository, issueNumber, true
+}
+
+// writeIssueComment comments on the triggering issue and reports whether the comment was written.
+// A token without the permission to comment is reported once and doesn't fail the migration.
+func (r *run) writeIssueComment(organization string, repository string, issueNumber int, comment string, what string) bool {
+	if r.issueCommentsForbidden.Load() {
+		return false
+	}
+
+	err := r.client.WriteToIssue(organization, repository, issueNumber, comment)
+	if errors.Is(err, api.ErrIssueCommentForbidden) {
+		r.issueCommentsForbidden.Store(true)
+		pterm.Warning.Printf("TARGET_TOKEN lacks issues:write on %s/%s; skipping %s comment\n", organization, repository, what)
+		return false
+	}
+	if err != nil {
+		pterm.Error.Printf("Error writing %s to issue: %v", what, err)
+		return false
+	}
+	return true
+}
+
+// formatRepositoryResult formats a repository result as a single line
+func formatRepositoryResult(result RepositoryResult) string {
+	if errors.Is(result.Err, errFetchReleases) {
+		return fmt.Sprintf("%s: %v", result.Repository, result.Err)
+	}
+	line := fmt.Sprintf("%s: %d releases, %d succeeded, %d failed", result.Repository, result.Releases, result.Releases-result.Failed, result.Failed)
+	if result.Skipped > 0 {
+		line += fmt.Sprintf(", %d skipped", result.Skipped)
+	}
+	return line
+}
@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/mona-actions/gh-migrate-releases/internal/logging"
+	"github.com/spf13/viper"
+)
+
+type recordingEventHandler struct {
+	results []RepositoryResult
+}
+
+func (h *recordingEventHandler) RepositoryCompleted(result RepositoryResult) {
+	h.results = append(h.results, result)
+}
+
+func TestMigrateRepositoriesEmitsResults(t *testing.T) {
+	handler := &recordingEventHandler{}
+	var calls int
+
+	migrate := func(repository string, fetched *repositoryReleases) RepositoryResult {
+		calls++
+		// Each repository result is emitted before the next repository starts
+		if len(handler.results) != calls-1 {
+			t.Errorf("Expected %d results emitted before migrating %s, got %d", calls-1, repository, len(handler.results))
+		}
+		return RepositoryResult{Repository: repository, Releases: 3, Failed: calls - 1}
+	}
+
+	s := newTestRun(t).migrateRepositories([]string{"org/repo1", "org/repo2"}, nil, migrate, handler)
+
+	if len(handler.results) != 2 || handler.results[0].Repository != "org/repo1" || handler.results[1].Repository != "org/repo2" {
+		t.Errorf("Unexpected emitted results: %v", handler.results)
+	}
+	if s.Releases != 6 || s.Failed != 1 || len(s.Results) != 2 {
+		t.Errorf("Unexpected aggregate summary: %+v", s)
+	}
+}
+
+func TestFormatRepositoryResult(t *testing.T) {
+	got := formatRepositoryResult(RepositoryResult{Repository: "org/repo", Releases: 5, Failed: 1, Skipped: 2})
+	want := "org/repo: 5 releases, 4 succeeded, 1 failed, 2 skipped"
+	if got != want {
+		t.Errorf("formatRepositoryResult() = %q, want %q", got, want)
+	}
+}
+
+func TestRepositoryResultStatus(t *testing.T) {
+	tests := []struct {
+		result RepositoryResult
+		want   RepositoryStatus
+	}{
+		{RepositoryResult{Releases: 3}, StatusSucceeded},
+		{RepositoryResult{}, StatusSucceeded},
+		{RepositoryResult{Releases: 3, Failed: 1, Err: fmt.Errorf("some releases failed to create")}, StatusPartiallyFailed},
+		{RepositoryResult{Releases: 3, Failed: 1}, StatusPartiallyFailed},
+		{RepositoryResult{Releases: 3, Failed: 3, Err: fmt.Errorf("target repository is archived")}, StatusErrored},
+		{RepositoryResult{Err: fmt.Errorf("%w: 404 Not Found", errFetchReleases)}, StatusErrored},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Status(); got != tt.want {
+			t.Errorf("Status() of %+v = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestPrintRepositoryStatuses(t *testing.T) {
+	var output bytes.Buffer
+	previous := logging.Output()
+	logging.SetOutput(&output)
+	t.Cleanup(func() { logging.SetOutput(previous) })
+
+	printRepositoryStatuses(Summary{Results: []RepositoryResult{
+		{Repository: "org/repo1", Releases: 2},
+		{Repository: "org/repo2", Releases: 3, Failed: 1, Err: fmt.Errorf("some releases failed to create")},
+		{Repository: "org/repo3", Err: fmt.Errorf("%w: 404 Not Found", errFetchReleases)},
+	}})
+
+	for _, line := range []string{
+		"org/repo1: succeeded, 2 releases",
+		"org/repo2: partially failed, 1 of 3 releases failed",
+		"org/repo3: errored: unable to fetch releases: 404 Not Found",
+		"Repositories: 1 succeeded, 1 partially failed, 1 errored",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", line, output.String())
+		}
+	}
+}
+
+// forbiddenCommentBackend refuses issue comments like a token without issues:write
+type forbiddenCommentBackend struct {
+	*fake.Backend
+	comments int
+}
+
+func (b *forbiddenCommentBackend) WriteToIssue(owner string, repository string, issueNumber int, comment string) error {
+	b.comments++
+	return fmt.Errorf("%w: 403 Resource not accessible by integration", api.ErrIssueCommentForbidden)
+}
+
+func TestIssueCommentForbidden(t *testing.T) {
+	backend := &forbiddenCommentBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+	t.Setenv("CI", "true")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_CONTEXT", `{"repository": {"owner": {"login": "org"}, "name": "migrations"}, "issue": {"number": 1}}`)
+	viper.Set("INCREMENTAL_ISSUE_COMMENTS", true)
+
+	r := newTestRun(t)
+	s := r.migrateRepositories([]string{"repo1", "repo2"}, nil, r.migrateRepositoryReleases, &defaultEventHandler{run: r})
+	if s.Releases != 4 || s.Failed != 0 {
+		t.Errorf("Expected the refused comments not to affect the migration, got %+v", s)
+	}
+	ReportSummary(s)
+
+	if backend.comments != 1 {
+		t.Errorf("Expected the comments to stop after the first refusal, got %d attempts", backend.comments)
+	}
+}
@@ -0,0 +1,65 @@
+package sync
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+)
+
+// parseExcludedRepositories reads the repositories excluded with EXCLUDE_REPOSITORIES, either a
+// file in the repository list format or a comma-separated list
+func parseExcludedRepositories(value string) ([]string, error) {
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		entries, err := files.ReadRepositoryListFromFile(value)
+		if err != nil {
+			return nil, err
+		}
+		var excluded []string
+		for _, entry := range entries {
+			excluded = append(excluded, entry.Repository)
+		}
+		return excluded, nil
+	}
+
+	var excluded []string
+	for _, repository := range strings.Split(value, ",") {
+		if repository = strings.TrimSpace(repository); repository != "" {
+			excluded = append(excluded, repository)
+		}
+	}
+	return excluded, nil
+}
+
+// isExcludedRepository reports whether a repository matches an excluded repository. An excluded
+// owner/name matches the repository with or without its owner, and a bare name matches the
+// repository of any owner.
+func (s *settings) isExcludedRepository(repository string, excluded string) bool {
+	owner, name := s.splitRepository(repository)
+	if !strings.Contains(excluded, "/") {
+		return strings.EqualFold(name, excluded)
+	}
+	excludedOwner, excludedName := s.splitRepository(excluded)
+	return strings.EqualFold(owner, excludedOwner) && strings.EqualFold(name, excludedName)
+}
+
+// excludeRepositories returns the repositories without the excluded ones, and the repositories
+// left out
+func (s *settings) excludeRepositories(repositories []string, excluded []string) ([]string, []string) {
+	var kept, removed []string
+	for _, repository := range repositories {
+		isExcluded := false
+		for _, exclusion := range excluded {
+			if s.isExcludedRepository(repository, exclusion) {
+				isExcluded = true
+				break
+			}
+		}
+		if isExcluded {
+			removed = append(removed, repository)
+		} else {
+			kept = append(kept, repository)
+		}
+	}
+	return kept, removed
+}
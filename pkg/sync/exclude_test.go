@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExcludeRepositories(t *testing.T) {
+	s := defaultSettings()
+	s.sourceOrganization = "source-org"
+
+	repositories := []string{"tools", "source-org/cli", "other-org/docs", "source-org/template", "other-org/tools"}
+	tests := []struct {
+		name     string
+		excluded []string
+		kept     []string
+		removed  []string
+	}{
+		{
+			name:     "bare name matches any owner",
+			excluded: []string{"tools", "Template"},
+			kept:     []string{"source-org/cli", "other-org/docs"},
+			removed:  []string{"tools", "source-org/template", "other-org/tools"},
+		},
+		{
+			name:     "owner/name matches with or without owner",
+			excluded: []string{"source-org/tools", "source-org/cli", "other-org/docs"},
+			kept:     []string{"source-org/template", "other-org/tools"},
+			removed:  []string{"tools", "source-org/cli", "other-org/docs"},
+		},
+		{
+			name:     "no match",
+			excluded: []string{"third-org/tools", "missing"},
+			kept:     repositories,
+		},
+	}
+
+	for _, tt := range tests {
+		kept, removed := s.excludeRepositories(repositories, tt.excluded)
+		if !reflect.DeepEqual(kept, tt.kept) || !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%s: got kept %v and removed %v, want %v and %v", tt.name, kept, removed, tt.kept, tt.removed)
+		}
+	}
+}
+
+func TestParseExcludedRepositories(t *testing.T) {
+	excluded, err := parseExcludedRepositories(" tools, other-org/docs ,")
+	if err != nil || !reflect.DeepEqual(excluded, []string{"tools", "other-org/docs"}) {
+		t.Errorf("Unexpected comma-separated exclusions %v: %v", excluded, err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "exclude.txt")
+	content := "https://github.com/source-org/template\nother-org/docs\n\n"
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write exclusions: %v", err)
+	}
+	excluded, err = parseExcludedRepositories(fileName)
+	if err != nil || !reflect.DeepEqual(excluded, []string{"source-org/template", "other-org/docs"}) {
+		t.Errorf("Unexpected exclusions from file %v: %v", excluded, err)
+	}
+}
@@ -0,0 +1,46 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// failedRepositories returns the repositories with failed releases as owner/name, followed by
+// their target when migrated to another organization, in the order they were migrated
+func (s *settings) failedRepositories(results []RepositoryResult) []string {
+	var repositories []string
+	for _, result := range results {
+		if result.Failed == 0 && result.Err == nil {
+			continue
+		}
+		owner, repository := s.splitRepository(result.Repository)
+		entry := owner + "/" + repository
+		if target, ok := s.repositoryTargets[result.Repository]; ok {
+			entry += " -> " + target + "/" + repository
+		}
+		repositories = append(repositories, entry)
+	}
+	return repositories
+}
+
+// writeFailuresFile writes the repositories with failed releases in the repository list format,
+// so the file can be passed as --repository-list-file to retry them. Releases and assets already
+// migrated are skipped on the next run. Without failures, the file of a previous run is removed
+// so its repositories aren't retried again.
+func (s *settings) writeFailuresFile(fileName string, results []RepositoryResult) error {
+	repositories := s.failedRepositories(results)
+	if len(repositories) == 0 {
+		if err := os.Remove(fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing failures file: %v", err)
+		}
+		return nil
+	}
+
+	err := os.WriteFile(fileName, []byte(strings.Join(repositories, "\n")+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing failures file: %v", err)
+	}
+	return nil
+}
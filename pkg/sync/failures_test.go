@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+)
+
+func TestWriteFailuresFile(t *testing.T) {
+	s := defaultSettings()
+	s.sourceOrganization = "source-org"
+
+	results := []RepositoryResult{
+		{Repository: "ok", Releases: 3},
+		{Repository: "partial", Releases: 3, Failed: 1, Err: errors.New("some releases failed to create")},
+		{Repository: "other-org/archived", Releases: 2, Failed: 2, Err: errors.New("archived")},
+		{Repository: "skipped", Releases: 1, Skipped: 2},
+	}
+
+	fileName := filepath.Join(t.TempDir(), "failures.txt")
+	if err := s.writeFailuresFile(fileName, results); err != nil {
+		t.Fatalf("writeFailuresFile returned an error: %v", err)
+	}
+
+	// The failures file can be read back as a repository list
+	repositories, err := files.ReadRepositoryListFromFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read failures file: %v", err)
+	}
+	expected := []files.RepositoryEntry{{Repository: "source-org/partial"}, {Repository: "other-org/archived"}}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
+
+func TestWriteFailuresFileWithoutFailures(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "failures.txt")
+	if err := defaultSettings().writeFailuresFile(fileName, []RepositoryResult{{Repository: "ok", Releases: 3}}); err != nil {
+		t.Fatalf("writeFailuresFile returned an error: %v", err)
+	}
+
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("Expected no failures file to be written")
+	}
+}
+
+func TestWriteFailuresFileClearsPreviousFailures(t *testing.T) {
+	s := defaultSettings()
+	s.sourceOrganization = "source-org"
+
+	fileName := filepath.Join(t.TempDir(), "failures.txt")
+	if err := s.writeFailuresFile(fileName, []RepositoryResult{{Repository: "partial", Releases: 3, Failed: 1}}); err != nil {
+		t.Fatalf("writeFailuresFile returned an error: %v", err)
+	}
+
+	// A clean retry removes the failures of the previous run
+	if err := s.writeFailuresFile(fileName, []RepositoryResult{{Repository: "partial", Releases: 3}}); err != nil {
+		t.Fatalf("writeFailuresFile returned an error: %v", err)
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("Expected the failures file of the previous run to be removed, got %v", err)
+	}
+}
+
+func TestWriteFailuresFileKeepsTargets(t *testing.T) {
+	s := defaultSettings()
+	s.sourceOrganization = "source-org"
+	if _, err := s.setRepositoryOverrides([]files.RepositoryEntry{{Repository: "tools", Target: "other-org/tools"}}); err != nil {
+		t.Fatalf("setRepositoryOverrides returned an error: %v", err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "failures.txt")
+	if err := s.writeFailuresFile(fileName, []RepositoryResult{{Repository: "tools", Releases: 1, Failed: 1}}); err != nil {
+		t.Fatalf("writeFailuresFile returned an error: %v", err)
+	}
+
+	repositories, err := files.ReadRepositoryListFromFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read failures file: %v", err)
+	}
+	expected := []files.RepositoryEntry{{Repository: "source-org/tools", Target: "other-org/tools"}}
+	if !reflect.DeepEqual(repositories, expected) {
+		t.Errorf("Expected %v, got %v", expected, repositories)
+	}
+}
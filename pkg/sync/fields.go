@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// migratableFields are the release fields that can be selected with MIGRATE_FIELDS
+var migratableFields = []string{"body", "name", "draft", "prerelease", "discussion_category", "make_latest", "assets"}
+
+// parseMigrateFields parses a comma-separated list of release fields. An empty list selects
+// every field.
+func parseMigrateFields(value string) (map[string]bool, error) {
+	fields := make(map[string]bool)
+	if strings.TrimSpace(value) == "" {
+		for _, field := range migratableFields {
+			fields[field] = true
+		}
+		return fields, nil
+	}
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		valid := false
+		for _, migratable := range migratableFields {
+			if field == migratable {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown release field %q, valid fields are: %s", field, strings.Join(migratableFields, ", "))
+		}
+		fields[field] = true
+	}
+
+	return fields, nil
+}
+
+// releasePayload builds the release sent to the target with only the selected fields, publishing
+// drafts with publishDrafts. The tag and target commitish are always sent since they identify
+// the release.
+func releasePayload(release *github.RepositoryRelease, fields map[string]bool, publishDrafts bool) *github.RepositoryRelease {
+	payload := &github.RepositoryRelease{
+		TagName:         release.TagName,
+		TargetCommitish: release.TargetCommitish,
+	}
+
+	// A nil or blank body is left out so it doesn't blank the body of an existing release
+	if fields["body"] && strings.TrimSpace(release.GetBody()) != "" {
+		payload.Body = release.Body
+	}
+	if fields["name"] {
+		payload.Name = release.Name
+	}
+	if fields["draft"] {
+		payload.Draft = release.Draft
+		// Migrated drafts can be published in the target
+		if publishDrafts && release.GetDraft() {
+			payload.Draft = github.Bool(false)
+		}
+	}
+	if fields["prerelease"] {
+		payload.Prerelease = release.Prerelease
+	}
+	if fields["discussion_category"] {
+		payload.DiscussionCategoryName = release.DiscussionCategoryName
+	}
+	if fields["make_latest"] {
+		payload.MakeLatest = release.MakeLatest
+	}
+
+	return payload
+}
@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestParseMigrateFields(t *testing.T) {
+	fields, err := parseMigrateFields("")
+	if err != nil {
+		t.Fatalf("parseMigrateFields returned an error: %v", err)
+	}
+	if len(fields) != len(migratableFields) {
+		t.Errorf("Expected all fields by default, got %v", fields)
+	}
+
+	fields, err = parseMigrateFields("body, Assets")
+	if err != nil {
+		t.Fatalf("parseMigrateFields returned an error: %v", err)
+	}
+	if len(fields) != 2 || !fields["body"] || !fields["assets"] {
+		t.Errorf("Expected body and assets, got %v", fields)
+	}
+
+	if _, err := parseMigrateFields("body,author"); err == nil {
+		t.Errorf("Expected an error for an unknown field")
+	}
+}
+
+func TestReleasePayload(t *testing.T) {
+	release := &github.RepositoryRelease{
+		ID:                     github.Int64(1),
+		TagName:                github.String("v1.0.0"),
+		TargetCommitish:        github.String("main"),
+		Name:                   github.String("Release 1.0.0"),
+		Body:                   github.String("notes"),
+		Draft:                  github.Bool(true),
+		Prerelease:             github.Bool(true),
+		DiscussionCategoryName: github.String("Announcements"),
+		MakeLatest:             github.String("false"),
+	}
+
+	payload := releasePayload(release, map[string]bool{"body": true, "assets": true}, false)
+
+	if payload.GetTagName() != "v1.0.0" || payload.GetTargetCommitish() != "main" {
+		t.Errorf("Expected the tag and commitish to always be set")
+	}
+	if payload.GetBody() != "notes" {
+		t.Errorf("Expected the body to be set")
+	}
+	if payload.Name != nil || payload.Draft != nil || payload.Prerelease != nil || payload.DiscussionCategoryName != nil || payload.MakeLatest != nil {
+		t.Errorf("Expected unselected fields not to be set: %v", payload)
+	}
+	if payload.ID != nil {
+		t.Errorf("Expected the source ID not to be copied")
+	}
+
+	payload = releasePayload(release, map[string]bool{"name": true, "draft": true, "prerelease": true, "discussion_category": true, "make_latest": true}, false)
+	if payload.Body != nil || payload.GetName() != "Release 1.0.0" || !payload.GetDraft() || !payload.GetPrerelease() || payload.GetDiscussionCategoryName() != "Announcements" || payload.GetMakeLatest() != "false" {
+		t.Errorf("Payload does not match the selected fields: %v", payload)
+	}
+}
+
+func TestReleasePayloadBlankBody(t *testing.T) {
+	for _, body := range []*string{nil, github.String(""), github.String(" \r\n\t")} {
+		release := &github.RepositoryRelease{TagName: github.String("v1.0.0"), Body: body}
+		if payload := releasePayload(release, map[string]bool{"body": true}, false); payload.Body != nil {
+			t.Errorf("Expected no body in the payload for body %q, got %q", release.GetBody(), payload.GetBody())
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package sync
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// isEmptyRelease reports whether a release looks like an auto-created tag shell:
+// no name, no body and no assets. Releases carrying assets are never empty.
+func isEmptyRelease(release *github.RepositoryRelease) bool {
+	return strings.TrimSpace(release.GetName()) == "" &&
+		strings.TrimSpace(release.GetBody()) == "" &&
+		len(release.Assets) == 0
+}
+
+// filterEmptyReleases removes empty releases from the list and returns the number removed
+func filterEmptyReleases(releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, int) {
+	var filtered []*github.RepositoryRelease
+	for _, release := range releases {
+		if isEmptyRelease(release) {
+			continue
+		}
+		filtered = append(filtered, release)
+	}
+	return filtered, len(releases) - len(filtered)
+}
+
+// filterDraftReleases removes the draft releases from the list and returns the number removed
+func filterDraftReleases(releases []*github.RepositoryRelease) ([]*github.RepositoryRelease, int) {
+	var filtered []*github.RepositoryRelease
+	for _, release := range releases {
+		if !release.GetDraft() {
+			filtered = append(filtered, release)
+		}
+	}
+	return filtered, len(releases) - len(filtered)
+}
+
+// filterReleasesByChannel keeps only prereleases when prereleasesOnly is set, otherwise only
+// stable releases, and returns the number of releases removed
+func filterReleasesByChannel(releases []*github.RepositoryRelease, prereleasesOnly bool) ([]*github.RepositoryRelease, int) {
+	var filtered []*github.RepositoryRelease
+	for _, release := range releases {
+		if release.GetPrerelease() == prereleasesOnly {
+			filtered = append(filtered, release)
+		}
+	}
+	return filtered, len(releases) - len(filtered)
+}
+
+// selectLatestRelease returns the release to mark as latest in the target. A prerelease-only
+// migration never marks a latest release. A stable-only migration falls back to the newest
+// stable release when the source latest release is not part of the migration. The source latest
+// release returned from the migrated releases has their migrated tag.
+func selectLatestRelease(releases []*github.RepositoryRelease, sourceLatest *github.RepositoryRelease, prereleasesOnly bool, stableOnly bool) *github.RepositoryRelease {
+	if prereleasesOnly {
+		return nil
+	}
+
+	if sourceLatest != nil {
+		for _, release := range releases {
+			if release.GetID() == sourceLatest.GetID() {
+				return release
+			}
+		}
+	}
+
+	if !stableOnly {
+		return sourceLatest
+	}
+
+	var newest *github.RepositoryRelease
+	for _, release := range releases {
+		if release.GetPrerelease() || release.GetDraft() {
+			continue
+		}
+		if newest == nil || release.GetPublishedAt().After(newest.GetPublishedAt().Time) {
+			newest = release
+		}
+	}
+	return newest
+}
+
+// filterReleasesByTag keeps the releases whose tag matches the glob pattern and returns the
+// number of releases excluded. An empty pattern keeps all releases.
+func filterReleasesByTag(releases []*github.RepositoryRelease, pattern string) ([]*github.RepositoryRelease, int) {
+	if pattern == "" {
+		return releases, 0
+	}
+
+	var kept []*github.RepositoryRelease
+	for _, release := range releases {
+		if matched, _ := path.Match(pattern, release.GetTagName()); matched {
+			kept = append(kept, release)
+		}
+	}
+	return kept, len(releases) - len(kept)
+}
+
+// parseTagPatterns compiles the TAG_FILTER and TAG_EXCLUDE regular expressions, nil when empty
+func parseTagPatterns(filter string, exclude string) (*regexp.Regexp, *regexp.Regexp, error) {
+	var include, excluded *regexp.Regexp
+	var err error
+	if filter != "" {
+		if include, err = regexp.Compile(filter); err != nil {
+			return nil, nil, fmt.Errorf("invalid tag filter %q: %v", filter, err)
+		}
+	}
+	if exclude != "" {
+		if excluded, err = regexp.Compile(exclude); err != nil {
+			return nil, nil, fmt.Errorf("invalid tag exclude %q: %v", exclude, err)
+		}
+	}
+	return include, excluded, nil
+}
+
+// filterReleasesByTagPattern keeps the releases whose tag matches include, when set, and doesn't
+// match exclude, when set, and returns the number of releases removed
+func filterReleasesByTagPattern(releases []*github.RepositoryRelease, include *regexp.Regexp, exclude *regexp.Regexp) ([]*github.RepositoryRelease, int) {
+	if include == nil && exclude == nil {
+		return releases, 0
+	}
+
+	var kept []*github.RepositoryRelease
+	for _, release := range releases {
+		tag := release.GetTagName()
+		if include != nil && !include.MatchString(tag) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(tag) {
+			continue
+		}
+		kept = append(kept, release)
+	}
+	return kept, len(releases) - len(kept)
+}
+
+// parseDateRange parses the RFC3339 SINCE and UNTIL dates, zero when empty
+func parseDateRange(since string, until string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if since != "" {
+		if from, err = time.Parse(time.RFC3339, since); err != nil {
+			return from, to, fmt.Errorf("invalid since date %q, expected an RFC3339 date such as 2024-01-02T15:04:05Z: %v", since, err)
+		}
+	}
+	if until != "" {
+		if to, err = time.Parse(time.RFC3339, until); err != nil {
+			return from, to, fmt.Errorf("invalid until date %q, expected an RFC3339 date such as 2024-01-02T15:04:05Z: %v", until, err)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, fmt.Errorf("until date %s is before since date %s", until, since)
+	}
+	return from, to, nil
+}
+
+// filterReleasesByDate keeps the releases published between since and until, both included when
+// set, and returns the number of releases removed. A release without a publish date, a draft, is
+// only kept with keepUnpublished.
+func filterReleasesByDate(releases []*github.RepositoryRelease, since time.Time, until time.Time, keepUnpublished bool) ([]*github.RepositoryRelease, int) {
+	if since.IsZero() && until.IsZero() {
+		return releases, 0
+	}
+
+	var kept []*github.RepositoryRelease
+	for _, release := range releases {
+		if release.PublishedAt == nil {
+			if keepUnpublished {
+				kept = append(kept, release)
+			}
+			continue
+		}
+		published := release.GetPublishedAt().Time
+		if (!since.IsZero() && published.Before(since)) || (!until.IsZero() && published.After(until)) {
+			continue
+		}
+		kept = append(kept, release)
+	}
+	return kept, len(releases) - len(kept)
+}
+
+// selectReleases applies the release filters of a repository and returns the releases to migrate
+// and the number of empty releases skipped
+func (s *settings) selectReleases(releases []*github.RepositoryRelease, options repositoryOptions, repository string) ([]*github.RepositoryRelease, int) {
+	// Skip tag-only releases if requested
+	var skipped int
+	if options.skipEmptyReleases {
+		releases, skipped = filterEmptyReleases(releases)
+		if skipped > 0 {
+			pterm.Info.Printf("Skipping %d empty releases in repository: %s\n", skipped, repository)
+		}
+	}
+
+	// Half-finished drafts are only migrated when requested
+	if !s.includeDrafts {
+		var drafts int
+		releases, drafts = filterDraftReleases(releases)
+		if drafts > 0 {
+			pterm.Info.Printf("Skipping %d draft releases in repository: %s\n", drafts, repository)
+		}
+	}
+
+	// Keep only the releases published in the date range of the run
+	var excludedByDate int
+	releases, excludedByDate = filterReleasesByDate(releases, s.since, s.until, s.includeDrafts)
+	if excludedByDate > 0 {
+		pterm.Info.Printf("Excluding %d releases published outside of the date range in repository: %s\n", excludedByDate, repository)
+	}
+
+	// Keep only the releases whose tag matches the tag patterns of the run, e.g. to leave nightly
+	// tags out
+	var excludedByPattern int
+	releases, excludedByPattern = filterReleasesByTagPattern(releases, s.tagFilter, s.tagExclude)
+	if excludedByPattern > 0 {
+		pterm.Info.Printf("Excluding %d releases by tag pattern in repository: %s\n", excludedByPattern, repository)
+	}
+
+	// Keep only the releases whose tag matches the repository tag filter
+	var excludedByTag int
+	releases, excludedByTag = filterReleasesByTag(releases, options.tagFilter)
+	if excludedByTag > 0 {
+		pterm.Info.Printf("Excluding %d releases not matching tag filter %s in repository: %s\n", excludedByTag, options.tagFilter, repository)
+	}
+
+	// Keep only stable releases or only prereleases if requested
+	if s.prereleasesOnly || s.stableOnly {
+		var excluded int
+		releases, excluded = filterReleasesByChannel(releases, s.prereleasesOnly)
+		if excluded > 0 {
+			pterm.Info.Printf("Excluding %d releases by prerelease status in repository: %s\n", excluded, repository)
+		}
+	}
+
+	return releases, skipped
+}
+
+// limitRepositories returns the first max repositories of a repository list. A max of 0 keeps
+// all repositories.
+func limitRepositories(repositories []string, max int) []string {
+	if max <= 0 || len(repositories) <= max {
+		return repositories
+	}
+	return repositories[:max]
+}
+
+// resumeAfterRepository returns the repositories of a repository list after the entry named
+// start, to resume a run that went through the list up to it. The entry is matched ignoring case,
+// like GitHub repository names, and must be in the list.
+func resumeAfterRepository(repositories []string, start string) ([]string, error) {
+	for i, repository := range repositories {
+		if strings.EqualFold(repository, start) {
+			return repositories[i+1:], nil
+		}
+	}
+	return nil, fmt.Errorf("repository %s is not in the repository list", start)
+}
@@ -0,0 +1,228 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+func TestIsEmptyRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		release *github.RepositoryRelease
+		want    bool
+	}{
+		{"tag shell", &github.RepositoryRelease{TagName: github.String("v1.0.0")}, true},
+		{"whitespace only", &github.RepositoryRelease{Name: github.String(" "), Body: github.String("\n")}, true},
+		{"named", &github.RepositoryRelease{Name: github.String("v1.0.0")}, false},
+		{"body", &github.RepositoryRelease{Body: github.String("notes")}, false},
+		{"assets only", &github.RepositoryRelease{Assets: []*github.ReleaseAsset{{Name: github.String("bin.zip")}}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isEmptyRelease(tt.release); got != tt.want {
+			t.Errorf("isEmptyRelease(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterEmptyReleases(t *testing.T) {
+	releases := []*github.RepositoryRelease{
+		{TagName: github.String("v1.0.0")},
+		{TagName: github.String("v1.1.0"), Name: github.String("v1.1.0")},
+		{TagName: github.String("v1.2.0"), Assets: []*github.ReleaseAsset{{Name: github.String("bin.zip")}}},
+	}
+
+	filtered, skipped := filterEmptyReleases(releases)
+	if skipped != 1 {
+		t.Errorf("Expected 1 skipped release, got %d", skipped)
+	}
+	if len(filtered) != 2 || filtered[0].GetTagName() != "v1.1.0" || filtered[1].GetTagName() != "v1.2.0" {
+		t.Errorf("Filtered releases do not match the expected releases")
+	}
+}
+
+func TestFilterReleasesByChannel(t *testing.T) {
+	releases := []*github.RepositoryRelease{
+		{TagName: github.String("v1.0.0")},
+		{TagName: github.String("v1.1.0-rc1"), Prerelease: github.Bool(true)},
+		{TagName: github.String("v1.1.0")},
+	}
+
+	stable, excluded := filterReleasesByChannel(releases, false)
+	if excluded != 1 || len(stable) != 2 {
+		t.Errorf("Expected 2 stable releases and 1 excluded, got %d and %d", len(stable), excluded)
+	}
+
+	prereleases, excluded := filterReleasesByChannel(releases, true)
+	if excluded != 2 || len(prereleases) != 1 || prereleases[0].GetTagName() != "v1.1.0-rc1" {
+		t.Errorf("Expected only v1.1.0-rc1 to be kept, got %d releases and %d excluded", len(prereleases), excluded)
+	}
+}
+
+func TestSelectLatestRelease(t *testing.T) {
+	older := &github.RepositoryRelease{ID: github.Int64(1), PublishedAt: &github.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	newer := &github.RepositoryRelease{ID: github.Int64(2), PublishedAt: &github.Timestamp{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}}
+	prerelease := &github.RepositoryRelease{ID: github.Int64(3), Prerelease: github.Bool(true), PublishedAt: &github.Timestamp{Time: time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)}}
+	releases := []*github.RepositoryRelease{older, newer}
+
+	// Default behavior keeps the source latest release
+	if got := selectLatestRelease(releases, older, false, false); got.GetID() != 1 {
+		t.Errorf("Expected source latest release 1, got %d", got.GetID())
+	}
+
+	// Stable only keeps the source latest release when it is migrated
+	if got := selectLatestRelease(releases, older, false, true); got.GetID() != 1 {
+		t.Errorf("Expected source latest release 1, got %d", got.GetID())
+	}
+
+	// Stable only falls back to the newest stable release
+	if got := selectLatestRelease(releases, prerelease, false, true); got.GetID() != 2 {
+		t.Errorf("Expected newest stable release 2, got %d", got.GetID())
+	}
+
+	// Prereleases only never marks a latest release
+	if got := selectLatestRelease([]*github.RepositoryRelease{prerelease}, prerelease, true, false); got != nil {
+		t.Errorf("Expected no latest release, got %d", got.GetID())
+	}
+}
+
+func TestFilterReleasesByTag(t *testing.T) {
+	releases := []*github.RepositoryRelease{
+		{TagName: github.String("v1.0.0")},
+		{TagName: github.String("v2.0.0")},
+		{TagName: github.String("v2.1.0")},
+	}
+
+	kept, excluded := filterReleasesByTag(releases, "v2.*")
+	if len(kept) != 2 || excluded != 1 {
+		t.Errorf("Expected 2 kept and 1 excluded, got %d and %d", len(kept), excluded)
+	}
+
+	kept, excluded = filterReleasesByTag(releases, "")
+	if len(kept) != 3 || excluded != 0 {
+		t.Errorf("Expected all releases without a pattern, got %d and %d", len(kept), excluded)
+	}
+}
+
+func TestFilterReleasesByTagPattern(t *testing.T) {
+	releases := []*github.RepositoryRelease{
+		{TagName: github.String("v1.0.0")},
+		{TagName: github.String("v2.0.0")},
+		{TagName: github.String("v2.1.0-nightly.20240501")},
+		{TagName: github.String("v2.1.0")},
+	}
+
+	tests := []struct {
+		filter  string
+		exclude string
+		want    string
+	}{
+		{"", "", "v1.0.0,v2.0.0,v2.1.0-nightly.20240501,v2.1.0"},
+		{`^v2\.`, "", "v2.0.0,v2.1.0-nightly.20240501,v2.1.0"},
+		{"", "nightly", "v1.0.0,v2.0.0,v2.1.0"},
+		{`^v2\.`, "nightly", "v2.0.0,v2.1.0"},
+	}
+	for _, tt := range tests {
+		include, exclude, err := parseTagPatterns(tt.filter, tt.exclude)
+		if err != nil {
+			t.Fatalf("parseTagPatterns(%q, %q) returned an error: %v", tt.filter, tt.exclude, err)
+		}
+		kept, excluded := filterReleasesByTagPattern(releases, include, exclude)
+		var tags []string
+		for _, release := range kept {
+			tags = append(tags, release.GetTagName())
+		}
+		if got := strings.Join(tags, ","); got != tt.want || excluded != len(releases)-len(kept) {
+			t.Errorf("filter %q, exclude %q: kept %q with %d excluded, want %q", tt.filter, tt.exclude, got, excluded, tt.want)
+		}
+	}
+
+	if _, _, err := parseTagPatterns("v2.(", ""); err == nil {
+		t.Error("Expected an error for an invalid tag filter")
+	}
+	if _, _, err := parseTagPatterns("", "[nightly"); err == nil {
+		t.Error("Expected an error for an invalid tag exclude")
+	}
+}
+
+func TestFilterReleasesByDate(t *testing.T) {
+	published := func(tag string, month time.Month) *github.RepositoryRelease {
+		return &github.RepositoryRelease{TagName: github.String(tag), PublishedAt: &github.Timestamp{Time: time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)}}
+	}
+	releases := []*github.RepositoryRelease{
+		published("v1.0.0", time.January),
+		published("v2.0.0", time.March),
+		published("v3.0.0", time.May),
+		{TagName: github.String("v4.0.0"), Draft: github.Bool(true)},
+	}
+
+	tests := []struct {
+		since           string
+		until           string
+		keepUnpublished bool
+		want            string
+	}{
+		{"", "", false, "v1.0.0,v2.0.0,v3.0.0,v4.0.0"},
+		{"2024-03-01T00:00:00Z", "", false, "v2.0.0,v3.0.0"},
+		{"", "2024-03-01T00:00:00Z", false, "v1.0.0,v2.0.0"},
+		{"2024-02-01T00:00:00Z", "2024-04-01T00:00:00+02:00", false, "v2.0.0"},
+		{"2024-02-01T00:00:00Z", "", true, "v2.0.0,v3.0.0,v4.0.0"},
+	}
+	for _, tt := range tests {
+		since, until, err := parseDateRange(tt.since, tt.until)
+		if err != nil {
+			t.Fatalf("parseDateRange(%q, %q) returned an error: %v", tt.since, tt.until, err)
+		}
+		kept, excluded := filterReleasesByDate(releases, since, until, tt.keepUnpublished)
+		var tags []string
+		for _, release := range kept {
+			tags = append(tags, release.GetTagName())
+		}
+		if got := strings.Join(tags, ","); got != tt.want || excluded != len(releases)-len(kept) {
+			t.Errorf("since %q, until %q: kept %q with %d excluded, want %q", tt.since, tt.until, got, excluded, tt.want)
+		}
+	}
+
+	if _, _, err := parseDateRange("2024-01-01", ""); err == nil {
+		t.Error("Expected an error for a since date without a time")
+	}
+	if _, _, err := parseDateRange("2024-05-01T00:00:00Z", "2024-01-01T00:00:00Z"); err == nil {
+		t.Error("Expected an error for an until date before the since date")
+	}
+}
+
+func TestLimitRepositories(t *testing.T) {
+	repositories := []string{"repo1", "repo2", "repo3"}
+
+	if got := limitRepositories(repositories, 2); len(got) != 2 || got[0] != "repo1" || got[1] != "repo2" {
+		t.Errorf("Expected the first 2 repositories, got %v", got)
+	}
+	if got := limitRepositories(repositories, 0); len(got) != 3 {
+		t.Errorf("Expected all repositories without a limit, got %v", got)
+	}
+	if got := limitRepositories(repositories, 5); len(got) != 3 {
+		t.Errorf("Expected all repositories with a limit above the list size, got %v", got)
+	}
+}
+
+func TestResumeAfterRepository(t *testing.T) {
+	repositories := []string{"repo1", "org/repo2", "repo3", "repo4"}
+
+	got, err := resumeAfterRepository(repositories, "ORG/Repo2")
+	if err != nil {
+		t.Fatalf("resumeAfterRepository returned an error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "repo3" || got[1] != "repo4" {
+		t.Errorf("Expected the repositories after org/repo2, got %v", got)
+	}
+
+	if got, err := resumeAfterRepository(repositories, "repo4"); err != nil || len(got) != 0 {
+		t.Errorf("Expected nothing left after the last repository, got %v and %v", got, err)
+	}
+	if _, err := resumeAfterRepository(repositories, "missing"); err == nil {
+		t.Errorf("Expected an error for a repository missing from the list")
+	}
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// releaseDate returns the publish date of a release, or its creation date for a draft
+func releaseDate(release *github.RepositoryRelease) time.Time {
+	if release.PublishedAt != nil {
+		return release.GetPublishedAt().Time
+	}
+	return release.GetCreatedAt().Time
+}
+
+// filterReleasesNewerThan keeps the releases published after cutoff and returns the number of
+// releases excluded
+func filterReleasesNewerThan(releases []*github.RepositoryRelease, cutoff time.Time) ([]*github.RepositoryRelease, int) {
+	var kept []*github.RepositoryRelease
+	for _, release := range releases {
+		if releaseDate(release).After(cutoff) {
+			kept = append(kept, release)
+		}
+	}
+	return kept, len(releases) - len(kept)
+}
+
+// targetCutoff returns the date after which source releases are missing in the target: the
+// publish date of the source release of the target latest release, or the publish date of the
+// target latest release when it has no source release. It returns false for a target without
+// releases, whose releases are all missing.
+func targetCutoff(releases []*github.RepositoryRelease, targetLatest *github.RepositoryRelease) (time.Time, bool) {
+	if targetLatest == nil {
+		return time.Time{}, false
+	}
+	// Migrated releases are published at the time of the migration
+	for _, release := range releases {
+		if release.GetTagName() == targetLatest.GetTagName() {
+			return releaseDate(release), true
+		}
+	}
+	return releaseDate(targetLatest), true
+}
+
+// newerThanTarget keeps the source releases published after the newest release of the target,
+// so a repeated sync doesn't go through the history already migrated
+func (r *run) newerThanTarget(releases []*github.RepositoryRelease, targetOrg string, repository string) []*github.RepositoryRelease {
+	targetLatest, err := r.client.GetTargetRepositoryLatestRelease(targetOrg, repository)
+	if err != nil {
+		pterm.Warning.Printf("Could not fetch the target latest release, migrating all releases: %v", err)
+		return releases
+	}
+
+	cutoff, ok := targetCutoff(releases, targetLatest)
+	if !ok {
+		pterm.Info.Printf("Target repository %s has no releases, migrating all releases\n", repository)
+		return releases
+	}
+
+	kept, excluded := filterReleasesNewerThan(releases, cutoff)
+	if excluded > 0 {
+		pterm.Info.Printf("Excluding %d releases not newer than target latest release %s in repository: %s\n", excluded, targetLatest.GetTagName(), repository)
+	}
+	return kept
+}
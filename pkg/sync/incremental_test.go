@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// growingSourceBackend hides the newest source releases, as before they were published
+type growingSourceBackend struct {
+	*fake.Backend
+	hidden   int
+	creation []string
+}
+
+func (b *growingSourceBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	return releases[b.hidden:], err
+}
+
+func (b *growingSourceBackend) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	releases, err := b.GetSourceRepositoryReleases(owner, repository)
+	return releases[0], err
+}
+
+func (b *growingSourceBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	b.creation = append(b.creation, release.GetTagName())
+	return b.Backend.CreateRelease(owner, repository, release)
+}
+
+func TestMigrateRepositoryReleasesNewerThanTarget(t *testing.T) {
+	backend := &growingSourceBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3}), hidden: 1}
+	testClient = backend
+	viper.Set("NEWER_THAN_TARGET", true)
+
+	// An empty target gets all releases
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if len(backend.creation) != 2 {
+		t.Fatalf("Expected all 2 releases to be created in the empty target, got %v", backend.creation)
+	}
+
+	// Only the release published since is created in the partially-populated target
+	backend.hidden = 0
+	backend.creation = nil
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Releases != 1 {
+		t.Fatalf("Expected 1 release to be migrated, got %d: %v", result.Releases, result.Err)
+	}
+	if len(backend.creation) != 1 || backend.creation[0] != "v3.0.0" {
+		t.Errorf("Expected only v3.0.0 to be created, got %v", backend.creation)
+	}
+
+	latest, _ := backend.GetTargetRepositoryLatestRelease("target-org", "repo")
+	if latest.GetTagName() != "v3.0.0" {
+		t.Errorf("Expected v3.0.0 to be marked as latest, got %s", latest.GetTagName())
+	}
+}
+
+func TestTargetCutoff(t *testing.T) {
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	migratedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	source := []*github.RepositoryRelease{{TagName: github.String("v1"), PublishedAt: &github.Timestamp{Time: january}}}
+
+	if _, ok := targetCutoff(source, nil); ok {
+		t.Error("Expected no cutoff for a target without releases")
+	}
+
+	// The source publish date is used rather than the date the release was migrated
+	cutoff, ok := targetCutoff(source, &github.RepositoryRelease{TagName: github.String("v1"), PublishedAt: &github.Timestamp{Time: migratedAt}})
+	if !ok || !cutoff.Equal(january) {
+		t.Errorf("Expected the source publish date as cutoff, got %v", cutoff)
+	}
+
+	cutoff, ok = targetCutoff(source, &github.RepositoryRelease{TagName: github.String("other"), PublishedAt: &github.Timestamp{Time: migratedAt}})
+	if !ok || !cutoff.Equal(migratedAt) {
+		t.Errorf("Expected the target publish date as cutoff, got %v", cutoff)
+	}
+}
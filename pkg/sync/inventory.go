@@ -0,0 +1,55 @@
+package sync
+
+import (
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+)
+
+// targetInventory holds the releases of a target repository with their assets, fetched in one
+// paginated sweep so re-runs check the existing releases locally instead of once per release
+type targetInventory struct {
+	releases map[string]*github.RepositoryRelease
+	all      []*github.RepositoryRelease
+}
+
+// loadTargetInventory fetches the releases of a target repository
+func (r *run) loadTargetInventory(owner string, repository string) (*targetInventory, error) {
+	releases, err := r.client.GetTargetRepositoryReleases(owner, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &targetInventory{releases: make(map[string]*github.RepositoryRelease, len(releases)), all: releases}
+	for _, release := range releases {
+		inventory.releases[release.GetTagName()] = release
+	}
+	return inventory, nil
+}
+
+// releaseExists checks if a release with matching tag_name, name, and target_commitish already
+// exists in the inventory, or in the target when there is no inventory
+func (r *run) releaseExists(inventory *targetInventory, owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	if inventory == nil {
+		return r.client.ReleaseExists(owner, repository, release)
+	}
+	return inventory.releaseExists(release)
+}
+
+// releaseExists checks if a release with matching tag_name, name, and target_commitish is in the
+// inventory, like api.ReleaseExists
+func (i *targetInventory) releaseExists(release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	if release == nil || release.TagName == nil {
+		return nil, false
+	}
+
+	existingRelease, ok := i.releases[release.GetTagName()]
+	if !ok {
+		// A tag differing in case is only matched with CASE_INSENSITIVE_TAGS
+		existingRelease = api.FindReleaseByTag(i.all, release.GetTagName())
+		if existingRelease == nil {
+			return nil, false
+		}
+	}
+
+	return existingRelease, api.ReleaseMatches(existingRelease, release)
+}
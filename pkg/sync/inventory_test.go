@@ -0,0 +1,87 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestTargetInventoryMatchesPerCallResults(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3})
+	r := newTestRun(t)
+	r.migrateRepositoryReleases("repo", nil)
+
+	inventory, err := r.loadTargetInventory("target-org", "repo")
+	if err != nil {
+		t.Fatalf("loadTargetInventory returned an error: %v", err)
+	}
+
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	renamed := *source[0]
+	renamed.Name = github.String("Renamed")
+	releases := append(source, &renamed, &github.RepositoryRelease{TagName: github.String("v9.9.9")}, nil)
+
+	for _, release := range releases {
+		cached, cachedExists := inventory.releaseExists(release)
+		perCall, perCallExists := backend.ReleaseExists("target-org", "repo", release)
+		if cachedExists != perCallExists || cached.GetID() != perCall.GetID() {
+			t.Errorf("Release %s: cached result (%d, %v) differs from per-call result (%d, %v)",
+				release.GetTagName(), cached.GetID(), cachedExists, perCall.GetID(), perCallExists)
+		}
+	}
+}
+
+// releaseExistsCountingBackend counts the target release lookups
+type releaseExistsCountingBackend struct {
+	*fake.Backend
+	lookups int
+}
+
+func (b *releaseExistsCountingBackend) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	b.lookups++
+	return b.Backend.ReleaseExists(owner, repository, release)
+}
+
+func TestMigrateRepositoryReleasesUsesTargetInventory(t *testing.T) {
+	backend := &releaseExistsCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3, AssetsPerRelease: 1, AssetSize: 10})}
+	testClient = backend
+
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected the re-run to succeed, got %d failed: %v", result.Failed, result.Err)
+	}
+	if backend.lookups != 0 {
+		t.Errorf("Expected releases to be checked against the inventory, got %d lookups", backend.lookups)
+	}
+	if len(backend.TargetReleases("target-org", "repo")) != 3 {
+		t.Errorf("Expected the re-run not to create releases again")
+	}
+}
+
+func TestTargetInventoryCaseInsensitiveTags(t *testing.T) {
+	existing := &github.RepositoryRelease{ID: github.Int64(1), TagName: github.String("V1.0"), Name: github.String("V1.0"), TargetCommitish: github.String("main")}
+	inventory := &targetInventory{
+		releases: map[string]*github.RepositoryRelease{"V1.0": existing},
+		all:      []*github.RepositoryRelease{existing},
+	}
+	lowercase := &github.RepositoryRelease{TagName: github.String("v1.0"), Name: github.String("v1.0"), TargetCommitish: github.String("main")}
+
+	defer viper.Reset()
+	for _, caseInsensitive := range []bool{false, true} {
+		viper.Set("CASE_INSENSITIVE_TAGS", caseInsensitive)
+
+		if _, exists := inventory.releaseExists(existing); !exists {
+			t.Errorf("Case insensitive %v: expected release V1.0 to exist", caseInsensitive)
+		}
+		found, exists := inventory.releaseExists(lowercase)
+		if exists != caseInsensitive {
+			t.Errorf("Case insensitive %v: expected release v1.0 to exist %v, got %v", caseInsensitive, caseInsensitive, exists)
+		}
+		if caseInsensitive && found.GetID() != 1 {
+			t.Errorf("Expected v1.0 to match release V1.0, got %v", found)
+		}
+	}
+}
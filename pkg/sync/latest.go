@@ -0,0 +1,55 @@
+package sync
+
+import (
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// makeLatestValue returns the make_latest value to send when creating a release. An empty value
+// keeps GitHub's default, the source latest release is then marked explicitly after the migration.
+// "false" ensures no migrated release becomes latest and "legacy" lets GitHub pick the latest
+// release from the creation date and semantic version.
+func makeLatestValue(neverMarkLatest bool, legacyLatest bool) string {
+	switch {
+	case neverMarkLatest:
+		return "false"
+	case legacyLatest:
+		return "legacy"
+	default:
+		return ""
+	}
+}
+
+// latestMarkingEnabled reports whether the source latest release is marked as latest in the
+// target after the migration. When it is not, the source latest release is not fetched.
+func latestMarkingEnabled(fields map[string]bool, neverMarkLatest bool, legacyLatest bool, prereleasesOnly bool) bool {
+	return fields["make_latest"] && !neverMarkLatest && !legacyLatest && !prereleasesOnly
+}
+
+// newerTargetLatest returns the latest release of the target when it is newer than the source
+// latest release, e.g. in an active target receiving an import of older history, or nil when the
+// source latest release can be marked as latest
+func (r *run) newerTargetLatest(releases []*github.RepositoryRelease, sourceLatest *github.RepositoryRelease, targetOrg string, repository string) *github.RepositoryRelease {
+	targetLatest, err := r.client.GetTargetRepositoryLatestRelease(targetOrg, repository)
+	if err != nil {
+		pterm.Warning.Printf("Could not fetch the target latest release: %v", err)
+		return nil
+	}
+
+	// A previously migrated release is dated by its source release
+	targetDate, ok := targetCutoff(releases, targetLatest)
+	if !ok || targetLatest.GetTagName() == sourceLatest.GetTagName() || !targetDate.After(releaseDate(sourceLatest)) {
+		return nil
+	}
+	return targetLatest
+}
+
+// targetReleaseID returns the ID of the target release with the given tag, or 0 when the target
+// has no such release
+func (r *run) targetReleaseID(targetOrg string, repository string, tagName string) int64 {
+	release, err := r.client.GetReleaseByTag(targetOrg, repository, tagName)
+	if err != nil {
+		return 0
+	}
+	return release.GetID()
+}
@@ -0,0 +1,136 @@
+package sync
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestMakeLatestValue(t *testing.T) {
+	tests := []struct {
+		neverMarkLatest bool
+		legacyLatest    bool
+		want            string
+	}{
+		{false, false, ""},
+		{true, false, "false"},
+		{false, true, "legacy"},
+	}
+
+	for _, tt := range tests {
+		if got := makeLatestValue(tt.neverMarkLatest, tt.legacyLatest); got != tt.want {
+			t.Errorf("makeLatestValue(%v, %v) = %q, want %q", tt.neverMarkLatest, tt.legacyLatest, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateRepositoryReleasesPreserveTargetLatest(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	viper.Set("PRESERVE_TARGET_LATEST", true)
+
+	// The target has a release published after all the source releases
+	active, err := backend.CreateRelease("target-org", "repo", &github.RepositoryRelease{TagName: github.String("v9.0.0"), Name: github.String("v9.0.0")})
+	if err != nil {
+		t.Fatalf("CreateRelease returned an error: %v", err)
+	}
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Releases != 2 {
+		t.Fatalf("Expected 2 releases to be migrated, got %d: %v", result.Releases, result.Err)
+	}
+	if got := backend.LatestReleaseID("target-org", "repo"); got != active.GetID() {
+		t.Errorf("Expected the target latest release to be kept, got release %d", got)
+	}
+}
+
+func TestMigrateRepositoryReleasesPreserveOlderTargetLatest(t *testing.T) {
+	backend := &growingSourceBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2}), hidden: 1}
+	testClient = backend
+	viper.Set("PRESERVE_TARGET_LATEST", true)
+
+	// A previously migrated release older than the new source latest release is replaced
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	backend.hidden = 0
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	latest, _ := backend.GetTargetRepositoryLatestRelease("target-org", "repo")
+	if latest.GetTagName() != "v2.0.0" {
+		t.Errorf("Expected v2.0.0 to be marked as latest, got %s", latest.GetTagName())
+	}
+}
+
+// designatedLatestBackend marks some source releases as prereleases and designates the source
+// latest release by tag, regardless of the publish dates
+type designatedLatestBackend struct {
+	*fake.Backend
+	prereleases map[string]bool
+	latestTag   string
+}
+
+func (b *designatedLatestBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	for _, release := range releases {
+		release.Prerelease = github.Bool(b.prereleases[release.GetTagName()])
+	}
+	return releases, err
+}
+
+func (b *designatedLatestBackend) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	releases, err := b.GetSourceRepositoryReleases(owner, repository)
+	for _, release := range releases {
+		if release.GetTagName() == b.latestTag {
+			return release, err
+		}
+	}
+	return nil, fmt.Errorf("no latest release")
+}
+
+func assertTargetLatest(t *testing.T, backend *designatedLatestBackend, want string) {
+	t.Helper()
+
+	latest, _ := backend.GetTargetRepositoryLatestRelease("target-org", "repo")
+	if latest.GetTagName() != want {
+		t.Errorf("Expected %s to be marked as latest, got %q", want, latest.GetTagName())
+	}
+}
+
+func TestMigrateRepositoryReleasesLatestIsNotNewest(t *testing.T) {
+	// The newest release is a prerelease, an older stable release is the latest
+	backend := &designatedLatestBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3}), prereleases: map[string]bool{"v3.0.0": true}, latestTag: "v1.0.0"}
+	testClient = backend
+
+	if result := newTestRun(t).migrateRepositoryReleases("repo", nil); result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+	assertTargetLatest(t, backend, "v1.0.0")
+}
+
+func TestMigrateRepositoryReleasesPrereleaseLatest(t *testing.T) {
+	backend := &designatedLatestBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3}), prereleases: map[string]bool{"v3.0.0": true}, latestTag: "v3.0.0"}
+	testClient = backend
+
+	if result := newTestRun(t).migrateRepositoryReleases("repo", nil); result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+	assertTargetLatest(t, backend, "v3.0.0")
+}
+
+func TestMigrateRepositoryReleasesLatestExcludedFromRun(t *testing.T) {
+	backend := &designatedLatestBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3}), latestTag: "v2.0.0"}
+	testClient = backend
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	// A later run excluding the latest release, migrated before, still marks it as latest
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if release.GetTagName() == "v3.0.0" {
+			backend.SetLatestRelease("target-org", "repo", release.GetID())
+		}
+	}
+	if result := newTestRun(t).migrateRepository("repo", nil, repositoryOptions{tagFilter: "v3*"}); result.Err != nil {
+		t.Fatalf("migrateRepository returned an error: %v", result.Err)
+	}
+	assertTargetLatest(t, backend, "v2.0.0")
+}
@@ -0,0 +1,394 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/mona-actions/gh-migrate-releases/internal/logging"
+	"github.com/pterm/pterm"
+	"github.com/spf13/viper"
+)
+
+// Migrator migrates the releases of a set of repositories. Its per-release settings, such as the
+// migrated fields or the release filters, are read from viper when the Migrator is built. They
+// belong to the Migrator with its client, release transform and state, so Migrators of the same
+// process don't share them; the output, the API request counters and the REST client settings,
+// such as the retry budget or the rate limits, are shared by the process.
+type Migrator struct {
+	source         api.Client
+	target         api.Client
+	repositories   []string
+	concurrency    int
+	dryRun         bool
+	handler        EventHandler
+	twoPhase       bool
+	checkpointFile string
+	output         io.Writer
+	transform      ReleaseTransform
+	retryManifest  string
+	stateFile      string
+	settings       *settings
+	// settingsErr is the error of the invalid settings, returned by Migrate
+	settingsErr error
+}
+
+// Option configures a Migrator
+type Option func(*Migrator)
+
+// NewMigrator returns a Migrator using the GitHub REST API for the source and the target
+func NewMigrator(options ...Option) *Migrator {
+	s, err := loadSettings()
+	m := &Migrator{
+		source:         api.NewRESTClient(),
+		target:         api.NewRESTClient(),
+		checkpointFile: "phase-checkpoint.json",
+		settings:       s,
+		settingsErr:    err,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// WithClient reads the source and writes the target with the same client
+func WithClient(client api.Client) Option {
+	return func(m *Migrator) {
+		m.source = client
+		m.target = client
+	}
+}
+
+// WithSourceClient sets the client reading the source releases and downloading their assets
+func WithSourceClient(client api.Client) Option {
+	return func(m *Migrator) {
+		m.source = client
+	}
+}
+
+// WithTargetClient sets the client creating the target releases and uploading their assets
+func WithTargetClient(client api.Client) Option {
+	return func(m *Migrator) {
+		m.target = client
+	}
+}
+
+// WithRepositories sets the repositories to migrate, as name or owner/name
+func WithRepositories(repositories ...string) Option {
+	return func(m *Migrator) {
+		m.repositories = repositories
+	}
+}
+
+// WithConcurrency sets the number of repositories whose releases are fetched concurrently ahead
+// of the migration. With 0, the default, each repository is fetched when it is migrated.
+func WithConcurrency(concurrency int) Option {
+	return func(m *Migrator) {
+		m.concurrency = concurrency
+	}
+}
+
+// WithDryRun only logs the changes the migration would make to the target
+func WithDryRun(dryRun bool) Option {
+	return func(m *Migrator) {
+		m.dryRun = dryRun
+	}
+}
+
+// WithEventHandler sets the handler notified as each repository is migrated. With nil, the
+// default, the progress is printed and commented on the configured issue.
+func WithEventHandler(handler EventHandler) Option {
+	return func(m *Migrator) {
+		m.handler = handler
+	}
+}
+
+// WithTwoPhase creates the releases of all repositories before migrating their assets, recording
+// the progress in checkpointFile
+func WithTwoPhase(checkpointFile string) Option {
+	return func(m *Migrator) {
+		m.twoPhase = true
+		m.checkpointFile = checkpointFile
+	}
+}
+
+// WithOutput writes the progress and the summary of the migration to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(m *Migrator) {
+		m.output = w
+	}
+}
+
+// WithReleaseTransform modifies each release with transform before it is looked up and created in
+// the target, after the body mapping and templates are applied. A release the transform fails on
+// is counted as failed.
+func WithReleaseTransform(transform ReleaseTransform) Option {
+	return func(m *Migrator) {
+		m.transform = transform
+	}
+}
+
+// WithRetryManifest only transfers again the assets listed in a failed assets file of a prior
+// run, instead of migrating the releases of the repositories
+func WithRetryManifest(fileName string) Option {
+	return func(m *Migrator) {
+		m.retryManifest = fileName
+	}
+}
+
+// WithStateFile records each release migrated with its assets in stateFile, and skips the
+// releases already recorded there by an interrupted run
+func WithStateFile(fileName string) Option {
+	return func(m *Migrator) {
+		m.stateFile = fileName
+	}
+}
+
+// withSettings sets the per-release settings of the Migrator, parsed from the viper configuration
+func withSettings(s *settings) Option {
+	return func(m *Migrator) {
+		m.settings = s
+		m.settingsErr = nil
+	}
+}
+
+// useOutput routes the output to the writer of the Migrator, if any, and returns the function
+// restoring the previous writer
+func (m *Migrator) useOutput() func() {
+	if m.output == nil {
+		return func() {}
+	}
+	previous := logging.Output()
+	logging.SetOutput(m.output)
+	return func() { logging.SetOutput(previous) }
+}
+
+// backend returns the client used by the migration
+func (m *Migrator) backend() api.Client {
+	var backend api.Client = splitClient{source: m.source, target: m.target}
+	if m.source == m.target {
+		backend = m.source
+	}
+	if m.dryRun {
+		backend = dryRunClient{Client: backend}
+	}
+	return backend
+}
+
+// Migrate migrates the releases of the repositories and returns the summary of the run. Once ctx
+// is cancelled, the remaining repositories fail with the context error, which is returned.
+func (m *Migrator) Migrate(ctx context.Context) (Summary, error) {
+	if m.settingsErr != nil {
+		return Summary{}, m.settingsErr
+	}
+	var retries []failedAsset
+	if m.retryManifest != "" {
+		var err error
+		retries, err = loadRetryManifest(m.retryManifest)
+		if err != nil {
+			return Summary{}, err
+		}
+		m.repositories = retryRepositories(retries)
+	}
+	if len(m.repositories) == 0 {
+		return Summary{}, fmt.Errorf("no repository or repository list specified")
+	}
+	r := newRun(m.backend(), m.settings)
+	r.transform = m.transform
+	r.dryRun = m.dryRun
+	if m.stateFile != "" {
+		state, err := loadMigrationState(m.stateFile, m.dryRun)
+		if err != nil {
+			return Summary{}, err
+		}
+		r.state = state
+	}
+	handler := m.handler
+	if handler == nil {
+		handler = &defaultEventHandler{run: r}
+	}
+	defer m.useOutput()()
+	// Leftover downloads, e.g. of failed uploads, are removed with the temp directory of the run
+	defer func() {
+		if err := api.RemoveTempDir(); err != nil {
+			pterm.Warning.Printf("%v\n", err)
+		}
+	}()
+	start := r.timings.now()
+
+	// Fetch the releases of all repositories concurrently before migrating them
+	var prefetched map[string]repositoryReleases
+	if m.concurrency > 0 {
+		prefetchSpinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Fetching releases from %d repositories...", len(m.repositories)))
+		prefetched = m.settings.prefetchReleases(m.repositories, m.concurrency, r.fetchRepositoryReleases)
+		prefetchSpinner.Success()
+	}
+
+	migrate := func(repository string, fetched *repositoryReleases) RepositoryResult {
+		if err := ctx.Err(); err != nil {
+			return RepositoryResult{Repository: repository, Err: err}
+		}
+		if m.twoPhase {
+			return r.migrateRepositoryReleasesWithoutAssets(repository, fetched)
+		}
+		return r.migrateRepositoryReleases(repository, fetched)
+	}
+
+	var summary Summary
+	if m.retryManifest != "" {
+		// Only repair the failed assets of a prior run
+		retry := r.retryFailedAssets(retries)
+		summary = r.migrateRepositories(m.repositories, nil, func(repository string, fetched *repositoryReleases) RepositoryResult {
+			if err := ctx.Err(); err != nil {
+				return RepositoryResult{Repository: repository, Err: err}
+			}
+			return retry(repository, fetched)
+		}, handler)
+	} else if m.twoPhase {
+		// Create all releases first, then migrate all assets
+		migrateAssets := func(repository string) RepositoryResult {
+			if err := ctx.Err(); err != nil {
+				return RepositoryResult{Repository: repository, Err: err}
+			}
+			return r.migrateRepositoryAssets(repository)
+		}
+		var err error
+		summary, err = r.migrateInPhases(m.repositories, prefetched, migrate, migrateAssets, handler, m.checkpointFile)
+		if err != nil {
+			return summary, err
+		}
+	} else {
+		summary = r.migrateRepositories(m.repositories, prefetched, migrate, handler)
+	}
+
+	summary.Elapsed = r.timings.now().Sub(start)
+
+	// Write the results coalesced since the last edit of the progress comment
+	r.flushIssueProgress()
+
+	// The deduplicated assets are only kept for the run
+	if m.settings.dedupeAssets && !m.settings.assetCache {
+		if err := api.ClearRunCache(); err != nil {
+			pterm.Warning.Printf("Error removing deduplicated assets: %v\n", err)
+		}
+	}
+
+	return summary, ctx.Err()
+}
+
+// ReportSummary reports the summary of a run like ReportSummary, to the writer of the Migrator
+func (m *Migrator) ReportSummary(summary Summary) {
+	defer m.useOutput()()
+	ReportSummary(summary)
+}
+
+// NewMigratorFromConfig validates the viper configuration, applies its global settings and
+// returns the Migrator it describes
+func NewMigratorFromConfig() (*Migrator, error) {
+	// Read the tokens from files or a secrets manager
+	if err := api.ResolveTokens(); err != nil {
+		return nil, err
+	}
+
+	if err := checkVars(); err != nil {
+		return nil, err
+	}
+	s, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validated by checkVars
+	customHeaders, _ := api.ParseCustomHeaders(viper.GetString("CUSTOM_HEADERS"))
+	api.SetCustomHeaders(customHeaders)
+
+	backend, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	if err := api.SetTempDir(viper.GetString("TEMP_DIR")); err != nil {
+		return nil, err
+	}
+	api.SetRetryBudget(viper.GetInt("MAX_TOTAL_RETRIES"))
+	api.SetRetryBaseDelay(viper.GetDuration("RETRY_BASE_DELAY"))
+	api.SetBandwidthLimit(viper.GetInt64("RATE_LIMIT_BYTES_PER_SEC"))
+	api.SetRequestRateLimit(viper.GetInt64("RATE_LIMIT_REQUESTS_PER_SEC"))
+
+	options := []Option{WithClient(backend), WithEventHandler(eventHandler), withSettings(s)}
+
+	// The repositories are read from the retry manifest
+	if viper.GetString("RETRY_MANIFEST") != "" {
+		options = append(options, WithRetryManifest(viper.GetString("RETRY_MANIFEST")))
+		return NewMigrator(options...), nil
+	}
+
+	var repositories []string
+	if viper.GetString("REPOSITORY_LIST") != "" {
+		// Read repository list from file
+		entries, err := files.ReadRepositoryListFromFile(viper.GetString("REPOSITORY_LIST"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading repository list: %v", err)
+		}
+		repositories, err = s.setRepositoryOverrides(entries)
+		if err != nil {
+			return nil, fmt.Errorf("error reading repository list: %v", err)
+		}
+		if err := checkEntryTargetsConfirmation(viper.GetString("CONFIRM_TARGET"), s.repositoryTargets); err != nil {
+			return nil, err
+		}
+		// Resume a run that stopped after a repository of the list
+		if start := viper.GetString("START_FROM_REPO"); start != "" {
+			total := len(repositories)
+			repositories, err = resumeAfterRepository(repositories, start)
+			if err != nil {
+				return nil, err
+			}
+			pterm.Info.Printf("Skipping the first %d of %d repositories in the repository list, up to %s\n", total-len(repositories), total, start)
+		}
+		options = append(options, WithConcurrency(viper.GetInt("PREFETCH_CONCURRENCY")))
+	} else if viper.GetString("REPOSITORY") != "" {
+		// Migrate releases from a single repository
+		repositories = []string{viper.GetString("REPOSITORY")}
+	} else if viper.GetString("SOURCE_ORGANIZATION") != "" {
+		// Migrate releases from every repository of the source organization
+		repositories, err = discoverRepositories(backend, viper.GetString("SOURCE_ORGANIZATION"), viper.GetBool("SKIP_ARCHIVED"), viper.GetBool("SKIP_FORKS"))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, WithConcurrency(viper.GetInt("PREFETCH_CONCURRENCY")))
+	} else {
+		return nil, fmt.Errorf("no repository, repository list or source organization specified")
+	}
+
+	// Leave out repositories such as archived ones or templates, e.g. from a generated list
+	if viper.GetString("EXCLUDE_REPOSITORIES") != "" {
+		excluded, err := parseExcludedRepositories(viper.GetString("EXCLUDE_REPOSITORIES"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading excluded repositories: %v", err)
+		}
+		var removed []string
+		repositories, removed = s.excludeRepositories(repositories, excluded)
+		if len(removed) > 0 {
+			pterm.Info.Printf("Excluding %d repositories: %s\n", len(removed), strings.Join(removed, ", "))
+		}
+	}
+
+	// Only process the first repositories of the list for a staged rollout
+	if maxRepos := viper.GetInt("MAX_REPOS"); maxRepos > 0 && len(repositories) > maxRepos {
+		pterm.Info.Printf("Processing the first %d of %d repositories in the repository list\n", maxRepos, len(repositories))
+		repositories = limitRepositories(repositories, maxRepos)
+	}
+	options = append(options, WithRepositories(repositories...))
+
+	if viper.GetString("STATE_FILE") != "" {
+		options = append(options, WithStateFile(viper.GetString("STATE_FILE")))
+	}
+	if viper.GetBool("TWO_PHASE") {
+		options = append(options, WithTwoPhase(viper.GetString("PHASE_CHECKPOINT")))
+	}
+	return NewMigrator(options...), nil
+}
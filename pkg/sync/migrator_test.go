@@ -0,0 +1,303 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/mona-actions/gh-migrate-releases/internal/logging"
+	"github.com/spf13/viper"
+)
+
+func TestMigratorMigrate(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 10})
+	handler := &recordingEventHandler{}
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo1", "repo2"), WithConcurrency(2), WithEventHandler(handler))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if summary.Releases != 4 || summary.Failed != 0 || len(summary.Results) != 2 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if len(handler.results) != 2 {
+		t.Errorf("Expected the handler to be notified of 2 repositories, got %d", len(handler.results))
+	}
+	for _, repository := range []string{"repo1", "repo2"} {
+		if got := len(backend.TargetReleases("target-org", repository)); got != 2 {
+			t.Errorf("Expected 2 releases in %s, got %d", repository, got)
+		}
+	}
+}
+
+func TestMigratorSourceAndTargetClients(t *testing.T) {
+	source := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	target := fake.New(fake.Scenario{})
+
+	migrator := NewMigrator(WithSourceClient(source), WithTargetClient(target), WithRepositories("repo"), WithEventHandler(&recordingEventHandler{}))
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if got := len(target.TargetReleases("target-org", "repo")); got != 2 {
+		t.Errorf("Expected 2 releases created with the target client, got %d", got)
+	}
+	if got := len(source.TargetReleases("target-org", "repo")); got != 0 {
+		t.Errorf("Expected no release created with the source client, got %d", got)
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 10})
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithDryRun(true), WithEventHandler(&recordingEventHandler{}))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if summary.Releases != 2 || summary.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")); got != 0 {
+		t.Errorf("Expected no release to be created in a dry run, got %d", got)
+	}
+}
+
+func TestMigratorsKeepTheirSettings(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3})
+
+	// Each Migrator keeps the settings it was built with and its own transform
+	viper.Set("TAG_FILTER", "^v1")
+	first := NewMigrator(WithClient(backend), WithRepositories("repo1"), WithReleaseTransform(legacyTransform), WithEventHandler(&recordingEventHandler{}))
+	viper.Set("TAG_FILTER", "^v3")
+	second := NewMigrator(WithClient(backend), WithRepositories("repo2"), WithEventHandler(&recordingEventHandler{}))
+	for _, migrator := range []*Migrator{second, first} {
+		if _, err := migrator.Migrate(context.Background()); err != nil {
+			t.Fatalf("Migrate returned an error: %v", err)
+		}
+	}
+
+	for repository, want := range map[string]string{"repo1": "legacy-v1.0.0", "repo2": "v3.0.0"} {
+		releases := backend.TargetReleases("target-org", repository)
+		if len(releases) != 1 || releases[0].GetTagName() != want {
+			t.Errorf("Expected only %s in %s, got %v", want, repository, releases)
+		}
+	}
+}
+
+func TestMigratorCancelled(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithEventHandler(&recordingEventHandler{}))
+	summary, err := migrator.Migrate(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a context cancellation error, got %v", err)
+	}
+	if len(summary.Results) != 1 || !errors.Is(summary.Results[0].Err, context.Canceled) {
+		t.Errorf("Expected the repository to fail with the cancellation, got %+v", summary.Results)
+	}
+	if got := len(backend.TargetReleases("target-org", "repo")); got != 0 {
+		t.Errorf("Expected no release to be created, got %d", got)
+	}
+}
+
+func TestMigratorWithoutRepositories(t *testing.T) {
+	useFakeBackend(t, fake.Scenario{})
+
+	if _, err := NewMigrator().Migrate(context.Background()); err == nil {
+		t.Errorf("Expected an error without repositories")
+	}
+}
+
+// lockedBuffer is a buffer safe for the concurrent writes of the spinners
+type lockedBuffer struct {
+	mu  gosync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestMigratorOutput(t *testing.T) {
+	t.Setenv("CI", "")
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	output := &lockedBuffer{}
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithOutput(output))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	migrator.ReportSummary(summary)
+
+	for _, expected := range []string{"repo: 2 releases, 2 succeeded, 0 failed", "Total Releases: 2", "Failed: 0"} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Expected the output to contain %q, got:\n%s", expected, output.String())
+		}
+	}
+	if logging.Output() != os.Stdout {
+		t.Errorf("Expected the output to be restored to stdout after the migration")
+	}
+}
+
+func TestMigratorReleaseTransform(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3, AssetsPerRelease: 1, AssetSize: 10})
+
+	transform := func(release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+		if release.GetTagName() == "v2.0.0" {
+			return nil, errors.New("unsupported release")
+		}
+		release.TagName = github.String("legacy-" + release.GetTagName())
+		return release, nil
+	}
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithReleaseTransform(transform), WithEventHandler(&recordingEventHandler{}))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+
+	if summary.Failed != 1 {
+		t.Errorf("Expected the release the transform failed on to be counted as failed, got %d failures", summary.Failed)
+	}
+	var tags []string
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		tags = append(tags, release.GetTagName())
+		if len(release.Assets) != 1 {
+			t.Errorf("Expected the asset of %s to be migrated, got %d assets", release.GetTagName(), len(release.Assets))
+		}
+	}
+	if strings.Join(tags, ",") != "legacy-v1.0.0,legacy-v3.0.0" {
+		t.Errorf("Expected the rewritten tags, got %v", tags)
+	}
+}
+
+// legacyTransform renames the tag of a copy of every release
+func legacyTransform(release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	renamed := *release
+	renamed.TagName = github.String("legacy-" + release.GetTagName())
+	return &renamed, nil
+}
+
+func TestMigratorReleaseTransformTwoPhase(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 2, AssetSize: 10})
+
+	// The asset phase finds the releases renamed by the release phase
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithReleaseTransform(legacyTransform), WithTwoPhase(checkpoint), WithEventHandler(&recordingEventHandler{}))
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if summary.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if !strings.HasPrefix(release.GetTagName(), "legacy-") || len(release.Assets) != 2 {
+			t.Errorf("Expected the assets of %s to be migrated, got %d assets", release.GetTagName(), len(release.Assets))
+		}
+	}
+}
+
+func TestMigratorReleaseTransformRetryManifest(t *testing.T) {
+	backend := &uploadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 2, AssetSize: 10}), failing: "asset-2.zip"}
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithReleaseTransform(legacyTransform), WithEventHandler(&recordingEventHandler{}))
+	prior, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	manifest := filepath.Join(t.TempDir(), "failed-assets.json")
+	if err := writeFailedAssetsFile(manifest, prior.run.recordedFailedAssets()); err != nil {
+		t.Fatalf("writeFailedAssetsFile returned an error: %v", err)
+	}
+
+	// The manifest records both tags, the retry finds the source and the renamed target release
+	assets, err := loadRetryManifest(manifest)
+	if err != nil {
+		t.Fatalf("loadRetryManifest returned an error: %v", err)
+	}
+	if len(assets) != 1 || assets[0].Tag != "legacy-v1.0.0" || assets[0].SourceTag != "v1.0.0" {
+		t.Fatalf("Unexpected failed assets: %+v", assets)
+	}
+	backend.failing = ""
+	backend.uploads = nil
+	summary, err := NewMigrator(WithClient(backend), WithRetryManifest(manifest), WithEventHandler(&recordingEventHandler{})).Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if summary.Releases != 1 || summary.Failed != 0 || strings.Join(backend.uploads, ",") != "asset-2.zip" {
+		t.Errorf("Expected the failed asset to be uploaded again, got %+v and uploads %v", summary, backend.uploads)
+	}
+	if releases := backend.TargetReleases("target-org", "repo"); len(releases) != 1 || len(releases[0].Assets) != 2 {
+		t.Errorf("Expected the renamed release to have both assets, got %+v", releases)
+	}
+}
+
+func TestMigratorReleaseTransformLatest(t *testing.T) {
+	backend := &designatedLatestBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3}), latestTag: "v1.0.0"}
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithReleaseTransform(legacyTransform), WithEventHandler(&recordingEventHandler{}))
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	assertTargetLatest(t, backend, "legacy-v1.0.0")
+
+	// A later run excluding the latest release, migrated before, looks it up with its renamed tag
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if release.GetTagName() == "legacy-v3.0.0" {
+			backend.SetLatestRelease("target-org", "repo", release.GetID())
+		}
+	}
+	testClient = backend
+	r := newTestRun(t)
+	r.transform = legacyTransform
+	if result := r.migrateRepository("repo", nil, repositoryOptions{tagFilter: "v3*"}); result.Err != nil {
+		t.Fatalf("migrateRepository returned an error: %v", result.Err)
+	}
+	assertTargetLatest(t, backend, "legacy-v1.0.0")
+}
+
+func TestMigratorRemovesTempDir(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 1, AssetSize: 10})
+	if err := api.SetTempDir(t.TempDir()); err != nil {
+		t.Fatalf("SetTempDir returned an error: %v", err)
+	}
+	t.Cleanup(func() { api.SetTempDir("") })
+
+	// A file left by a failed upload
+	dir := api.TempDir()
+	if err := os.WriteFile(dir+"/leftover.zip", []byte("contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithEventHandler(&recordingEventHandler{}))
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the temp directory of the run to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+)
+
+// repositoryOptions are the settings of a repository migration. They default to the global
+// flags and can be overridden per repository in the repository list.
+type repositoryOptions struct {
+	skipAssets        bool
+	skipEmptyReleases bool
+	tagFilter         string
+}
+
+// defaultRepositoryOptions returns the options set by the global flags
+func (s *settings) defaultRepositoryOptions() repositoryOptions {
+	return repositoryOptions{
+		skipAssets:        !s.fields["assets"],
+		skipEmptyReleases: s.skipEmptyReleases,
+	}
+}
+
+// applyOverrides returns options with the overrides of a repository list line applied
+func applyOverrides(options repositoryOptions, overrides map[string]string) (repositoryOptions, error) {
+	// Apply in a stable order so errors are reported consistently
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := overrides[key]
+		var err error
+		switch key {
+		case "skip_assets":
+			options.skipAssets, err = strconv.ParseBool(value)
+		case "skip_empty_releases":
+			options.skipEmptyReleases, err = strconv.ParseBool(value)
+		case "tag_filter":
+			_, err = path.Match(value, "")
+			options.tagFilter = value
+		default:
+			return options, fmt.Errorf("unknown repository override %q, expected one of skip_assets, skip_empty_releases, tag_filter", key)
+		}
+		if err != nil {
+			return options, fmt.Errorf("invalid value %q for repository override %s: %v", value, key, err)
+		}
+	}
+
+	return options, nil
+}
+
+// setRepositoryOverrides validates the overrides of a repository list and records them for the
+// migration, returning the repository entries
+func (s *settings) setRepositoryOverrides(entries []files.RepositoryEntry) ([]string, error) {
+	s.repositoryOverrides = map[string]map[string]string{}
+	s.repositoryTargets = map[string]string{}
+
+	var repositories []string
+	for _, entry := range entries {
+		if _, err := applyOverrides(s.defaultRepositoryOptions(), entry.Overrides); err != nil {
+			return nil, fmt.Errorf("%s: %v", entry.Repository, err)
+		}
+		if len(entry.Overrides) > 0 {
+			s.repositoryOverrides[entry.Repository] = entry.Overrides
+		}
+		if entry.Target != "" {
+			owner, err := targetOwner(entry.Repository, entry.Target)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", entry.Repository, err)
+			}
+			s.repositoryTargets[entry.Repository] = owner
+		}
+		repositories = append(repositories, entry.Repository)
+	}
+	return repositories, nil
+}
+
+// targetOwner returns the owner of the target of a repository list entry, given as owner/name.
+// Releases are migrated to the repository with the same name, which the target must keep.
+func targetOwner(repository string, target string) (string, error) {
+	owner, name, found := strings.Cut(target, "/")
+	if !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("invalid target repository %q, expected owner/name", target)
+	}
+	sourceName := repositoryName(repository)
+	if !strings.EqualFold(name, sourceName) {
+		return "", fmt.Errorf("target repository %q must keep the name of the source repository %s", target, sourceName)
+	}
+	return owner, nil
+}
+
+// targetOrg returns the owner a repository entry is migrated to: the target of its repository
+// list entry, or the target organization
+func (s *settings) targetOrg(repository string) string {
+	if owner, ok := s.repositoryTargets[repository]; ok {
+		return owner
+	}
+	return s.targetOrganization
+}
+
+// optionsForRepository returns the options of a repository entry, validated by setRepositoryOverrides
+func (s *settings) optionsForRepository(repository string) repositoryOptions {
+	options, _ := applyOverrides(s.defaultRepositoryOptions(), s.repositoryOverrides[repository])
+	return options
+}
@@ -0,0 +1,120 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/spf13/viper"
+)
+
+func TestApplyOverridesPrecedence(t *testing.T) {
+	viper.Set("SKIP_EMPTY_RELEASES", true)
+	viper.Set("MIGRATE_FIELDS", "body,assets")
+	defer viper.Reset()
+
+	defaults := newTestRun(t).settings.defaultRepositoryOptions()
+	if defaults.skipAssets || !defaults.skipEmptyReleases || defaults.tagFilter != "" {
+		t.Fatalf("Unexpected defaults: %+v", defaults)
+	}
+
+	// Overrides win over the global flags, the other options keep their defaults
+	options, err := applyOverrides(defaults, map[string]string{"skip_assets": "true", "tag_filter": "v2.*"})
+	if err != nil {
+		t.Fatalf("applyOverrides returned an error: %v", err)
+	}
+	if !options.skipAssets || !options.skipEmptyReleases || options.tagFilter != "v2.*" {
+		t.Errorf("Unexpected options: %+v", options)
+	}
+
+	options, err = applyOverrides(defaults, map[string]string{"skip_empty_releases": "false"})
+	if err != nil || options.skipEmptyReleases {
+		t.Errorf("Expected skip_empty_releases=false to override the global flag, got %+v %v", options, err)
+	}
+}
+
+func TestApplyOverridesInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"unknown": "true"},
+		{"skip_assets": "maybe"},
+		{"tag_filter": "v2.["},
+	}
+
+	for _, overrides := range tests {
+		if _, err := applyOverrides(repositoryOptions{}, overrides); err == nil {
+			t.Errorf("Expected an error for overrides %v", overrides)
+		}
+	}
+}
+
+func TestMigrateRepositoryReleasesWithOverrides(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3, AssetsPerRelease: 1, AssetSize: 10})
+
+	r := newTestRun(t)
+	_, err := r.settings.setRepositoryOverrides([]files.RepositoryEntry{
+		{Repository: "repo", Overrides: map[string]string{"skip_assets": "true", "tag_filter": "v2.*"}},
+		{Repository: "other"},
+	})
+	if err != nil {
+		t.Fatalf("setRepositoryOverrides returned an error: %v", err)
+	}
+
+	r.migrateRepositoryReleases("repo", nil)
+	target := backend.TargetReleases("target-org", "repo")
+	if len(target) != 1 || target[0].GetTagName() != "v2.0.0" || len(target[0].Assets) != 0 {
+		t.Errorf("Expected only v2.0.0 without assets, got %v", target)
+	}
+
+	// Repositories without overrides use the global flags
+	r.migrateRepositoryReleases("other", nil)
+	target = backend.TargetReleases("target-org", "other")
+	if len(target) != 3 || len(target[0].Assets) != 1 {
+		t.Errorf("Expected all releases with their assets, got %v", target)
+	}
+}
+
+func TestMigrateRepositoryReleasesToEntryTargets(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 10})
+
+	r := newTestRun(t)
+	_, err := r.settings.setRepositoryOverrides([]files.RepositoryEntry{
+		{Repository: "source-org/tools", Target: "other-org/tools"},
+		{Repository: "cli"},
+	})
+	if err != nil {
+		t.Fatalf("setRepositoryOverrides returned an error: %v", err)
+	}
+
+	summary := r.migrateRepositories([]string{"source-org/tools", "cli"}, nil, func(repository string, fetched *repositoryReleases) RepositoryResult {
+		return r.migrateRepositoryReleases(repository, fetched)
+	}, &recordingEventHandler{})
+	if summary.Failed != 0 {
+		t.Fatalf("Expected no failure, got %+v", summary)
+	}
+
+	// The entry with a target goes to its owner, with its assets and latest release
+	target := backend.TargetReleases("other-org", "tools")
+	if len(target) != 2 || len(target[0].Assets) != 1 {
+		t.Errorf("Expected the releases of tools with their assets in other-org, got %v", target)
+	}
+	if backend.LatestReleaseID("other-org", "tools") == 0 {
+		t.Errorf("Expected the latest release to be marked in other-org")
+	}
+	if got := len(backend.TargetReleases("target-org", "tools")); got != 0 {
+		t.Errorf("Expected no release of tools in the target organization, got %d", got)
+	}
+
+	// The other entries use the target organization
+	if got := len(backend.TargetReleases("target-org", "cli")); got != 2 {
+		t.Errorf("Expected the releases of cli in the target organization, got %d", got)
+	}
+}
+
+func TestSetRepositoryOverridesInvalidTargets(t *testing.T) {
+	s := defaultSettings()
+	for _, target := range []string{"other-org", "other-org/renamed", "/tools", "other-org/tools/extra"} {
+		if _, err := s.setRepositoryOverrides([]files.RepositoryEntry{{Repository: "org/tools", Target: target}}); err == nil {
+			t.Errorf("Expected an error for target %q", target)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// Strategies, selected with OVERSIZE_ASSET_STRATEGY, for an asset of MAX_ASSET_SIZE or larger
+const (
+	oversizeAssetSkip  = "skip"
+	oversizeAssetSplit = "split"
+	oversizeAssetFail  = "fail"
+)
+
+var oversizeAssetStrategies = []string{oversizeAssetSkip, oversizeAssetSplit, oversizeAssetFail}
+
+// parseOversizeAssetStrategy validates an OVERSIZE_ASSET_STRATEGY value, defaulting to skipping the asset
+func parseOversizeAssetStrategy(value string) (string, error) {
+	strategy := strings.ToLower(strings.TrimSpace(value))
+	if strategy == "" {
+		return oversizeAssetSkip, nil
+	}
+	for _, valid := range oversizeAssetStrategies {
+		if strategy == valid {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unknown oversize asset strategy %q, valid strategies are: %s", value, strings.Join(oversizeAssetStrategies, ", "))
+}
+
+// isOversizeAsset reports whether an asset is too large to be uploaded as is
+func isOversizeAsset(asset *github.ReleaseAsset, maxSize int64) bool {
+	return maxSize > 0 && int64(asset.GetSize()) >= maxSize
+}
+
+func (r *run) recordOversizeAsset(repository string, release *github.RepositoryRelease, asset *github.ReleaseAsset) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.oversizeAssets = append(r.oversizeAssets, fmt.Sprintf("%s@%s: %s (%d bytes)", repository, release.GetTagName(), asset.GetName(), asset.GetSize()))
+}
+
+// recordedOversizeAssets returns the oversize assets recorded during the run
+func (r *run) recordedOversizeAssets() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.oversizeAssets...)
+}
+
+// migrateOversizeAsset handles an asset too large to be uploaded with the given strategy and
+// reports whether it succeeded. A skipped asset is reported but doesn't fail.
+func (r *run) migrateOversizeAsset(repository string, release *github.RepositoryRelease, newRelease *github.RepositoryRelease, asset *github.ReleaseAsset, digest string, strategy string, maxSize int64) bool {
+	switch strategy {
+	case oversizeAssetSkip:
+		r.recordOversizeAsset(repository, release, asset)
+		pterm.Warning.Printf("Skipping asset %s of release %s: its size of %d bytes is over the maximum asset size of %d bytes", asset.GetName(), release.GetName(), asset.GetSize(), maxSize)
+		return true
+	case oversizeAssetFail:
+		r.recordOversizeAsset(repository, release, asset)
+		pterm.Error.Printf("Asset %s of release %s is too large to upload: its size of %d bytes is over the maximum asset size of %d bytes", asset.GetName(), release.GetName(), asset.GetSize(), maxSize)
+		return false
+	}
+
+	// The manifest is uploaded last, once all the parts are
+	manifestName := api.SplitManifestName(asset.GetName())
+	for _, existing := range newRelease.Assets {
+		if existing.GetName() == manifestName {
+			pterm.Info.Printf("Asset %s already exists as split parts in release %s, skipping", asset.GetName(), release.GetName())
+			return true
+		}
+	}
+
+	maxRetries := r.settings.maxRetries
+	stopDownload := r.timings.track(stageDownloading)
+	err := api.Retry(maxRetries, func() error {
+		return r.client.DownloadReleaseAssetsCached(asset, digest)
+	})
+	stopDownload()
+	if err != nil {
+		pterm.Error.Printf("Error downloading assets: %v", err)
+		return false
+	}
+
+	parts, err := r.client.SplitAsset(asset, maxSize-1)
+	if err != nil {
+		pterm.Error.Printf("Error splitting asset %s: %v", asset.GetName(), err)
+		return false
+	}
+	pterm.Info.Printf("Uploading asset %s of release %s as %d parts and a manifest", asset.GetName(), release.GetName(), len(parts)-1)
+
+	stopUpload := r.timings.track(stageUploading)
+	defer stopUpload()
+	for _, part := range parts {
+		if api.AssetExists(newRelease, part.GetName(), int64(part.GetSize())) {
+			continue
+		}
+		err := api.Retry(maxRetries, func() error {
+			return r.client.UploadAssetViaURL(newRelease.GetUploadURL(), part)
+		})
+		if err != nil {
+			pterm.Error.Printf("Error uploading part %s: %v", part.GetName(), err)
+			return false
+		}
+	}
+	return true
+}
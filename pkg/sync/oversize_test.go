@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestParseOversizeAssetStrategy(t *testing.T) {
+	for value, want := range map[string]string{"": oversizeAssetSkip, "Split": oversizeAssetSplit, "fail": oversizeAssetFail} {
+		if got, err := parseOversizeAssetStrategy(value); err != nil || got != want {
+			t.Errorf("parseOversizeAssetStrategy(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := parseOversizeAssetStrategy("object-store"); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
+
+// useOversizeAssets migrates releases with one 100 bytes asset over a maximum asset size of 50 bytes
+func useOversizeAssets(t *testing.T, strategy string) *fake.Backend {
+	t.Helper()
+
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 100})
+	viper.Set("MAX_ASSET_SIZE", 50)
+	viper.Set("OVERSIZE_ASSET_STRATEGY", strategy)
+	return backend
+}
+
+func TestMigrateOversizeAssetSkip(t *testing.T) {
+	backend := useOversizeAssets(t, oversizeAssetSkip)
+	viper.Set("FAIL_ON_ASSET_ERROR", true)
+
+	r := newTestRun(t)
+	result := r.migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected the skipped assets not to fail the releases, got %d failed: %v", result.Failed, result.Err)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if len(release.Assets) != 0 {
+			t.Errorf("Expected no asset to be uploaded to %s, got %d", release.GetTagName(), len(release.Assets))
+		}
+	}
+	if got := len(r.recordedOversizeAssets()); got != 2 {
+		t.Errorf("Expected 2 oversize assets to be reported, got %d", got)
+	}
+}
+
+func TestMigrateOversizeAssetSplit(t *testing.T) {
+	backend := useOversizeAssets(t, oversizeAssetSplit)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected the releases to be migrated, got %d failed: %v", result.Failed, result.Err)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		// 49 + 49 + 2 bytes and the manifest
+		if len(release.Assets) != 4 {
+			t.Fatalf("Expected 3 parts and a manifest in %s, got %d assets", release.GetTagName(), len(release.Assets))
+		}
+		if size := release.Assets[0].GetSize(); size != 49 {
+			t.Errorf("Expected parts under the maximum asset size, got %d bytes", size)
+		}
+	}
+
+	// The split assets are not uploaded again
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if len(release.Assets) != 4 {
+			t.Errorf("Expected the parts not to be uploaded again to %s, got %d assets", release.GetTagName(), len(release.Assets))
+		}
+	}
+}
+
+func TestMigrateOversizeAssetFail(t *testing.T) {
+	useOversizeAssets(t, oversizeAssetFail)
+	viper.Set("FAIL_ON_ASSET_ERROR", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Failed != 2 {
+		t.Errorf("Expected both releases to fail on their oversize asset, got %d failed", result.Failed)
+	}
+}
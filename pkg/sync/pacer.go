@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"math/rand/v2"
+	gosync "sync"
+	"time"
+)
+
+// pacer serializes calls and spaces them by at least delay. Some GHES instances have "latest"
+// flapping and eventual consistency glitches when releases are created in quick succession.
+type pacer struct {
+	mu    gosync.Mutex
+	delay time.Duration
+	last  time.Time
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newPacer(delay time.Duration) *pacer {
+	return &pacer{delay: delay, now: time.Now, sleep: time.Sleep}
+}
+
+// Do runs fn once the delay since the previous call has elapsed. Calls never overlap.
+func (p *pacer) Do(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.delay > 0 && !p.last.IsZero() {
+		if elapsed := p.now().Sub(p.last); elapsed < p.delay {
+			p.sleep(p.delay - elapsed)
+		}
+	}
+
+	fn()
+	p.last = p.now()
+}
+
+// repositoryDelay spaces the migrations of the repositories of a run by a delay plus a random
+// jitter, so whole-repository operations don't hit a shared instance back-to-back
+type repositoryDelay struct {
+	delay  time.Duration
+	jitter time.Duration
+	sleep  func(time.Duration)
+	random func(time.Duration) time.Duration
+}
+
+func newRepositoryDelay(delay time.Duration, jitter time.Duration) *repositoryDelay {
+	return &repositoryDelay{delay: delay, jitter: jitter, sleep: time.Sleep, random: rand.N[time.Duration]}
+}
+
+// wait sleeps between the migrations of two repositories
+func (d *repositoryDelay) wait() {
+	pause := max(d.delay, 0)
+	if d.jitter > 0 {
+		pause += d.random(d.jitter)
+	}
+	if pause > 0 {
+		d.sleep(pause)
+	}
+}
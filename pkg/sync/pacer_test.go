@@ -0,0 +1,72 @@
+package sync
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPacerAppliesDelay(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sleeps []time.Duration
+
+	p := newPacer(2 * time.Second)
+	p.now = func() time.Time { return now }
+	p.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+		now = now.Add(d)
+	}
+
+	var calls int
+	for i := 0; i < 3; i++ {
+		p.Do(func() { calls++ })
+		// Half of the delay elapses between calls
+		now = now.Add(time.Second)
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != time.Second {
+		t.Errorf("Expected two 1s sleeps, got %v", sleeps)
+	}
+}
+
+func TestPacerWithoutDelay(t *testing.T) {
+	p := newPacer(0)
+	p.sleep = func(d time.Duration) {
+		t.Errorf("Expected no sleep without a delay, got %v", d)
+	}
+
+	for i := 0; i < 3; i++ {
+		p.Do(func() {})
+	}
+}
+
+func TestRepositoryDelayBetweenRepositories(t *testing.T) {
+	var events []string
+	delay := newRepositoryDelay(30*time.Second, 10*time.Second)
+	delay.random = func(n time.Duration) time.Duration { return n / 2 }
+	delay.sleep = func(d time.Duration) { events = append(events, "sleep "+d.String()) }
+	r := newTestRun(t)
+	r.repoDelay = delay
+
+	migrate := func(repository string, fetched *repositoryReleases) RepositoryResult {
+		events = append(events, "migrate "+repository)
+		return RepositoryResult{Repository: repository}
+	}
+	r.migrateRepositories([]string{"repo1", "repo2", "repo3"}, nil, migrate, &recordingEventHandler{})
+
+	want := []string{"migrate repo1", "sleep 35s", "migrate repo2", "sleep 35s", "migrate repo3"}
+	if !slices.Equal(events, want) {
+		t.Errorf("Expected the delay between repositories only, got %q", events)
+	}
+}
+
+func TestRepositoryDelayNone(t *testing.T) {
+	delay := newRepositoryDelay(0, 0)
+	delay.sleep = func(d time.Duration) {
+		t.Errorf("Expected no sleep without a delay, got %v", d)
+	}
+	delay.wait()
+}
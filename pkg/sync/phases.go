@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+	"github.com/pterm/pterm"
+)
+
+// phaseCheckpoint records the progress of a two-phase migration so an interrupted run resumes
+// with the phase it was in
+type phaseCheckpoint struct {
+	ReleasesCreated bool     `json:"releases_created"`
+	AssetsMigrated  []string `json:"assets_migrated"`
+}
+
+// loadPhaseCheckpoint reads a checkpoint, returning an empty checkpoint when the file doesn't exist
+func loadPhaseCheckpoint(fileName string) (phaseCheckpoint, error) {
+	var checkpoint phaseCheckpoint
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return checkpoint, fmt.Errorf("invalid phase checkpoint %s: %v", fileName, err)
+	}
+	return checkpoint, nil
+}
+
+// assetMigrateFunc migrates the assets of a repository whose releases were already created
+type assetMigrateFunc func(repository string) RepositoryResult
+
+// migrateInPhases first creates the releases of all repositories without their assets, then
+// migrates the assets of all repositories. The checkpoint is saved after each step so a failed
+// asset phase can be retried without creating the releases again. The checkpoint is removed once
+// every repository has been migrated.
+func (r *run) migrateInPhases(repositories []string, prefetched map[string]repositoryReleases, migrate migrateFunc, migrateAssets assetMigrateFunc, handler EventHandler, checkpointFile string) (Summary, error) {
+	var s Summary
+
+	checkpoint, err := loadPhaseCheckpoint(checkpointFile)
+	if err != nil {
+		return s, err
+	}
+
+	if checkpoint.ReleasesCreated {
+		pterm.Info.Printf("Releases already created according to %s, resuming with the assets\n", checkpointFile)
+	} else {
+		pterm.Info.Println("Phase 1: creating releases")
+		s = r.migrateRepositories(repositories, prefetched, migrate, handler)
+
+		checkpoint.ReleasesCreated = true
+		if err := files.CreateJSON(checkpoint, checkpointFile); err != nil {
+			return s, fmt.Errorf("error writing phase checkpoint: %v", err)
+		}
+	}
+
+	pterm.Info.Println("Phase 2: migrating assets")
+	done := make(map[string]bool, len(checkpoint.AssetsMigrated))
+	for _, repository := range checkpoint.AssetsMigrated {
+		done[repository] = true
+	}
+
+	allMigrated := true
+	var migrated int
+	for _, repository := range repositories {
+		if done[repository] {
+			continue
+		}
+		if migrated > 0 {
+			r.repoDelay.wait()
+		}
+		migrated++
+
+		start := r.timings.now()
+		result := migrateAssets(repository)
+		result.Duration = r.timings.now().Sub(start)
+		if result.Err != nil {
+			pterm.Error.Printf("Error migrating repository assets: %v", result.Err)
+		}
+		handler.RepositoryCompleted(result)
+		s.addAssets(result)
+
+		// Repositories with missing assets are retried by the next run
+		if result.Err != nil {
+			allMigrated = false
+			continue
+		}
+		checkpoint.AssetsMigrated = append(checkpoint.AssetsMigrated, repository)
+		if err := files.CreateJSON(checkpoint, checkpointFile); err != nil {
+			return s, fmt.Errorf("error writing phase checkpoint: %v", err)
+		}
+	}
+
+	if allMigrated {
+		os.Remove(checkpointFile)
+	}
+	return s, nil
+}
+
+// migrateRepositoryReleasesWithoutAssets creates the releases of a repository without their assets
+func (r *run) migrateRepositoryReleasesWithoutAssets(repositoryEntry string, fetched *repositoryReleases) RepositoryResult {
+	options := r.settings.optionsForRepository(repositoryEntry)
+	options.skipAssets = true
+	return r.migrateRepository(repositoryEntry, fetched, options)
+}
+
+// migrateRepositoryAssets migrates the assets of the releases of a repository to the releases
+// with the same tag in the target
+func (r *run) migrateRepositoryAssets(repositoryEntry string) RepositoryResult {
+	s := r.settings
+	owner, repository := s.splitRepository(repositoryEntry)
+	targetOrg := s.targetOrg(repositoryEntry)
+	options := s.optionsForRepository(repositoryEntry)
+
+	result := RepositoryResult{Repository: repositoryEntry}
+	if options.skipAssets {
+		return result
+	}
+
+	fetched := r.fetchRepositoryReleases(owner, repository)
+	if fetched.err != nil {
+		result.Err = fetched.err
+		return result
+	}
+	releases, _ := s.selectReleases(fetched.releases, options, repository)
+	if err := mapping.ApplyTagPrefix(releases, s.tagPrefix, owner, repository); err != nil {
+		result.Err = err
+		return result
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start("Migrating assets to target repository...", repository)
+	var incomplete bool
+	for _, release := range releases {
+		if len(release.Assets) == 0 {
+			continue
+		}
+		result.Releases++
+
+		// The releases created by the first phase may have been renamed by the release transform
+		tag, err := r.targetTag(owner+"/"+repository, release)
+		if err != nil {
+			pterm.Warning.Printf("Error transforming release %s: %v", release.GetTagName(), err)
+			result.Failed++
+			continue
+		}
+		sourceTag := release.GetTagName()
+		renamed := *release
+		renamed.TagName = github.String(tag)
+
+		// Already migrated with its assets according to the state file of an interrupted run
+		if r.state.completed(owner+"/"+repository, targetOrg, &renamed) {
+			continue
+		}
+
+		newRelease, err := r.client.GetReleaseByTag(targetOrg, repository, tag)
+		if err != nil {
+			pterm.Warning.Printf("Could not retrieve target release %s: %v", tag, err)
+			result.Failed++
+			continue
+		}
+
+		failedAssets := r.migrateReleaseAssets(owner, repository, targetOrg, sourceTag, &renamed, newRelease, spinner)
+		if failedAssets > 0 {
+			pterm.Warning.Printf("Release %s is missing %d assets", release.GetName(), failedAssets)
+			incomplete = true
+		} else {
+			r.recordCompletedRelease(owner+"/"+repository, targetOrg, &renamed)
+		}
+		if releaseFailedByAssets(failedAssets, s.failOnAssetError) {
+			result.Failed++
+		}
+	}
+
+	if incomplete || result.Failed > 0 {
+		spinner.UpdateText("Some assets failed to migrate")
+		spinner.Fail()
+		result.Err = fmt.Errorf("some assets failed to migrate")
+	} else {
+		spinner.UpdateText("All assets migrated successfully!")
+		spinner.Success()
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+)
+
+// phaseRecordingBackend records the release creations and asset uploads in order
+type phaseRecordingBackend struct {
+	*fake.Backend
+	calls []string
+}
+
+func (b *phaseRecordingBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	b.calls = append(b.calls, "create")
+	return b.Backend.CreateRelease(owner, repository, release)
+}
+
+func (b *phaseRecordingBackend) UploadAssetViaURL(uploadURL string, asset *github.ReleaseAsset) error {
+	b.calls = append(b.calls, "upload")
+	return b.Backend.UploadAssetViaURL(uploadURL, asset)
+}
+
+func TestMigrateInPhases(t *testing.T) {
+	backend := &phaseRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 2, AssetSize: 10})}
+	testClient = backend
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	r := newTestRun(t)
+	s, err := r.migrateInPhases([]string{"repo1", "repo2"}, nil, r.migrateRepositoryReleasesWithoutAssets, r.migrateRepositoryAssets, &recordingEventHandler{}, checkpointFile)
+	if err != nil {
+		t.Fatalf("migrateInPhases returned an error: %v", err)
+	}
+	if s.Releases != 4 || s.Failed != 0 || len(s.Results) != 2 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+
+	// All releases are created before any asset is uploaded
+	calls := strings.Join(backend.calls, " ")
+	if calls != strings.Repeat("create ", 4)+strings.TrimSpace(strings.Repeat("upload ", 8)) {
+		t.Errorf("Expected all creations before uploads, got %s", calls)
+	}
+	for _, repository := range []string{"repo1", "repo2"} {
+		for _, release := range backend.TargetReleases("target-org", repository) {
+			if len(release.Assets) != 2 {
+				t.Errorf("Expected 2 assets in %s %s, got %d", repository, release.GetTagName(), len(release.Assets))
+			}
+		}
+	}
+
+	// The checkpoint is removed once every repository is migrated
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed")
+	}
+}
+
+func TestMigrateInPhasesResumesAssetPhase(t *testing.T) {
+	backend := &phaseRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 1, AssetSize: 10})}
+	testClient = backend
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// The releases were created and repo1 assets migrated by a previous run
+	newTestRun(t).migrateRepositoryReleasesWithoutAssets("repo1", nil)
+	newTestRun(t).migrateRepositoryReleasesWithoutAssets("repo2", nil)
+	backend.calls = nil
+	if err := os.WriteFile(checkpointFile, []byte(`{"releases_created":true,"assets_migrated":["repo1"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write checkpoint: %v", err)
+	}
+
+	r := newTestRun(t)
+	_, err := r.migrateInPhases([]string{"repo1", "repo2"}, nil, r.migrateRepositoryReleasesWithoutAssets, r.migrateRepositoryAssets, &recordingEventHandler{}, checkpointFile)
+	if err != nil {
+		t.Fatalf("migrateInPhases returned an error: %v", err)
+	}
+
+	// Only the assets of repo2 are migrated
+	if strings.Join(backend.calls, " ") != "upload" {
+		t.Errorf("Expected a single upload, got %v", backend.calls)
+	}
+	if len(backend.TargetReleases("target-org", "repo1")[0].Assets) != 0 {
+		t.Errorf("Expected repo1 to be skipped")
+	}
+	if len(backend.TargetReleases("target-org", "repo2")[0].Assets) != 1 {
+		t.Errorf("Expected repo2 assets to be migrated")
+	}
+}
+
+func TestMigrateInPhasesKeepsCheckpointOnAssetFailure(t *testing.T) {
+	useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 1, AssetSize: 10})
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	failingAssets := func(repository string) RepositoryResult {
+		return RepositoryResult{Repository: repository, Releases: 1, Err: errors.New("some assets failed to migrate")}
+	}
+	r := newTestRun(t)
+	_, err := r.migrateInPhases([]string{"repo"}, nil, r.migrateRepositoryReleasesWithoutAssets, failingAssets, &recordingEventHandler{}, checkpointFile)
+	if err != nil {
+		t.Fatalf("migrateInPhases returned an error: %v", err)
+	}
+
+	checkpoint, err := loadPhaseCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if !checkpoint.ReleasesCreated || len(checkpoint.AssetsMigrated) != 0 {
+		t.Errorf("Expected the asset phase to be retried by the next run, got %+v", checkpoint)
+	}
+}
@@ -0,0 +1,78 @@
+package sync
+
+import (
+	gosync "sync"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// repositoryReleases holds the source releases of a repository fetched ahead of the migration
+type repositoryReleases struct {
+	releases      []*github.RepositoryRelease
+	latestRelease *github.RepositoryRelease
+	err           error
+	latestErr     error
+}
+
+type fetchFunc func(owner string, repository string) repositoryReleases
+
+// fetchRepositoryReleases gets the releases of a source repository, and its latest release
+// when latest marking is enabled
+func (r *run) fetchRepositoryReleases(owner string, repository string) repositoryReleases {
+	defer r.timings.track(stageListing)()
+
+	var result repositoryReleases
+	result.releases, result.err = r.client.GetSourceRepositoryReleases(owner, repository)
+	if result.err != nil {
+		return result
+	}
+
+	// Annotated tags without a release keep history, such as release notes, in their message
+	if r.settings.includeTagsWithoutReleases {
+		tagReleases, err := r.client.GetSourceTagsWithoutReleases(owner, repository, result.releases)
+		if err != nil {
+			pterm.Warning.Printf("Could not list tags without releases: %v", err)
+		}
+		result.releases = append(result.releases, tagReleases...)
+	}
+
+	s := r.settings
+	if !latestMarkingEnabled(s.fields, s.neverMarkLatest, s.legacyLatest, s.prereleasesOnly) {
+		return result
+	}
+	result.latestRelease, result.latestErr = r.client.GetSourceRepositoryLatestRelease(owner, repository)
+	return result
+}
+
+// prefetchReleases fetches the releases of all repositories using at most concurrency workers.
+// Results are keyed by the repository entry as given in the list.
+func (s *settings) prefetchReleases(repositories []string, concurrency int, fetch fetchFunc) map[string]repositoryReleases {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(map[string]repositoryReleases, len(repositories))
+	var mu gosync.Mutex
+	var wg gosync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, repository := range repositories {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(repository string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			owner, name := s.splitRepository(repository)
+			result := fetch(owner, name)
+
+			mu.Lock()
+			results[repository] = result
+			mu.Unlock()
+		}(repository)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,61 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestPrefetchReleasesMatchesSequential(t *testing.T) {
+	repositories := []string{"org/repo1", "org/repo2", "other/repo3", "org/repo4", "org/repo5"}
+
+	fetch := func(owner string, repository string) repositoryReleases {
+		tag := owner + "/" + repository
+		return repositoryReleases{
+			releases:      []*github.RepositoryRelease{{TagName: github.String(tag)}},
+			latestRelease: &github.RepositoryRelease{ID: github.Int64(int64(len(tag)))},
+		}
+	}
+
+	s := defaultSettings()
+	sequential := s.prefetchReleases(repositories, 1, fetch)
+	parallel := s.prefetchReleases(repositories, 3, fetch)
+
+	if len(parallel) != len(repositories) {
+		t.Fatalf("Expected %d results, got %d", len(repositories), len(parallel))
+	}
+	for _, repository := range repositories {
+		seq, par := sequential[repository], parallel[repository]
+		if len(par.releases) != 1 || par.releases[0].GetTagName() != seq.releases[0].GetTagName() {
+			t.Errorf("Releases for %s do not match the sequential fetch", repository)
+		}
+		if par.latestRelease.GetID() != seq.latestRelease.GetID() {
+			t.Errorf("Latest release for %s does not match the sequential fetch", repository)
+		}
+	}
+}
+
+func TestIncludeTagsWithoutReleases(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, TagsWithoutReleases: 1})
+	viper.Set("INCLUDE_TAGS_WITHOUT_RELEASES", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+	if result.Releases != 3 {
+		t.Errorf("Expected the tag without release to be migrated along with the releases, got %d releases", result.Releases)
+	}
+
+	releases := backend.TargetReleases("target-org", "repo")
+	if len(releases) != 3 {
+		t.Fatalf("Expected 3 target releases, got %d", len(releases))
+	}
+	tagRelease := releases[2]
+	if tagRelease.GetTagName() != "v3.0.0" || !strings.HasPrefix(tagRelease.GetBody(), "Tag message of v3.0.0") {
+		t.Errorf("Expected a release created from the tag message, got %s with body %q", tagRelease.GetTagName(), tagRelease.GetBody())
+	}
+}
@@ -0,0 +1,29 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/pterm/pterm"
+)
+
+// checkArchived fails when the target repository is archived, since releases cannot be created
+// in it. An archived source repository is fine as its releases can still be read.
+func (r *run) checkArchived(owner string, repository string, targetOrg string) error {
+	sourceRepository, err := r.client.GetSourceRepository(owner, repository)
+	if err != nil {
+		pterm.Warning.Printf("Could not check if the source repository is archived: %v\n", err)
+	} else if sourceRepository.GetArchived() {
+		pterm.Info.Printf("Source repository %s/%s is archived, its releases are read-only but can be migrated\n", owner, repository)
+	}
+
+	targetRepository, err := r.client.GetTargetRepository(targetOrg, repository)
+	if err != nil {
+		pterm.Warning.Printf("Could not check if the target repository is archived: %v\n", err)
+		return nil
+	}
+	if targetRepository.GetArchived() {
+		return fmt.Errorf("target repository %s/%s is archived: unarchive it in its settings before migrating releases", targetOrg, repository)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/pterm/pterm"
+)
+
+// progressCommentMarker identifies the issue comment edited with the progress of the run
+const progressCommentMarker = "<!-- gh-migrate-releases:progress -->"
+
+// progressComment coalesces the repository results of a run into a single issue comment,
+// edited with the cumulative table at most once per interval
+type progressComment struct {
+	mu        gosync.Mutex
+	interval  time.Duration
+	now       func() time.Time
+	results   []RepositoryResult
+	pending   bool
+	lastWrite time.Time
+	commentID int64
+}
+
+func newProgressComment(interval time.Duration) *progressComment {
+	return &progressComment{interval: interval, now: time.Now}
+}
+
+// add records a result and returns the comment to write, or false when the comment was written
+// less than the interval ago
+func (c *progressComment) add(result RepositoryResult) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results = append(c.results, result)
+	c.pending = true
+	if !c.lastWrite.IsZero() && c.now().Sub(c.lastWrite) < c.interval {
+		return "", false
+	}
+	return c.take(), true
+}
+
+// flush returns the comment with the results not written yet, or false when there are none
+func (c *progressComment) flush() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.pending {
+		return "", false
+	}
+	return c.take(), true
+}
+
+// take marks the results as written and returns the comment listing them. Must be called with
+// c.mu held.
+func (c *progressComment) take() string {
+	c.pending = false
+	c.lastWrite = c.now()
+	return formatProgressTable(c.results)
+}
+
+// writeProgressComment edits the progress comment of the run, created on the first write, on the
+// triggering issue
+func (r *run) writeProgressComment(organization string, repository string, issueNumber int, comment string) {
+	if r.issueCommentsForbidden.Load() {
+		return
+	}
+
+	c := r.issueProgress
+	c.mu.Lock()
+	commentID := c.commentID
+	c.mu.Unlock()
+
+	commentID, err := r.client.UpsertIssueComment(organization, repository, issueNumber, commentID, progressCommentMarker, comment)
+	if errors.Is(err, api.ErrIssueCommentForbidden) {
+		r.issueCommentsForbidden.Store(true)
+		pterm.Warning.Printf("TARGET_TOKEN lacks issues:write on %s/%s; skipping progress comment\n", organization, repository)
+		return
+	}
+	if err != nil {
+		pterm.Error.Printf("Error writing progress to issue: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.commentID = commentID
+	c.mu.Unlock()
+}
+
+// formatProgressTable formats the results of the run so far as a Markdown table
+func formatProgressTable(results []RepositoryResult) string {
+	var builder strings.Builder
+	builder.WriteString(progressCommentMarker + "\n")
+	fmt.Fprintf(&builder, "Migrated %d repositories so far\n\n", len(results))
+	builder.WriteString("| Repository | Releases | Succeeded | Failed | Skipped |\n")
+	builder.WriteString("| ---------- | -------- | --------- | ------ | ------- |\n")
+	for _, result := range results {
+		if errors.Is(result.Err, errFetchReleases) {
+			fmt.Fprintf(&builder, "| %s | could not fetch releases | | | |\n", result.Repository)
+			continue
+		}
+		fmt.Fprintf(&builder, "| %s | %d | %d | %d | %d |\n", result.Repository, result.Releases, result.Releases-result.Failed, result.Failed, result.Skipped)
+	}
+	return builder.String()
+}
+
+// flushIssueProgress writes the results not written yet to the progress comment at the end of
+// the run
+func (r *run) flushIssueProgress() {
+	if r.issueProgress == nil {
+		return
+	}
+
+	organization, repository, issueNumber, ok := triggeringIssue()
+	if !ok {
+		return
+	}
+	if comment, due := r.issueProgress.flush(); due {
+		r.writeProgressComment(organization, repository, issueNumber, comment)
+	}
+}
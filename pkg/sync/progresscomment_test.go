@@ -0,0 +1,77 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestProgressCommentCoalescesUpdates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	c := newProgressComment(time.Minute)
+	c.now = func() time.Time { return now }
+
+	// The first result is written right away
+	if comment, due := c.add(RepositoryResult{Repository: "repo1", Releases: 2}); !due || !strings.Contains(comment, "| repo1 | 2 | 2 | 0 | 0 |") {
+		t.Fatalf("Expected the first result to be written, got %v %q", due, comment)
+	}
+
+	// The next results within the interval are coalesced
+	for i, repository := range []string{"repo2", "repo3"} {
+		now = start.Add(time.Duration(i+1) * 20 * time.Second)
+		if _, due := c.add(RepositoryResult{Repository: repository, Releases: 1}); due {
+			t.Errorf("Expected %s to be coalesced within the interval", repository)
+		}
+	}
+
+	// Once the interval has elapsed, the comment lists all results so far
+	now = start.Add(61 * time.Second)
+	comment, due := c.add(RepositoryResult{Repository: "repo4", Releases: 3, Failed: 1})
+	if !due {
+		t.Fatal("Expected a write once the interval has elapsed")
+	}
+	for _, row := range []string{"| repo1 |", "| repo2 |", "| repo3 |", "| repo4 | 3 | 2 | 1 | 0 |"} {
+		if !strings.Contains(comment, row) {
+			t.Errorf("Expected the comment to contain %q, got:\n%s", row, comment)
+		}
+	}
+	if !strings.HasPrefix(comment, progressCommentMarker) {
+		t.Errorf("Expected the comment to start with the marker, got:\n%s", comment)
+	}
+	if _, due := c.flush(); due {
+		t.Error("Expected nothing to flush after a write")
+	}
+
+	now = start.Add(70 * time.Second)
+	c.add(RepositoryResult{Repository: "repo5"})
+	if comment, due := c.flush(); !due || !strings.Contains(comment, "| repo5 |") {
+		t.Errorf("Expected the flush to write the pending result, got %v %q", due, comment)
+	}
+}
+
+func TestProgressCommentEditsSingleComment(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	t.Setenv("CI", "true")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_CONTEXT", `{"repository": {"owner": {"login": "org"}, "name": "migrations"}, "issue": {"number": 1}}`)
+	viper.Set("INCREMENTAL_ISSUE_COMMENTS", true)
+	viper.Set("ISSUE_COMMENT_INTERVAL", time.Hour)
+
+	r := newTestRun(t)
+	r.migrateRepositories([]string{"repo1", "repo2", "repo3"}, nil, r.migrateRepositoryReleases, &defaultEventHandler{run: r})
+	r.flushIssueProgress()
+
+	comments := backend.Comments()
+	if len(comments) != 1 {
+		t.Fatalf("Expected a single progress comment, got %d: %q", len(comments), comments)
+	}
+	for _, repository := range []string{"repo1", "repo2", "repo3"} {
+		if !strings.Contains(comments[0], "| "+repository+" | 2 | 2 | 0 | 0 |") {
+			t.Errorf("Expected the progress comment to list %s, got:\n%s", repository, comments[0])
+		}
+	}
+}
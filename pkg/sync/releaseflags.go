@@ -0,0 +1,59 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/pterm/pterm"
+)
+
+// ReleaseFlags are the draft and prerelease flags of a migrated release in the source and, once
+// migrated, in the target
+type ReleaseFlags struct {
+	Tag              string
+	SourceDraft      bool
+	SourcePrerelease bool
+	TargetDraft      bool
+	TargetPrerelease bool
+	// Matched reports whether the target flags match the source for the migrated fields; a
+	// field left out of MIGRATE_FIELDS can't diverge
+	Matched bool
+}
+
+// checkReleaseFlags compares the flags of a target release with its source release, warning
+// when a migrated flag diverges
+func checkReleaseFlags(release *github.RepositoryRelease, newRelease *github.RepositoryRelease, fields map[string]bool, publishDrafts bool) ReleaseFlags {
+	flags := ReleaseFlags{
+		Tag:              release.GetTagName(),
+		SourceDraft:      release.GetDraft(),
+		SourcePrerelease: release.GetPrerelease(),
+		TargetDraft:      newRelease.GetDraft(),
+		TargetPrerelease: newRelease.GetPrerelease(),
+		Matched:          true,
+	}
+
+	// A draft published with PUBLISH_DRAFTS is expected to be published in the target
+	wantDraft := flags.SourceDraft && !publishDrafts
+	if fields["draft"] && wantDraft != flags.TargetDraft {
+		flags.Matched = false
+		pterm.Warning.Printf("Release %s is draft=%v in the target but draft=%v in the source", release.GetName(), flags.TargetDraft, flags.SourceDraft)
+	}
+	if fields["prerelease"] && flags.SourcePrerelease != flags.TargetPrerelease {
+		flags.Matched = false
+		pterm.Warning.Printf("Release %s is prerelease=%v in the target but prerelease=%v in the source", release.GetName(), flags.TargetPrerelease, flags.SourcePrerelease)
+	}
+	return flags
+}
+
+// divergedReleaseFlags lists the releases of a run whose target flags diverge from the source
+func divergedReleaseFlags(summary Summary) []string {
+	var diverged []string
+	for _, result := range summary.Results {
+		for _, flags := range result.ReleaseFlags {
+			if !flags.Matched {
+				diverged = append(diverged, fmt.Sprintf("%s@%s: draft %v→%v, prerelease %v→%v", result.Repository, flags.Tag, flags.SourceDraft, flags.TargetDraft, flags.SourcePrerelease, flags.TargetPrerelease))
+			}
+		}
+	}
+	return diverged
+}
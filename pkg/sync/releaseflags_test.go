@@ -0,0 +1,136 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// flagDroppingBackend marks the first source release as prerelease and the second as draft, and
+// drops the prerelease flag of the created releases
+type flagDroppingBackend struct {
+	*fake.Backend
+}
+
+func (b *flagDroppingBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	releases[0].Prerelease = github.Bool(true)
+	releases[1].Draft = github.Bool(true)
+	return releases, err
+}
+
+func (b *flagDroppingBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	dropped := *release
+	dropped.Prerelease = nil
+	return b.Backend.CreateRelease(owner, repository, &dropped)
+}
+
+func TestMigrateRepositoryReleasesReportsFlags(t *testing.T) {
+	backend := &flagDroppingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3})}
+	testClient = backend
+	viper.Set("INCLUDE_DRAFTS", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if len(result.ReleaseFlags) != 3 {
+		t.Fatalf("Expected the flags of 3 releases, got %+v", result.ReleaseFlags)
+	}
+
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	for _, flags := range result.ReleaseFlags {
+		var want *github.RepositoryRelease
+		for _, release := range source {
+			if release.GetTagName() == flags.Tag {
+				want = release
+			}
+		}
+		target := backend.TargetReleases("target-org", "repo")
+		var got *github.RepositoryRelease
+		for _, release := range target {
+			if release.GetTagName() == flags.Tag {
+				got = release
+			}
+		}
+		if flags.SourceDraft != want.GetDraft() || flags.SourcePrerelease != want.GetPrerelease() {
+			t.Errorf("Expected the source flags of %s, got %+v", flags.Tag, flags)
+		}
+		if flags.TargetDraft != got.GetDraft() || flags.TargetPrerelease != got.GetPrerelease() {
+			t.Errorf("Expected the target flags of %s, got %+v", flags.Tag, flags)
+		}
+		if matched := flags.Tag != source[0].GetTagName(); flags.Matched != matched {
+			t.Errorf("Expected %s to have matched %v, got %+v", flags.Tag, matched, flags)
+		}
+	}
+
+	var s Summary
+	s.add(result)
+	diverged := divergedReleaseFlags(s)
+	if len(diverged) != 1 || !strings.Contains(diverged[0], "repo@"+source[0].GetTagName()) {
+		t.Errorf("Expected the dropped prerelease flag to be reported, got %q", diverged)
+	}
+}
+
+func TestReleaseFlagsOnlyCompareMigratedFields(t *testing.T) {
+	backend := &flagDroppingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+	viper.Set("MIGRATE_FIELDS", "name,body,draft")
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	for _, flags := range result.ReleaseFlags {
+		if !flags.Matched {
+			t.Errorf("Expected a prerelease flag left out of the migrated fields not to diverge, got %+v", flags)
+		}
+	}
+}
+
+// draftBackend marks the first source release as draft
+type draftBackend struct {
+	*fake.Backend
+}
+
+func (b *draftBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	releases[0].Draft = github.Bool(true)
+	return releases, err
+}
+
+func TestMigrateRepositoryReleasesDrafts(t *testing.T) {
+	backend := &draftBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3})}
+	testClient = backend
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	draftTag := source[0].GetTagName()
+
+	// Drafts are skipped by default
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Releases != 2 || result.Failed != 0 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if release.GetTagName() == draftTag {
+			t.Fatalf("Expected draft %s not to be migrated", draftTag)
+		}
+	}
+
+	// A migrated draft stays a draft unless published
+	for _, publish := range []bool{false, true} {
+		viper.Set("INCLUDE_DRAFTS", true)
+		viper.Set("PUBLISH_DRAFTS", publish)
+		backend.Backend = fake.New(fake.Scenario{ReleasesPerRepository: 3})
+		result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+		if result.Releases != 3 || result.Failed != 0 {
+			t.Fatalf("Unexpected result: %+v", result)
+		}
+		for _, release := range backend.TargetReleases("target-org", "repo") {
+			if release.GetTagName() == draftTag && release.GetDraft() == publish {
+				t.Errorf("With publish %v, expected draft %s to be draft=%v, got %v", publish, draftTag, !publish, release.GetDraft())
+			}
+		}
+		for _, flags := range result.ReleaseFlags {
+			if !flags.Matched {
+				t.Errorf("With publish %v, expected the flags of %s to match, got %+v", publish, flags.Tag, flags)
+			}
+		}
+	}
+}
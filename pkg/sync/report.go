@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+)
+
+// summaryReport is the summary of a run written to the summary file
+type summaryReport struct {
+	Releases           int                `json:"releases"`
+	Succeeded          int                `json:"succeeded"`
+	Failed             int                `json:"failed"`
+	Skipped            int                `json:"skipped"`
+	Updated            int                `json:"updated"`
+	Unchanged          int                `json:"unchanged"`
+	APIRequests        int                `json:"api_requests"`
+	RequestsByCategory map[string]int     `json:"api_requests_by_category"`
+	StageSeconds       map[string]float64 `json:"stage_seconds"`
+	Repositories       []repositoryReport `json:"repositories"`
+}
+
+// repositoryReport is the result of a repository in the summary file
+type repositoryReport struct {
+	Repository string `json:"repository"`
+	Status     string `json:"status"`
+	Releases   int    `json:"releases"`
+	Failed     int    `json:"failed"`
+	Skipped    int    `json:"skipped"`
+	Error      string `json:"error,omitempty"`
+	// ReleaseFlags are the draft and prerelease flags of the releases in the target
+	ReleaseFlags []releaseFlagsReport `json:"release_flags,omitempty"`
+}
+
+// releaseFlagsReport is the draft and prerelease status of a release in the summary file
+type releaseFlagsReport struct {
+	Tag              string `json:"tag"`
+	Draft            bool   `json:"draft"`
+	Prerelease       bool   `json:"prerelease"`
+	SourceDraft      bool   `json:"source_draft"`
+	SourcePrerelease bool   `json:"source_prerelease"`
+	Matched          bool   `json:"matched"`
+}
+
+// newSummaryReport builds the summary report of a run
+func newSummaryReport(s Summary, stages *stageTimings) summaryReport {
+	report := summaryReport{
+		Releases:           s.Releases,
+		Succeeded:          s.Releases - s.Failed,
+		Failed:             s.Failed,
+		Skipped:            s.Skipped,
+		Updated:            s.Updated,
+		Unchanged:          s.Unchanged,
+		RequestsByCategory: map[string]int{},
+		StageSeconds:       map[string]float64{},
+	}
+
+	counts, total := api.RequestCounts()
+	report.APIRequests = total
+	for _, count := range counts {
+		report.RequestsByCategory[count.Category] = count.Count
+	}
+
+	for stage, duration := range stages.snapshot() {
+		report.StageSeconds[stage] = duration.Seconds()
+	}
+
+	for _, result := range s.Results {
+		repository := repositoryReport{Repository: result.Repository, Status: string(result.Status()), Releases: result.Releases, Failed: result.Failed, Skipped: result.Skipped}
+		if result.Err != nil {
+			repository.Error = result.Err.Error()
+		}
+		for _, flags := range result.ReleaseFlags {
+			repository.ReleaseFlags = append(repository.ReleaseFlags, releaseFlagsReport{
+				Tag:              flags.Tag,
+				Draft:            flags.TargetDraft,
+				Prerelease:       flags.TargetPrerelease,
+				SourceDraft:      flags.SourceDraft,
+				SourcePrerelease: flags.SourcePrerelease,
+				Matched:          flags.Matched,
+			})
+		}
+		report.Repositories = append(report.Repositories, repository)
+	}
+
+	return report
+}
+
+// writeSummaryFile writes the summary of a run as JSON
+func writeSummaryFile(fileName string, s Summary, stages *stageTimings) error {
+	return files.CreateJSON(newSummaryReport(s, stages), fileName)
+}
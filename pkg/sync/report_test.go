@@ -0,0 +1,106 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+)
+
+func TestWriteSummaryFile(t *testing.T) {
+	var s Summary
+	s.add(RepositoryResult{Repository: "repo", Releases: 3, Failed: 1, Err: errors.New("some releases failed to create")})
+	s.add(RepositoryResult{Repository: "other", Releases: 2, Skipped: 1, ReleaseFlags: []ReleaseFlags{
+		{Tag: "v1.0.0", SourcePrerelease: true, TargetPrerelease: true, Matched: true},
+		{Tag: "v2.0.0", SourceDraft: true, Matched: false},
+	}})
+
+	stages := newStageTimings()
+	stages.durations[stageListing] = 1500 * time.Millisecond
+	stages.durations[stageUploading] = 3 * time.Second
+
+	fileName := filepath.Join(t.TempDir(), "summary.json")
+	if err := writeSummaryFile(fileName, s, stages); err != nil {
+		t.Fatalf("writeSummaryFile returned an error: %v", err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read summary file: %v", err)
+	}
+	var report summaryReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse summary file: %v", err)
+	}
+
+	if report.Releases != 5 || report.Succeeded != 4 || report.Failed != 1 || report.Skipped != 1 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if report.StageSeconds[stageListing] != 1.5 || report.StageSeconds[stageUploading] != 3 {
+		t.Errorf("Unexpected stage timings: %v", report.StageSeconds)
+	}
+	if len(report.Repositories) != 2 || report.Repositories[0].Error != "some releases failed to create" {
+		t.Errorf("Unexpected repositories: %+v", report.Repositories)
+	}
+	if flags := report.Repositories[1].ReleaseFlags; len(flags) != 2 || !flags[0].Prerelease || !flags[0].Matched || flags[1].Draft || !flags[1].SourceDraft || flags[1].Matched {
+		t.Errorf("Unexpected release flags: %+v", flags)
+	}
+}
+
+func TestMigrateRepositoryReleasesRecordsTimings(t *testing.T) {
+	useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 1, AssetSize: 10})
+	r := newTestRun(t)
+	r.migrateRepositoryReleases("repo", nil)
+
+	durations := r.timings.snapshot()
+	for _, stage := range stageOrder {
+		if _, ok := durations[stage]; !ok {
+			t.Errorf("Expected time to be recorded for stage %s", stage)
+		}
+	}
+}
+
+func TestSummaryTableIncludesDurations(t *testing.T) {
+	r := newTestRun(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.timings.now = func() time.Time { return now }
+
+	// Each repository takes a second longer than the previous one
+	var took time.Duration
+	migrate := func(repository string, fetched *repositoryReleases) RepositoryResult {
+		took += time.Second
+		now = now.Add(took)
+		return RepositoryResult{Repository: repository, Releases: 2}
+	}
+	s := r.migrateRepositories([]string{"repo1", "repo2"}, nil, migrate, &recordingEventHandler{})
+	s.Elapsed = 3 * time.Second
+
+	table := formatSummaryTable(s)
+	for _, row := range []string{
+		"| No. of Releases | Succeeded | Failed | Duration |",
+		"| 4 | 4 | 0 | 3s |",
+		"| Repository | Status | Releases | Succeeded | Failed | Duration |",
+		"| repo1 | succeeded | 2 | 2 | 0 | 1s |",
+		"| repo2 | succeeded | 2 | 2 | 0 | 2s |",
+	} {
+		if !strings.Contains(table, row) {
+			t.Errorf("Expected the summary table to contain %q, got:\n%s", row, table)
+		}
+	}
+
+	// Every row of a table has the columns of its header
+	for _, block := range strings.Split(strings.TrimSpace(table), "\n\n") {
+		lines := strings.Split(block, "\n")
+		columns := strings.Count(lines[0], "|")
+		for _, line := range lines[1:] {
+			if strings.Count(line, "|") != columns {
+				t.Errorf("Expected %d separators in row %q", columns, line)
+			}
+		}
+	}
+}
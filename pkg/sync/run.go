@@ -0,0 +1,59 @@
+package sync
+
+import (
+	gosync "sync"
+	"sync/atomic"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+)
+
+// run is the state of a single Migrate call: the backend and the settings it migrates with, and
+// what it records for the summary. Each call has its own run, so Migrators of the same process
+// don't share any of it.
+type run struct {
+	client    api.Client
+	settings  *settings
+	transform ReleaseTransform
+	dryRun    bool
+	// state is nil when no state file is used
+	state *migrationState
+
+	// createPacer paces the release creations and repoDelay the repositories of the run
+	createPacer *pacer
+	repoDelay   *repositoryDelay
+	// assetConcurrency tunes the asset transfer workers of the run with AUTO_CONCURRENCY
+	assetConcurrency *concurrencyTuner
+	timings          *stageTimings
+
+	// issueProgress is the progress comment of the run, with ISSUE_COMMENT_INTERVAL
+	issueProgress *progressComment
+	// issueCommentsForbidden is set once the target token was refused an issue comment, so the
+	// following comments of the run are not attempted
+	issueCommentsForbidden atomic.Bool
+
+	mu gosync.Mutex
+	// createdTags maps the source releases of the run, by repository and tag before the release
+	// transform, to the tag of their target release, so the releases a transform renamed are
+	// found again by the asset phase and the latest release. Releases recreated from tags have
+	// no ID, so they are keyed by tag.
+	createdTags map[[2]string]string
+	// failedAssets lists the assets that failed to transfer, written to the failed assets file
+	failedAssets []failedAsset
+	// oversizeAssets lists the oversize assets skipped or failed, reported in the summary
+	oversizeAssets []string
+	// rejectedAssets lists the assets left out for their content type, reported in the summary
+	rejectedAssets []string
+}
+
+// newRun returns a run migrating with client and the settings
+func newRun(client api.Client, s *settings) *run {
+	return &run{
+		client:           client,
+		settings:         s,
+		createPacer:      newPacer(s.createDelay),
+		repoDelay:        newRepositoryDelay(s.repoDelay, s.repoDelayJitter),
+		assetConcurrency: newConcurrencyTuner(s.autoConcurrencyMax),
+		timings:          newStageTimings(),
+		createdTags:      map[[2]string]string{},
+	}
+}
@@ -0,0 +1,221 @@
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
+	"github.com/spf13/viper"
+)
+
+// settingsSource is where the settings are read from, such as the viper configuration of the
+// sync command
+type settingsSource interface {
+	GetString(key string) string
+	GetBool(key string) bool
+	GetInt(key string) int
+	GetInt64(key string) int64
+	GetDuration(key string) time.Duration
+}
+
+// settings are the settings of the releases and assets of a migration, parsed and validated
+// once when the Migrator is built
+type settings struct {
+	sourceHostname     string
+	targetHostname     string
+	sourceOrganization string
+	targetOrganization string
+
+	// Release selection
+	fields                     map[string]bool
+	skipEmptyReleases          bool
+	includeDrafts              bool
+	includeTagsWithoutReleases bool
+	since                      time.Time
+	until                      time.Time
+	tagFilter                  *regexp.Regexp
+	tagExclude                 *regexp.Regexp
+	prereleasesOnly            bool
+	stableOnly                 bool
+	newerThanTarget            bool
+	tagPrefix                  string
+
+	// Release creation
+	mappingFile               string
+	authorAttribution         string
+	authorAttributionPosition string
+	recordSourceIDs           bool
+	linkSourceRelease         bool
+	bodyTemplate              *template.Template
+	publishDrafts             bool
+	neverMarkLatest           bool
+	legacyLatest              bool
+	preserveTargetLatest      bool
+	migrateAutolinks          bool
+	migrateAnnotatedTags      bool
+	createMissingTags         bool
+	missingCommitStrategy     string
+	updateExisting            bool
+	createDelay               time.Duration
+	repoDelay                 time.Duration
+	repoDelayJitter           time.Duration
+
+	// Assets
+	assetNameTemplate     *template.Template
+	allowedContentTypes   []string
+	matchAssetLabels      bool
+	replaceBrokenAssets   bool
+	preserveAssetOrder    bool
+	maxAssetSize          int64
+	oversizeAssetStrategy string
+	checksumsFile         string
+	checksumsAlgorithm    string
+	assetCache            bool
+	dedupeAssets          bool
+	downloadConcurrency   int
+	uploadConcurrency     int
+	assetConcurrency      int
+	autoConcurrency       bool
+	autoConcurrencyMax    int
+	maxRetries            int
+	failOnAssetError      bool
+
+	// Reporting
+	summaryFile              string
+	failuresFile             string
+	failedAssetsFile         string
+	mappingStats             bool
+	incrementalIssueComments bool
+	issueCommentInterval     time.Duration
+
+	// repositoryOverrides holds the overrides of the repository list keyed by repository entry
+	repositoryOverrides map[string]map[string]string
+	// repositoryTargets holds the target owners of the repository list entries migrated to
+	// another organization than the target organization, keyed by repository entry
+	repositoryTargets map[string]string
+}
+
+// loadSettings parses the settings of the viper configuration
+func loadSettings() (*settings, error) {
+	return parseSettings(viper.GetViper())
+}
+
+// defaultSettings returns the settings of a Migrator without any configuration
+func defaultSettings() *settings {
+	// The zero values of the settings are all valid
+	s, _ := parseSettings(viper.New())
+	return s
+}
+
+// parseSettings parses and validates the settings read from source
+func parseSettings(source settingsSource) (*settings, error) {
+	s := &settings{
+		sourceHostname:             source.GetString("SOURCE_HOSTNAME"),
+		targetHostname:             source.GetString("TARGET_HOSTNAME"),
+		sourceOrganization:         source.GetString("SOURCE_ORGANIZATION"),
+		targetOrganization:         source.GetString("TARGET_ORGANIZATION"),
+		skipEmptyReleases:          source.GetBool("SKIP_EMPTY_RELEASES"),
+		includeDrafts:              source.GetBool("INCLUDE_DRAFTS"),
+		includeTagsWithoutReleases: source.GetBool("INCLUDE_TAGS_WITHOUT_RELEASES"),
+		prereleasesOnly:            source.GetBool("PRERELEASES_ONLY"),
+		stableOnly:                 source.GetBool("STABLE_ONLY"),
+		newerThanTarget:            source.GetBool("NEWER_THAN_TARGET"),
+		tagPrefix:                  source.GetString("TAG_PREFIX"),
+		mappingFile:                source.GetString("MAPPING_FILE"),
+		authorAttribution:          source.GetString("AUTHOR_ATTRIBUTION"),
+		authorAttributionPosition:  source.GetString("AUTHOR_ATTRIBUTION_POSITION"),
+		recordSourceIDs:            source.GetBool("RECORD_SOURCE_IDS"),
+		linkSourceRelease:          source.GetBool("LINK_SOURCE_RELEASE"),
+		publishDrafts:              source.GetBool("PUBLISH_DRAFTS"),
+		neverMarkLatest:            source.GetBool("NEVER_MARK_LATEST"),
+		legacyLatest:               source.GetBool("LEGACY_LATEST"),
+		preserveTargetLatest:       source.GetBool("PRESERVE_TARGET_LATEST"),
+		migrateAutolinks:           source.GetBool("MIGRATE_AUTOLINKS"),
+		migrateAnnotatedTags:       source.GetBool("MIGRATE_ANNOTATED_TAGS"),
+		createMissingTags:          source.GetBool("CREATE_MISSING_TAGS"),
+		updateExisting:             source.GetBool("UPDATE_EXISTING"),
+		createDelay:                source.GetDuration("CREATE_DELAY"),
+		repoDelay:                  source.GetDuration("REPO_DELAY"),
+		repoDelayJitter:            source.GetDuration("REPO_DELAY_JITTER"),
+		matchAssetLabels:           source.GetBool("MATCH_ASSET_LABELS"),
+		replaceBrokenAssets:        source.GetBool("REPLACE_BROKEN_ASSETS"),
+		preserveAssetOrder:         source.GetBool("PRESERVE_ASSET_ORDER"),
+		maxAssetSize:               source.GetInt64("MAX_ASSET_SIZE"),
+		checksumsFile:              source.GetString("CHECKSUMS_FILE"),
+		assetCache:                 source.GetBool("ASSET_CACHE"),
+		dedupeAssets:               source.GetBool("DEDUPE_ASSETS"),
+		downloadConcurrency:        source.GetInt("DOWNLOAD_CONCURRENCY"),
+		uploadConcurrency:          source.GetInt("UPLOAD_CONCURRENCY"),
+		assetConcurrency:           source.GetInt("ASSET_CONCURRENCY"),
+		autoConcurrency:            source.GetBool("AUTO_CONCURRENCY"),
+		autoConcurrencyMax:         source.GetInt("AUTO_CONCURRENCY_MAX"),
+		maxRetries:                 source.GetInt("MAX_RETRIES"),
+		failOnAssetError:           source.GetBool("FAIL_ON_ASSET_ERROR"),
+		summaryFile:                source.GetString("SUMMARY_FILE"),
+		failuresFile:               source.GetString("FAILURES_FILE"),
+		failedAssetsFile:           source.GetString("FAILED_ASSETS_FILE"),
+		mappingStats:               source.GetBool("MAPPING_STATS"),
+		incrementalIssueComments:   source.GetBool("INCREMENTAL_ISSUE_COMMENTS"),
+		issueCommentInterval:       source.GetDuration("ISSUE_COMMENT_INTERVAL"),
+		repositoryOverrides:        map[string]map[string]string{},
+		repositoryTargets:          map[string]string{},
+	}
+
+	var err error
+	if s.prereleasesOnly && s.stableOnly {
+		return nil, fmt.Errorf("cannot specify both prereleases only and stable only")
+	} else if s.neverMarkLatest && s.legacyLatest {
+		return nil, fmt.Errorf("cannot specify both never mark latest and legacy latest")
+	} else if s.fields, err = parseMigrateFields(source.GetString("MIGRATE_FIELDS")); err != nil {
+		return nil, err
+	} else if s.missingCommitStrategy, err = parseMissingCommitStrategy(source.GetString("MISSING_COMMIT_STRATEGY")); err != nil {
+		return nil, err
+	} else if s.autoConcurrency && s.autoConcurrencyMax < 1 {
+		return nil, fmt.Errorf("the maximum auto concurrency must be at least 1")
+	} else if s.since, s.until, err = parseDateRange(source.GetString("SINCE"), source.GetString("UNTIL")); err != nil {
+		return nil, err
+	} else if s.tagFilter, s.tagExclude, err = parseTagPatterns(source.GetString("TAG_FILTER"), source.GetString("TAG_EXCLUDE")); err != nil {
+		return nil, err
+	} else if s.oversizeAssetStrategy, err = parseOversizeAssetStrategy(source.GetString("OVERSIZE_ASSET_STRATEGY")); err != nil {
+		return nil, err
+	} else if s.checksumsAlgorithm, err = api.ParseChecksumAlgorithm(source.GetString("CHECKSUMS_ALGORITHM")); err != nil {
+		return nil, err
+	} else if _, err := mapping.ParseTagPrefix(s.tagPrefix); err != nil {
+		return nil, err
+	} else if _, err := mapping.ParseAttributionTemplate(s.authorAttribution); err != nil {
+		return nil, err
+	} else if position := s.authorAttributionPosition; position != "" && position != "top" && position != "bottom" {
+		return nil, fmt.Errorf("invalid author attribution position %q: expected top or bottom", position)
+	} else if s.allowedContentTypes, err = parseAllowedContentTypes(source.GetString("ALLOWED_CONTENT_TYPES")); err != nil {
+		return nil, err
+	} else if s.tagPrefix != "" && (s.migrateAnnotatedTags || s.createMissingTags) {
+		return nil, fmt.Errorf("cannot specify a tag prefix with migrate annotated tags or create missing tags")
+	}
+
+	// Parsed once, the releases and assets are rendered with the parsed templates
+	if value := source.GetString("ASSET_NAME_TEMPLATE"); value != "" {
+		if s.assetNameTemplate, err = parseAssetNameTemplate(value); err != nil {
+			return nil, err
+		}
+	}
+	if value := source.GetString("BODY_TEMPLATE"); value != "" {
+		if s.bodyTemplate, err = mapping.ParseBodyTemplate(value); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// bodyMapping returns the source and target whose links and organization are mapped in the
+// release bodies
+func (s *settings) bodyMapping() mapping.BodyMapping {
+	return mapping.BodyMapping{
+		SourceHostname:     s.sourceHostname,
+		TargetHostname:     s.targetHostname,
+		SourceOrganization: s.sourceOrganization,
+		TargetOrganization: s.targetOrganization,
+	}
+}
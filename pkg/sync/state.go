@@ -0,0 +1,133 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	gosync "sync"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/pterm/pterm"
+)
+
+// completedRelease is a release migrated with all its assets, as recorded in the state file. The
+// tag is the tag of the target release, with the tag prefix.
+type completedRelease struct {
+	Repository         string   `json:"repository"`
+	TargetOrganization string   `json:"target_organization"`
+	Tag                string   `json:"tag"`
+	Assets             []string `json:"assets"`
+}
+
+// migrationState records the releases already migrated so a run resumed after a crash skips
+// them without checking them against the target. It is written after each migrated release.
+type migrationState struct {
+	mu       gosync.Mutex
+	fileName string
+	readOnly bool
+	releases []completedRelease
+	index    map[[3]string]int
+}
+
+// loadMigrationState reads the state file of a prior run, returning an empty state when the file
+// doesn't exist yet. A read-only state, e.g. in a dry run, is never written.
+func loadMigrationState(fileName string, readOnly bool) (*migrationState, error) {
+	state := &migrationState{fileName: fileName, readOnly: readOnly, index: map[[3]string]int{}}
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+	if err := json.Unmarshal(data, &state.releases); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %v", fileName, err)
+	}
+	for i, release := range state.releases {
+		if release.Repository == "" || release.Tag == "" {
+			return nil, fmt.Errorf("invalid state file %s: entry %d needs a repository and a tag", fileName, i+1)
+		}
+		state.index[[3]string{release.Repository, release.TargetOrganization, release.Tag}] = i
+	}
+	return state, nil
+}
+
+// completed reports whether the release was already migrated with each of its current assets
+func (s *migrationState) completed(repository string, targetOrg string, release *github.RepositoryRelease) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.index[[3]string{repository, targetOrg, release.GetTagName()}]
+	if !ok {
+		return false
+	}
+	// An asset added to the source release since then still needs to be migrated
+	migrated := make(map[string]bool, len(s.releases[i].Assets))
+	for _, name := range s.releases[i].Assets {
+		migrated[name] = true
+	}
+	for _, asset := range release.Assets {
+		if !migrated[asset.GetName()] {
+			return false
+		}
+	}
+	return true
+}
+
+// allCompleted reports whether all the releases were already migrated, so the target releases
+// don't need to be fetched
+func (s *migrationState) allCompleted(repository string, targetOrg string, releases []*github.RepositoryRelease) bool {
+	if s == nil {
+		return false
+	}
+	for _, release := range releases {
+		if !s.completed(repository, targetOrg, release) {
+			return false
+		}
+	}
+	return true
+}
+
+// record records a release migrated with all its assets and writes the state file
+func (s *migrationState) record(repository string, targetOrg string, release *github.RepositoryRelease) error {
+	if s == nil || s.readOnly {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := completedRelease{Repository: repository, TargetOrganization: targetOrg, Tag: release.GetTagName(), Assets: []string{}}
+	for _, asset := range release.Assets {
+		entry.Assets = append(entry.Assets, asset.GetName())
+	}
+	key := [3]string{repository, targetOrg, release.GetTagName()}
+	if i, ok := s.index[key]; ok {
+		s.releases[i] = entry
+	} else {
+		s.index[key] = len(s.releases)
+		s.releases = append(s.releases, entry)
+	}
+
+	// Replace the file at once so a kill during the write keeps the previous state
+	tmpFile := s.fileName + ".tmp"
+	if err := files.CreateJSON(s.releases, tmpFile); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	if err := os.Rename(tmpFile, s.fileName); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
+
+// recordCompletedRelease records a release migrated with all of its assets in the state of the
+// run, only warning when the state file can't be written
+func (r *run) recordCompletedRelease(repository string, targetOrg string, release *github.RepositoryRelease) {
+	if err := r.state.record(repository, targetOrg, release); err != nil {
+		pterm.Warning.Printf("%v", err)
+	}
+}
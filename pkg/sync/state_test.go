@@ -0,0 +1,149 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+)
+
+// targetLookupBackend counts the lookups of target releases
+type targetLookupBackend struct {
+	*uploadRecordingBackend
+	lookups int
+}
+
+func (b *targetLookupBackend) GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	b.lookups++
+	return b.uploadRecordingBackend.GetTargetRepositoryReleases(owner, repository)
+}
+
+func (b *targetLookupBackend) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	b.lookups++
+	return b.uploadRecordingBackend.ReleaseExists(owner, repository, release)
+}
+
+func TestMigratorStateFile(t *testing.T) {
+	uploads := &uploadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 2, AssetSize: 10}), failing: "asset-2.zip"}
+	backend := &targetLookupBackend{uploadRecordingBackend: uploads}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithStateFile(stateFile), WithEventHandler(&recordingEventHandler{}))
+
+	// A release with a failed asset isn't recorded
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file without a completed release, got %v", err)
+	}
+
+	uploads.failing = ""
+	uploads.uploads = nil
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if got := strings.Join(uploads.uploads, ","); got != "asset-2.zip,asset-2.zip" {
+		t.Errorf("Expected the failed assets to be uploaded again, got %q", got)
+	}
+	state, err := loadMigrationState(stateFile, true)
+	if err != nil {
+		t.Fatalf("loadMigrationState returned an error: %v", err)
+	}
+	if len(state.releases) != 2 || state.releases[0].Tag != "v2.0.0" || strings.Join(state.releases[0].Assets, ",") != "asset-1.zip,asset-2.zip" {
+		t.Errorf("Unexpected state: %+v", state.releases)
+	}
+
+	// The recorded releases are skipped without looking them up in the target
+	uploads.uploads = nil
+	backend.lookups = 0
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if summary.Releases != 2 || summary.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if backend.lookups != 0 || len(uploads.uploads) != 0 {
+		t.Errorf("Expected no target lookup nor upload, got %d lookups and uploads %v", backend.lookups, uploads.uploads)
+	}
+}
+
+func TestMigratorStateFileTwoPhase(t *testing.T) {
+	uploads := &uploadRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, AssetsPerRelease: 2, AssetSize: 10}), failing: "asset-2.zip"}
+	backend := &targetLookupBackend{uploadRecordingBackend: uploads}
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	migrator := NewMigrator(WithClient(backend), WithRepositories("repo"), WithStateFile(stateFile), WithTwoPhase(filepath.Join(dir, "checkpoint.json")), WithEventHandler(&recordingEventHandler{}))
+
+	// The releases are only recorded once the asset phase migrated all of their assets
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file without a completed release, got %v", err)
+	}
+
+	uploads.failing = ""
+	if _, err := migrator.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	state, err := loadMigrationState(stateFile, true)
+	if err != nil {
+		t.Fatalf("loadMigrationState returned an error: %v", err)
+	}
+	if len(state.releases) != 2 || strings.Join(state.releases[0].Assets, ",") != "asset-1.zip,asset-2.zip" {
+		t.Errorf("Unexpected state: %+v", state.releases)
+	}
+
+	// Both phases skip the recorded releases
+	uploads.uploads = nil
+	backend.lookups = 0
+	summary, err := migrator.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate returned an error: %v", err)
+	}
+	if summary.Failed != 0 || backend.lookups != 0 || len(uploads.uploads) != 0 {
+		t.Errorf("Expected no target lookup nor upload, got %+v, %d lookups and uploads %v", summary, backend.lookups, uploads.uploads)
+	}
+}
+
+func TestMigrationStateCompleted(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	state, err := loadMigrationState(stateFile, false)
+	if err != nil {
+		t.Fatalf("loadMigrationState returned an error: %v", err)
+	}
+	release := &github.RepositoryRelease{TagName: github.String("v1.0.0"), Assets: []*github.ReleaseAsset{{Name: github.String("a.zip")}}}
+	if state.completed("source-org/repo", "target-org", release) {
+		t.Error("Expected a release missing from the state not to be completed")
+	}
+	if err := state.record("source-org/repo", "target-org", release); err != nil {
+		t.Fatalf("record returned an error: %v", err)
+	}
+
+	reloaded, err := loadMigrationState(stateFile, false)
+	if err != nil {
+		t.Fatalf("loadMigrationState returned an error: %v", err)
+	}
+	if !reloaded.completed("source-org/repo", "target-org", release) {
+		t.Error("Expected the recorded release to be completed")
+	}
+	if reloaded.completed("source-org/repo", "other-org", release) {
+		t.Error("Expected a release recorded for another target organization not to be completed")
+	}
+	release.Assets = append(release.Assets, &github.ReleaseAsset{Name: github.String("b.zip")})
+	if reloaded.completed("source-org/repo", "target-org", release) {
+		t.Error("Expected a release with a new asset not to be completed")
+	}
+
+	if err := os.WriteFile(stateFile, []byte(`[{"repository":"source-org/repo"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadMigrationState(stateFile, false); err == nil {
+		t.Error("Expected an error for an entry without a tag")
+	}
+}
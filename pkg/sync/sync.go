@@ -1,70 +1,106 @@
 package sync
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v62/github"
 	"github.com/mona-actions/gh-migrate-releases/internal/api"
-	"github.com/mona-actions/gh-migrate-releases/internal/files"
+	"github.com/mona-actions/gh-migrate-releases/internal/logging"
 	"github.com/mona-actions/gh-migrate-releases/internal/mapping"
 	"github.com/pterm/pterm"
 	"github.com/spf13/viper"
 )
 
-func SyncReleases() {
-	// Get all releases from source repository
-	checkVars()
-
-	var totalReleases, totalFailed int
-
-	if viper.GetString("REPOSITORY_LIST") != "" {
-		// Read repository list from file
-		repositories, err := files.ReadRepositoryListFromFile(viper.GetString("REPOSITORY_LIST"))
-		if err != nil {
-			pterm.Error.Printf("Error reading repository list: %v", err)
-			os.Exit(1)
-		}
-
-		// Loop through each repository in the list
-		for _, repository := range repositories {
+// SyncReleases migrates the releases described by the viper configuration and reports the
+// summary of the run. Repositories that fail are reported in the summary, an error is only
+// returned for an invalid configuration or a cancelled run, leaving the exit code to the caller.
+func SyncReleases(ctx context.Context) error {
+	migrator, err := NewMigratorFromConfig()
+	if err != nil {
+		return err
+	}
 
-			releasesCount, failedReleases, err := migrateRepositoryReleases(repository)
-			if err != nil {
-				pterm.Error.Printf("Error migrating repository releases: %v", err)
-			}
+	summary, err := migrator.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	ReportSummary(summary)
+	return nil
+}
 
-			totalReleases += releasesCount
-			totalFailed += failedReleases
+// ReportSummary writes the summary and failures files and prints the summary of a run, or
+// comments it on the triggering issue in GitHub Actions
+func ReportSummary(summary Summary) {
+	totalFailed, totalSkipped := summary.Failed, summary.Skipped
+	r := summary.reportRun()
+	s := r.settings
 
+	// Write the summary for tooling
+	if s.summaryFile != "" {
+		err := writeSummaryFile(s.summaryFile, summary, r.timings)
+		if err != nil {
+			pterm.Error.Printf("Error writing summary file: %v\n", err)
 		}
-	} else if viper.GetString("REPOSITORY") != "" {
-		// Migrate releases from a single repository
-		repository := viper.GetString("REPOSITORY")
+	}
 
-		releasesCount, failedReleases, err := migrateRepositoryReleases(repository)
+	// Write the repositories to retry in the next run
+	if s.failuresFile != "" {
+		err := s.writeFailuresFile(s.failuresFile, summary.Results)
 		if err != nil {
-			pterm.Error.Printf("Error migrating repository releases: %v", err)
+			pterm.Error.Printf("Error: %v\n", err)
+		} else if totalFailed > 0 {
+			pterm.Info.Printf("Repositories with failures written to %s\n", s.failuresFile)
 		}
+	}
 
-		totalReleases += releasesCount
-		totalFailed += failedReleases
-
-	} else {
-		pterm.Error.Println("Error: No repository or repository list specified")
-		os.Exit(1)
+	// Write the assets to retry with --retry-manifest in the next run
+	if s.failedAssetsFile != "" {
+		assets := r.recordedFailedAssets()
+		if err := writeFailedAssetsFile(s.failedAssetsFile, assets); err != nil {
+			pterm.Error.Printf("Error: %v\n", err)
+		} else if len(assets) > 0 {
+			pterm.Info.Printf("%d failed assets written to %s\n", len(assets), s.failedAssetsFile)
+		}
 	}
 
 	// checks if running in a GitHub Actions Environment
 	if os.Getenv("CI") == "true" && os.Getenv("GITHUB_ACTIONS") == "true" {
 		// Print in a README Table format the number of releases created
-		message := fmt.Sprintf(
-			"| No. of Releases | Succeeded | Failed |\n"+
-				"| --------------- | --------- | ------ |\n"+
-				"| %d | %d | %d |\n",
-			totalReleases, totalReleases-totalFailed, totalFailed,
-		)
+		message := formatSummaryTable(summary)
+		if s.skipEmptyReleases {
+			message += fmt.Sprintf("\nSkipped empty releases: %d\n", totalSkipped)
+		}
+		if s.updateExisting {
+			message += fmt.Sprintf("\nUpdated existing releases: %d, unchanged: %d\n", summary.Updated, summary.Unchanged)
+		}
+		if summary.FailedRepositories > 0 {
+			message += fmt.Sprintf("\nRepositories whose releases could not be fetched: %d\n", summary.FailedRepositories)
+		}
+		if oversize := r.recordedOversizeAssets(); len(oversize) > 0 {
+			message += fmt.Sprintf("\nOversize assets not migrated: %s\n", strings.Join(oversize, ", "))
+		}
+		if rejected := r.recordedRejectedAssets(); len(rejected) > 0 {
+			message += fmt.Sprintf("\nAssets with a content type not allowed: %s\n", strings.Join(rejected, ", "))
+		}
+		if diverged := divergedReleaseFlags(summary); len(diverged) > 0 {
+			message += fmt.Sprintf("\nReleases whose draft or prerelease flags diverge from the source: %s\n", strings.Join(diverged, ", "))
+		}
+		if s.assetCache || s.dedupeAssets {
+			hits, misses := api.CacheStats()
+			message += fmt.Sprintf("\nAsset cache hits: %d, misses: %d\n", hits, misses)
+		}
+		message += fmt.Sprintf("\nAPI requests: %s\n", formatRequestCounts())
+		message += fmt.Sprintf("\nStage timings: %s\n", r.timings.format())
+		if used, exhausted := api.RetriesUsed(); exhausted {
+			message += fmt.Sprintf("\nRetries: %d, the retry budget was exhausted\n", used)
+		} else if used > 0 {
+			message += fmt.Sprintf("\nRetries: %d\n", used)
+		}
 		organization, repository, issueNumber, err := api.GetDatafromGitHubContext()
 		if issueNumber == 0 {
 			return // skip if is not an issue event
@@ -72,72 +108,340 @@ func SyncReleases() {
 			if err != nil {
 				pterm.Error.Printf("Error getting issue number: %v", err)
 			}
-			err = api.WriteToIssue(organization, repository, issueNumber, message)
-			if err != nil {
-				pterm.Error.Printf("Error writing releases table to issue: %v", err)
+			// The summary isn't lost when it can't be commented
+			if !r.writeIssueComment(organization, repository, issueNumber, message, "summary") {
+				r.printSummary(summary)
 			}
 		}
 	} else {
-		pterm.Info.Printf("Total Releases: %d\n", totalReleases)
-		pterm.Info.Printf("Succeeded: %d\n", totalReleases-totalFailed)
-		pterm.Info.Printf("Failed: %d\n", totalFailed)
+		r.printSummary(summary)
+	}
+}
 
+// reportRun returns the run of the summary, or a run without records for a summary built by the
+// caller
+func (s Summary) reportRun() *run {
+	if s.run != nil {
+		return s.run
 	}
+	return newRun(api.NewRESTClient(), defaultSettings())
+}
 
+// formatSummaryTable formats the totals and the result of each repository of a run, with their
+// status and the time they took, as Markdown tables
+func formatSummaryTable(summary Summary) string {
+	var builder strings.Builder
+	builder.WriteString("| No. of Releases | Succeeded | Failed | Duration |\n")
+	builder.WriteString("| --------------- | --------- | ------ | -------- |\n")
+	fmt.Fprintf(&builder, "| %d | %d | %d | %s |\n", summary.Releases, summary.Releases-summary.Failed, summary.Failed, summary.Elapsed.Round(time.Millisecond))
+
+	if len(summary.Results) == 0 {
+		return builder.String()
+	}
+	builder.WriteString("\n| Repository | Status | Releases | Succeeded | Failed | Duration |\n")
+	builder.WriteString("| ---------- | ------ | -------- | --------- | ------ | -------- |\n")
+	for _, result := range summary.Results {
+		fmt.Fprintf(&builder, "| %s | %s | %d | %d | %d | %s |\n", result.Repository, result.Status(), result.Releases, result.Releases-result.Failed, result.Failed, result.Duration.Round(time.Millisecond))
+	}
+	return builder.String()
 }
 
-func checkVars() {
+// printSummary prints the summary of a run
+func (r *run) printSummary(summary Summary) {
+	s := r.settings
+	totalReleases, totalFailed, totalSkipped := summary.Releases, summary.Failed, summary.Skipped
+	pterm.Info.Printf("Total Releases: %d\n", totalReleases)
+	pterm.Info.Printf("Succeeded: %d\n", totalReleases-totalFailed)
+	pterm.Info.Printf("Failed: %d\n", totalFailed)
+	if summary.FailedRepositories > 0 {
+		pterm.Warning.Printf("Repositories whose releases could not be fetched: %d\n", summary.FailedRepositories)
+	}
+	printRepositoryStatuses(summary)
+	if s.skipEmptyReleases {
+		pterm.Info.Printf("Skipped empty releases: %d\n", totalSkipped)
+	}
+	if s.updateExisting {
+		pterm.Info.Printf("Updated existing releases: %d, unchanged: %d\n", summary.Updated, summary.Unchanged)
+	}
+	for _, asset := range r.recordedOversizeAssets() {
+		pterm.Warning.Printf("Oversize asset not migrated: %s\n", asset)
+	}
+	if rejected := r.recordedRejectedAssets(); len(rejected) > 0 {
+		pterm.Warning.Printf("Assets with a content type not allowed: %d\n", len(rejected))
+		for _, asset := range rejected {
+			pterm.Warning.Printf("Content type not allowed: %s\n", asset)
+		}
+	}
+	for _, release := range divergedReleaseFlags(summary) {
+		pterm.Warning.Printf("Draft or prerelease flags diverge from the source: %s\n", release)
+	}
+	if s.assetCache || s.dedupeAssets {
+		hits, misses := api.CacheStats()
+		pterm.Info.Printf("Asset cache hits: %d, misses: %d\n", hits, misses)
+	}
+	pterm.Info.Printf("API requests: %s\n", formatRequestCounts())
+	pterm.Info.Printf("Stage timings: %s\n", r.timings.format())
+	if used, exhausted := api.RetriesUsed(); exhausted {
+		pterm.Warning.Printf("Retries: %d, the retry budget was exhausted\n", used)
+	} else if used > 0 {
+		pterm.Info.Printf("Retries: %d\n", used)
+	}
+	if s.mappingStats {
+		printMappingStats()
+	}
+}
+
+// printRepositoryStatuses prints whether each repository of the run succeeded, partially failed
+// or errored, followed by the number of repositories with each status
+func printRepositoryStatuses(summary Summary) {
+	counts := map[RepositoryStatus]int{}
+	for _, result := range summary.Results {
+		status := result.Status()
+		counts[status]++
+		switch {
+		case status == StatusSucceeded:
+			pterm.Success.Printf("%s: %s, %d releases\n", result.Repository, status, result.Releases)
+		case status == StatusPartiallyFailed:
+			pterm.Warning.Printf("%s: %s, %d of %d releases failed\n", result.Repository, status, result.Failed, result.Releases)
+		case result.Err != nil:
+			pterm.Error.Printf("%s: %s: %v\n", result.Repository, status, result.Err)
+		default:
+			pterm.Error.Printf("%s: %s, all %d releases failed\n", result.Repository, status, result.Releases)
+		}
+	}
+	if len(summary.Results) > 0 {
+		pterm.Info.Printf("Repositories: %d %s, %d %s, %d %s\n", counts[StatusSucceeded], StatusSucceeded, counts[StatusPartiallyFailed], StatusPartiallyFailed, counts[StatusErrored], StatusErrored)
+	}
+}
+
+// printMappingStats prints the substitutions made with the mapping file and the rules that
+// never matched, which are likely typos
+func printMappingStats() {
+	var total int
+	var unused []string
+	for _, rule := range mapping.MappingStats() {
+		total += rule.Hits
+		if rule.Hits == 0 {
+			unused = append(unused, rule.Source)
+		}
+	}
+
+	pterm.Info.Printf("Mapping substitutions: %d\n", total)
+	if len(unused) > 0 {
+		pterm.Warning.Printf("Unused mapping rules: %s\n", strings.Join(unused, ", "))
+	}
+}
+
+// formatRequestCounts formats the API requests sent during the run, e.g. "12 (create: 2, list: 10)"
+func formatRequestCounts() string {
+	counts, total := api.RequestCounts()
+	var categories []string
+	for _, count := range counts {
+		categories = append(categories, fmt.Sprintf("%s: %d", count.Category, count.Count))
+	}
+	if len(categories) == 0 {
+		return fmt.Sprint(total)
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(categories, ", "))
+}
+
+// checkVars validates the configuration of the repositories and the connections of the run, the
+// settings of its releases being validated by loadSettings
+func checkVars() error {
 	//check that repository and repository list are not sent at the same time
 	if viper.GetString("REPOSITORY") != "" && viper.GetString("REPOSITORY_LIST") != "" {
-		pterm.Error.Println("Error: Cannot specify both a repository and a repository list")
-		os.Exit(1)
+		return fmt.Errorf("cannot specify both a repository and a repository list")
+	} else if viper.GetString("RETRY_MANIFEST") != "" && (viper.GetString("REPOSITORY") != "" || viper.GetString("REPOSITORY_LIST") != "") {
+		return fmt.Errorf("cannot specify a retry manifest with a repository or a repository list")
+	} else if err := checkTargetConfirmation(viper.GetString("CONFIRM_TARGET"), viper.GetString("TARGET_ORGANIZATION"), viper.GetString("REPOSITORY")); err != nil {
+		return err
 	} else if viper.GetString("REPOSITORY") != "" && viper.GetString("SOURCE_ORGANIZATION") == "" {
-		pterm.Error.Println("Error: Source organization is required when specifying a repository")
-		os.Exit(1)
+		return fmt.Errorf("source organization is required when specifying a repository")
+	} else if _, err := api.ParseCustomHeaders(viper.GetString("CUSTOM_HEADERS")); err != nil {
+		return err
+	} else if err := validateEndpointURLs(); err != nil {
+		return err
+	} else if err := checkEnterpriseHosts(); err != nil {
+		return err
 	}
+	return nil
 }
 
-func migrateRepositoryReleases(repository string) (int, int, error) {
-	var owner string
+// validateEndpointURLs validates the API and upload URLs given in place of the ones derived from
+// the source and target hostnames
+func validateEndpointURLs() error {
+	for _, key := range []string{"SOURCE_API_URL", "SOURCE_UPLOAD_URL", "TARGET_API_URL", "TARGET_UPLOAD_URL"} {
+		if value := viper.GetString(key); value != "" {
+			if err := api.ValidateEndpointURL(value); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkEnterpriseHosts refuses, with REQUIRE_ENTERPRISE, a source or target resolving to
+// github.com, e.g. because its hostname was left empty
+func checkEnterpriseHosts() error {
+	if !viper.GetBool("REQUIRE_ENTERPRISE") {
+		return nil
+	}
+	if api.ResolvesToGitHubDotCom(viper.GetString("SOURCE_HOSTNAME"), viper.GetString("SOURCE_API_URL")) {
+		return fmt.Errorf("the source resolves to github.com, set a source hostname or drop --require-enterprise")
+	}
+	if api.ResolvesToGitHubDotCom(viper.GetString("TARGET_HOSTNAME"), viper.GetString("TARGET_API_URL")) {
+		return fmt.Errorf("the target resolves to github.com, set a target hostname or drop --require-enterprise")
+	}
+	return nil
+}
+
+// splitRepository returns the owner and name of a repository entry, defaulting the owner
+// to the source organization when the entry has no owner
+func (s *settings) splitRepository(repository string) (string, string) {
 	// if repository includes owner, split it
 	if strings.Contains(repository, "/") {
 		repositoryParts := strings.Split(repository, "/")
-		owner = repositoryParts[0]
-		repository = repositoryParts[1]
-	} else {
-		owner = viper.GetString("SOURCE_ORGANIZATION")
+		return repositoryParts[0], repositoryParts[1]
 	}
+	return s.sourceOrganization, repository
+}
 
-	targetOrg := viper.GetString("TARGET_ORGANIZATION")
+// repositoryName returns the name of a repository entry, given as name or owner/name
+func repositoryName(repository string) string {
+	_, name := (&settings{}).splitRepository(repository)
+	return name
+}
+
+// migrateRepositories migrates each repository in turn and reports its result to the event
+// handler as soon as it completes
+func (r *run) migrateRepositories(repositories []string, prefetched map[string]repositoryReleases, migrate migrateFunc, handler EventHandler) Summary {
+	s := Summary{run: r}
+	for i, repository := range repositories {
+		if i > 0 {
+			r.repoDelay.wait()
+		}
+
+		var fetched *repositoryReleases
+		if result, ok := prefetched[repository]; ok {
+			fetched = &result
+		}
+
+		logging.SetContext(repository, "")
+		start := r.timings.now()
+		result := migrate(repository, fetched)
+		result.Duration = r.timings.now().Sub(start)
+		logging.SetContext("", "")
+		if result.Err != nil {
+			pterm.Error.Printf("Error migrating repository releases: %v", result.Err)
+		}
+		handler.RepositoryCompleted(result)
+
+		s.add(result)
+	}
+	return s
+}
+
+// migrateRepositoryReleases migrates the releases of a repository. When fetched is nil the
+// source releases are fetched here, otherwise the prefetched releases are used.
+func (r *run) migrateRepositoryReleases(repositoryEntry string, fetched *repositoryReleases) RepositoryResult {
+	return r.migrateRepository(repositoryEntry, fetched, r.settings.optionsForRepository(repositoryEntry))
+}
+
+// migrateRepository migrates the releases of a repository with the given options
+func (r *run) migrateRepository(repositoryEntry string, fetched *repositoryReleases, options repositoryOptions) RepositoryResult {
+	s := r.settings
+	owner, repository := s.splitRepository(repositoryEntry)
+
+	targetOrg := s.targetOrg(repositoryEntry)
+	fields := s.fields
 
 	fetchReleasesSpinner, _ := pterm.DefaultSpinner.Start("Fetching releases from repository: ", repository)
-	releases, err := api.GetSourceRepositoryReleases(owner, repository)
+	if fetched == nil {
+		result := r.fetchRepositoryReleases(owner, repository)
+		fetched = &result
+	}
+	releases, err := fetched.releases, fetched.err
 	if err != nil {
-		pterm.Fatal.Printf("Error: %v", err)
+		// Move on to the next repository, e.g. when a repository of the list was deleted
 		fetchReleasesSpinner.Fail()
+		return RepositoryResult{Repository: repositoryEntry, Err: fmt.Errorf("%w: %v", errFetchReleases, err)}
+	}
+
+	releases, skipped := s.selectReleases(releases, options, repository)
+
+	// Validated by loadSettings
+	_ = mapping.ApplyTagPrefix(releases, s.tagPrefix, owner, repository)
+
+	// Only migrate the releases published since the newest release of the target
+	if s.newerThanTarget {
+		releases = r.newerThanTarget(releases, targetOrg, repository)
 	}
+	prereleasesOnly, stableOnly := s.prereleasesOnly, s.stableOnly
 
-	// Get the latest release ID for comparison
-	var latestID int64
-	latestRelease, err := api.GetSourceRepositoryLatestRelease(owner, repository)
+	// Get the source latest release, matched by tag with the created target release
+	sourceLatest, err := fetched.latestRelease, fetched.latestErr
 	if err != nil {
 		pterm.Warning.Printf("Could not fetch latest release: %v", err)
-	} else {
-		latestID = latestRelease.GetID()
+	}
+	latestRelease := selectLatestRelease(releases, sourceLatest, prereleasesOnly, stableOnly)
+	if latestRelease != nil && latestRelease == sourceLatest {
+		// Not part of this run, the target release is looked up with its migrated tag
+		copied := *latestRelease
+		_ = mapping.ApplyTagPrefix([]*github.RepositoryRelease{&copied}, s.tagPrefix, owner, repository)
+		latestRelease = &copied
+	}
+	if latestRelease.GetPrerelease() {
+		pterm.Info.Printf("Source latest release %s is a prerelease", latestRelease.GetName())
+	}
+
+	// Keep the latest release of a target already having a newer release
+	var targetLatest *github.RepositoryRelease
+	if s.preserveTargetLatest && latestRelease != nil && makeLatestValue(s.neverMarkLatest, s.legacyLatest) == "" {
+		targetLatest = r.newerTargetLatest(releases, latestRelease, targetOrg, repository)
 	}
 
 	fetchReleasesSpinner.UpdateText(fmt.Sprintf(" %d Releases fetched successfully!", len(releases)))
 	fetchReleasesSpinner.Success()
 
+	// Releases cannot be created in an archived target
+	releasesCount := len(releases)
+	if err := r.checkArchived(owner, repository, targetOrg); err != nil {
+		return RepositoryResult{Repository: repositoryEntry, Releases: releasesCount, Failed: releasesCount, Skipped: skipped, Err: err}
+	}
+
+	// Recreate the autolinks so references in the release bodies render as links
+	if s.migrateAutolinks {
+		created, err := r.client.MigrateAutolinks(owner, targetOrg, repository)
+		if err != nil {
+			pterm.Warning.Printf("Error migrating autolinks: %v", err)
+		} else if created > 0 {
+			pterm.Info.Printf("Created %d autolinks in repository: %s\n", created, repository)
+		}
+	}
+
+	// Fetch the existing target releases once to check them locally on re-runs. An incremental
+	// sync only checks the few new releases one by one.
+	var inventory *targetInventory
+	if !s.newerThanTarget && !r.state.allCompleted(owner+"/"+repository, targetOrg, releases) {
+		inventory, err = r.loadTargetInventory(targetOrg, repository)
+		if err != nil {
+			pterm.Warning.Printf("Could not fetch target releases, checking them one by one: %v", err)
+		}
+	}
+
 	// Create releases in target repository
 	createReleasesSpinner, _ := pterm.DefaultSpinner.Start("Creating releases in target repository...", repository)
 	var failed int
-	releasesCount := len(releases)
 	var newLatestReleaseID int64
+	var releaseFlags []ReleaseFlags
+	var updated, unchanged int
+	// The transform may rename the releases, the source latest release included
+	latestTag := latestRelease.GetTagName()
 
 	//loop through each release and create it in the target repository
 	for _, release := range releases {
 
+		logging.SetTag(release.GetTagName())
 		createReleasesSpinner.UpdateText("Creating release: " + release.GetName())
 
 		// Modify release body to map new handles and map old urls to new urls
@@ -145,32 +449,125 @@ func migrateRepositoryReleases(repository string) (int, int, error) {
 		if err != nil {
 			pterm.Warning.Printf("Error adding source timestamps: %v", err)
 		}
-		release.Body, err = mapping.ModifyReleaseBody(release.Body, viper.GetString("MAPPING_FILE"))
+		release.Body, err = mapping.ModifyReleaseBody(release.Body, s.mappingFile, s.bodyMapping())
 		if err != nil {
 			pterm.Warning.Printf("Error modifying release body: %v", err)
 		}
+		if s.authorAttribution != "" {
+			release, err = mapping.AddAuthorAttribution(release, s.authorAttribution, s.authorAttributionPosition, s.mappingFile)
+			if err != nil {
+				pterm.Warning.Printf("Error adding author attribution: %v", err)
+			}
+		}
+		// Added after mapping so the source release URL is kept
+		release, err = mapping.AddSourceReferences(release, s.recordSourceIDs, s.linkSourceRelease)
+		if err != nil {
+			pterm.Warning.Printf("Error adding source references: %v", err)
+		}
+		if s.bodyTemplate != nil {
+			release.Body, err = mapping.ApplyBodyTemplate(release.Body, s.bodyTemplate, mapping.TemplateData{
+				Release:            release,
+				SourceOrganization: owner,
+				TargetOrganization: targetOrg,
+				Repository:         repository,
+				SourceURL:          release.GetHTMLURL(),
+				MigratedAt:         time.Now(),
+			})
+			if err != nil {
+				pterm.Warning.Printf("Error applying body template: %v", err)
+			}
+		}
+
+		// Control whether the created release becomes the latest release in the target
+		if makeLatest := makeLatestValue(s.neverMarkLatest, s.legacyLatest); makeLatest != "" {
+			release.MakeLatest = github.String(makeLatest)
+		} else if targetLatest != nil {
+			release.MakeLatest = github.String("false")
+		}
+
+		// Let an embedding program modify the release last, the target release is then found again
+		// with the source tag
+		sourceTag := release.GetTagName()
+		release, err = r.transformRelease(release)
+		if err != nil {
+			failed++
+			pterm.Warning.Printf("Error transforming release: %v", err)
+			continue
+		}
+
+		// Already migrated with its assets according to the state file of an interrupted run
+		if r.state.completed(owner+"/"+repository, targetOrg, release) {
+			pterm.Info.Printf("Release %s already migrated according to the state file, skipping it", release.GetName())
+			continue
+		}
 
 		// Check if release already exists before creating
-		existingRelease, releaseExists := api.ReleaseExists(targetOrg, repository, release)
+		existingRelease, releaseExists := r.releaseExists(inventory, targetOrg, repository, release)
 
 		var newRelease *github.RepositoryRelease
+		existed := releaseExists
 
 		if releaseExists {
 			pterm.Info.Printf("Release already exists with matching tag_name, name, and target_commitish: %v... skipping creation", release.GetName())
 			newRelease = existingRelease
 		} else {
+			// Recreate the annotated tag so its message and tagger are kept
+			if s.migrateAnnotatedTags {
+				created, err := r.client.MigrateAnnotatedTag(owner, targetOrg, repository, release.GetTagName())
+				if err != nil {
+					pterm.Warning.Printf("Error migrating annotated tag %s: %v", release.GetTagName(), err)
+				} else if created {
+					pterm.Info.Printf("Recreated annotated tag %s", release.GetTagName())
+				}
+			}
+
+			// Create the tag before the release so it points to the source commit
+			if s.createMissingTags {
+				created, err := r.client.CreateMissingTag(owner, targetOrg, repository, release.GetTagName())
+				if err != nil {
+					failed++
+					pterm.Warning.Printf("Error creating tag %s: %v", release.GetTagName(), err)
+					continue
+				} else if created {
+					pterm.Info.Printf("Created tag %s", release.GetTagName())
+				}
+			}
+
 			// Create release api call
-			newRelease, err = api.CreateRelease(repository, release)
+			payload := releasePayload(release, fields, s.publishDrafts)
+			r.createPacer.Do(func() {
+				defer r.timings.track(stageCreating)()
+				newRelease, err = r.client.CreateRelease(targetOrg, repository, payload)
+			})
+
+			// The commit of the release can be missing when the source history was rewritten
+			if isMissingCommitError(release, err) {
+				strategy := s.missingCommitStrategy
+				if strategy == missingCommitSkip {
+					pterm.Warning.Printf("Skipping release %s: commit %s not found in the target", release.GetName(), release.GetTargetCommitish())
+					skipped++
+					releasesCount--
+					continue
+				}
+				if retry := missingCommitPayload(payload, strategy); retry != nil {
+					pterm.Warning.Printf("Commit %s not found in the target, creating release %s with strategy %s", release.GetTargetCommitish(), release.GetName(), strategy)
+					r.createPacer.Do(func() {
+						defer r.timings.track(stageCreating)()
+						newRelease, err = r.client.CreateRelease(targetOrg, repository, retry)
+					})
+				}
+			}
 			if err != nil {
-				if strings.Contains(err.Error(), "already exists") {
+				if errors.Is(err, api.ErrReleaseExists) {
 					pterm.Info.Printf("Release already exists: %v... fetching existing release", release.GetName())
 					// Get the existing release to check for assets
-					existingRelease, err := api.GetReleaseByTag(targetOrg, repository, release.GetTagName())
+					existingRelease, err := r.client.GetReleaseByTag(targetOrg, repository, release.GetTagName())
 					if err != nil {
 						pterm.Warning.Printf("Could not retrieve existing release: %v", err)
 						continue
 					}
 					newRelease = existingRelease
+					existed = true
 				} else {
 					failed++
 					createReleasesSpinner.Fail()
@@ -180,49 +577,84 @@ func migrateRepositoryReleases(repository string) (int, int, error) {
 			}
 		}
 
-		// Check if this release was the latest in the source repository
-		if latestID != 0 && release.GetID() == latestID {
-			newLatestReleaseID = newRelease.GetID()
+		// Bring an existing release up to date, only editing it when it changed
+		if existed && s.updateExisting {
+			if update := releaseUpdate(newRelease, releasePayload(release, fields, s.publishDrafts)); update == nil {
+				pterm.Info.Printf("Release %s is up to date, not updating it", release.GetName())
+				unchanged++
+			} else {
+				edited, err := r.client.UpdateRelease(targetOrg, repository, newRelease.GetID(), update)
+				if err != nil {
+					failed++
+					pterm.Warning.Printf("Error updating release: %v", err)
+					continue
+				}
+				pterm.Info.Printf("Updated release %s", release.GetName())
+				// Editing a release doesn't change its assets
+				edited.Assets = newRelease.Assets
+				newRelease = edited
+				updated++
+			}
 		}
 
-		// Download assets from source repository and upload to target repository
-		for _, asset := range release.Assets {
+		// Confirm the draft and prerelease flags landed in the target
+		releaseFlags = append(releaseFlags, checkReleaseFlags(release, newRelease, fields, s.publishDrafts))
+		r.recordCreatedRelease(owner+"/"+repository, sourceTag, newRelease)
 
-			// Check if the asset already exists in the target release
-			if api.AssetExists(newRelease, asset.GetName(), int64(asset.GetSize())) {
-				createReleasesSpinner.UpdateText(fmt.Sprintf("Asset %s already exists, skipping...", asset.GetName()))
-				pterm.Info.Printf("Asset %s already exists in release %s, skipping", asset.GetName(), release.GetName())
-				continue
-			}
+		// Check if this release was the latest in the source repository
+		if latestRelease != nil && sourceTag == latestTag {
+			newLatestReleaseID = newRelease.GetID()
+		}
 
-			err := api.DownloadReleaseAssets(asset)
-			createReleasesSpinner.UpdateText("Downloading asset..." + asset.GetName())
-			if err != nil {
-				pterm.Error.Printf("Error downloading assets: %v", err)
-				continue
+		if options.skipAssets {
+			// A release without assets is complete once created, the asset phase of a two-phase
+			// migration records the others
+			if len(release.Assets) == 0 {
+				r.recordCompletedRelease(owner+"/"+repository, targetOrg, release)
 			}
-			createReleasesSpinner.UpdateText("Uploading assets..." + asset.GetName())
+			continue
+		}
 
-			err = api.UploadAssetViaURL(newRelease.GetUploadURL(), asset)
-			if err != nil {
-				pterm.Error.Printf("Error uploading assets: %v", err)
-				createReleasesSpinner.Fail()
-				continue
-			}
+		// Download assets from source repository and upload to target repository
+		failedAssets := r.migrateReleaseAssets(owner, repository, targetOrg, sourceTag, release, newRelease, createReleasesSpinner)
+		if releaseFailedByAssets(failedAssets, s.failOnAssetError) {
+			pterm.Warning.Printf("Release %s is missing %d assets, counting it as failed", release.GetName(), failedAssets)
+			failed++
+		}
+		if failedAssets == 0 {
+			r.recordCompletedRelease(owner+"/"+repository, targetOrg, release)
 		}
 	}
 
-	// Set the latest release in the target repository
-	if newLatestReleaseID != 0 {
-		err := api.SetLatestRelease(targetOrg, repository, newLatestReleaseID)
-		if latestRelease != nil {
-			pterm.Info.Printf("Marking release %s as latest", latestRelease.GetName())
+	// The source latest release may have been excluded from this run but migrated before
+	if newLatestReleaseID == 0 && latestRelease != nil && fields["make_latest"] {
+		latest := *latestRelease
+		latest.TagName = github.String(latestTag)
+		if tag, err := r.targetTag(owner+"/"+repository, &latest); err != nil {
+			pterm.Warning.Printf("Error transforming latest release: %v", err)
 		} else {
-			pterm.Info.Printf("Marking release (unknown name) as latest")
+			newLatestReleaseID = r.targetReleaseID(targetOrg, repository, tag)
 		}
+	}
+
+	// Set the latest release in the target repository
+	if !fields["make_latest"] {
+		pterm.Info.Printf("Not marking a latest release: make_latest is not a migrated field")
+	} else if s.neverMarkLatest {
+		pterm.Info.Printf("Not marking a latest release: --never-mark-latest is set")
+	} else if s.legacyLatest {
+		pterm.Info.Printf("Not marking a latest release: GitHub picks the latest release by date and version")
+	} else if targetLatest != nil {
+		pterm.Info.Printf("Not marking a latest release: target latest release %s is newer than %s", targetLatest.GetName(), latestRelease.GetName())
+	} else if newLatestReleaseID != 0 {
+		err := r.client.SetLatestRelease(targetOrg, repository, newLatestReleaseID)
 		if err != nil {
 			pterm.Warning.Printf("Error marking latest release: %v", err)
+		} else {
+			pterm.Info.Printf("Marked release %s as latest", latestRelease.GetName())
 		}
+	} else if prereleasesOnly {
+		pterm.Info.Printf("Not marking a latest release: only prereleases were migrated")
 	} else {
 		pterm.Warning.Printf("Could not mark latest release: no releases found or failed to create")
 	}
@@ -230,11 +662,11 @@ func migrateRepositoryReleases(repository string) (int, int, error) {
 	if failed > 0 {
 		createReleasesSpinner.UpdateText("Some Releases failed to create")
 		createReleasesSpinner.Fail()
-		return releasesCount, failed, fmt.Errorf("some releases failed to create")
+		return RepositoryResult{Repository: repositoryEntry, Releases: releasesCount, Failed: failed, Skipped: skipped, Err: fmt.Errorf("some releases failed to create"), ReleaseFlags: releaseFlags, Updated: updated, Unchanged: unchanged}
 	} else {
 		createReleasesSpinner.UpdateText("All Releases created successfully!")
 		createReleasesSpinner.Success()
-		return releasesCount, failed, nil
+		return RepositoryResult{Repository: repositoryEntry, Releases: releasesCount, Failed: failed, Skipped: skipped, ReleaseFlags: releaseFlags, Updated: updated, Unchanged: unchanged}
 	}
 
 }
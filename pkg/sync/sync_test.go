@@ -0,0 +1,398 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// useFakeBackend replaces the backend with a fake for the duration of a test
+func useFakeBackend(t *testing.T, scenario fake.Scenario) *fake.Backend {
+	t.Helper()
+
+	backend := fake.New(scenario)
+	testClient = backend
+	viper.Set("SOURCE_ORGANIZATION", "source-org")
+	viper.Set("TARGET_ORGANIZATION", "target-org")
+	t.Cleanup(func() {
+		testClient = nil
+		viper.Reset()
+	})
+
+	return backend
+}
+
+// testClient is the backend of the runs of a test, set by useFakeBackend
+var testClient api.Client
+
+// newTestRun returns a run of testClient with the settings of the viper configuration
+func newTestRun(t *testing.T) *run {
+	t.Helper()
+
+	s, err := loadSettings()
+	if err != nil {
+		t.Fatalf("loadSettings returned an error: %v", err)
+	}
+	return newRun(testClient, s)
+}
+
+func TestMigrateRepositoryReleasesWithFakeBackend(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3, AssetsPerRelease: 2, AssetSize: 10})
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+	if result.Releases != 3 || result.Failed != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	target := backend.TargetReleases("target-org", "repo")
+	if len(target) != 3 {
+		t.Fatalf("Expected 3 target releases, got %d", len(target))
+	}
+	for _, release := range target {
+		if len(release.Assets) != 2 {
+			t.Errorf("Expected 2 assets in release %s, got %d", release.GetTagName(), len(release.Assets))
+		}
+	}
+
+	// The newest source release is the latest one
+	if backend.LatestReleaseID("target-org", "repo") != target[2].GetID() {
+		t.Errorf("Expected release %s to be marked as latest", target[2].GetTagName())
+	}
+
+	// Running again does not duplicate releases or assets
+	result = newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || len(backend.TargetReleases("target-org", "repo")) != 3 {
+		t.Errorf("Expected the second run to be idempotent: %+v", result)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if len(release.Assets) != 2 {
+			t.Errorf("Expected 2 assets in release %s after the second run, got %d", release.GetTagName(), len(release.Assets))
+		}
+	}
+}
+
+func TestMigrateRepositoryReleasesArchivedTarget(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, ArchivedRepositories: []string{"target-org/repo"}})
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "archived") {
+		t.Errorf("Expected an archived target error, got %v", result.Err)
+	}
+	if result.Failed != 2 {
+		t.Errorf("Expected all releases to be failed, got %+v", result)
+	}
+	if len(backend.TargetReleases("target-org", "repo")) != 0 {
+		t.Errorf("Expected no releases to be created in an archived target")
+	}
+}
+
+func TestMigrateRepositoryReleasesArchivedSource(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2, ArchivedRepositories: []string{"source-org/repo"}})
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Errorf("Expected an archived source to be migrated, got %v", result.Err)
+	}
+	if len(backend.TargetReleases("target-org", "repo")) != 2 {
+		t.Errorf("Expected 2 releases to be created")
+	}
+}
+
+// latestCountingBackend counts the source latest release requests
+type latestCountingBackend struct {
+	*fake.Backend
+	latestCalls int
+}
+
+func (b *latestCountingBackend) GetSourceRepositoryLatestRelease(owner string, repository string) (*github.RepositoryRelease, error) {
+	b.latestCalls++
+	return b.Backend.GetSourceRepositoryLatestRelease(owner, repository)
+}
+
+func TestMigrateRepositoryReleasesSkipsLatestFetch(t *testing.T) {
+	backend := &latestCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+	viper.Set("NEVER_MARK_LATEST", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+	if backend.latestCalls != 0 {
+		t.Errorf("Expected the latest release not to be fetched, got %d requests", backend.latestCalls)
+	}
+	if backend.LatestReleaseID("target-org", "repo") != 0 {
+		t.Errorf("Expected no release to be marked as latest")
+	}
+
+	viper.Set("NEVER_MARK_LATEST", false)
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if backend.latestCalls != 1 {
+		t.Errorf("Expected the latest release to be fetched once, got %d requests", backend.latestCalls)
+	}
+}
+
+// orderRecordingBackend records the tag and release creations in order
+type orderRecordingBackend struct {
+	*fake.Backend
+	calls []string
+}
+
+func (b *orderRecordingBackend) CreateMissingTag(sourceOwner string, targetOwner string, repository string, tagName string) (bool, error) {
+	b.calls = append(b.calls, "tag "+tagName)
+	return b.Backend.CreateMissingTag(sourceOwner, targetOwner, repository, tagName)
+}
+
+func (b *orderRecordingBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	b.calls = append(b.calls, "release "+release.GetTagName())
+	return b.Backend.CreateRelease(owner, repository, release)
+}
+
+func TestMigrateRepositoryReleasesCreatesTagsBeforeReleases(t *testing.T) {
+	backend := &orderRecordingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+	viper.Set("CREATE_MISSING_TAGS", true)
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("migrateRepositoryReleases returned an error: %v", result.Err)
+	}
+
+	expected := []string{"tag v2.0.0", "release v2.0.0", "tag v1.0.0", "release v1.0.0"}
+	if strings.Join(backend.calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected calls %v, got %v", expected, backend.calls)
+	}
+}
+
+func TestMigrateRepositoryReleasesReplacesMislabeledAssets(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 1, AssetSize: 10})
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	// The label of the source asset changes after the first migration
+	source, _ := backend.GetSourceRepositoryReleases("source-org", "repo")
+	source[0].Assets[0].Label = github.String("Installer")
+
+	viper.Set("MATCH_ASSET_LABELS", true)
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if label := backend.TargetReleases("target-org", "repo")[0].Assets[0].GetLabel(); label != "" {
+		t.Errorf("Expected the label to be kept without --replace-broken-assets, got %q", label)
+	}
+
+	viper.Set("REPLACE_BROKEN_ASSETS", true)
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	assets := backend.TargetReleases("target-org", "repo")[0].Assets
+	if len(assets) != 1 || assets[0].GetLabel() != "Installer" {
+		t.Errorf("Expected the asset to be replaced with the new label, got %v", assets)
+	}
+}
+
+func TestMigrateRepositoryReleasesRecoversInterruptedUploads(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1, AssetsPerRelease: 2, AssetSize: 10})
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+
+	// The process died while uploading the first asset, leaving it in the starter state
+	stuck := backend.TargetReleases("target-org", "repo")[0].Assets[0]
+	stuck.State = github.String("starter")
+
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Failed != 0 {
+		t.Fatalf("Expected the re-run to succeed, got %+v", result)
+	}
+	assets := backend.TargetReleases("target-org", "repo")[0].Assets
+	if len(assets) != 2 {
+		t.Fatalf("Expected 2 assets after the recovery, got %v", assets)
+	}
+	for _, asset := range assets {
+		if asset.GetState() != "uploaded" || asset.GetID() == stuck.GetID() {
+			t.Errorf("Expected the stuck asset to be replaced by an uploaded one, got %v", asset)
+		}
+	}
+	if assets[1].GetName() != stuck.GetName() {
+		t.Errorf("Expected %s to be uploaded again, got %v", stuck.GetName(), assets)
+	}
+}
+
+// missingCommitBackend fails to create releases on a commit SHA, as when the commit is missing in the target
+type missingCommitBackend struct {
+	*fake.Backend
+	sha string
+}
+
+func (b *missingCommitBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	for _, release := range releases {
+		release.TargetCommitish = github.String(b.sha)
+	}
+	return releases, err
+}
+
+func (b *missingCommitBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if release.GetTargetCommitish() == b.sha {
+		return nil, errors.New("422 Validation Failed [{Resource:Release Field:target_commitish Code:invalid Message:No commit found for SHA: " + b.sha + "}]")
+	}
+	return b.Backend.CreateRelease(owner, repository, release)
+}
+
+func TestMigrateRepositoryReleasesMissingCommitStrategies(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	tests := []struct {
+		strategy string
+		created  int
+		draft    bool
+		failed   int
+		skipped  int
+	}{
+		{strategy: "fail", created: 0, failed: 1},
+		{strategy: "skip", created: 0, skipped: 1},
+		{strategy: "default-branch", created: 1},
+		{strategy: "draft", created: 1, draft: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			backend := &missingCommitBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1}), sha: sha}
+			testClient = backend
+			viper.Set("MISSING_COMMIT_STRATEGY", test.strategy)
+
+			result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+			if result.Failed != test.failed || result.Skipped != test.skipped {
+				t.Errorf("Expected %d failed and %d skipped releases, got %d and %d", test.failed, test.skipped, result.Failed, result.Skipped)
+			}
+
+			created := backend.TargetReleases("target-org", "repo")
+			if len(created) != test.created {
+				t.Fatalf("Expected %d created releases, got %d", test.created, len(created))
+			}
+			if test.created > 0 {
+				if created[0].GetTargetCommitish() == sha {
+					t.Errorf("Expected the release to be created without the missing commit")
+				}
+				if created[0].GetDraft() != test.draft {
+					t.Errorf("Expected draft to be %v, got %v", test.draft, created[0].GetDraft())
+				}
+			}
+		})
+	}
+}
+
+// unreadableRepositoryBackend fails to list the releases of one source repository
+type unreadableRepositoryBackend struct {
+	*fake.Backend
+	unreadable string
+}
+
+func (b *unreadableRepositoryBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	if repository == b.unreadable {
+		return nil, errors.New("404 Not Found")
+	}
+	return b.Backend.GetSourceRepositoryReleases(owner, repository)
+}
+
+func TestMigrateRepositoriesContinuesAfterFetchFailure(t *testing.T) {
+	backend := &unreadableRepositoryBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2}), unreadable: "deleted-repo"}
+	testClient = backend
+
+	handler := &recordingEventHandler{}
+	r := newTestRun(t)
+	s := r.migrateRepositories([]string{"repo1", "deleted-repo", "repo2"}, nil, r.migrateRepositoryReleases, handler)
+
+	if len(handler.results) != 3 {
+		t.Fatalf("Expected all 3 repositories to be processed, got %d", len(handler.results))
+	}
+	if !errors.Is(handler.results[1].Err, errFetchReleases) {
+		t.Errorf("Expected a fetch error for the deleted repository, got %v", handler.results[1].Err)
+	}
+	if s.FailedRepositories != 1 || s.Releases != 4 || s.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+	for _, repository := range []string{"repo1", "repo2"} {
+		if len(backend.TargetReleases("target-org", repository)) != 2 {
+			t.Errorf("Expected the releases of %s to be migrated", repository)
+		}
+	}
+	if failed := r.settings.failedRepositories(s.Results); len(failed) != 1 || failed[0] != "source-org/deleted-repo" {
+		t.Errorf("Expected the deleted repository to be retried, got %v", failed)
+	}
+}
+
+// existingReleaseBackend reports every release as missing, so creating it hits the existing release
+type existingReleaseBackend struct {
+	*fake.Backend
+}
+
+func (b *existingReleaseBackend) ReleaseExists(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, bool) {
+	return nil, false
+}
+
+func (b *existingReleaseBackend) GetTargetRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	return nil, errors.New("inventory unavailable")
+}
+
+func (b *existingReleaseBackend) CreateRelease(owner string, repository string, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if _, err := b.Backend.GetReleaseByTag("target-org", repository, release.GetTagName()); err == nil {
+		return nil, fmt.Errorf("%w: reworded by the API", api.ErrReleaseExists)
+	}
+	return b.Backend.CreateRelease(owner, repository, release)
+}
+
+func TestMigrateRepositoryReleasesDetectsExistingRelease(t *testing.T) {
+	backend := &existingReleaseBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+
+	newTestRun(t).migrateRepositoryReleases("repo", nil)
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected existing releases to be reused, got %d failed: %v", result.Failed, result.Err)
+	}
+	if target := backend.TargetReleases("target-org", "repo"); len(target) != 2 {
+		t.Errorf("Expected 2 target releases, got %d", len(target))
+	}
+}
+
+func TestSyncReleasesInvalidConfig(t *testing.T) {
+	useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 1})
+	viper.Set("SOURCE_TOKEN", "source-token")
+	viper.Set("TARGET_TOKEN", "target-token")
+	viper.Set("REPOSITORY", "repo")
+	viper.Set("REPOSITORY_LIST", "repositories.txt")
+
+	// The error is returned to the command instead of exiting
+	err := SyncReleases(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "both a repository and a repository list") {
+		t.Errorf("Expected an error for a repository with a repository list, got %v", err)
+	}
+}
+
+func TestCheckEnterpriseHostsRequiresHostnames(t *testing.T) {
+	defer viper.Reset()
+
+	// Without the flag, github.com is allowed
+	if err := checkEnterpriseHosts(); err != nil {
+		t.Errorf("Expected github.com to be allowed without the flag, got %v", err)
+	}
+
+	viper.Set("REQUIRE_ENTERPRISE", true)
+	if err := checkEnterpriseHosts(); err == nil || !strings.Contains(err.Error(), "source") {
+		t.Errorf("Expected the empty source hostname to be refused, got %v", err)
+	}
+
+	viper.Set("SOURCE_HOSTNAME", "github.example.com")
+	if err := checkEnterpriseHosts(); err == nil || !strings.Contains(err.Error(), "target") {
+		t.Errorf("Expected the empty target hostname to be refused, got %v", err)
+	}
+
+	viper.Set("TARGET_HOSTNAME", "github.example.com")
+	if err := checkEnterpriseHosts(); err != nil {
+		t.Errorf("Expected enterprise hostnames to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+func TestMigrateRepositoryReleasesWithTagPrefix(t *testing.T) {
+	backend := useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})
+	viper.Set("TAG_PREFIX", "{{.Owner}}/")
+
+	// Releases of two source repositories with the same tags end up in one target repository
+	for _, repository := range []string{"org-a/repo", "org-b/repo"} {
+		result := newTestRun(t).migrateRepositoryReleases(repository, nil)
+		if result.Err != nil || result.Failed != 0 {
+			t.Fatalf("newTestRun(t).migrateRepositoryReleases(%s) failed %d releases: %v", repository, result.Failed, result.Err)
+		}
+	}
+
+	tags := map[string]bool{}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		tags[release.GetTagName()] = true
+	}
+	for _, tag := range []string{"org-a/v1.0.0", "org-a/v2.0.0", "org-b/v1.0.0", "org-b/v2.0.0"} {
+		if !tags[tag] {
+			t.Errorf("Expected a release with tag %s, got %v", tag, tags)
+		}
+	}
+
+	// A re-run finds the releases with the prefixed tag
+	result := newTestRun(t).migrateRepositoryReleases("org-a/repo", nil)
+	if result.Err != nil || result.Failed != 0 {
+		t.Fatalf("Expected the re-run to succeed, got %d failed: %v", result.Failed, result.Err)
+	}
+	if target := backend.TargetReleases("target-org", "repo"); len(target) != 4 {
+		t.Errorf("Expected the re-run not to create releases again, got %d releases", len(target))
+	}
+}
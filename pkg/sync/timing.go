@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+	gosync "sync"
+	"time"
+)
+
+// Stages of the migration whose time is reported in the summary
+const (
+	stageListing     = "listing"
+	stageCreating    = "creating"
+	stageDownloading = "downloading"
+	stageUploading   = "uploading"
+)
+
+var stageOrder = []string{stageListing, stageCreating, stageDownloading, stageUploading}
+
+// stageTimings accumulates the time spent in each stage across the run. It is safe for
+// concurrent use, so the time of concurrent operations adds up.
+type stageTimings struct {
+	mu        gosync.Mutex
+	durations map[string]time.Duration
+	now       func() time.Time
+}
+
+func newStageTimings() *stageTimings {
+	return &stageTimings{durations: map[string]time.Duration{}, now: time.Now}
+}
+
+// track starts timing a stage and returns the function that stops it, e.g.
+// defer r.timings.track(stageListing)()
+func (s *stageTimings) track(stage string) func() {
+	start := s.now()
+	return func() {
+		elapsed := s.now().Sub(start)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.durations[stage] += elapsed
+	}
+}
+
+// snapshot returns the time spent in each stage
+func (s *stageTimings) snapshot() map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(s.durations))
+	for stage, duration := range s.durations {
+		durations[stage] = duration
+	}
+	return durations
+}
+
+// format formats the stages with recorded time, e.g. "listing: 1.2s, creating: 3s"
+func (s *stageTimings) format() string {
+	durations := s.snapshot()
+	var parts []string
+	for _, stage := range stageOrder {
+		if duration, ok := durations[stage]; ok {
+			parts = append(parts, fmt.Sprintf("%s: %s", stage, duration.Round(time.Millisecond)))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
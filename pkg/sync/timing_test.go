@@ -0,0 +1,55 @@
+package sync
+
+import (
+	gosync "sync"
+	"testing"
+	"time"
+)
+
+func TestStageTimingsAccumulate(t *testing.T) {
+	var mu gosync.Mutex
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newStageTimings()
+	s.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return clock
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		clock = clock.Add(d)
+	}
+
+	stop := s.track(stageListing)
+	advance(2 * time.Second)
+	stop()
+
+	// Concurrent uploads add up
+	var wg gosync.WaitGroup
+	stops := make([]func(), 4)
+	for i := range stops {
+		stops[i] = s.track(stageUploading)
+	}
+	advance(time.Second)
+	for _, stop := range stops {
+		wg.Add(1)
+		go func(stop func()) {
+			defer wg.Done()
+			stop()
+		}(stop)
+	}
+	wg.Wait()
+
+	durations := s.snapshot()
+	if durations[stageListing] != 2*time.Second {
+		t.Errorf("Expected 2s of listing, got %v", durations[stageListing])
+	}
+	// 4 concurrent uploads of 1s each
+	if durations[stageUploading] != 4*time.Second {
+		t.Errorf("Expected 4s of uploading, got %v", durations[stageUploading])
+	}
+	if got := s.format(); got != "listing: 2s, uploading: 4s" {
+		t.Errorf("Unexpected format: %s", got)
+	}
+}
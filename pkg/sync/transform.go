@@ -0,0 +1,54 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// ReleaseTransform modifies a release before it is created in the target, e.g. to rewrite its tag
+// or strip fields, returning the release to create
+type ReleaseTransform func(*github.RepositoryRelease) (*github.RepositoryRelease, error)
+
+// transformRelease applies the release transform of the run, if any, to a release
+func (r *run) transformRelease(release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	if r.transform == nil {
+		return release, nil
+	}
+	transformed, err := r.transform(release)
+	if err != nil {
+		return nil, err
+	}
+	if transformed == nil {
+		return nil, fmt.Errorf("the release transform returned no release")
+	}
+	return transformed, nil
+}
+
+// recordCreatedRelease records the target release created from the source release of repository
+// with the tag sourceTag
+func (r *run) recordCreatedRelease(repository string, sourceTag string, newRelease *github.RepositoryRelease) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.createdTags[[2]string{repository, sourceTag}] = newRelease.GetTagName()
+}
+
+// targetTag returns the tag of the target release of a source release of repository: the tag of
+// the release created from it during the run, or otherwise its tag after the release transform,
+// e.g. for a release migrated by a previous run
+func (r *run) targetTag(repository string, release *github.RepositoryRelease) (string, error) {
+	r.mu.Lock()
+	tag := r.createdTags[[2]string{repository, release.GetTagName()}]
+	r.mu.Unlock()
+	if tag != "" {
+		return tag, nil
+	}
+
+	copied := *release
+	transformed, err := r.transformRelease(&copied)
+	if err != nil {
+		return "", err
+	}
+	return transformed.GetTagName(), nil
+}
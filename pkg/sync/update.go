@@ -0,0 +1,34 @@
+package sync
+
+import "github.com/google/go-github/v62/github"
+
+// releaseUpdate returns the edit bringing an existing target release up to date with the
+// payload built from the source, or nil when the release already matches. Only the name, body
+// and draft and prerelease flags are compared, the other fields not being returned by GitHub or,
+// like the latest release, being set separately.
+func releaseUpdate(existing *github.RepositoryRelease, payload *github.RepositoryRelease) *github.RepositoryRelease {
+	update := &github.RepositoryRelease{TagName: existing.TagName}
+	changed := false
+
+	if payload.Name != nil && payload.GetName() != existing.GetName() {
+		update.Name = payload.Name
+		changed = true
+	}
+	if payload.Body != nil && payload.GetBody() != existing.GetBody() {
+		update.Body = payload.Body
+		changed = true
+	}
+	if payload.Draft != nil && payload.GetDraft() != existing.GetDraft() {
+		update.Draft = payload.Draft
+		changed = true
+	}
+	if payload.Prerelease != nil && payload.GetPrerelease() != existing.GetPrerelease() {
+		update.Prerelease = payload.Prerelease
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return update
+}
@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/mona-actions/gh-migrate-releases/internal/api/fake"
+	"github.com/spf13/viper"
+)
+
+// updateCountingBackend counts the releases edited in the target and can change the body of the
+// source releases
+type updateCountingBackend struct {
+	*fake.Backend
+	updates int
+	body    string
+}
+
+func (b *updateCountingBackend) GetSourceRepositoryReleases(owner string, repository string) ([]*github.RepositoryRelease, error) {
+	releases, err := b.Backend.GetSourceRepositoryReleases(owner, repository)
+	if b.body != "" {
+		for _, release := range releases {
+			release.Body = github.String(b.body)
+		}
+	}
+	return releases, err
+}
+
+func (b *updateCountingBackend) UpdateRelease(owner string, repository string, releaseID int64, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	b.updates++
+	return b.Backend.UpdateRelease(owner, repository, releaseID, release)
+}
+
+func TestUpdateExistingSkipsUnchangedReleases(t *testing.T) {
+	backend := &updateCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 3})}
+	testClient = backend
+
+	if result := newTestRun(t).migrateRepositoryReleases("repo", nil); result.Err != nil {
+		t.Fatalf("First run returned an error: %v", result.Err)
+	}
+
+	viper.Set("UPDATE_EXISTING", true)
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("Second run returned an error: %v", result.Err)
+	}
+	if backend.updates != 0 {
+		t.Errorf("Expected no release to be edited, got %d edits", backend.updates)
+	}
+	if result.Unchanged != 3 || result.Updated != 0 {
+		t.Errorf("Expected 3 unchanged releases, got %d unchanged and %d updated", result.Unchanged, result.Updated)
+	}
+}
+
+func TestUpdateExistingEditsChangedReleases(t *testing.T) {
+	backend := &updateCountingBackend{Backend: useFakeBackend(t, fake.Scenario{ReleasesPerRepository: 2})}
+	testClient = backend
+
+	if result := newTestRun(t).migrateRepositoryReleases("repo", nil); result.Err != nil {
+		t.Fatalf("First run returned an error: %v", result.Err)
+	}
+
+	viper.Set("UPDATE_EXISTING", true)
+	backend.body = "Edited notes"
+	result := newTestRun(t).migrateRepositoryReleases("repo", nil)
+	if result.Err != nil {
+		t.Fatalf("Second run returned an error: %v", result.Err)
+	}
+	if backend.updates != 2 || result.Updated != 2 || result.Unchanged != 0 {
+		t.Errorf("Expected 2 edited releases, got %d edits, %d updated and %d unchanged", backend.updates, result.Updated, result.Unchanged)
+	}
+	for _, release := range backend.TargetReleases("target-org", "repo") {
+		if !strings.HasPrefix(release.GetBody(), "Edited notes") {
+			t.Errorf("Release %s has body %q, expected the edited notes", release.GetTagName(), release.GetBody())
+		}
+	}
+}
+
+func TestReleaseUpdateOnlyIncludesChangedFields(t *testing.T) {
+	existing := &github.RepositoryRelease{TagName: github.String("v1.0.0"), Name: github.String("One"), Body: github.String("Notes"), Draft: github.Bool(false)}
+
+	if update := releaseUpdate(existing, &github.RepositoryRelease{Name: github.String("One"), Body: github.String("Notes"), Draft: github.Bool(false)}); update != nil {
+		t.Errorf("Expected no update for a matching release, got %+v", update)
+	}
+
+	update := releaseUpdate(existing, &github.RepositoryRelease{Name: github.String("One"), Body: github.String("New notes"), Draft: github.Bool(false)})
+	if update == nil {
+		t.Fatal("Expected an update for a changed body")
+	}
+	if update.Name != nil || update.Draft != nil || update.GetBody() != "New notes" {
+		t.Errorf("Expected only the body to be updated, got %+v", update)
+	}
+}